@@ -0,0 +1,126 @@
+package capture
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andaru/netconf/session"
+	"github.com/stretchr/testify/assert"
+)
+
+// replyHandler replies to each message with an <rpc-reply>
+type replyHandler struct{ msgs int }
+
+func (h *replyHandler) OnEstablish(s *session.Session) {}
+func (h *replyHandler) OnMessage(s *session.Session) {
+	if _, err := io.ReadAll(s.Incoming()); err != nil {
+		s.State.Status = session.StatusClosed
+		return
+	}
+	h.msgs++
+	s.Outgoing().Write([]byte(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><ok/></rpc-reply>`))
+	s.Outgoing().Close()
+}
+func (h *replyHandler) OnError(s *session.Session) {}
+func (h *replyHandler) OnClose(s *session.Session) {}
+
+type closeBuffer struct{ *bytes.Buffer }
+
+func (cb closeBuffer) Close() error { return nil }
+
+const hello = `<hello xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<capabilities>
+	<capability>urn:ietf:params:netconf:base:1.0</capability>
+	<capability>urn:ietf:params:netconf:base:1.1</capability>
+</capabilities>
+</hello>]]>]]>`
+
+func TestRecordReplay(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		caps    session.Capabilities
+		input   string
+		wantIn  int
+		wantOut int
+	}{
+		{
+			name:    "end of message",
+			caps:    session.Capabilities{"urn:ietf:params:netconf:base:1.0"},
+			input:   hello + `<rpc message-id="1"/>]]>]]><rpc message-id="2"/>]]>]]>`,
+			wantIn:  3,
+			wantOut: 3,
+		},
+		{
+			name:    "chunked",
+			caps:    session.Capabilities{"urn:ietf:params:netconf:base:1.0", "urn:ietf:params:netconf:base:1.1"},
+			input:   hello + "\n#5\n<rpc \n#16\nmessage-id=\"1\"/>\n##\n\n#21\n<rpc message-id=\"2\"/>\n##\n",
+			wantIn:  3,
+			wantOut: 3,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			a := assert.New(t)
+			config := session.Config{ID: 1, Capabilities: tc.caps}
+
+			// record a session
+			capture := &bytes.Buffer{}
+			rec := NewRecorder(capture)
+			dst := closeBuffer{&bytes.Buffer{}}
+			s := session.New(rec.Reader(strings.NewReader(tc.input)), rec.WriteCloser(dst), config)
+			h := &replyHandler{}
+			s.Run(h)
+			a.NoError(rec.Err())
+			a.Equal(2, h.msgs)
+
+			records, err := ReadRecords(capture)
+			a.NoError(err)
+			var gotIn, gotOut int
+			for _, r := range records {
+				a.False(r.Time.IsZero())
+				if r.End && r.Dir == DirIn {
+					gotIn++
+				} else if r.End {
+					gotOut++
+				}
+			}
+			a.Equal(tc.wantIn, gotIn)
+			a.Equal(tc.wantOut, gotOut)
+			a.Equal(dst.String(), string(Expected(records)))
+
+			// replay it against a new session
+			rp := NewReplayer(records)
+			s = session.New(rp, rp, config)
+			h = &replyHandler{}
+			s.Run(h)
+			a.NoError(rp.Err())
+			a.Equal(2, h.msgs)
+			a.Equal(string(Expected(records)), string(rp.Written()))
+		})
+	}
+}
+
+func TestReplayerWaitsForOutgoing(t *testing.T) {
+	a := assert.New(t)
+	now := time.Now()
+	rp := NewReplayer([]Record{
+		{Time: now, Dir: DirOut, Data: []byte("foo]]>]]>"), End: true},
+		{Time: now, Dir: DirIn, Data: []byte("bar]]>]]>"), End: true},
+	})
+	got := make(chan string)
+	go func() {
+		b := make([]byte, 64)
+		n, _ := rp.Read(b)
+		got <- string(b[:n])
+	}()
+	select {
+	case <-got:
+		a.Fail("read returned before the outgoing message was written")
+	case <-time.After(10 * time.Millisecond):
+	}
+	rp.Write([]byte("foo]]"))
+	rp.Write([]byte(">]]>"))
+	a.Equal("bar]]>]]>", <-got)
+}
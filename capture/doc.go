@@ -0,0 +1,17 @@
+/*
+Package capture records and replays NETCONF session transport traffic.
+
+A Recorder wraps the io.Reader and io.WriteCloser passed to session.New,
+writing each read and write to a capture file as a series of JSON
+records, along with the time and the direction of the data. Records are
+split at NETCONF message boundaries, and the record ending each message
+is marked as such, for either framing mode.
+
+A Replayer plays a capture back as a fake peer. Its Read method returns
+data the recorded session read from its peer, while its Write method
+consumes data written by the session under test. Incoming data is only
+returned once the session under test has written as many messages as
+the recorded session had at the same point, so that replays are
+deterministic regardless of timing.
+*/
+package capture
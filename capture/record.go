@@ -0,0 +1,44 @@
+package capture
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// Direction is the direction of a Record's data, relative to the
+// recorded session.
+type Direction string
+
+const (
+	// DirIn is data read by the recorded session from its peer
+	DirIn Direction = "in"
+	// DirOut is data written by the recorded session to its peer
+	DirOut Direction = "out"
+)
+
+// Record is a single capture entry
+type Record struct {
+	// Time is the time the data was read or written
+	Time time.Time `json:"time"`
+	// Dir is the direction of the data
+	Dir Direction `json:"dir"`
+	// Data is the raw transport data, including any framing
+	Data []byte `json:"data,omitempty"`
+	// End is true if this record ends a NETCONF message
+	End bool `json:"end,omitempty"`
+}
+
+// ReadRecords returns all records read from the capture r
+func ReadRecords(r io.Reader) (records []Record, err error) {
+	dec := json.NewDecoder(r)
+	for {
+		var rec Record
+		if err = dec.Decode(&rec); err == io.EOF {
+			return records, nil
+		} else if err != nil {
+			return records, err
+		}
+		records = append(records, rec)
+	}
+}
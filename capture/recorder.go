@@ -0,0 +1,101 @@
+package capture
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/andaru/netconf/framing"
+)
+
+// Recorder writes session transport traffic to a capture.
+//
+// Use Reader and WriteCloser to wrap the transport's reader and writer,
+// respectively, before passing them to session.New.
+type Recorder struct {
+	// Now returns the current time, and defaults to time.Now
+	Now func() time.Time
+
+	mu  sync.Mutex
+	enc *json.Encoder
+	in  *framing.Tracker
+	out *framing.Tracker
+	err error
+}
+
+// NewRecorder returns a new Recorder writing capture records to w
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{Now: time.Now, enc: json.NewEncoder(w), in: framing.NewTracker(), out: framing.NewTracker()}
+}
+
+// Reader returns an io.Reader reading from src, recording all data read
+func (r *Recorder) Reader(src io.Reader) io.Reader { return &recordReader{r: r, src: src} }
+
+// WriteCloser returns an io.WriteCloser writing to dst, recording all data written
+func (r *Recorder) WriteCloser(dst io.WriteCloser) io.WriteCloser {
+	return &recordWriter{r: r, dst: dst}
+}
+
+// Err returns the first error seen while recording, if any
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// record writes b to the capture, split at message boundaries.
+// Framing errors stop message boundary detection for the direction,
+// but data continues to be recorded.
+func (r *Recorder) record(dir Direction, b []byte) {
+	if len(b) == 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return
+	}
+	t := r.in
+	if dir == DirOut {
+		t = r.out
+	}
+	now := r.Now()
+	ends, _ := t.Feed(b)
+	var err error
+	var last int
+	for _, end := range ends {
+		if err == nil {
+			err = r.enc.Encode(Record{Time: now, Dir: dir, Data: b[last:end], End: true})
+		}
+		last = end
+	}
+	if err == nil && last < len(b) {
+		err = r.enc.Encode(Record{Time: now, Dir: dir, Data: b[last:]})
+	}
+	r.err = err
+}
+
+type recordReader struct {
+	r   *Recorder
+	src io.Reader
+}
+
+func (rr *recordReader) Read(p []byte) (n int, err error) {
+	n, err = rr.src.Read(p)
+	rr.r.record(DirIn, p[:n])
+	return n, err
+}
+
+type recordWriter struct {
+	r   *Recorder
+	dst io.WriteCloser
+}
+
+func (rw *recordWriter) Write(b []byte) (n int, err error) {
+	n, err = rw.dst.Write(b)
+	rw.r.record(DirOut, b[:n])
+	return n, err
+}
+
+func (rw *recordWriter) Close() error { return rw.dst.Close() }
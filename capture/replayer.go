@@ -0,0 +1,132 @@
+package capture
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/andaru/netconf/framing"
+)
+
+// Replayer plays back a capture as a fake peer of a session under test,
+// implementing io.Reader and io.WriteCloser for use with session.New.
+//
+// Read returns the data the recorded session read from its peer.
+// Before returning the data of each incoming record, Read waits until
+// the session under test has written as many messages as the recorded
+// session had written at that point in the capture.
+type Replayer struct {
+	// Realtime, if true, delays incoming data by the time between
+	// records seen in the capture.
+	Realtime bool
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	records []Record
+	pending []byte
+	last    time.Time
+	wantOut int
+	gotOut  int
+	out     *framing.Tracker
+	written []byte
+	closed  bool
+	err     error
+}
+
+// NewReplayer returns a new Replayer for the capture records
+func NewReplayer(records []Record) *Replayer {
+	r := &Replayer{records: records, out: framing.NewTracker()}
+	r.cond = sync.NewCond(&r.mu)
+	return r
+}
+
+// Read reads the next recorded incoming data, implementing io.Reader.
+// Returns io.EOF after the last incoming record has been read, or
+// once the Replayer has been closed.
+func (r *Replayer) Read(p []byte) (n int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for len(r.pending) == 0 {
+		if len(r.records) == 0 || r.closed {
+			return 0, io.EOF
+		}
+		rec := r.records[0]
+		if rec.Dir == DirOut {
+			if rec.End {
+				r.wantOut++
+			}
+			r.records = r.records[1:]
+			continue
+		}
+		// wait for the session under test to catch up
+		for r.gotOut < r.wantOut && !r.closed {
+			r.cond.Wait()
+		}
+		if r.closed {
+			return 0, io.EOF
+		}
+		if r.Realtime && !r.last.IsZero() {
+			r.mu.Unlock()
+			time.Sleep(rec.Time.Sub(r.last))
+			r.mu.Lock()
+		}
+		r.last = rec.Time
+		r.pending = rec.Data
+		r.records = r.records[1:]
+	}
+	n = copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+// Write consumes data written by the session under test, implementing io.Writer.
+func (r *Replayer) Write(b []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return 0, io.ErrClosedPipe
+	}
+	r.written = append(r.written, b...)
+	ends, err := r.out.Feed(b)
+	if err != nil && r.err == nil {
+		r.err = err
+	}
+	if len(ends) > 0 {
+		r.gotOut += len(ends)
+		r.cond.Broadcast()
+	}
+	return len(b), nil
+}
+
+// Close closes the Replayer, causing further reads to return io.EOF.
+func (r *Replayer) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	r.cond.Broadcast()
+	return nil
+}
+
+// Written returns all data written by the session under test
+func (r *Replayer) Written() []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]byte(nil), r.written...)
+}
+
+// Err returns any framing error seen in data written by the session under test
+func (r *Replayer) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// Expected returns all data written by the recorded session
+func Expected(records []Record) (b []byte) {
+	for _, rec := range records {
+		if rec.Dir == DirOut {
+			b = append(b, rec.Data...)
+		}
+	}
+	return b
+}
@@ -0,0 +1,502 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/andaru/netconf/yang"
+)
+
+// ncNS is the NETCONF base namespace, of the operation attribute
+const ncNS = "urn:ietf:params:xml:ns:netconf:base:1.0"
+
+// generator generates Go source for the data nodes of YANG modules
+type generator struct {
+	ctx     *yang.Context
+	modules []*yang.Module
+
+	structs bytes.Buffer
+	types   bytes.Buffer
+	// names holds the package-level identifiers in use
+	names map[string]bool
+	// queue holds the containers and lists whose structs are pending
+	queue []pending
+	// enums holds enumeration type names, by *yang.Typedef (for
+	// typedefs) or *yang.Type (for inline enumerations)
+	enums map[interface{}]string
+	// identities holds identity type names, by base identity
+	identities map[*yang.Identity]string
+	// prefixes holds the module prefixes used in identity values, by namespace
+	prefixes map[string]string
+	// fmt is set if the fmt package is imported
+	fmt bool
+}
+
+type pending struct {
+	node *yang.Node
+	name string
+}
+
+// generate returns the gofmt'ed source of package pkg, holding Go types
+// for the data nodes of modules, which are resolved in c.
+func generate(c *yang.Context, pkg string, modules []*yang.Module) ([]byte, error) {
+	g := &generator{
+		ctx:        c,
+		modules:    modules,
+		names:      map[string]bool{"Data": true, "ParseData": true},
+		enums:      map[interface{}]string{},
+		identities: map[*yang.Identity]string{},
+		prefixes:   map[string]string{},
+	}
+	g.data()
+	for len(g.queue) > 0 {
+		p := g.queue[0]
+		g.queue = g.queue[1:]
+		g.structType(p.node, p.name)
+	}
+
+	var b bytes.Buffer
+	names := make([]string, len(modules))
+	for i, m := range modules {
+		names[i] = m.Name
+	}
+	fmt.Fprintf(&b, "// Code generated by yanggen from %s. DO NOT EDIT.\n\n", strings.Join(names, ", "))
+	fmt.Fprintf(&b, "package %s\n\nimport (\n\t\"bytes\"\n\t\"encoding/xml\"\n\t\"errors\"\n", pkg)
+	if g.fmt {
+		b.WriteString("\t\"fmt\"\n")
+	}
+	if len(g.identities) > 0 {
+		b.WriteString("\t\"strings\"\n")
+	}
+	b.WriteString("\n\t\"github.com/andaru/netconf/ops\"\n)\n\n")
+	b.WriteString("// Module namespaces\nconst (\n")
+	for _, m := range modules {
+		fmt.Fprintf(&b, "\t%sNS = %q\n", g.ident(camel(m.Name)), m.Namespace)
+	}
+	b.WriteString(")\n\n")
+	b.Write(g.structs.Bytes())
+	b.Write(g.types.Bytes())
+	if len(g.identities) > 0 {
+		g.identityHelpers(&b)
+	}
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated invalid source: %v", err)
+	}
+	return src, nil
+}
+
+// ident returns a unique package-level identifier based on name
+func (g *generator) ident(name string) string {
+	id := name
+	for i := 2; g.names[id]; i++ {
+		id = fmt.Sprintf("%s%d", name, i)
+	}
+	g.names[id] = true
+	return id
+}
+
+// data generates the Data type, holding the modules' top-level data nodes
+func (g *generator) data() {
+	var nodes []*yang.Node
+	for _, m := range g.modules {
+		nodes = append(nodes, m.Root.DataChildren()...)
+	}
+	w := &g.structs
+	w.WriteString(`// Data holds top-level data nodes, as found in the <config> of an
+// <edit-config> operation or the <data> of a <get> or <get-config> reply
+type Data struct {
+	XMLName xml.Name ` + "`xml:\"data\"`\n")
+	g.fields(w, nodes, "", map[string]bool{"XMLName": true})
+	w.WriteString(`}
+
+// EditConfig returns an <edit-config> operation applying d to the target datastore
+func (d *Data) EditConfig(target ops.Datastore) (*ops.EditConfig, error) {
+	content, err := d.content()
+	if err != nil {
+		return nil, err
+	}
+	return &ops.EditConfig{Target: target, Config: &ops.Inline{Content: content}}, nil
+}
+
+// Filter returns a subtree filter selecting the nodes of d, for a <get>
+// or <get-config> operation
+func (d *Data) Filter() (*ops.Filter, error) {
+	content, err := d.content()
+	if err != nil {
+		return nil, err
+	}
+	return &ops.Filter{Type: "subtree", Content: content}, nil
+}
+
+// content returns the XML encoding of the nodes of d
+func (d *Data) content() ([]byte, error) {
+	b, err := xml.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(bytes.TrimPrefix(b, []byte("<data>")), []byte("</data>")), nil
+}
+
+// ParseData decodes the <data> of a <get> or <get-config> reply
+func ParseData(reply *ops.RPCReply) (*Data, error) {
+	if reply.Data == nil {
+		return nil, errors.New("reply has no data")
+	}
+	var b bytes.Buffer
+	b.WriteString("<data>")
+	b.Write(reply.Data.Content)
+	b.WriteString("</data>")
+	d := &Data{}
+	if err := xml.Unmarshal(b.Bytes(), d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+`)
+}
+
+// structType generates the struct type name for the container or list s
+func (g *generator) structType(s *yang.Node, name string) {
+	w := &g.structs
+	fmt.Fprintf(w, "// %s is the %s %s\ntype %s struct {\n", name, s.Kind, s.Path(), name)
+	fmt.Fprintf(w, "\tXMLName xml.Name `xml:\"%s %s\"`\n", s.Module.Namespace, s.Name)
+	w.WriteString("\t// Operation is the <edit-config> operation attribute (e.g., \"delete\")\n")
+	fmt.Fprintf(w, "\tOperation string `xml:\"%s operation,attr,omitempty\"`\n", ncNS)
+	g.fields(w, s.DataChildren(), name, map[string]bool{"XMLName": true, "Operation": true})
+	w.WriteString("}\n\n")
+}
+
+// fields writes the struct fields of the data nodes, children of the
+// struct type parent, whose field names in use are held by used
+func (g *generator) fields(w *bytes.Buffer, nodes []*yang.Node, parent string, used map[string]bool) {
+	for _, n := range nodes {
+		if !n.IsData() {
+			continue
+		}
+		name := fieldName(n, used)
+		tag := n.Module.Namespace + " " + n.Name
+		var typ string
+		switch n.Kind {
+		case yang.KindContainer:
+			typ = "*" + g.enqueue(n, parent+name)
+			tag += ",omitempty"
+		case yang.KindList:
+			typ = "[]" + g.enqueue(n, parent+name)
+		case yang.KindAnydata, yang.KindAnyxml:
+			typ = "*ops.Inline"
+			tag += ",omitempty"
+		case yang.KindLeafList:
+			typ = "[]" + g.goType(n, n.Type, parent+name, 0)
+		case yang.KindLeaf:
+			typ = g.goType(n, n.Type, parent+name, 0)
+			if !n.IsKey() && !(n.Mandatory && !inChoice(n)) {
+				typ = "*" + typ
+				tag += ",omitempty"
+			}
+		}
+		fmt.Fprintf(w, "\t%s %s `xml:\"%s\"`\n", name, typ, tag)
+	}
+}
+
+// enqueue queues the struct type for the container or list n, returning
+// its type name
+func (g *generator) enqueue(n *yang.Node, name string) string {
+	name = g.ident(name)
+	g.queue = append(g.queue, pending{n, name})
+	return name
+}
+
+// fieldName returns a unique field name for n
+func fieldName(n *yang.Node, used map[string]bool) string {
+	name := camel(n.Name)
+	if used[name] {
+		name += camel(n.Module.Name)
+	}
+	for i := 2; used[name]; i++ {
+		name = fmt.Sprintf("%s%d", camel(n.Name), i)
+	}
+	used[name] = true
+	return name
+}
+
+// inChoice returns true if the data node n is within a choice of its
+// parent data node
+func inChoice(n *yang.Node) bool {
+	return n.Parent != nil && (n.Parent.Kind == yang.KindChoice || n.Parent.Kind == yang.KindCase)
+}
+
+// goType returns the Go type of leaf or leaf-list n's type t, named name
+// if a type is generated. depth limits leafref chains.
+func (g *generator) goType(n *yang.Node, t *yang.Type, name string, depth int) string {
+	switch t.Kind {
+	case yang.TypeInt8, yang.TypeInt16, yang.TypeInt32, yang.TypeInt64,
+		yang.TypeUint8, yang.TypeUint16, yang.TypeUint32, yang.TypeUint64:
+		return t.Kind.String()
+	case yang.TypeBoolean:
+		return "bool"
+	case yang.TypeEmpty:
+		return "ops.Empty"
+	case yang.TypeEnumeration:
+		return g.enumType(t, name)
+	case yang.TypeIdentityref:
+		if len(t.Bases) > 0 {
+			return g.identityType(t.Bases[0])
+		}
+	case yang.TypeLeafref:
+		if target := t.Target; target != nil && depth < 8 {
+			return g.goType(target, target.Type, name, depth+1)
+		}
+	}
+	// decimal64, string, bits, binary, union and instance-identifier
+	// values are held in their XML lexical form
+	return "string"
+}
+
+// enumType generates the enumeration type of t, returning its name
+func (g *generator) enumType(t *yang.Type, name string) string {
+	var key interface{} = t
+	if t.Typedef != nil && len(t.Enums) == len(t.Typedef.Type.Enums) {
+		key, name = t.Typedef, camel(t.Typedef.Name)
+	}
+	if typ, ok := g.enums[key]; ok {
+		return typ
+	}
+	typ := g.ident(name)
+	g.enums[key] = typ
+	g.fmt = true
+	names := g.ident(lowerInitial(typ) + "Names")
+
+	w := &g.types
+	fmt.Fprintf(w, "// %s is an enumeration\ntype %s int64\n\n// %s values\nconst (\n", typ, typ, typ)
+	consts := make([]string, len(t.Enums))
+	for i, e := range t.Enums {
+		consts[i] = g.constName(typ, e.Name)
+		fmt.Fprintf(w, "\t%s %s = %d\n", consts[i], typ, e.Value)
+	}
+	fmt.Fprintf(w, ")\n\nvar %s = map[%s]string{\n", names, typ)
+	for i, e := range t.Enums {
+		fmt.Fprintf(w, "\t%s: %q,\n", consts[i], e.Name)
+	}
+	fmt.Fprintf(w, `}
+
+// String returns the enum's name
+func (e %[1]s) String() string {
+	if s, ok := %[2]s[e]; ok {
+		return s
+	}
+	return fmt.Sprintf("%[1]s(%%d)", int64(e))
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (e %[1]s) MarshalText() ([]byte, error) {
+	if s, ok := %[2]s[e]; ok {
+		return []byte(s), nil
+	}
+	return nil, fmt.Errorf("invalid %[1]s value %%d", int64(e))
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (e *%[1]s) UnmarshalText(b []byte) error {
+	for v, s := range %[2]s {
+		if s == string(b) {
+			*e = v
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid %[1]s %%q", b)
+}
+
+`, typ, names)
+	return typ
+}
+
+// constName returns a unique constant name for the value name of type typ
+func (g *generator) constName(typ, name string) string {
+	c := camel(name)
+	if c == "" {
+		c = "Value"
+	}
+	return g.ident(typ + c)
+}
+
+// identityType generates the type of identities derived from base,
+// returning its name
+func (g *generator) identityType(base *yang.Identity) string {
+	if typ, ok := g.identities[base]; ok {
+		return typ
+	}
+	typ := g.ident(camel(base.Name))
+	g.identities[base] = typ
+	g.fmt = true
+	names := g.ident(lowerInitial(typ) + "Names")
+
+	var derived []*yang.Identity
+	for _, m := range g.ctx.Modules {
+		for _, id := range m.Identities {
+			if id.DerivedFrom(base) {
+				derived = append(derived, id)
+			}
+		}
+	}
+	sort.Slice(derived, func(i, j int) bool {
+		a, b := derived[i], derived[j]
+		if a.Module != b.Module {
+			return a.Module.Name < b.Module.Name
+		}
+		if a.Pos.File != b.Pos.File {
+			return a.Pos.File < b.Pos.File
+		}
+		return a.Pos.Line < b.Pos.Line
+	})
+
+	w := &g.types
+	fmt.Fprintf(w, "// %s is an identity derived from %s:%s\ntype %s int\n\n// %s values\nconst (\n",
+		typ, base.Module.Name, base.Name, typ, typ)
+	consts := make([]string, len(derived))
+	for i, id := range derived {
+		consts[i] = g.constName(typ, id.Name)
+		if i == 0 {
+			fmt.Fprintf(w, "\t%s %s = iota + 1\n", consts[i], typ)
+		} else {
+			fmt.Fprintf(w, "\t%s\n", consts[i])
+		}
+	}
+	fmt.Fprintf(w, ")\n\nvar %s = map[%s]xml.Name{\n", names, typ)
+	for i, id := range derived {
+		g.prefixes[id.Module.Namespace] = id.Module.Prefix
+		fmt.Fprintf(w, "\t%s: {Space: %q, Local: %q},\n", consts[i], id.Module.Namespace, id.Name)
+	}
+	fmt.Fprintf(w, `}
+
+// String returns the identity's name
+func (v %[1]s) String() string {
+	if name, ok := %[2]s[v]; ok {
+		return name.Local
+	}
+	return fmt.Sprintf("%[1]s(%%d)", int(v))
+}
+
+// MarshalXML implements xml.Marshaler, encoding the identity with a
+// namespace prefix
+func (v %[1]s) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	name, ok := %[2]s[v]
+	if !ok {
+		return fmt.Errorf("invalid %[1]s value %%d", int(v))
+	}
+	return marshalIdentity(e, start, name)
+}
+
+// UnmarshalXML implements xml.Unmarshaler
+func (v *%[1]s) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	name, err := unmarshalIdentity(d, start)
+	if err != nil {
+		return err
+	}
+	for id, n := range %[2]s {
+		if n.Local == name.Local && (name.Space == "" || n.Space == name.Space) {
+			*v = id
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid %[1]s %%q", name.Local)
+}
+
+`, typ, names)
+	return typ
+}
+
+// identityHelpers writes the functions marshaling identity values
+func (g *generator) identityHelpers(w *bytes.Buffer) {
+	spaces := make([]string, 0, len(g.prefixes))
+	for ns := range g.prefixes {
+		spaces = append(spaces, ns)
+	}
+	sort.Strings(spaces)
+	w.WriteString("// identityPrefixes holds the prefixes of identity modules, by namespace\nvar identityPrefixes = map[string]string{\n")
+	for _, ns := range spaces {
+		fmt.Fprintf(w, "\t%q: %q,\n", ns, g.prefixes[ns])
+	}
+	w.WriteString(`}
+
+// marshalIdentity encodes the identity name as the content of start
+func marshalIdentity(e *xml.Encoder, start xml.StartElement, name xml.Name) error {
+	prefix := identityPrefixes[name.Space]
+	start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "xmlns:" + prefix}, Value: name.Space})
+	return e.EncodeElement(prefix+":"+name.Local, start)
+}
+
+// unmarshalIdentity decodes the identity named by the content of start.
+// Its prefix is resolved using namespaces declared by start, or else by
+// the identity modules' prefixes. If the prefix is unknown, the name's
+// Space is empty.
+func unmarshalIdentity(d *xml.Decoder, start xml.StartElement) (xml.Name, error) {
+	var s string
+	if err := d.DecodeElement(&s, &start); err != nil {
+		return xml.Name{}, err
+	}
+	i := strings.IndexByte(s, ':')
+	if i < 0 {
+		return xml.Name{Local: s}, nil
+	}
+	name := xml.Name{Local: s[i+1:]}
+	for _, a := range start.Attr {
+		if a.Name.Space == "xmlns" && a.Name.Local == s[:i] {
+			name.Space = a.Value
+			return name, nil
+		}
+	}
+	for ns, prefix := range identityPrefixes {
+		if prefix == s[:i] {
+			name.Space = ns
+		}
+	}
+	return name, nil
+}
+`)
+}
+
+// initialisms are words written in upper case in Go identifiers
+var initialisms = map[string]bool{
+	"ACL": true, "API": true, "ASCII": true, "CPU": true, "DNS": true, "EOF": true, "HTTP": true,
+	"HTTPS": true, "ID": true, "IP": true, "JSON": true, "MAC": true, "MTU": true, "RPC": true, "SCTP": true,
+	"SSH": true, "TCP": true, "TLS": true, "TTL": true, "UDP": true, "UID": true, "URI": true,
+	"URL": true, "UUID": true, "VLAN": true, "XML": true,
+}
+
+// camel returns the YANG identifier s in exported Go camel case, e.g.,
+// "ip-address" becomes "IPAddress"
+func camel(s string) string {
+	var b strings.Builder
+	for _, w := range strings.FieldsFunc(s, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		if u := strings.ToUpper(w); initialisms[u] {
+			b.WriteString(u)
+			continue
+		}
+		r := []rune(w)
+		r[0] = unicode.ToUpper(r[0])
+		b.WriteString(string(r))
+	}
+	if r := []rune(b.String()); len(r) > 0 && !unicode.IsLetter(r[0]) {
+		return "X" + b.String()
+	}
+	return b.String()
+}
+
+// lowerInitial returns the camel case identifier s unexported, e.g.,
+// "MTUValue" becomes "mtuValue"
+func lowerInitial(s string) string {
+	r := []rune(s)
+	for i := 0; i < len(r) && unicode.IsUpper(r[i]); i++ {
+		if i > 0 && i+1 < len(r) && unicode.IsLower(r[i+1]) {
+			break
+		}
+		r[i] = unicode.ToLower(r[i])
+	}
+	return string(r)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andaru/netconf/yang"
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update", false, "update the generated example package")
+
+// TestGenerate checks the generated example package is up to date; its
+// own tests exercise the generated code.
+func TestGenerate(t *testing.T) {
+	assert := assert.New(t)
+	c := yang.NewContext("testdata")
+	if err := c.Load("example-system", "example-system-ext"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Resolve(); err != nil {
+		t.Fatal(err)
+	}
+	src, err := generate(c, "example", []*yang.Module{c.Module("example-system"), c.Module("example-system-ext")})
+	if !assert.NoError(err) {
+		return
+	}
+	file := filepath.Join("internal", "example", "example.go")
+	if *update {
+		if err := os.WriteFile(file, src, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(file)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(string(want), string(src), "run go generate in internal/example")
+}
+
+func TestCamel(t *testing.T) {
+	assert := assert.New(t)
+	for in, want := range map[string]string{
+		"hostname":      "Hostname",
+		"ip-address":    "IPAddress",
+		"dns_server":    "DNSServer",
+		"mtu":           "MTU",
+		"10mbps":        "X10mbps",
+		"interface.id":  "InterfaceID",
+		"ietf-netconf":  "IetfNetconf",
+		"already-Camel": "AlreadyCamel",
+	} {
+		assert.Equal(want, camel(in), in)
+	}
+	assert.Equal("mtuNames", lowerInitial("MTUNames"))
+	assert.Equal("systemMode", lowerInitial("SystemMode"))
+	assert.Equal("ipAddress", lowerInitial("IPAddress"))
+}
@@ -0,0 +1,5 @@
+// Package example holds the Go types generated by yanggen for the
+// example-system module, as used by the generator's tests.
+package example
+
+//go:generate go run github.com/andaru/netconf/cmd/yanggen -path ../../testdata -package example -o example.go example-system example-system-ext
@@ -0,0 +1,148 @@
+package example
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/andaru/netconf/data"
+	"github.com/andaru/netconf/netconftest"
+	"github.com/andaru/netconf/ops"
+	"github.com/andaru/netconf/yang"
+	"github.com/stretchr/testify/assert"
+)
+
+func loadSchema(t *testing.T) *yang.Context {
+	t.Helper()
+	c := yang.NewContext("../../testdata")
+	if err := c.Load("example-system", "example-system-ext"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Resolve(); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestEditConfig(t *testing.T) {
+	assert := assert.New(t)
+	mtu, port := uint16(1500), uint16(830)
+	mode, proto, location := SystemModeManual, ProtocolSCTP, "lab"
+	d := &Data{
+		System: &System{
+			Hostname:  "r1",
+			MTU:       &mtu,
+			Mode:      &mode,
+			Proto:     &proto,
+			Protocols: []Protocol{ProtocolTCP, ProtocolUDP},
+			DNSServer: []string{"10.0.0.53"},
+			Server:    []SystemServer{{Name: "a", Port: &port}},
+			SSH:       &ops.Empty{},
+			Location:  &location,
+		},
+		Interface: []Interface{{Name: "eth0", Operation: "delete"}},
+	}
+	edit, err := d.EditConfig(ops.Candidate)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(ops.Candidate, edit.Target)
+	netconftest.AssertXMLEqual(t, `<system xmlns="urn:example:system"><hostname>r1</hostname><mtu>1500</mtu>`+
+		`<mode>manual</mode><proto xmlns:ext="urn:example:system-ext">ext:sctp</proto>`+
+		`<dns-server>10.0.0.53</dns-server>`+
+		`<protocols xmlns:sys="urn:example:system">sys:tcp</protocols><protocols xmlns:sys="urn:example:system">sys:udp</protocols>`+
+		`<server><name>a</name><port>830</port></server><ssh></ssh>`+
+		`<location xmlns="urn:example:system-ext">lab</location></system>`+
+		`<interface xmlns="urn:example:system" xmlns:nc="urn:ietf:params:xml:ns:netconf:base:1.0" nc:operation="delete">`+
+		`<name>eth0</name></interface>`,
+		string(edit.Config.Content))
+
+	// the configuration is valid according to the schema
+	root, err := data.Parse(bytes.NewReader(edit.Config.Content), loadSchema(t))
+	if assert.NoError(err) {
+		assert.NoError(data.Validate(root, data.Options{}))
+	}
+
+	// the operation encodes
+	var b bytes.Buffer
+	assert.NoError(xml.NewEncoder(&b).Encode(&ops.RPC{MessageID: "1", Operation: edit}))
+	assert.Contains(b.String(), `<target><candidate></candidate></target><config><system xmlns="urn:example:system">`)
+}
+
+func TestFilter(t *testing.T) {
+	assert := assert.New(t)
+	f, err := (&Data{Interface: []Interface{{Name: "eth0"}}}).Filter()
+	if assert.NoError(err) {
+		assert.Equal("subtree", f.Type)
+		netconftest.AssertXMLEqual(t, `<interface xmlns="urn:example:system"><name>eth0</name></interface>`, string(f.Content))
+	}
+}
+
+func TestParseData(t *testing.T) {
+	assert := assert.New(t)
+	reply, err := ops.DecodeReply(strings.NewReader(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="1">` +
+		`<data><system xmlns="urn:example:system" xmlns:sys="urn:example:system" xmlns:x="urn:example:system-ext">` +
+		`<hostname>r1</hostname><admin-state>testing</admin-state><proto>x:sctp</proto>` +
+		`<protocols>sys:udp</protocols><protocols>tcp</protocols>` +
+		`<server><name>a</name><state>up</state></server><uptime>18446744073709551615</uptime>` +
+		`<logging><level>3</level></logging><tls/><extra><any xmlns="urn:other">thing</any></extra></system>` +
+		`<interface xmlns="urn:example:system"><name>eth0</name></interface>` +
+		`<interface xmlns="urn:example:system"><name>eth1</name></interface></data></rpc-reply>`))
+	if !assert.NoError(err) {
+		return
+	}
+	d, err := ParseData(reply)
+	if !assert.NoError(err) {
+		return
+	}
+	s := d.System
+	if !assert.NotNil(s) {
+		return
+	}
+	assert.Equal("r1", s.Hostname)
+	if assert.NotNil(s.AdminState) {
+		assert.Equal(AdminStateTesting, *s.AdminState)
+		assert.Equal("testing", s.AdminState.String())
+	}
+	if assert.NotNil(s.Proto) {
+		// the prefix is resolved by module prefix when declared on an ancestor
+		assert.Equal(ProtocolSCTP, *s.Proto)
+	}
+	assert.Equal([]Protocol{ProtocolUDP, ProtocolTCP}, s.Protocols)
+	if assert.Len(s.Server, 1) && assert.NotNil(s.Server[0].State) {
+		assert.Equal(AdminStateUp, *s.Server[0].State)
+	}
+	if assert.NotNil(s.Uptime) {
+		assert.Equal(uint64(18446744073709551615), *s.Uptime)
+	}
+	if assert.NotNil(s.Logging) {
+		assert.Equal(uint8(3), s.Logging.Level)
+	}
+	assert.NotNil(s.TLS)
+	assert.Nil(s.SSH)
+	if assert.NotNil(s.Extra) {
+		assert.Equal(`<any xmlns="urn:other">thing</any>`, string(s.Extra.Content))
+	}
+	assert.Equal([]Interface{
+		{XMLName: xml.Name{Space: ExampleSystemNS, Local: "interface"}, Name: "eth0"},
+		{XMLName: xml.Name{Space: ExampleSystemNS, Local: "interface"}, Name: "eth1"},
+	}, d.Interface)
+
+	_, err = ParseData(&ops.RPCReply{OK: &ops.Empty{}})
+	assert.EqualError(err, "reply has no data")
+}
+
+func TestEnumErrors(t *testing.T) {
+	assert := assert.New(t)
+	var m SystemMode
+	assert.EqualError(m.UnmarshalText([]byte("bogus")), `invalid SystemMode "bogus"`)
+	_, err := SystemMode(9).MarshalText()
+	assert.EqualError(err, "invalid SystemMode value 9")
+	assert.Equal("SystemMode(9)", SystemMode(9).String())
+
+	var p Protocol
+	assert.Error(xml.Unmarshal([]byte(`<proto>sys:bogus</proto>`), &p))
+	_, err = xml.Marshal(Protocol(0))
+	assert.EqualError(err, "invalid Protocol value 0")
+}
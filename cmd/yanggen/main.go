@@ -0,0 +1,79 @@
+// Command yanggen generates Go types for the data nodes of YANG modules,
+// with encoding/xml tags in each module's namespace, for use with the
+// ops package.
+//
+// Usage:
+//
+//	yanggen [-path dir[,dir...]] [-package name] [-o file] module...
+//
+// Each named module is loaded from the search path, along with its
+// imports, and a Data type is generated holding its top-level data
+// nodes. Nodes added by augments from the named modules are included.
+//
+// Containers and lists become structs, and leaf-lists slices. Optional
+// leafs are pointers, while list keys and mandatory leafs are values.
+// Enumerations and identityrefs become integer types with constants for
+// their values (all identities derived from the identityref's base,
+// in any loaded module). Values of decimal64, bits, binary, union and
+// instance-identifier types are strings, in their XML lexical form, and
+// anydata and anyxml nodes are *ops.Inline. RPCs, actions and
+// notifications are not generated.
+//
+// Data's EditConfig and Filter methods build <edit-config> operations
+// and <get> filters, while ParseData decodes <get> and <get-config>
+// replies.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/andaru/netconf/yang"
+)
+
+func main() {
+	path := flag.String("path", ".", "comma separated module search `directories`")
+	pkg := flag.String("package", "main", "generated package `name`")
+	out := flag.String("o", "", "output `file` (default stdout)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: yanggen [flags] module...\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := run(strings.Split(*path, ","), *pkg, *out, flag.Args()); err != nil {
+		fmt.Fprintln(os.Stderr, "yanggen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(path []string, pkg, out string, names []string) error {
+	c := yang.NewContext(path...)
+	if err := c.Load(names...); err != nil {
+		return err
+	}
+	if err := c.Resolve(); err != nil {
+		return err
+	}
+	modules := make([]*yang.Module, len(names))
+	for i, name := range names {
+		if i := strings.IndexByte(name, '@'); i >= 0 {
+			name = name[:i]
+		}
+		modules[i] = c.Module(name)
+	}
+	src, err := generate(c, pkg, modules)
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	return os.WriteFile(out, src, 0o644)
+}
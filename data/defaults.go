@@ -0,0 +1,229 @@
+package data
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/andaru/netconf/ops"
+	"github.com/andaru/netconf/xmlutil"
+	"github.com/andaru/netconf/yang"
+)
+
+// defaultAttr is the RFC6243 wd:default attribute of report-all-tagged mode
+var defaultAttr = xml.Attr{Name: xml.Name{Space: ops.DefaultNS, Local: "default"}, Value: "true"}
+
+// WithDefaults applies the RFC6243 with-defaults retrieval mode to the
+// tree root, which holds the explicitly set data of a datastore (the
+// explicit basic-mode), before it is filtered for a reply.
+//
+// In report-all modes, leafs and leaf-lists with schema defaults are
+// added where missing, including those of default cases, and within
+// non-presence containers created to hold them. Defaults whose when
+// conditions are not satisfied are not added. In report-all-tagged mode,
+// nodes holding their default value are marked with the wd:default
+// attribute. In trim mode, leafs holding their default value are
+// removed. Explicit mode leaves the tree unchanged.
+//
+// Defaults of state nodes are added only if state is true. An unknown
+// mode is reported as an *ops.RPCError.
+func WithDefaults(root *Node, mode string, state bool) error {
+	d := &defaulter{state: state, xpaths: map[*yang.XPath]*XPath{}}
+	switch mode {
+	case "", ops.WithDefaultsExplicit:
+		return nil
+	case ops.WithDefaultsTrim:
+		trim(root)
+		return nil
+	case ops.WithDefaultsReportAll:
+	case ops.WithDefaultsReportAllTagged:
+		d.tag = true
+	default:
+		return invalidValue(fmt.Sprintf("unsupported with-defaults mode %q", mode))
+	}
+	d.node(root, topLevel(root.Context()))
+	return nil
+}
+
+// IsDefault returns true if n is a leaf holding its schema default value,
+// or a leaf-list instance of a leaf-list holding only default values.
+func (n *Node) IsDefault() bool {
+	s := n.Schema
+	if s == nil || len(s.Default) == 0 || len(n.Children) > 0 {
+		return false
+	}
+	switch s.Kind {
+	case yang.KindLeaf:
+		return strings.TrimSpace(n.Value) == s.Default[0]
+	case yang.KindLeafList:
+		var values []string
+		for _, c := range n.Parent.Children {
+			if c.Schema == s {
+				values = append(values, strings.TrimSpace(c.Value))
+			}
+		}
+		if len(values) != len(s.Default) {
+			return false
+		}
+		for i := range values {
+			if values[i] != s.Default[i] {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+type defaulter struct {
+	tag, state bool
+	xpaths     map[*yang.XPath]*XPath
+}
+
+// node adds the defaults of the schema nodes ss to n, returning true
+// if any were added
+func (d *defaulter) node(n *Node, ss []*yang.Node) (added bool) {
+	for _, s := range ss {
+		if !d.state && !s.Config {
+			continue
+		}
+		switch s.Kind {
+		case yang.KindChoice:
+			if c := activeCase(n, s); c != nil {
+				added = d.node(n, c.Children) || added
+			}
+		case yang.KindCase:
+			added = d.node(n, s.Children) || added
+		case yang.KindLeaf, yang.KindLeafList:
+			if instances := n.instances(s); len(instances) > 0 {
+				for _, c := range instances {
+					d.mark(c)
+				}
+				continue
+			}
+			for _, v := range s.Default {
+				c := &Node{Name: xml.Name{Space: s.Module.Namespace, Local: s.Name}, Value: v}
+				if isValueKind(s.Type) {
+					c.NS = xmlutil.PrefixMap{s.Module.Prefix: s.Module.Namespace}
+				}
+				if !d.append(n, c) {
+					break
+				}
+				added = true
+			}
+			// leaf-list instances are default once all are added
+			for _, c := range n.instances(s) {
+				d.mark(c)
+			}
+		case yang.KindContainer:
+			if instances := n.instances(s); len(instances) > 0 {
+				for _, c := range instances {
+					added = d.node(c, c.Schema.Children) || added
+				}
+				continue
+			}
+			if s.Presence != "" {
+				continue
+			}
+			c := &Node{Name: xml.Name{Space: s.Module.Namespace, Local: s.Name}}
+			if !d.append(n, c) {
+				continue
+			}
+			if d.node(c, s.Children) {
+				added = true
+			} else {
+				c.Remove()
+			}
+		case yang.KindList:
+			for _, c := range n.instances(s) {
+				added = d.node(c, c.Schema.Children) || added
+			}
+		}
+	}
+	return added
+}
+
+// append appends the default node c to n, returning false (and removing
+// c) if its when conditions are not satisfied
+func (d *defaulter) append(n, c *Node) bool {
+	if n.Append(c); c.Schema == nil {
+		c.Remove()
+		return false
+	}
+	for i := range c.Schema.When {
+		ctx := c
+		if c.Schema.When[i].Parent {
+			ctx = n
+		}
+		if !d.eval(ctx, &c.Schema.When[i].XPath) {
+			c.Remove()
+			return false
+		}
+	}
+	return true
+}
+
+// eval evaluates the YANG XPath expression x as a boolean, with context
+// node n. Expressions failing to evaluate are false.
+func (d *defaulter) eval(n *Node, x *yang.XPath) bool {
+	compiled := d.xpaths[x]
+	if compiled == nil {
+		var err error
+		if compiled, err = compileYANG(*x); err != nil {
+			return false
+		}
+		d.xpaths[x] = compiled
+	}
+	ok, err := compiled.Bool(n)
+	return ok && err == nil
+}
+
+// mark tags n with the wd:default attribute, if tagging default nodes
+func (d *defaulter) mark(n *Node) {
+	if !d.tag || !n.IsDefault() {
+		return
+	}
+	for _, a := range n.Attr {
+		if a.Name == defaultAttr.Name {
+			return
+		}
+	}
+	n.Attr = append(n.Attr, defaultAttr)
+}
+
+// instances returns the children of n with schema node s
+func (n *Node) instances(s *yang.Node) (nodes []*Node) {
+	for _, c := range n.Children {
+		if c.Schema == s {
+			nodes = append(nodes, c)
+		}
+	}
+	return
+}
+
+// activeCase returns the case of the choice s with data in n, or its
+// default case
+func activeCase(n *Node, s *yang.Node) *yang.Node {
+	for _, c := range n.Children {
+		for p := c.Schema; p != nil && p != s; p = p.Parent {
+			if p.Parent == s {
+				return p
+			}
+		}
+	}
+	if len(s.Default) > 0 {
+		return s.Child(nil, s.Default[0])
+	}
+	return nil
+}
+
+// trim removes the descendant leafs of n holding their default value
+func trim(n *Node) {
+	for _, c := range append([]*Node(nil), n.Children...) {
+		if c.Schema != nil && c.Schema.Kind == yang.KindLeaf && !c.Schema.IsKey() && c.IsDefault() {
+			c.Remove()
+			continue
+		}
+		trim(c)
+	}
+}
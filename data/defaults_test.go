@@ -0,0 +1,91 @@
+package data
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/andaru/netconf/ops"
+	"github.com/andaru/netconf/yang"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithDefaults(t *testing.T) {
+	c := yang.NewContext("testdata")
+	if err := c.Load("example-defaults"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Resolve(); err != nil {
+		t.Fatal(err)
+	}
+	const (
+		explicit = `<settings xmlns="urn:example:defaults"><mtu>1500</mtu><peer><name>p1</name></peer></settings>`
+		wd       = `xmlns:wd="urn:ietf:params:xml:ns:netconf:default:1.0" wd:default="true"`
+	)
+	for _, tc := range []struct {
+		name    string
+		xml     string
+		mode    string
+		state   bool
+		want    string
+		wantErr string
+	}{
+		{
+			name: "explicit",
+			xml:  explicit,
+			mode: ops.WithDefaultsExplicit,
+			want: explicit,
+		},
+		{
+			name: "report-all",
+			xml:  explicit,
+			mode: ops.WithDefaultsReportAll,
+			want: `<settings xmlns="urn:example:defaults"><mtu>1500</mtu><peer><name>p1</name><port>179</port></peer>` +
+				`<dns>a</dns><dns>b</dns><timers><hello>10</hello></timers><rate>5</rate></settings>`,
+		},
+		{
+			name:  "report-all state",
+			xml:   `<settings xmlns="urn:example:defaults"><delay>7</delay><timers><hello>30</hello></timers></settings>`,
+			mode:  ops.WithDefaultsReportAll,
+			state: true,
+			want: `<settings xmlns="urn:example:defaults"><delay>7</delay><timers><hello>30</hello><dead>40</dead></timers>` +
+				`<mtu>1500</mtu><dns>a</dns><dns>b</dns><counter>0</counter></settings>`,
+		},
+		{
+			name: "report-all-tagged",
+			xml:  explicit,
+			mode: ops.WithDefaultsReportAllTagged,
+			want: `<settings xmlns="urn:example:defaults"><mtu ` + wd + `>1500</mtu><peer><name>p1</name><port ` + wd + `>179</port></peer>` +
+				`<dns ` + wd + `>a</dns><dns ` + wd + `>b</dns><timers><hello ` + wd + `>10</hello></timers><rate ` + wd + `>5</rate></settings>`,
+		},
+		{
+			name: "trim",
+			xml:  `<settings xmlns="urn:example:defaults"><mtu>1500</mtu><timers><hello>11</hello></timers><peer><name>p1</name><port>179</port></peer></settings>`,
+			mode: ops.WithDefaultsTrim,
+			want: `<settings xmlns="urn:example:defaults"><timers><hello>11</hello></timers><peer><name>p1</name></peer></settings>`,
+		},
+		{
+			name:    "unknown mode",
+			mode:    "report-some",
+			wantErr: `rpc-error: protocol: invalid-value: unsupported with-defaults mode "report-some"`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			a := assert.New(t)
+			root, err := Parse(strings.NewReader(tc.xml), c)
+			if !a.NoError(err) {
+				return
+			}
+			err = WithDefaults(root, tc.mode, tc.state)
+			if tc.wantErr != "" {
+				a.EqualError(err, tc.wantErr)
+				return
+			}
+			if !a.NoError(err) {
+				return
+			}
+			var b strings.Builder
+			a.NoError(root.WriteXML(&b))
+			a.Equal(tc.want, b.String())
+		})
+	}
+}
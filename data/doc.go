@@ -0,0 +1,66 @@
+/*
+Package data holds YANG modelled NETCONF data trees, bound to the schema
+of a yang.Context, and validates them.
+
+Parse reads XML data, such as the content of an <edit-config> <config>
+element or a <get-config> reply's <data> element, into a tree of Nodes:
+
+	root, err := data.Parse(r, schema)
+
+Validate checks a tree against its schema, reporting unknown elements,
+type, range, length and pattern violations, missing mandatory nodes and
+list keys, duplicate entries, unique, min-elements and max-elements
+violations, leafref and instance-identifier targets, and must and when
+conditions. Each violation is an ops.RPCError, with the error-tag,
+error-app-tag, error-path and error-info required by RFC6241 and RFC7950
+section 15, so a server may answer <validate> directly:
+
+	if err := data.Validate(root, data.Options{}); err != nil {
+		if errs, ok := err.(data.Errors); ok {
+			reply.Errors = errs
+		}
+	}
+
+Use Options.Partial to validate <edit-config> content before it is
+merged into a datastore, deferring the constraints that apply to the
+resulting datastore, and Options.State for data including state nodes.
+
+# Encoding
+
+WriteXML writes a tree as XML. WriteJSON and ParseJSON encode and decode
+trees as RFC7951 JSON, using the schema's type information: members are
+qualified by module name where their namespace changes, 64-bit integers
+and decimal64 values are strings, empty values are [null], and
+identityref and instance-identifier values use module names in place of
+XML namespace prefixes. XMLToJSON and JSONToXML transcode between the two:
+
+	err := data.XMLToJSON(w, r, schema)
+
+EncodeXML and EncodeJSON encode a subset of a tree's nodes, while the
+DecodeXML and DecodeJSON methods decode content into an existing node,
+such as a list entry or an rpc's input.
+
+# Retrieval
+
+SubtreeFilter and XPathFilter select the nodes of a tree matched by an
+RFC6241 <filter>, for replies to <get> and <get-config>. GetData answers
+the NMDA <get-data> operation (RFC8526), applying its filters, config
+filter, origin filters and max-depth to the content of the selected
+datastore:
+
+	tree, err := data.GetData(operational, op)
+
+WithDefaults applies an RFC6243 with-defaults mode to a tree, adding,
+tagging or trimming the default values of its schema's leafs and
+leaf-lists.
+
+The RFC8342 or:origin annotations of operational data are read and set
+with the Origin and SetOrigin methods, and are encoded by WriteXML.
+
+# XPath
+
+CompileXPath compiles XPath 1.0 expressions, which are evaluated over
+data trees with the YANG function library (current, deref, re-match,
+derived-from, derived-from-or-self, enum-value and bit-is-set).
+*/
+package data
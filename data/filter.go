@@ -0,0 +1,167 @@
+package data
+
+import (
+	"io"
+	"strings"
+
+	"github.com/andaru/netconf/yang"
+)
+
+// SubtreeFilter returns a copy of the tree root holding the nodes
+// selected by the RFC6241 subtree filter read from r, the content of a
+// <filter type="subtree"> element. An empty filter selects no nodes.
+//
+// Filter elements with no namespace match elements of any namespace.
+// List entries are returned with their keys.
+func SubtreeFilter(root *Node, r io.Reader) (*Node, error) {
+	filter, err := Parse(r, root.Context())
+	if err != nil {
+		return nil, err
+	}
+	f := selection{}
+	f.subtree(root, filter.Children)
+	return f.copy(root), nil
+}
+
+// XPathFilter returns a copy of the tree root holding the nodes selected
+// by x, along with their descendants, their ancestors and the keys of
+// ancestor list entries.
+func XPathFilter(root *Node, x *XPath) (*Node, error) {
+	nodes, err := x.Select(root)
+	if err != nil {
+		return nil, err
+	}
+	f := selection{}
+	for _, n := range nodes {
+		f.all(n)
+		for p := n.Parent; p != nil; p = p.Parent {
+			f[p] = true
+		}
+	}
+	return f.copy(root), nil
+}
+
+// Copy returns a deep copy of n, detached from n's parent. Copying a
+// root node copies the whole tree.
+func (n *Node) Copy() *Node {
+	c := n.shallowCopy()
+	for _, child := range n.Children {
+		cc := child.Copy()
+		cc.Parent = c
+		c.Children = append(c.Children, cc)
+	}
+	return c
+}
+
+func (n *Node) shallowCopy() *Node {
+	c := &Node{Name: n.Name, Schema: n.Schema, Value: n.Value, NS: n.NS, ctx: n.ctx}
+	if len(n.Attr) > 0 {
+		c.Attr = append(c.Attr, n.Attr...)
+	}
+	return c
+}
+
+// selection holds the nodes of a tree selected by a filter
+type selection map[*Node]bool
+
+// all selects n and its descendants
+func (f selection) all(n *Node) {
+	f[n] = true
+	for _, c := range n.Children {
+		f.all(c)
+	}
+}
+
+// subtree selects the children of n matched by the sibling filter nodes
+// fs (RFC6241 section 6.2), returning true if n is selected
+func (f selection) subtree(n *Node, fs []*Node) bool {
+	var content, other []*Node
+	for _, c := range fs {
+		if len(c.Children) == 0 && strings.TrimSpace(c.Value) != "" {
+			content = append(content, c)
+		} else {
+			other = append(other, c)
+		}
+	}
+	// every content match node must match for the sibling set to match
+	var matched []*Node
+	for _, c := range content {
+		found := false
+		for _, x := range n.Children {
+			if len(x.Children) == 0 && filterMatch(x, c) && strings.TrimSpace(x.Value) == strings.TrimSpace(c.Value) {
+				matched = append(matched, x)
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(content) > 0 && len(other) == 0 {
+		for _, c := range n.Children {
+			f.all(c)
+		}
+		return true
+	}
+	for _, x := range matched {
+		f[x] = true
+	}
+	selected := len(matched) > 0
+	for _, x := range n.Children {
+		for _, c := range other {
+			if !filterMatch(x, c) {
+				continue
+			}
+			if len(c.Children) == 0 {
+				// a selection node selects the entire subtree
+				f.all(x)
+				selected = true
+			} else if f.subtree(x, c.Children) {
+				f[x] = true
+				selected = true
+			}
+		}
+	}
+	return selected
+}
+
+// filterMatch returns true if the filter node c matches the name and
+// attributes of the data node x
+func filterMatch(x, c *Node) bool {
+	if x.Name.Local != c.Name.Local || c.Name.Space != "" && x.Name.Space != c.Name.Space {
+		return false
+	}
+	for _, a := range c.Attr {
+		found := false
+		for _, b := range x.Attr {
+			if a == b {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// copy returns a copy of n, holding its selected descendants. List
+// entries are copied with their keys.
+func (f selection) copy(n *Node) *Node {
+	c := n.shallowCopy()
+	keys := map[*Node]bool{}
+	if n.Schema != nil && n.Schema.Kind == yang.KindList {
+		for _, k := range n.Keys() {
+			keys[k] = true
+		}
+	}
+	for _, child := range n.Children {
+		if f[child] || keys[child] {
+			cc := f.copy(child)
+			cc.Parent = c
+			c.Children = append(c.Children, cc)
+		}
+	}
+	return c
+}
@@ -0,0 +1,331 @@
+package data
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/andaru/netconf/yang"
+)
+
+type funcExpr struct {
+	name string
+	args []xexpr
+	fn   xfunc
+	// ns holds the expression's namespace declarations, for functions
+	// taking prefixed identity names as arguments
+	ns map[string]string
+}
+
+type xfunc func(c *xctx, f *funcExpr, args []interface{}) (interface{}, error)
+
+func (f *funcExpr) eval(c *xctx) (interface{}, error) {
+	args := make([]interface{}, len(f.args))
+	for i, a := range f.args {
+		v, err := a.eval(c)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	return f.fn(c, f, args)
+}
+
+var functions map[string]struct {
+	min, max int
+	fn       xfunc
+}
+
+func init() {
+	functions = map[string]struct {
+		min, max int
+		fn       xfunc
+	}{
+		// XPath 1.0 core function library
+		"last":     {0, 0, func(c *xctx, _ *funcExpr, _ []interface{}) (interface{}, error) { return float64(c.size), nil }},
+		"position": {0, 0, func(c *xctx, _ *funcExpr, _ []interface{}) (interface{}, error) { return float64(c.pos), nil }},
+		"count": {1, 1, func(c *xctx, _ *funcExpr, args []interface{}) (interface{}, error) {
+			nodes, err := nodeSet(args[0])
+			return float64(len(nodes)), err
+		}},
+		"local-name":    {0, 1, nameFunc(func(n *Node) string { return n.Name.Local })},
+		"name":          {0, 1, nameFunc(qualifiedName)},
+		"namespace-uri": {0, 1, nameFunc(func(n *Node) string { return n.Name.Space })},
+		"string": {0, 1, func(c *xctx, _ *funcExpr, args []interface{}) (interface{}, error) {
+			return toString(arg(c, args, 0)), nil
+		}},
+		"concat": {2, -1, func(_ *xctx, _ *funcExpr, args []interface{}) (interface{}, error) {
+			var b strings.Builder
+			for _, a := range args {
+				b.WriteString(toString(a))
+			}
+			return b.String(), nil
+		}},
+		"starts-with": {2, 2, stringsFunc(func(a, b string) interface{} { return strings.HasPrefix(a, b) })},
+		"contains":    {2, 2, stringsFunc(func(a, b string) interface{} { return strings.Contains(a, b) })},
+		"substring-before": {2, 2, stringsFunc(func(a, b string) interface{} {
+			if i := strings.Index(a, b); i >= 0 {
+				return a[:i]
+			}
+			return ""
+		})},
+		"substring-after": {2, 2, stringsFunc(func(a, b string) interface{} {
+			if i := strings.Index(a, b); i >= 0 {
+				return a[i+len(b):]
+			}
+			return ""
+		})},
+		"substring": {2, 3, func(_ *xctx, _ *funcExpr, args []interface{}) (interface{}, error) {
+			s := []rune(toString(args[0]))
+			start := round(toNumber(args[1]))
+			end := math.Inf(1)
+			if len(args) == 3 {
+				end = start + round(toNumber(args[2]))
+			}
+			var b strings.Builder
+			for i, r := range s {
+				if p := float64(i + 1); p >= start && p < end {
+					b.WriteRune(r)
+				}
+			}
+			return b.String(), nil
+		}},
+		"string-length": {0, 1, func(c *xctx, _ *funcExpr, args []interface{}) (interface{}, error) {
+			return float64(len([]rune(toString(arg(c, args, 0))))), nil
+		}},
+		"normalize-space": {0, 1, func(c *xctx, _ *funcExpr, args []interface{}) (interface{}, error) {
+			return strings.Join(strings.Fields(toString(arg(c, args, 0))), " "), nil
+		}},
+		"translate": {3, 3, func(_ *xctx, _ *funcExpr, args []interface{}) (interface{}, error) {
+			from, to := []rune(toString(args[1])), []rune(toString(args[2]))
+			return strings.Map(func(r rune) rune {
+				for i, f := range from {
+					if f == r {
+						if i < len(to) {
+							return to[i]
+						}
+						return -1
+					}
+				}
+				return r
+			}, toString(args[0])), nil
+		}},
+		"boolean": {1, 1, func(_ *xctx, _ *funcExpr, args []interface{}) (interface{}, error) { return toBool(args[0]), nil }},
+		"not":     {1, 1, func(_ *xctx, _ *funcExpr, args []interface{}) (interface{}, error) { return !toBool(args[0]), nil }},
+		"true":    {0, 0, func(*xctx, *funcExpr, []interface{}) (interface{}, error) { return true, nil }},
+		"false":   {0, 0, func(*xctx, *funcExpr, []interface{}) (interface{}, error) { return false, nil }},
+		"lang":    {1, 1, func(*xctx, *funcExpr, []interface{}) (interface{}, error) { return false, nil }},
+		"number": {0, 1, func(c *xctx, _ *funcExpr, args []interface{}) (interface{}, error) {
+			return toNumber(arg(c, args, 0)), nil
+		}},
+		"sum": {1, 1, func(_ *xctx, _ *funcExpr, args []interface{}) (interface{}, error) {
+			nodes, err := nodeSet(args[0])
+			var sum float64
+			for _, n := range nodes {
+				sum += toNumber(stringValue(n))
+			}
+			return sum, err
+		}},
+		"floor":   {1, 1, numberFunc(math.Floor)},
+		"ceiling": {1, 1, numberFunc(math.Ceil)},
+		"round":   {1, 1, numberFunc(round)},
+
+		// YANG function library (RFC7950 section 10)
+		"current": {0, 0, func(c *xctx, _ *funcExpr, _ []interface{}) (interface{}, error) { return []*Node{c.current}, nil }},
+		"re-match": {2, 2, func(_ *xctx, _ *funcExpr, args []interface{}) (interface{}, error) {
+			re, err := yang.CompilePattern(toString(args[1]))
+			if err != nil {
+				return nil, err
+			}
+			return re.MatchString(toString(args[0])), nil
+		}},
+		"deref": {1, 1, func(_ *xctx, _ *funcExpr, args []interface{}) (interface{}, error) {
+			nodes, err := nodeSet(args[0])
+			if err != nil || len(nodes) == 0 {
+				return []*Node{}, err
+			}
+			return deref(nodes[0])
+		}},
+		"derived-from":         {2, 2, derivedFrom(false)},
+		"derived-from-or-self": {2, 2, derivedFrom(true)},
+		"enum-value": {1, 1, func(_ *xctx, _ *funcExpr, args []interface{}) (interface{}, error) {
+			nodes, err := nodeSet(args[0])
+			if err != nil || len(nodes) == 0 || nodes[0].Schema == nil {
+				return math.NaN(), err
+			}
+			if e := enumValue(nodes[0].Schema.Type, nodes[0].Value); e != nil {
+				return float64(e.Value), nil
+			}
+			return math.NaN(), nil
+		}},
+		"bit-is-set": {2, 2, func(_ *xctx, _ *funcExpr, args []interface{}) (interface{}, error) {
+			nodes, err := nodeSet(args[0])
+			if err != nil || len(nodes) == 0 {
+				return false, err
+			}
+			for _, bit := range strings.Fields(nodes[0].Value) {
+				if bit == toString(args[1]) {
+					return true, nil
+				}
+			}
+			return false, nil
+		}},
+	}
+}
+
+// arg returns args[i], or the context node if absent
+func arg(c *xctx, args []interface{}, i int) interface{} {
+	if i < len(args) {
+		return args[i]
+	}
+	return []*Node{c.node}
+}
+
+func nodeSet(v interface{}) ([]*Node, error) {
+	if nodes, ok := v.([]*Node); ok {
+		return nodes, nil
+	}
+	return nil, fmt.Errorf("argument is not a node-set")
+}
+
+func nameFunc(name func(*Node) string) xfunc {
+	return func(c *xctx, _ *funcExpr, args []interface{}) (interface{}, error) {
+		nodes, err := nodeSet(arg(c, args, 0))
+		if err != nil || len(nodes) == 0 || nodes[0].Parent == nil {
+			return "", err
+		}
+		return name(nodes[0]), nil
+	}
+}
+
+// qualifiedName returns n's name, prefixed by its module's prefix
+func qualifiedName(n *Node) string {
+	if n.Schema != nil {
+		return n.Schema.Module.Prefix + ":" + n.Name.Local
+	}
+	return n.Name.Local
+}
+
+func stringsFunc(f func(a, b string) interface{}) xfunc {
+	return func(_ *xctx, _ *funcExpr, args []interface{}) (interface{}, error) {
+		return f(toString(args[0]), toString(args[1])), nil
+	}
+}
+
+func numberFunc(f func(float64) float64) xfunc {
+	return func(_ *xctx, _ *funcExpr, args []interface{}) (interface{}, error) {
+		return f(toNumber(args[0])), nil
+	}
+}
+
+// round rounds per XPath 1.0, towards positive infinity for halves
+func round(f float64) float64 {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return f
+	}
+	return math.Floor(f + 0.5)
+}
+
+// deref returns the nodes referenced by the leafref or
+// instance-identifier node n
+func deref(n *Node) ([]*Node, error) {
+	if n.Schema == nil || n.Schema.Type == nil {
+		return []*Node{}, nil
+	}
+	switch t := n.Schema.Type; t.Kind {
+	case yang.TypeLeafref:
+		x, err := compileYANG(t.Path)
+		if err != nil {
+			return nil, err
+		}
+		targets, err := x.Select(n)
+		if err != nil {
+			return nil, err
+		}
+		var nodes []*Node
+		for _, target := range targets {
+			if target.Value == n.Value {
+				nodes = append(nodes, target)
+			}
+		}
+		return nodes, nil
+	case yang.TypeInstanceIdentifier:
+		x, err := CompileXPath(n.Value, n.NS)
+		if err != nil {
+			return nil, err
+		}
+		return x.Select(n.Root())
+	}
+	return []*Node{}, nil
+}
+
+func derivedFrom(orSelf bool) xfunc {
+	return func(_ *xctx, f *funcExpr, args []interface{}) (interface{}, error) {
+		nodes, err := nodeSet(args[0])
+		if err != nil {
+			return false, err
+		}
+		ref := toString(args[1])
+		for _, n := range nodes {
+			base := identity(n.Context(), ref, f.ns, n.Name.Space)
+			id := identityValue(n)
+			if base != nil && id != nil && (id.DerivedFrom(base) || orSelf && id == base) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+}
+
+// identity returns the identity referenced by ref, whose prefix is
+// declared in ns, or is in the namespace def if unprefixed
+func identity(c *yang.Context, ref string, ns map[string]string, def string) *yang.Identity {
+	prefix, name := "", ref
+	if i := strings.IndexByte(ref, ':'); i >= 0 {
+		prefix, name = ref[:i], ref[i+1:]
+	}
+	space, ok := ns[prefix]
+	if !ok {
+		if prefix != "" {
+			return nil
+		}
+		space = def
+	}
+	if m := c.ModuleByNamespace(space); m != nil {
+		return m.Identities[name]
+	}
+	return nil
+}
+
+// identityValue returns the identity named by the value of the
+// identityref node n, or nil
+func identityValue(n *Node) *yang.Identity {
+	return identity(n.Context(), n.Value, n.NS, n.Name.Space)
+}
+
+// enumValue returns the enum of t (or its union member types) named name
+func enumValue(t *yang.Type, name string) *yang.Enum {
+	if t == nil {
+		return nil
+	}
+	for i := range t.Enums {
+		if t.Enums[i].Name == name {
+			return &t.Enums[i]
+		}
+	}
+	for _, member := range t.Union {
+		if e := enumValue(member, name); e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+// compileYANG compiles the YANG XPath expression x
+func compileYANG(x yang.XPath) (*XPath, error) {
+	ns := make(map[string]string, len(x.Prefixes))
+	for prefix, m := range x.Prefixes {
+		ns[prefix] = m.Namespace
+	}
+	return CompileXPath(x.Expr, ns)
+}
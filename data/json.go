@@ -0,0 +1,528 @@
+package data
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+
+	"github.com/andaru/netconf/xmlutil"
+	"github.com/andaru/netconf/yang"
+)
+
+// WriteJSON writes the children of n to w as an RFC7951 JSON object.
+//
+// Member names are qualified by their module name where their namespace
+// differs from their parent's. Values are encoded per their YANG type:
+// 64-bit integers and decimal64 values as strings, empty values as
+// [null], and identityref and instance-identifier values with module
+// name prefixes. Metadata annotations (attributes) are not encoded.
+func (n *Node) WriteJSON(w io.Writer) error { return EncodeJSON(w, n.Children) }
+
+// EncodeJSON writes the nodes (siblings, such as the targets of a
+// request) to w as an RFC7951 JSON object, with module qualified member
+// names, as for WriteJSON.
+func EncodeJSON(w io.Writer, nodes []*Node) error {
+	e := &jsonEncoder{}
+	if err := e.object(nodes, false, true); err != nil {
+		return err
+	}
+	_, err := w.Write(e.Bytes())
+	return err
+}
+
+type jsonEncoder struct {
+	bytes.Buffer
+}
+
+// str writes s as a JSON string
+func (e *jsonEncoder) str(s string) {
+	enc := json.NewEncoder(e)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	e.Truncate(e.Len() - 1) // trailing newline
+}
+
+// member writes the member name of n, qualified if top is set or its
+// namespace differs from its parent's
+func (e *jsonEncoder) member(n *Node, top bool) error {
+	name := n.Name.Local
+	if top || n.Parent == nil || n.Parent.Name.Space != n.Name.Space {
+		m := n.Context().ModuleByNamespace(n.Name.Space)
+		if m == nil {
+			return fmt.Errorf("%s: unknown namespace %q", path(n), n.Name.Space)
+		}
+		name = m.Name + ":" + name
+	}
+	e.str(name)
+	e.WriteByte(':')
+	return nil
+}
+
+// object writes the sibling nodes as a JSON object, whose members are
+// qualified if top is set. If generic is set, the nodes are anydata or
+// anyxml content, with no schema.
+func (e *jsonEncoder) object(nodes []*Node, generic, top bool) error {
+	// group list entries, leaf-list values and repeated elements
+	type group struct {
+		nodes []*Node
+	}
+	var groups []*group
+	byKey := map[interface{}]*group{}
+	for _, c := range nodes {
+		var key interface{} = c.Schema
+		if generic {
+			key = c.Name
+		} else if c.Schema == nil {
+			return fmt.Errorf("%s: unknown element", path(c))
+		}
+		g := byKey[key]
+		if g == nil {
+			g = &group{}
+			byKey[key] = g
+			groups = append(groups, g)
+		}
+		g.nodes = append(g.nodes, c)
+	}
+	e.WriteByte('{')
+	for i, g := range groups {
+		if i > 0 {
+			e.WriteByte(',')
+		}
+		first := g.nodes[0]
+		if err := e.member(first, top); err != nil {
+			return err
+		}
+		var kind yang.Kind
+		switch {
+		case generic && len(g.nodes) > 1:
+			kind = yang.KindLeafList
+		case generic:
+			kind = yang.KindLeaf
+		default:
+			kind = first.Schema.Kind
+		}
+		if kind == yang.KindList || kind == yang.KindLeafList {
+			e.WriteByte('[')
+		}
+		for j, c := range g.nodes {
+			if j > 0 {
+				e.WriteByte(',')
+			}
+			var err error
+			switch {
+			case generic && len(c.Children) > 0:
+				err = e.object(c.Children, true, false)
+			case generic:
+				e.str(c.Value)
+			case kind == yang.KindAnydata || kind == yang.KindAnyxml:
+				err = e.object(c.Children, true, false)
+			case kind != yang.KindLeaf && kind != yang.KindLeafList:
+				// containers, lists and operation input and output
+				err = e.object(c.Children, false, false)
+			default:
+				err = e.value(c)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if kind == yang.KindList || kind == yang.KindLeafList {
+			e.WriteByte(']')
+		}
+	}
+	e.WriteByte('}')
+	return nil
+}
+
+// value writes the value of the leaf or leaf-list node n
+func (e *jsonEncoder) value(n *Node) error {
+	v, err := jsonValue(n, n.Schema.Type, n.Value)
+	if err != nil {
+		return err
+	}
+	switch v := v.(type) {
+	case string:
+		e.str(v)
+	case json.Number:
+		e.WriteString(string(v))
+	case bool:
+		fmt.Fprint(e, v)
+	default:
+		e.WriteString("[null]")
+	}
+	return nil
+}
+
+// jsonValue returns the JSON value of the XML value of node n, of type t:
+// a string, json.Number, bool, or nil for the empty type.
+func jsonValue(n *Node, t *yang.Type, value string) (interface{}, error) {
+	if t == nil {
+		return value, nil
+	}
+	bad := func() error { return fmt.Errorf("%s: invalid %v value %q", path(n), t.Kind, value) }
+	switch t.Kind {
+	case yang.TypeInt8, yang.TypeInt16, yang.TypeInt32, yang.TypeUint8, yang.TypeUint16, yang.TypeUint32:
+		i, ok := new(big.Int).SetString(value, 10)
+		if !ok {
+			return nil, bad()
+		}
+		return json.Number(i.String()), nil
+	case yang.TypeBoolean:
+		if value != "true" && value != "false" {
+			return nil, bad()
+		}
+		return value == "true", nil
+	case yang.TypeEmpty:
+		return nil, nil
+	case yang.TypeIdentityref:
+		id := identity(n.Context(), value, n.NS, n.Name.Space)
+		if id == nil {
+			return nil, bad()
+		}
+		return id.Module.Name + ":" + id.Name, nil
+	case yang.TypeInstanceIdentifier:
+		var err error
+		v := mapNames(value, func(name string) string {
+			i := strings.IndexByte(name, ':')
+			if i < 0 {
+				return name
+			}
+			m := n.Context().ModuleByNamespace(n.NS[name[:i]])
+			if m == nil {
+				err = bad()
+				return name
+			}
+			return m.Name + name[i:]
+		})
+		return v, err
+	case yang.TypeUnion:
+		for _, member := range t.Union {
+			if checkValue(n, member, value) == nil {
+				return jsonValue(n, member, value)
+			}
+		}
+	case yang.TypeLeafref:
+		if t.Target != nil && t.Target.Type != nil {
+			return jsonValue(n, t.Target.Type, value)
+		}
+	}
+	return value, nil
+}
+
+// path returns n's instance identifier, for error messages
+func path(n *Node) string {
+	p, _ := n.Path()
+	return p
+}
+
+// ParseJSON parses the RFC7951 JSON object read from r into a new data
+// tree using the schema of c, the inverse of WriteJSON. Metadata
+// annotations (members named "@...") are ignored.
+func ParseJSON(r io.Reader, c *yang.Context) (*Node, error) {
+	root := NewRoot(c)
+	if err := root.DecodeJSON(r); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// DecodeJSON parses the RFC7951 JSON object read from r, appending its
+// members to n's children, as for ParseJSON. Members of an object
+// decoded into a non-root node are qualified where their namespace
+// differs from n's.
+func (n *Node) DecodeJSON(r io.Reader) error {
+	d := json.NewDecoder(r)
+	d.UseNumber()
+	p := &jsonParser{d: d}
+	if err := p.object(n, n.Schema != nil && (n.Schema.Kind == yang.KindAnydata || n.Schema.Kind == yang.KindAnyxml)); err != nil {
+		return err
+	}
+	if _, err := d.Token(); err != io.EOF {
+		return fmt.Errorf("unexpected data after JSON object")
+	}
+	return nil
+}
+
+type jsonParser struct {
+	d *json.Decoder
+}
+
+func (p *jsonParser) delim(want json.Delim) error {
+	tok, err := p.d.Token()
+	if err != nil {
+		return err
+	}
+	if tok != want {
+		return fmt.Errorf("expected %q, found %v", want, tok)
+	}
+	return nil
+}
+
+// skip skips the next JSON value
+func (p *jsonParser) skip() error {
+	var v json.RawMessage
+	return p.d.Decode(&v)
+}
+
+// object parses a JSON object of the children of parent. If generic is
+// set, parent is anydata or anyxml content, with no schema.
+func (p *jsonParser) object(parent *Node, generic bool) error {
+	if err := p.delim('{'); err != nil {
+		return err
+	}
+	for p.d.More() {
+		tok, err := p.d.Token()
+		if err != nil {
+			return err
+		}
+		member := tok.(string)
+		if strings.HasPrefix(member, "@") {
+			if err := p.skip(); err != nil {
+				return err
+			}
+			continue
+		}
+		name, err := p.name(parent, member)
+		if err != nil {
+			return err
+		}
+		if generic {
+			if err := p.generic(parent, name); err != nil {
+				return err
+			}
+			continue
+		}
+		s := parent.childSchema(name)
+		if s == nil {
+			return fmt.Errorf("%s: unknown member %q", path(parent), member)
+		}
+		switch s.Kind {
+		case yang.KindContainer, yang.KindInput, yang.KindOutput, yang.KindRPC, yang.KindAction, yang.KindNotification:
+			err = p.object(p.append(parent, name), false)
+		case yang.KindAnydata, yang.KindAnyxml:
+			err = p.object(p.append(parent, name), true)
+		case yang.KindLeaf:
+			err = p.value(p.append(parent, name))
+		case yang.KindList, yang.KindLeafList:
+			if err = p.delim('['); err != nil {
+				return err
+			}
+			for err == nil && p.d.More() {
+				if s.Kind == yang.KindList {
+					err = p.object(p.append(parent, name), false)
+				} else {
+					err = p.value(p.append(parent, name))
+				}
+			}
+			if err == nil {
+				err = p.delim(']')
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return p.delim('}')
+}
+
+func (p *jsonParser) append(parent *Node, name xml.Name) *Node {
+	n := &Node{Name: name}
+	parent.Append(n)
+	return n
+}
+
+// name returns the element name of the member of parent
+func (p *jsonParser) name(parent *Node, member string) (xml.Name, error) {
+	i := strings.IndexByte(member, ':')
+	if i < 0 {
+		if parent.Parent == nil {
+			return xml.Name{}, fmt.Errorf("top-level member %q is not namespace-qualified", member)
+		}
+		return xml.Name{Space: parent.Name.Space, Local: member}, nil
+	}
+	m := parent.Context().Module(member[:i])
+	if m == nil {
+		return xml.Name{}, fmt.Errorf("%s: unknown module %q", path(parent), member[:i])
+	}
+	return xml.Name{Space: m.Namespace, Local: member[i+1:]}, nil
+}
+
+// generic parses anydata or anyxml content named name, within parent
+func (p *jsonParser) generic(parent *Node, name xml.Name) error {
+	tok, err := p.d.Token()
+	if err != nil {
+		return err
+	}
+	switch tok {
+	case json.Delim('{'):
+		// reparse the object, having consumed its opening delimiter
+		n := p.append(parent, name)
+		for p.d.More() {
+			tok, err := p.d.Token()
+			if err != nil {
+				return err
+			}
+			member := tok.(string)
+			if strings.HasPrefix(member, "@") {
+				if err := p.skip(); err != nil {
+					return err
+				}
+				continue
+			}
+			childName, err := p.name(n, member)
+			if err != nil {
+				return err
+			}
+			if err := p.generic(n, childName); err != nil {
+				return err
+			}
+		}
+		return p.delim('}')
+	case json.Delim('['):
+		for p.d.More() {
+			if err := p.generic(parent, name); err != nil {
+				return err
+			}
+		}
+		return p.delim(']')
+	case nil:
+		p.append(parent, name)
+	default:
+		p.append(parent, name).Value = fmt.Sprint(tok)
+	}
+	return nil
+}
+
+// value parses the JSON value of the leaf or leaf-list node n
+func (p *jsonParser) value(n *Node) error {
+	tok, err := p.d.Token()
+	if err != nil {
+		return err
+	}
+	if tok == json.Delim('[') {
+		// [null] encodes the empty type
+		if tok, err = p.d.Token(); err != nil {
+			return err
+		}
+		if tok != nil {
+			return fmt.Errorf("%s: invalid value", path(n))
+		}
+		if err := p.delim(']'); err != nil {
+			return err
+		}
+		tok = []interface{}{nil}
+	}
+	v, ok := xmlValue(n, n.Schema.Type, tok)
+	if !ok {
+		return fmt.Errorf("%s: invalid %v value %v", path(n), n.Schema.Type.Kind, tok)
+	}
+	n.Value = v
+	return nil
+}
+
+// xmlValue returns the XML value of the JSON value tok of node n, of
+// type t, declaring any namespace prefixes used in n.NS.
+func xmlValue(n *Node, t *yang.Type, tok interface{}) (string, bool) {
+	if t == nil {
+		return fmt.Sprint(tok), true
+	}
+	s, isString := tok.(string)
+	switch t.Kind {
+	case yang.TypeInt8, yang.TypeInt16, yang.TypeInt32, yang.TypeUint8, yang.TypeUint16, yang.TypeUint32:
+		num, ok := tok.(json.Number)
+		return string(num), ok
+	case yang.TypeBoolean:
+		b, ok := tok.(bool)
+		return fmt.Sprint(b), ok
+	case yang.TypeEmpty:
+		_, ok := tok.([]interface{})
+		return "", ok
+	case yang.TypeIdentityref:
+		if !isString {
+			return "", false
+		}
+		m := n.Schema.Module
+		if i := strings.IndexByte(s, ':'); i >= 0 {
+			if m = n.Context().Module(s[:i]); m == nil {
+				return "", false
+			}
+			s = s[i+1:]
+		}
+		return declare(n, m) + ":" + s, true
+	case yang.TypeInstanceIdentifier:
+		if !isString {
+			return "", false
+		}
+		ok := true
+		var cur *yang.Module
+		v := mapNames(s, func(name string) string {
+			if i := strings.IndexByte(name, ':'); i >= 0 {
+				if cur = n.Context().Module(name[:i]); cur == nil {
+					ok = false
+					return name
+				}
+				name = name[i+1:]
+			}
+			if cur == nil {
+				ok = false
+				return name
+			}
+			return declare(n, cur) + ":" + name
+		})
+		return v, ok
+	case yang.TypeUnion:
+		for _, member := range t.Union {
+			if v, ok := xmlValue(n, member, tok); ok && checkValue(n, member, v) == nil {
+				return v, true
+			}
+		}
+		return "", false
+	case yang.TypeLeafref:
+		if t.Target != nil && t.Target.Type != nil {
+			return xmlValue(n, t.Target.Type, tok)
+		}
+		switch tok.(type) {
+		case string, json.Number, bool:
+			return fmt.Sprint(tok), true
+		}
+		return "", false
+	}
+	return s, isString
+}
+
+// declare declares the prefix of module m in n.NS, returning the prefix
+func declare(n *Node, m *yang.Module) string {
+	if n.NS == nil {
+		n.NS = xmlutil.PrefixMap{}
+	}
+	prefix := m.Prefix
+	for i := 0; n.NS[prefix] != "" && n.NS[prefix] != m.Namespace; i++ {
+		prefix = fmt.Sprintf("%s%d", m.Prefix, i)
+	}
+	n.NS[prefix] = m.Namespace
+	return prefix
+}
+
+// XMLToJSON converts the XML data read from r (as read by Parse) to
+// RFC7951 JSON written to w, using the schema of c.
+func XMLToJSON(w io.Writer, r io.Reader, c *yang.Context) error {
+	root, err := Parse(r, c)
+	if err != nil {
+		return err
+	}
+	return root.WriteJSON(w)
+}
+
+// JSONToXML converts the RFC7951 JSON object read from r to XML data
+// written to w, using the schema of c.
+func JSONToXML(w io.Writer, r io.Reader, c *yang.Context) error {
+	root, err := ParseJSON(r, c)
+	if err != nil {
+		return err
+	}
+	return root.WriteXML(w)
+}
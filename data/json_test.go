@@ -0,0 +1,136 @@
+package data
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/andaru/netconf/yang"
+	"github.com/stretchr/testify/assert"
+)
+
+func loadExtSchema(t *testing.T) *yang.Context {
+	t.Helper()
+	c := yang.NewContext("testdata")
+	if err := c.Load("example-data", "example-data-ext"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Resolve(); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestJSON(t *testing.T) {
+	c := loadExtSchema(t)
+	for _, tc := range []struct {
+		name string
+		xml  string
+		json string
+	}{
+		{
+			name: "types",
+			xml: `<system xmlns="urn:example:data" xmlns:ex="urn:example:data">` +
+				`<hostname>r1</hostname><mtu>1500</mtu><ratio>0.25</ratio><counter>-5</counter>` +
+				`<limit>7</limit><enabled>true</enabled><mode>manual</mode><flags>b a</flags>` +
+				`<proto>ex:tcp</proto><dns>a</dns><dns>b</dns><ssh/></system>`,
+			json: `{"example-data:system":{"hostname":"r1","mtu":1500,"ratio":"0.25","counter":"-5",` +
+				`"limit":7,"enabled":true,"mode":"manual","flags":"b a",` +
+				`"proto":"example-data:tcp","dns":["a","b"],"ssh":[null]}}`,
+		},
+		{
+			name: "union enumeration",
+			xml:  `<system xmlns="urn:example:data"><limit>unlimited</limit></system>`,
+			json: `{"example-data:system":{"limit":"unlimited"}}`,
+		},
+		{
+			name: "lists",
+			xml: `<system xmlns="urn:example:data">` +
+				`<server><name>a</name><port>830</port></server><server><name>b</name></server>` +
+				`<primary>a</primary></system>`,
+			json: `{"example-data:system":{"server":[{"name":"a","port":830},{"name":"b"}],"primary":"a"}}`,
+		},
+		{
+			name: "leafref target present",
+			xml: `<system xmlns="urn:example:data">` +
+				`<server><name>a</name><weight>5</weight></server><default-weight>5</default-weight></system>`,
+			json: `{"example-data:system":{"server":[{"name":"a","weight":5}],"default-weight":5}}`,
+		},
+		{
+			name: "leafref target absent",
+			xml:  `<system xmlns="urn:example:data"><default-weight>5</default-weight></system>`,
+			json: `{"example-data:system":{"default-weight":5}}`,
+		},
+		{
+			name: "augment and foreign identity",
+			xml: `<system xmlns="urn:example:data"><proto xmlns:ext="urn:example:data-ext">ext:sctp</proto>` +
+				`<location xmlns="urn:example:data-ext">lab</location></system>`,
+			json: `{"example-data:system":{"proto":"example-data-ext:sctp","example-data-ext:location":"lab"}}`,
+		},
+		{
+			name: "instance-identifier",
+			xml: `<system xmlns="urn:example:data">` +
+				`<target xmlns:ex="urn:example:data">/ex:system/ex:server[ex:name='a']/ex:port</target></system>`,
+			json: `{"example-data:system":{"target":"/example-data:system/example-data:server[example-data:name='a']/example-data:port"}}`,
+		},
+		{
+			name: "anydata",
+			xml: `<system xmlns="urn:example:data"><extra>` +
+				`<hostname>x</hostname><location xmlns="urn:example:data-ext"><room>1</room><room>2</room></location>` +
+				`</extra></system>`,
+			json: `{"example-data:system":{"extra":{"hostname":"x","example-data-ext:location":{"room":["1","2"]}}}}`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			var b bytes.Buffer
+			if !assert.NoError(XMLToJSON(&b, strings.NewReader(tc.xml), c)) {
+				return
+			}
+			assert.Equal(tc.json, b.String())
+
+			// decode the JSON, and check it encodes to the same XML and JSON
+			root, err := ParseJSON(strings.NewReader(tc.json), c)
+			if !assert.NoError(err) {
+				return
+			}
+			b.Reset()
+			assert.NoError(root.WriteJSON(&b))
+			assert.Equal(tc.json, b.String())
+
+			want, err := Parse(strings.NewReader(tc.xml), c)
+			if !assert.NoError(err) {
+				return
+			}
+			var got, wantXML bytes.Buffer
+			assert.NoError(root.WriteXML(&got))
+			assert.NoError(want.WriteXML(&wantXML))
+			assert.Equal(wantXML.String(), got.String())
+		})
+	}
+}
+
+func TestJSONErrors(t *testing.T) {
+	c := loadExtSchema(t)
+	for _, tc := range []struct {
+		name string
+		json string
+		want string
+	}{
+		{"unqualified top-level", `{"system":{}}`, `top-level member "system" is not namespace-qualified`},
+		{"unknown module", `{"foo:system":{}}`, `/: unknown module "foo"`},
+		{"unknown member", `{"example-data:system":{"foo":1}}`, `/ex:system: unknown member "foo"`},
+		{"int as string", `{"example-data:system":{"mtu":"1500"}}`, `/ex:system/ex:mtu: invalid uint16 value 1500`},
+		{"int64 as number", `{"example-data:system":{"counter":5}}`, `/ex:system/ex:counter: invalid int64 value 5`},
+		{"bad boolean", `{"example-data:system":{"enabled":"true"}}`, `/ex:system/ex:enabled: invalid boolean value true`},
+		{"leafref as string", `{"example-data:system":{"default-weight":"5"}}`, `/ex:system/ex:default-weight: invalid leafref value 5`},
+		{"bad empty", `{"example-data:system":{"ssh":[1]}}`, `/ex:system/ex:ssh: invalid value`},
+		{"unknown identity module", `{"example-data:system":{"proto":"foo:tcp"}}`, `/ex:system/ex:proto: invalid identityref value foo:tcp`},
+		{"trailing data", `{} {}`, `unexpected data after JSON object`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseJSON(strings.NewReader(tc.json), c)
+			assert.EqualError(t, err, tc.want)
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package data
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/andaru/netconf/ops"
+	"github.com/andaru/netconf/yang"
+)
+
+// originAttr is the name of the RFC8342 or:origin metadata annotation
+var originAttr = xml.Name{Space: ops.OriginNS, Local: "origin"}
+
+// Origin returns the origin of n, being its or:origin annotation or that
+// of its nearest annotated ancestor, or the zero Identity if none.
+func (n *Node) Origin() ops.Identity {
+	for ; n != nil; n = n.Parent {
+		for _, a := range n.Attr {
+			if a.Name == originAttr {
+				return n.identity(strings.TrimSpace(a.Value))
+			}
+		}
+	}
+	return ops.Identity{}
+}
+
+// identity resolves the prefix of the identity s using the namespace
+// declarations of n, falling back to the module prefixes of n's schema
+// context
+func (n *Node) identity(s string) ops.Identity {
+	prefix, local := "", s
+	if i := strings.IndexByte(s, ':'); i >= 0 {
+		prefix, local = s[:i], s[i+1:]
+	}
+	if space, ok := n.NS[prefix]; ok {
+		return ops.Identity{Space: space, Local: local}
+	}
+	if prefix == "or" {
+		return ops.Identity{Space: ops.OriginNS, Local: local}
+	}
+	for _, m := range n.Context().Modules {
+		if m.Prefix == prefix {
+			return ops.Identity{Space: m.Namespace, Local: local}
+		}
+	}
+	return ops.Identity{Local: local}
+}
+
+// hasOrigin returns true if n has an or:origin annotation
+func (n *Node) hasOrigin() bool {
+	for _, a := range n.Attr {
+		if a.Name == originAttr {
+			return true
+		}
+	}
+	return false
+}
+
+// originPrefix returns the namespace prefix of n's or:origin annotation
+// value, if any
+func originPrefix(n *Node) string {
+	for _, a := range n.Attr {
+		if i := strings.IndexByte(a.Value, ':'); a.Name == originAttr && i > 0 {
+			return strings.TrimSpace(a.Value[:i])
+		}
+	}
+	return ""
+}
+
+// SetOrigin sets the or:origin annotation of n to origin, or removes it
+// if origin is the zero Identity.
+func (n *Node) SetOrigin(origin ops.Identity) {
+	attrs := n.Attr[:0:0]
+	for _, a := range n.Attr {
+		if a.Name != originAttr {
+			attrs = append(attrs, a)
+		}
+	}
+	n.Attr = attrs
+	if origin.Local == "" {
+		return
+	}
+	prefix := "or"
+	if origin.Space != ops.OriginNS {
+		if m := n.Context().ModuleByNamespace(origin.Space); m != nil {
+			prefix = m.Prefix
+		}
+	}
+	n.NS = merge(n.NS, map[string]string{prefix: origin.Space})
+	n.Attr = append(n.Attr, xml.Attr{Name: originAttr, Value: prefix + ":" + origin.Local})
+}
+
+// GetData returns a copy of the tree root holding the nodes selected by
+// the parameters of the NMDA <get-data> operation op (RFC8526), root
+// being the content of the datastore op selects. The copy may be used
+// as the content of the reply's <data> element.
+//
+// Nodes are selected by op's subtree or XPath filter, then by its config
+// filter and origin filters, then limited to op's max-depth. Origin
+// filters match the origin of a node or any identity derived from it, if
+// the ietf-origin module is loaded in the tree's schema context. Unless
+// op requests with-origin, or:origin annotations are removed.
+//
+// Invalid combinations of parameters, such as an origin filter for a
+// datastore other than DSOperational, are reported as an *ops.RPCError.
+func GetData(root *Node, op *ops.GetData) (*Node, error) {
+	if err := checkGetData(op); err != nil {
+		return nil, err
+	}
+	var err error
+	tree := root.Copy()
+	switch {
+	case op.SubtreeFilter != nil:
+		if tree, err = SubtreeFilter(tree, bytes.NewReader(op.SubtreeFilter.Content)); err != nil {
+			return nil, invalidValue(err.Error())
+		}
+	case op.XPathFilter != nil:
+		x, err := CompileXPath(op.XPathFilter.Select, op.XPathFilter.Namespaces())
+		if err != nil {
+			return nil, invalidValue(err.Error())
+		}
+		if tree, err = XPathFilter(tree, x); err != nil {
+			return nil, invalidValue(err.Error())
+		}
+	}
+	if op.ConfigFilter != nil {
+		f := selection{}
+		f.config(tree, *op.ConfigFilter)
+		tree = f.copy(tree)
+	}
+	if len(op.OriginFilter) > 0 || len(op.NegatedOriginFilter) > 0 {
+		f := selection{}
+		f.origin(tree, op.OriginFilter, op.NegatedOriginFilter)
+		tree = f.copy(tree)
+	}
+	if op.MaxDepth > 0 {
+		for _, c := range tree.Children {
+			c.LimitDepth(int(op.MaxDepth))
+		}
+	}
+	if op.WithOrigin == nil {
+		removeOrigins(tree)
+	}
+	return tree, nil
+}
+
+// checkGetData checks the parameters of op for conflicts
+func checkGetData(op *ops.GetData) error {
+	switch {
+	case op.Datastore.Local == "":
+		return &ops.RPCError{Type: ops.ErrorTypeProtocol, Tag: ops.ErrorTagMissingElement, Severity: ops.SeverityError,
+			Message: "missing datastore"}
+	case op.SubtreeFilter != nil && op.XPathFilter != nil:
+		return invalidValue("subtree-filter and xpath-filter are mutually exclusive")
+	case len(op.OriginFilter) > 0 && len(op.NegatedOriginFilter) > 0:
+		return invalidValue("origin-filter and negated-origin-filter are mutually exclusive")
+	case op.Datastore == ops.DSOperational:
+		return nil
+	case len(op.OriginFilter) > 0 || len(op.NegatedOriginFilter) > 0:
+		return invalidValue(fmt.Sprintf("origin filters are not valid for datastore %q", op.Datastore.Local))
+	case op.WithOrigin != nil:
+		return invalidValue(fmt.Sprintf("with-origin is not valid for datastore %q", op.Datastore.Local))
+	}
+	return nil
+}
+
+func invalidValue(msg string) *ops.RPCError {
+	return &ops.RPCError{Type: ops.ErrorTypeProtocol, Tag: ops.ErrorTagInvalidValue, Severity: ops.SeverityError, Message: msg}
+}
+
+// config selects the descendants of n that are configuration nodes if
+// config is true, or state nodes otherwise, along with their ancestors.
+// Nodes not found in the schema are treated as configuration.
+func (f selection) config(n *Node, config bool) {
+	for _, c := range n.Children {
+		if (c.Schema == nil || c.Schema.Config) == config {
+			f[c] = true
+			f.ancestors(c)
+		}
+		f.config(c, config)
+	}
+}
+
+// origin selects the leaf descendants of n whose origin matches one of
+// include, or none of exclude, along with their ancestors
+func (f selection) origin(n *Node, include, exclude []ops.Identity) {
+	ids := include
+	if len(ids) == 0 {
+		ids = exclude
+	}
+	c := n.Context()
+	for _, x := range n.Children {
+		match := false
+		for _, id := range ids {
+			if originMatch(c, x.Origin(), id) {
+				match = true
+				break
+			}
+		}
+		if match == (len(include) > 0) && len(x.Children) == 0 {
+			f[x] = true
+			f.ancestors(x)
+		}
+		f.origin(x, include, exclude)
+	}
+}
+
+// ancestors selects the ancestors of n
+func (f selection) ancestors(n *Node) {
+	for p := n.Parent; p != nil; p = p.Parent {
+		f[p] = true
+	}
+}
+
+// originMatch returns true if origin is filter, or is derived from it
+func originMatch(c *yang.Context, origin, filter ops.Identity) bool {
+	if origin == filter {
+		return true
+	}
+	base, id := schemaIdentity(c, filter), schemaIdentity(c, origin)
+	return base != nil && id != nil && id.DerivedFrom(base)
+}
+
+func schemaIdentity(c *yang.Context, id ops.Identity) *yang.Identity {
+	if m := c.ModuleByNamespace(id.Space); m != nil {
+		return m.Identities[id.Local]
+	}
+	return nil
+}
+
+// removeOrigins removes the or:origin annotations of n and its descendants
+func removeOrigins(n *Node) {
+	if n.hasOrigin() {
+		n.SetOrigin(ops.Identity{})
+	}
+	for _, c := range n.Children {
+		removeOrigins(c)
+	}
+}
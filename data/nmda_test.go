@@ -0,0 +1,108 @@
+package data
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/andaru/netconf/ops"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetData(t *testing.T) {
+	c := loadSchema(t)
+	const operational = `<system xmlns="urn:example:data" xmlns:o="urn:ietf:params:xml:ns:yang:ietf-origin" o:origin="o:intended">` +
+		`<hostname>r1</hostname>` +
+		`<server><name>primary</name><address>10.0.0.1</address><weight o:origin="o:default">10</weight></server>` +
+		`<uptime o:origin="o:system">10</uptime></system>`
+	for _, tc := range []struct {
+		name    string
+		op      ops.GetData
+		want    string
+		wantErr string
+	}{
+		{
+			name: "all",
+			op:   ops.GetData{Datastore: ops.DSOperational},
+			want: `<system xmlns="urn:example:data"><hostname>r1</hostname>` +
+				`<server><name>primary</name><address>10.0.0.1</address><weight>10</weight></server><uptime>10</uptime></system>`,
+		},
+		{
+			name: "subtree filter",
+			op:   ops.GetData{Datastore: ops.DSOperational, SubtreeFilter: &ops.Inline{Content: []byte(`<system xmlns="urn:example:data"><server><address/></server></system>`)}},
+			want: `<system xmlns="urn:example:data"><server><name>primary</name><address>10.0.0.1</address></server></system>`,
+		},
+		{
+			name: "xpath filter",
+			op: ops.GetData{Datastore: ops.DSOperational, XPathFilter: &ops.XPathFilter{Select: "/ex:system/ex:hostname",
+				Attrs: []xml.Attr{{Name: xml.Name{Space: "xmlns", Local: "ex"}, Value: "urn:example:data"}}}},
+			want: `<system xmlns="urn:example:data"><hostname>r1</hostname></system>`,
+		},
+		{
+			name: "state only",
+			op:   ops.GetData{Datastore: ops.DSOperational, ConfigFilter: new(bool)},
+			want: `<system xmlns="urn:example:data"><uptime>10</uptime></system>`,
+		},
+		{
+			name: "max-depth",
+			op:   ops.GetData{Datastore: ops.DSOperational, MaxDepth: 2, SubtreeFilter: &ops.Inline{Content: []byte(`<system xmlns="urn:example:data"><hostname/></system>`)}},
+			want: `<system xmlns="urn:example:data"><hostname>r1</hostname></system>`,
+		},
+		{
+			name: "origin filter",
+			op:   ops.GetData{Datastore: ops.DSOperational, OriginFilter: []ops.Identity{ops.OriginSystem, ops.OriginDefault}, WithOrigin: &ops.Empty{}},
+			want: `<system xmlns="urn:example:data" xmlns:o="urn:ietf:params:xml:ns:yang:ietf-origin" o:origin="o:intended">` +
+				`<server><name>primary</name><weight xmlns:o="urn:ietf:params:xml:ns:yang:ietf-origin" o:origin="o:default">10</weight></server>` +
+				`<uptime xmlns:o="urn:ietf:params:xml:ns:yang:ietf-origin" o:origin="o:system">10</uptime></system>`,
+		},
+		{
+			name: "negated origin filter",
+			op:   ops.GetData{Datastore: ops.DSOperational, NegatedOriginFilter: []ops.Identity{ops.OriginIntended}},
+			want: `<system xmlns="urn:example:data"><server><name>primary</name><weight>10</weight></server><uptime>10</uptime></system>`,
+		},
+		{
+			name:    "origin filter for running",
+			op:      ops.GetData{Datastore: ops.DSRunning, OriginFilter: []ops.Identity{ops.OriginSystem}},
+			wantErr: `rpc-error: protocol: invalid-value: origin filters are not valid for datastore "running"`,
+		},
+		{
+			name:    "both filters",
+			op:      ops.GetData{Datastore: ops.DSRunning, SubtreeFilter: &ops.Inline{}, XPathFilter: &ops.XPathFilter{Select: "/"}},
+			wantErr: `rpc-error: protocol: invalid-value: subtree-filter and xpath-filter are mutually exclusive`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			a := assert.New(t)
+			root, err := Parse(strings.NewReader(operational), c)
+			if !a.NoError(err) {
+				return
+			}
+			tree, err := GetData(root, &tc.op)
+			if tc.wantErr != "" {
+				a.EqualError(err, tc.wantErr)
+				return
+			}
+			if !a.NoError(err) {
+				return
+			}
+			var b strings.Builder
+			a.NoError(tree.WriteXML(&b))
+			a.Equal(tc.want, b.String())
+		})
+	}
+}
+
+func TestOrigin(t *testing.T) {
+	a := assert.New(t)
+	root, err := Parse(strings.NewReader(`<system xmlns="urn:example:data" xmlns:o="urn:ietf:params:xml:ns:yang:ietf-origin" o:origin="o:learned">`+
+		`<hostname>r1</hostname></system>`), loadSchema(t))
+	if !a.NoError(err) {
+		return
+	}
+	hostname := root.Children[0].Children[0]
+	a.Equal(ops.OriginLearned, hostname.Origin())
+	hostname.SetOrigin(ops.OriginDynamic)
+	a.Equal(ops.OriginDynamic, hostname.Origin())
+	hostname.SetOrigin(ops.Identity{})
+	a.Equal(ops.OriginLearned, hostname.Origin())
+}
@@ -0,0 +1,284 @@
+package data
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/andaru/netconf/xmlutil"
+	"github.com/andaru/netconf/yang"
+)
+
+// Node is a node of a data tree, being an XML element bound to its YANG
+// schema node. The root node of a tree has no name, and holds the tree's
+// top-level elements as its children.
+type Node struct {
+	// Name is the node's element name
+	Name xml.Name
+	// Schema is the node's schema node. It is nil for the root node, for
+	// elements not found in the schema, and for anydata and anyxml content.
+	Schema   *yang.Node
+	Parent   *Node
+	Children []*Node
+	// Value is the text content of the element, for leaf and leaf-list
+	// nodes, and for unknown elements with no child elements
+	Value string
+	// Attr holds the element's attributes, excluding namespace declarations
+	Attr []xml.Attr
+	// NS holds the namespace declarations in scope of the element, for
+	// leaf and leaf-list nodes and nodes with an or:origin annotation, to
+	// resolve prefixes in their values. The default namespace is held
+	// with an empty prefix.
+	NS xmlutil.PrefixMap
+
+	// ctx is the schema context of a root node
+	ctx *yang.Context
+}
+
+// NewRoot returns a new root node for a data tree using the schema of c
+func NewRoot(c *yang.Context) *Node { return &Node{ctx: c} }
+
+// Parse parses the sequence of XML elements read from r (such as the
+// content of a <config> or <data> element) into a new data tree using
+// the schema of c. Elements not found in the schema are retained, with
+// a nil Schema, to be reported by Validate.
+func Parse(r io.Reader, c *yang.Context) (*Node, error) {
+	root := NewRoot(c)
+	if err := root.DecodeXML(r); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// DecodeXML parses the sequence of XML elements read from r, appending
+// them to n's children, as for Parse.
+func (n *Node) DecodeXML(r io.Reader) error {
+	d := xml.NewDecoder(r)
+	ns := []xmlutil.PrefixMap{{}}
+	var text strings.Builder
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			scope := ns[len(ns)-1]
+			if decls := namespaces(t.Attr); len(decls) > 0 {
+				scope = merge(scope, decls)
+			}
+			ns = append(ns, scope)
+			child := &Node{Name: t.Name, Attr: attrs(t.Attr)}
+			n.Append(child)
+			if child.Schema != nil && (child.Schema.Kind == yang.KindLeaf || child.Schema.Kind == yang.KindLeafList) || child.hasOrigin() {
+				child.NS = scope
+			}
+			n = child
+			text.Reset()
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			if len(n.Children) == 0 {
+				n.Value = text.String()
+				if n.Schema != nil && n.Schema.Type != nil && n.Schema.Type.Kind != yang.TypeString {
+					n.Value = strings.TrimSpace(n.Value)
+				}
+			}
+			text.Reset()
+			ns = ns[:len(ns)-1]
+			n = n.Parent
+		}
+	}
+	return nil
+}
+
+// namespaces returns the namespace declarations of attrs
+func namespaces(attrs []xml.Attr) xmlutil.PrefixMap {
+	m := xmlutil.NewPrefixMap(attrs...)
+	for _, a := range attrs {
+		if a.Name.Space == "" && a.Name.Local == "xmlns" {
+			m[""] = a.Value
+		}
+	}
+	return m
+}
+
+func merge(a, b xmlutil.PrefixMap) xmlutil.PrefixMap {
+	m := xmlutil.PrefixMap{}
+	for k, v := range a {
+		m[k] = v
+	}
+	for k, v := range b {
+		m[k] = v
+	}
+	return m
+}
+
+// attrs returns attrs, excluding namespace declarations
+func attrs(attrs []xml.Attr) (out []xml.Attr) {
+	for _, a := range attrs {
+		if a.Name.Space != "xmlns" && !(a.Name.Space == "" && a.Name.Local == "xmlns") {
+			out = append(out, a)
+		}
+	}
+	return
+}
+
+// Append appends child to n's children, binding child to its schema node
+// if known
+func (n *Node) Append(child *Node) {
+	child.Parent = n
+	child.Schema = n.childSchema(child.Name)
+	n.Children = append(n.Children, child)
+}
+
+// childSchema returns the schema node of a child element of n with name
+func (n *Node) childSchema(name xml.Name) *yang.Node {
+	if n.Parent == nil {
+		c := n.ctx
+		if c == nil {
+			return nil
+		}
+		if m := c.ModuleByNamespace(name.Space); m != nil {
+			return m.Root.DataChild(m, name.Local)
+		}
+		return nil
+	}
+	if n.Schema == nil {
+		return nil
+	}
+	switch n.Schema.Kind {
+	case yang.KindAnydata, yang.KindAnyxml:
+		return nil
+	}
+	m := n.Schema.Module
+	if name.Space != m.Namespace {
+		if m = n.Context().ModuleByNamespace(name.Space); m == nil {
+			return nil
+		}
+	}
+	return n.Schema.DataChild(m, name.Local)
+}
+
+// Root returns the root node of n's tree
+func (n *Node) Root() *Node {
+	for n.Parent != nil {
+		n = n.Parent
+	}
+	return n
+}
+
+// Context returns the schema context of n's tree
+func (n *Node) Context() *yang.Context {
+	if c := n.Root().ctx; c != nil {
+		return c
+	}
+	return yang.NewContext()
+}
+
+// Child returns the first child of n with name, or nil
+func (n *Node) Child(name xml.Name) *Node {
+	for _, c := range n.Children {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// Remove removes n from its parent's children
+func (n *Node) Remove() {
+	if p := n.Parent; p != nil {
+		for i, c := range p.Children {
+			if c == n {
+				p.Children = append(p.Children[:i:i], p.Children[i+1:]...)
+				break
+			}
+		}
+		n.Parent = nil
+	}
+}
+
+// LimitDepth removes the descendants of n deeper than depth levels, n
+// being the first level, such as for the max-depth of a <get-data>
+// request or the depth of a RESTCONF query.
+func (n *Node) LimitDepth(depth int) {
+	if depth <= 1 {
+		n.Children = nil
+		return
+	}
+	for _, c := range n.Children {
+		c.LimitDepth(depth - 1)
+	}
+}
+
+// Keys returns the key leaf nodes of a list entry, in key order. Missing
+// keys are omitted.
+func (n *Node) Keys() (keys []*Node) {
+	if n.Schema == nil {
+		return nil
+	}
+	for _, k := range n.Schema.Keys {
+		if leaf := n.Child(xml.Name{Space: n.Name.Space, Local: k}); leaf != nil {
+			keys = append(keys, leaf)
+		}
+	}
+	return
+}
+
+// Path returns n's instance identifier, such as
+// "/sys:system/sys:server[sys:name='a']/sys:port", using module prefixes,
+// along with the namespaces of those prefixes.
+func (n *Node) Path() (path string, ns map[string]string) {
+	ns = map[string]string{}
+	var parts []string
+	for ; n != nil && n.Parent != nil; n = n.Parent {
+		prefix := n.prefix(ns)
+		step := prefix + n.Name.Local
+		if n.Schema != nil {
+			switch n.Schema.Kind {
+			case yang.KindList:
+				for _, k := range n.Keys() {
+					step += "[" + prefix + k.Name.Local + "=" + quote(k.Value) + "]"
+				}
+			case yang.KindLeafList:
+				step += "[.=" + quote(n.Value) + "]"
+			}
+		}
+		parts = append([]string{step}, parts...)
+	}
+	return "/" + strings.Join(parts, "/"), ns
+}
+
+// prefix returns the prefix (with a trailing colon) of n's namespace,
+// adding it to ns
+func (n *Node) prefix(ns map[string]string) string {
+	if n.Name.Space == "" {
+		return ""
+	}
+	prefix := ""
+	if n.Schema != nil {
+		prefix = n.Schema.Module.Prefix
+	} else if m := n.Context().ModuleByNamespace(n.Name.Space); m != nil {
+		prefix = m.Prefix
+	}
+	if prefix == "" || (ns[prefix] != "" && ns[prefix] != n.Name.Space) {
+		// choose a unique prefix for unknown namespaces
+		for i := len(ns); ns[prefix] != "" || prefix == ""; i++ {
+			prefix = fmt.Sprintf("ns%d", i)
+		}
+	}
+	ns[prefix] = n.Name.Space
+	return prefix + ":"
+}
+
+// quote returns s as an XPath string literal
+func quote(s string) string {
+	if strings.Contains(s, "'") {
+		return `"` + s + `"`
+	}
+	return "'" + s + "'"
+}
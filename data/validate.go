@@ -0,0 +1,477 @@
+package data
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/andaru/netconf/ops"
+	"github.com/andaru/netconf/yang"
+)
+
+// Options control data tree validation
+type Options struct {
+	// State permits state data (config false nodes), such as found in
+	// <get> replies. Otherwise, the tree is validated as configuration.
+	State bool
+	// Partial validates only the nodes present in the tree, such as the
+	// content of an <edit-config>. Mandatory nodes, min-elements,
+	// max-elements, unique, must, when and leafref constraints, which
+	// apply to the resulting datastore, are not checked.
+	Partial bool
+}
+
+// Errors is a list of validation errors, as returned by Validate. It
+// may be used as the Errors of an ops.RPCReply.
+type Errors []ops.RPCError
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i := range e {
+		msgs[i] = e[i].Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Values for RPCError AppTag, per RFC7950 section 15
+const (
+	AppTagDataNotUnique    = "data-not-unique"
+	AppTagTooManyElements  = "too-many-elements"
+	AppTagTooFewElements   = "too-few-elements"
+	AppTagMustViolation    = "must-violation"
+	AppTagInstanceRequired = "instance-required"
+	AppTagMissingChoice    = "missing-choice"
+)
+
+// Validate validates the data tree root against its schema, returning
+// Errors holding an rpc-error (with error-path and error-app-tag, where
+// appropriate) for each violation found, or nil if the tree is valid.
+func Validate(root *Node, opts Options) error {
+	v := &validator{opts: opts, xpaths: map[*yang.XPath]*XPath{}}
+	v.node(root)
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+type validator struct {
+	opts   Options
+	errs   Errors
+	xpaths map[*yang.XPath]*XPath
+}
+
+// fail records a validation error at node n
+func (v *validator) fail(n *Node, tag, appTag, msg string, info ...string) {
+	path, ns := n.Path()
+	err := ops.RPCError{
+		Type:     ops.ErrorTypeApplication,
+		Tag:      tag,
+		Severity: ops.SeverityError,
+		AppTag:   appTag,
+		Path:     path,
+		PathNS:   ns,
+		Message:  msg,
+	}
+	if len(info) > 0 {
+		err.Info = &ops.Inline{Content: []byte(strings.Join(info, ""))}
+	}
+	v.errs = append(v.errs, err)
+}
+
+var (
+	textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	attrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+)
+
+// element returns an error-info element with text content
+func element(name, text string, attrs ...xml.Attr) string {
+	var b strings.Builder
+	b.WriteString("<" + name)
+	for _, a := range attrs {
+		b.WriteString(" " + a.Name.Local + `="` + attrEscaper.Replace(a.Value) + `"`)
+	}
+	b.WriteString(">" + textEscaper.Replace(text) + "</" + name + ">")
+	return b.String()
+}
+
+// compile returns the compiled YANG XPath expression x
+func (v *validator) compile(x *yang.XPath) (*XPath, error) {
+	if compiled := v.xpaths[x]; compiled != nil {
+		return compiled, nil
+	}
+	compiled, err := compileYANG(*x)
+	if err == nil {
+		v.xpaths[x] = compiled
+	}
+	return compiled, err
+}
+
+// eval evaluates the YANG XPath expression x as a boolean, with
+// context node n. Errors are recorded, evaluating to true.
+func (v *validator) eval(n *Node, x *yang.XPath) bool {
+	compiled, err := v.compile(x)
+	if err == nil {
+		var ok bool
+		if ok, err = compiled.Bool(n); err == nil {
+			return ok
+		}
+	}
+	v.fail(n, ops.ErrorTagOperationFailed, "", err.Error())
+	return true
+}
+
+// node validates the children of n
+func (v *validator) node(n *Node) {
+	var order []*yang.Node
+	instances := map[*yang.Node][]*Node{}
+	cases := map[*yang.Node]*yang.Node{}
+	for _, c := range n.Children {
+		if c.Schema == nil {
+			v.unknown(c)
+			continue
+		}
+		if !v.opts.State && !c.Schema.Config {
+			v.fail(c, ops.ErrorTagUnknownElement, "", fmt.Sprintf("state data %q is not permitted in configuration", c.Name.Local),
+				element("bad-element", c.Name.Local))
+			continue
+		}
+		if !v.cases(c, cases) {
+			continue
+		}
+		if instances[c.Schema] == nil {
+			order = append(order, c.Schema)
+		}
+		instances[c.Schema] = append(instances[c.Schema], c)
+		v.child(c)
+	}
+	for _, s := range order {
+		v.instances(s, instances[s])
+	}
+	if v.opts.Partial {
+		return
+	}
+	if n.Parent == nil {
+		v.mandatory(n, topLevel(n.Context()))
+	} else {
+		v.mandatory(n, n.Schema.Children)
+	}
+}
+
+// unknown records an error for the unknown element n
+func (v *validator) unknown(n *Node) {
+	if n.Parent.Schema != nil && n.Parent.Schema.Module.Namespace == n.Name.Space || n.Context().ModuleByNamespace(n.Name.Space) != nil {
+		v.fail(n.Parent, ops.ErrorTagUnknownElement, "", fmt.Sprintf("unknown element %q", n.Name.Local),
+			element("bad-element", n.Name.Local))
+		return
+	}
+	v.fail(n.Parent, ops.ErrorTagUnknownNamespace, "", fmt.Sprintf("unknown namespace %q", n.Name.Space),
+		element("bad-element", n.Name.Local), element("bad-namespace", n.Name.Space))
+}
+
+// topLevel returns the top-level schema nodes of the modules of c
+func topLevel(c *yang.Context) (nodes []*yang.Node) {
+	names := make([]string, 0, len(c.Modules))
+	for name := range c.Modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if root := c.Modules[name].Root; root != nil {
+			nodes = append(nodes, root.Children...)
+		}
+	}
+	return
+}
+
+// cases checks that n is not in a different case of a choice than its
+// preceding siblings, recorded in cases by choice
+func (v *validator) cases(n *Node, cases map[*yang.Node]*yang.Node) bool {
+	for s := n.Schema; s.Parent != nil && s.Parent.Kind == yang.KindCase; s = s.Parent.Parent {
+		c, choice := s.Parent, s.Parent.Parent
+		if prev := cases[choice]; prev != nil && prev != c {
+			v.fail(n, ops.ErrorTagBadElement, "", fmt.Sprintf("element %q is in case %q, but case %q of choice %q is present",
+				n.Name.Local, c.Name, prev.Name, choice.Name), element("bad-element", n.Name.Local))
+			return false
+		}
+		cases[choice] = c
+	}
+	return true
+}
+
+// child validates the data node n
+func (v *validator) child(n *Node) {
+	s := n.Schema
+	switch s.Kind {
+	case yang.KindLeaf, yang.KindLeafList:
+		v.value(n)
+	case yang.KindList:
+		for _, k := range s.Keys {
+			if n.Child(xml.Name{Space: n.Name.Space, Local: k}) == nil {
+				v.fail(n, ops.ErrorTagMissingElement, "", fmt.Sprintf("list %q entry is missing key %q", s.Name, k),
+					element("bad-element", k))
+			}
+		}
+		v.node(n)
+	case yang.KindContainer:
+		v.node(n)
+	}
+	if v.opts.Partial {
+		return
+	}
+	for i := range s.When {
+		ctx := n
+		if s.When[i].Parent {
+			ctx = n.Parent
+		}
+		if !v.eval(ctx, &s.When[i].XPath) {
+			v.fail(n, ops.ErrorTagUnknownElement, "", fmt.Sprintf("when condition %q is not satisfied", s.When[i].Expr),
+				element("bad-element", n.Name.Local))
+			return
+		}
+	}
+	// conditions of choices and cases containing n
+	for p := s.Parent; p != nil && (p.Kind == yang.KindChoice || p.Kind == yang.KindCase); p = p.Parent {
+		for i := range p.When {
+			if !v.eval(n.Parent, &p.When[i].XPath) {
+				v.fail(n, ops.ErrorTagUnknownElement, "", fmt.Sprintf("when condition %q of %v %q is not satisfied", p.When[i].Expr, p.Kind, p.Name),
+					element("bad-element", n.Name.Local))
+				return
+			}
+		}
+	}
+	for i := range s.Must {
+		must := &s.Must[i]
+		if v.eval(n, &must.XPath) {
+			continue
+		}
+		msg, appTag := must.ErrorMessage, must.ErrorAppTag
+		if msg == "" {
+			msg = fmt.Sprintf("must constraint %q is not satisfied", must.Expr)
+		}
+		if appTag == "" {
+			appTag = AppTagMustViolation
+		}
+		v.fail(n, ops.ErrorTagOperationFailed, appTag, msg)
+	}
+}
+
+// value validates the value of the leaf or leaf-list node n
+func (v *validator) value(n *Node) {
+	if len(n.Children) > 0 {
+		v.fail(n, ops.ErrorTagBadElement, "", fmt.Sprintf("%v %q has child elements", n.Schema.Kind, n.Name.Local),
+			element("bad-element", n.Name.Local))
+		return
+	}
+	t := n.Schema.Type
+	if t == nil {
+		return
+	}
+	if inv := checkValue(n, t, n.Value); inv != nil {
+		v.fail(n, ops.ErrorTagInvalidValue, inv.appTag, inv.message)
+		return
+	}
+	if v.opts.Partial || !t.RequireInstance || t.Kind != yang.TypeLeafref && t.Kind != yang.TypeInstanceIdentifier {
+		return
+	}
+	targets, err := deref(n)
+	switch {
+	case err != nil:
+		v.fail(n, ops.ErrorTagInvalidValue, "", err.Error())
+	case len(targets) == 0:
+		v.fail(n, ops.ErrorTagDataMissing, AppTagInstanceRequired, fmt.Sprintf("required instance %q of %q not found", n.Value, n.Name.Local))
+	}
+}
+
+// instances validates the instances of the schema node s in a parent
+func (v *validator) instances(s *yang.Node, nodes []*Node) {
+	switch s.Kind {
+	case yang.KindList, yang.KindLeafList:
+		seen := map[string]bool{}
+		for _, n := range nodes {
+			key := n.Value
+			if s.Kind == yang.KindList {
+				if len(s.Keys) == 0 {
+					break
+				}
+				key = keyString(n.Keys())
+			} else if !s.Config {
+				break
+			}
+			if seen[key] {
+				v.fail(n, ops.ErrorTagOperationFailed, "", fmt.Sprintf("duplicate %v %q entry", s.Kind, s.Name))
+			}
+			seen[key] = true
+		}
+	default:
+		for _, n := range nodes[1:] {
+			v.fail(n, ops.ErrorTagBadElement, "", fmt.Sprintf("duplicate %v %q", s.Kind, s.Name),
+				element("bad-element", n.Name.Local))
+		}
+		return
+	}
+	if v.opts.Partial {
+		return
+	}
+	if s.MaxElements > 0 && len(nodes) > s.MaxElements {
+		v.fail(nodes[s.MaxElements], ops.ErrorTagOperationFailed, AppTagTooManyElements,
+			fmt.Sprintf("%v %q has more than %d entries", s.Kind, s.Name, s.MaxElements))
+	}
+	for _, unique := range s.Unique {
+		v.unique(s, unique, nodes)
+	}
+}
+
+func keyString(keys []*Node) string {
+	values := make([]string, len(keys))
+	for i, k := range keys {
+		values[i] = k.Value
+	}
+	return strings.Join(values, "\x00")
+}
+
+// unique validates the unique statement (of descendant paths) of list s
+func (v *validator) unique(s *yang.Node, unique []string, entries []*Node) {
+	seen := map[string][]*Node{}
+	for _, entry := range entries {
+		leafs := make([]*Node, 0, len(unique))
+		for _, path := range unique {
+			if leaf := descendant(entry, path); leaf != nil {
+				leafs = append(leafs, leaf)
+			}
+		}
+		if len(leafs) < len(unique) {
+			// entries lacking any of the leafs are not constrained
+			continue
+		}
+		key := keyString(leafs)
+		if prev := seen[key]; prev != nil {
+			var info []string
+			for _, leaf := range leafs {
+				path, ns := leaf.Path()
+				info = append(info, element("non-unique", path, nsAttrs(ns)...))
+			}
+			v.fail(entry, ops.ErrorTagOperationFailed, AppTagDataNotUnique,
+				fmt.Sprintf("unique constraint %q of list %q violated", strings.Join(unique, " "), s.Name), info...)
+			continue
+		}
+		seen[key] = leafs
+	}
+}
+
+func nsAttrs(ns map[string]string) (attrs []xml.Attr) {
+	prefixes := make([]string, 0, len(ns))
+	for prefix := range ns {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	for _, prefix := range prefixes {
+		attrs = append(attrs, xml.Attr{Name: xml.Name{Local: "xmlns:" + prefix}, Value: ns[prefix]})
+	}
+	return
+}
+
+// descendant returns the descendant of n identified by the descendant
+// schema node identifier path, whose prefixes are ignored
+func descendant(n *Node, path string) *Node {
+	for _, step := range strings.Split(path, "/") {
+		if i := strings.IndexByte(step, ':'); i >= 0 {
+			step = step[i+1:]
+		}
+		var next *Node
+		for _, c := range n.Children {
+			if c.Name.Local == step {
+				next = c
+				break
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		n = next
+	}
+	return n
+}
+
+// mandatory checks that the mandatory nodes and minimum elements among
+// the schema nodes children are present in n
+func (v *validator) mandatory(n *Node, children []*yang.Node) {
+	for _, s := range children {
+		if !v.opts.State && !s.Config {
+			continue
+		}
+		switch s.Kind {
+		case yang.KindLeaf, yang.KindAnydata, yang.KindAnyxml:
+			if s.Mandatory && count(n, s) == 0 && v.applies(n, s) {
+				v.fail(placeholder(n, s), ops.ErrorTagDataMissing, "", fmt.Sprintf("mandatory %v %q is missing", s.Kind, s.Name))
+			}
+		case yang.KindList, yang.KindLeafList:
+			if c := count(n, s); s.MinElements > c && v.applies(n, s) {
+				v.fail(placeholder(n, s), ops.ErrorTagOperationFailed, AppTagTooFewElements,
+					fmt.Sprintf("%v %q has fewer than %d entries", s.Kind, s.Name, s.MinElements))
+			}
+		case yang.KindContainer:
+			// mandatory nodes within non-presence containers are required
+			// even when the container is absent
+			if s.Presence == "" && count(n, s) == 0 && v.applies(n, s) {
+				v.mandatory(placeholder(n, s), s.Children)
+			}
+		case yang.KindChoice:
+			var active *yang.Node
+			for _, c := range n.Children {
+				for p := c.Schema; p != nil && p.Parent != nil && active == nil; p = p.Parent {
+					if p.Parent == s {
+						active = p
+					}
+				}
+			}
+			switch {
+			case active != nil:
+				v.mandatory(n, active.Children)
+			case s.Mandatory && v.applies(n, s):
+				v.fail(n, ops.ErrorTagDataMissing, AppTagMissingChoice, fmt.Sprintf("mandatory choice %q is missing", s.Name),
+					element("missing-choice", s.Name))
+			case len(s.Default) > 0:
+				if c := s.Child(nil, s.Default[0]); c != nil {
+					v.mandatory(n, c.Children)
+				}
+			}
+		}
+	}
+}
+
+// count returns the number of children of n with schema node s
+func count(n *Node, s *yang.Node) (c int) {
+	for _, child := range n.Children {
+		if child.Schema == s {
+			c++
+		}
+	}
+	return
+}
+
+// placeholder returns a node for the absent schema node s, within n
+func placeholder(n *Node, s *yang.Node) *Node {
+	return &Node{Name: xml.Name{Space: s.Module.Namespace, Local: s.Name}, Schema: s, Parent: n}
+}
+
+// applies returns true if the when conditions of the absent schema node
+// s (and any choices and cases containing it) within n are satisfied
+func (v *validator) applies(n *Node, s *yang.Node) bool {
+	for p := s; p != nil; p = p.Parent {
+		for i := range p.When {
+			ctx := n
+			if p == s && !p.When[i].Parent && s.IsData() {
+				ctx = placeholder(n, s)
+			}
+			if !v.eval(ctx, &p.When[i].XPath) {
+				return false
+			}
+		}
+		if p.Parent == nil || p.Parent.Kind != yang.KindChoice && p.Parent.Kind != yang.KindCase {
+			break
+		}
+	}
+	return true
+}
@@ -0,0 +1,202 @@
+package data
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/andaru/netconf/yang"
+	"github.com/stretchr/testify/assert"
+)
+
+func loadSchema(t *testing.T) *yang.Context {
+	t.Helper()
+	c := yang.NewContext("testdata")
+	if err := c.Load("example-data"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Resolve(); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+const validSystem = `<hostname>r1</hostname>` +
+	`<server><name>primary</name><address>10.0.0.1</address><weight>20</weight></server>` +
+	`<server><name>backup</name><address>10.0.0.2</address></server>` +
+	`<primary>primary</primary><ssh/>`
+
+func TestValidate(t *testing.T) {
+	c := loadSchema(t)
+	for _, tc := range []struct {
+		name string
+		xml  string
+		opts Options
+		// want holds "error-tag error-app-tag error-path: error-message" per error
+		want []string
+	}{
+		{
+			name: "valid",
+			xml: `<system xmlns="urn:example:data" xmlns:ex="urn:example:data">` + validSystem +
+				`<mtu>1500</mtu><ratio>0.25</ratio><enabled>true</enabled><mode>manual</mode><flags>b a</flags>` +
+				`<proto>ex:tcp</proto><dns>a</dns><dns>b</dns><logging><level>5</level><file>x</file></logging>` +
+				`<extra><anything xmlns="urn:other"/></extra></system>`,
+		},
+		{
+			name: "empty",
+			// mandatory nodes within the non-presence container are required
+			want: []string{
+				`data-missing  /ex:system/ex:hostname: mandatory leaf "hostname" is missing`,
+				`operation-failed too-few-elements /ex:system/ex:server: list "server" has fewer than 1 entries`,
+				`data-missing missing-choice /ex:system: mandatory choice "transport" is missing`,
+			},
+		},
+		{
+			name: "empty partial",
+			opts: Options{Partial: true},
+		},
+		{
+			name: "state data",
+			xml:  `<system xmlns="urn:example:data">` + validSystem + `<uptime>10</uptime></system>`,
+			want: []string{`unknown-element  /ex:system/ex:uptime: state data "uptime" is not permitted in configuration`},
+		},
+		{
+			name: "state data permitted",
+			xml:  `<system xmlns="urn:example:data">` + validSystem + `<uptime>10</uptime></system>`,
+			opts: Options{State: true},
+		},
+		{
+			name: "unknown element",
+			xml:  `<system xmlns="urn:example:data">` + validSystem + `<foo/></system>`,
+			want: []string{`unknown-element  /ex:system: unknown element "foo"`},
+		},
+		{
+			name: "unknown namespace",
+			xml:  `<system xmlns="urn:example:data">` + validSystem + `</system><top xmlns="urn:other"/>`,
+			want: []string{`unknown-namespace  /: unknown namespace "urn:other"`},
+		},
+		{
+			name: "types",
+			xml: `<system xmlns="urn:example:data">` + validSystem +
+				`<mtu>10</mtu><ratio>0.125</ratio><enabled>yes</enabled><mode>off</mode><flags>a c</flags>` +
+				`<proto xmlns:ex="urn:example:data">ex:protocol</proto></system>`,
+			want: []string{
+				`invalid-value  /ex:system/ex:mtu: value 10 is not within range "68..9000"`,
+				`invalid-value  /ex:system/ex:ratio: invalid decimal64 value "0.125"`,
+				`invalid-value  /ex:system/ex:enabled: invalid boolean value "yes"`,
+				`invalid-value  /ex:system/ex:mode: invalid enumeration value "off"`,
+				`invalid-value  /ex:system/ex:flags: invalid bits value "a c"`,
+				`invalid-value  /ex:system/ex:proto: identity "ex:protocol" is not derived from example-data:protocol`,
+			},
+		},
+		{
+			name: "pattern",
+			xml:  `<system xmlns="urn:example:data">` + strings.Replace(validSystem, "r1", "R1", 1) + `</system>`,
+			want: []string{`invalid-value bad-hostname /ex:system/ex:hostname: invalid hostname`},
+		},
+		{
+			name: "mandatory",
+			xml:  `<system xmlns="urn:example:data"><server><name>a</name></server><logging/></system>`,
+			want: []string{
+				`data-missing  /ex:system/ex:logging/ex:level: mandatory leaf "level" is missing`,
+				`data-missing  /ex:system/ex:hostname: mandatory leaf "hostname" is missing`,
+				`data-missing missing-choice /ex:system: mandatory choice "transport" is missing`,
+			},
+		},
+		{
+			name: "partial",
+			xml:  `<system xmlns="urn:example:data"><server><name>a</name><weight>20</weight></server><primary>b</primary></system>`,
+			opts: Options{Partial: true},
+		},
+		{
+			name: "list keys",
+			xml: `<system xmlns="urn:example:data">` + validSystem +
+				`<server><address>10.0.0.3</address></server><server><name>backup</name></server></system>`,
+			opts: Options{Partial: true},
+			want: []string{
+				`missing-element  /ex:system/ex:server: list "server" entry is missing key "name"`,
+				`operation-failed  /ex:system/ex:server[ex:name='backup']: duplicate list "server" entry`,
+			},
+		},
+		{
+			name: "duplicate leaf",
+			xml:  `<system xmlns="urn:example:data">` + validSystem + `<mtu>1500</mtu><mtu>1500</mtu></system>`,
+			want: []string{`bad-element  /ex:system/ex:mtu: duplicate leaf "mtu"`},
+		},
+		{
+			name: "choice cases",
+			xml:  `<system xmlns="urn:example:data">` + validSystem + `<tls/></system>`,
+			want: []string{`bad-element  /ex:system/ex:tls: element "tls" is in case "tls", but case "ssh" of choice "transport" is present`},
+		},
+		{
+			name: "elements",
+			xml: `<system xmlns="urn:example:data"><hostname>r1</hostname><ssh/>` +
+				`<dns>a</dns><dns>b</dns><dns>c</dns></system>`,
+			want: []string{
+				`operation-failed too-many-elements /ex:system/ex:dns[.='c']: leaf-list "dns" has more than 2 entries`,
+				`operation-failed too-few-elements /ex:system/ex:server: list "server" has fewer than 1 entries`,
+			},
+		},
+		{
+			name: "unique",
+			xml: `<system xmlns="urn:example:data">` + validSystem +
+				`<server><name>c</name><address>10.0.0.1</address></server>` +
+				`<server><name>d</name><address>10.0.0.1</address><port>1</port></server>` +
+				`<server><name>e</name><address>10.0.0.1</address><port>1</port></server></system>`,
+			want: []string{`operation-failed data-not-unique /ex:system/ex:server[ex:name='e']: unique constraint "address port" of list "server" violated`},
+		},
+		{
+			name: "must",
+			xml:  `<system xmlns="urn:example:data">` + strings.Replace(validSystem, "<name>backup</name>", "<name>backup</name><weight>11</weight>", 1) + `</system>`,
+			want: []string{`operation-failed must-violation /ex:system/ex:server[ex:name='backup']/ex:weight: weight too high`},
+		},
+		{
+			name: "when",
+			xml:  `<system xmlns="urn:example:data">` + validSystem + `<logging><level>1</level><file>x</file></logging></system>`,
+			want: []string{`unknown-element  /ex:system/ex:logging/ex:file: when condition "../level > 3" is not satisfied`},
+		},
+		{
+			name: "leafref",
+			xml:  `<system xmlns="urn:example:data">` + strings.Replace(validSystem, "<primary>primary", "<primary>other", 1) + `</system>`,
+			want: []string{`data-missing instance-required /ex:system/ex:primary: required instance "other" of "primary" not found`},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			a := assert.New(t)
+			root, err := Parse(strings.NewReader(tc.xml), c)
+			if !a.NoError(err) {
+				return
+			}
+			err = Validate(root, tc.opts)
+			if tc.want == nil {
+				a.NoError(err)
+				return
+			}
+			var got []string
+			if errs, ok := err.(Errors); a.True(ok, "%v", err) {
+				for _, e := range errs {
+					got = append(got, fmt.Sprintf("%s %s %s: %s", e.Tag, e.AppTag, e.Path, e.Message))
+				}
+			}
+			a.Equal(tc.want, got)
+		})
+	}
+}
+
+func TestValidateErrorInfo(t *testing.T) {
+	a := assert.New(t)
+	root, err := Parse(strings.NewReader(`<system xmlns="urn:example:data">`+validSystem+
+		`<foo/><server><name>c</name><address>10.0.0.1</address><port>1</port></server>`+
+		`<server><name>d</name><address>10.0.0.1</address><port>1</port></server></system>`), loadSchema(t))
+	if !a.NoError(err) {
+		return
+	}
+	errs, ok := Validate(root, Options{}).(Errors)
+	if !a.True(ok) || !a.Len(errs, 2) {
+		return
+	}
+	a.Equal(map[string]string{"ex": "urn:example:data"}, errs[0].PathNS)
+	a.Equal(`<bad-element>foo</bad-element>`, string(errs[0].Info.Content))
+	a.Equal(`<non-unique xmlns:ex="urn:example:data">/ex:system/ex:server[ex:name='d']/ex:address</non-unique>`+
+		`<non-unique xmlns:ex="urn:example:data">/ex:system/ex:server[ex:name='d']/ex:port</non-unique>`, string(errs[1].Info.Content))
+}
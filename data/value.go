@@ -0,0 +1,133 @@
+package data
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/andaru/netconf/yang"
+)
+
+// invalid describes an invalid value, along with the error-message
+// and error-app-tag of any restriction it violates
+type invalid struct {
+	message string
+	appTag  string
+}
+
+var decimalSyntax = regexp.MustCompile(`^[-+]?[0-9]+(\.[0-9]+)?$`)
+
+// checkValue checks the value of the leaf or leaf-list node n against
+// type t, returning nil if valid
+func checkValue(n *Node, t *yang.Type, value string) *invalid {
+	bad := func() *invalid { return &invalid{message: fmt.Sprintf("invalid %v value %q", t.Kind, value)} }
+	switch t.Kind {
+	case yang.TypeInt8, yang.TypeInt16, yang.TypeInt32, yang.TypeInt64,
+		yang.TypeUint8, yang.TypeUint16, yang.TypeUint32, yang.TypeUint64:
+		i, ok := new(big.Int).SetString(value, 10)
+		if !ok {
+			return bad()
+		}
+		return checkRange(t.Range, new(big.Rat).SetInt(i), value)
+	case yang.TypeDecimal64:
+		if !decimalSyntax.MatchString(value) {
+			return bad()
+		}
+		if i := strings.IndexByte(value, '.'); i >= 0 && len(value)-i-1 > t.FractionDigits {
+			return bad()
+		}
+		r, _ := new(big.Rat).SetString(value)
+		return checkRange(t.Range, r, value)
+	case yang.TypeString:
+		if err := checkLength(t.Length, utf8.RuneCountInString(value), value); err != nil {
+			return err
+		}
+		for _, p := range t.Patterns {
+			if !p.Match(value) {
+				msg := p.ErrorMessage
+				if msg == "" {
+					msg = fmt.Sprintf("value %q does not match pattern %q", value, p.Expr)
+				}
+				return &invalid{message: msg, appTag: p.ErrorAppTag}
+			}
+		}
+	case yang.TypeBinary:
+		b, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(value), ""))
+		if err != nil {
+			return bad()
+		}
+		return checkLength(t.Length, len(b), value)
+	case yang.TypeBoolean:
+		if value != "true" && value != "false" {
+			return bad()
+		}
+	case yang.TypeEmpty:
+		if value != "" {
+			return bad()
+		}
+	case yang.TypeEnumeration:
+		if enumValue(t, value) == nil {
+			return bad()
+		}
+	case yang.TypeBits:
+		seen := map[string]bool{}
+		for _, name := range strings.Fields(value) {
+			found := false
+			for _, b := range t.Bits {
+				found = found || b.Name == name
+			}
+			if !found || seen[name] {
+				return bad()
+			}
+			seen[name] = true
+		}
+	case yang.TypeIdentityref:
+		id := identity(n.Context(), value, n.NS, n.Name.Space)
+		if id == nil {
+			return bad()
+		}
+		for _, base := range t.Bases {
+			if !id.DerivedFrom(base) {
+				return &invalid{message: fmt.Sprintf("identity %q is not derived from %s:%s", value, base.Module.Name, base.Name)}
+			}
+		}
+	case yang.TypeInstanceIdentifier:
+		if _, err := CompileXPath(value, n.NS); err != nil {
+			return bad()
+		}
+	case yang.TypeUnion:
+		for _, member := range t.Union {
+			if checkValue(n, member, value) == nil {
+				return nil
+			}
+		}
+		return &invalid{message: fmt.Sprintf("value %q does not match any union member type", value)}
+	}
+	// leafref values are checked against their target instances
+	return nil
+}
+
+func checkRange(r *yang.Restriction, v *big.Rat, value string) *invalid {
+	if r == nil || r.Contains(v) {
+		return nil
+	}
+	msg := r.ErrorMessage
+	if msg == "" {
+		msg = fmt.Sprintf("value %s is not within range %q", value, r)
+	}
+	return &invalid{message: msg, appTag: r.ErrorAppTag}
+}
+
+func checkLength(r *yang.Restriction, length int, value string) *invalid {
+	if r == nil || r.Contains(big.NewRat(int64(length), 1)) {
+		return nil
+	}
+	msg := r.ErrorMessage
+	if msg == "" {
+		msg = fmt.Sprintf("length of value %q is not within %q", value, r)
+	}
+	return &invalid{message: msg, appTag: r.ErrorAppTag}
+}
@@ -0,0 +1,203 @@
+package data
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/andaru/netconf/ops"
+	"github.com/andaru/netconf/yang"
+)
+
+// WriteXML writes the children of n to w as a sequence of XML elements,
+// the inverse of Parse. Namespaces are declared where they change, and
+// for prefixes used in identityref and instance-identifier values. List
+// keys are written first, in key order.
+func (n *Node) WriteXML(w io.Writer) error { return EncodeXML(w, n.Children) }
+
+// EncodeXML writes the nodes to w as a sequence of XML elements, each
+// declaring its namespace.
+func EncodeXML(w io.Writer, nodes []*Node) error {
+	bw := bufio.NewWriter(w)
+	xw := &xmlWriter{w: bw}
+	for _, c := range nodes {
+		xw.element(c, "")
+	}
+	if err := bw.Flush(); xw.err == nil {
+		xw.err = err
+	}
+	return xw.err
+}
+
+type xmlWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (x *xmlWriter) write(s ...string) {
+	for _, v := range s {
+		if x.err == nil {
+			_, x.err = x.w.WriteString(v)
+		}
+	}
+}
+
+// element writes n, whose parent element's default namespace is ns
+func (x *xmlWriter) element(n *Node, ns string) {
+	x.write("<", n.Name.Local)
+	if n.Name.Space != ns {
+		x.write(` xmlns="`, attrEscaper.Replace(n.Name.Space), `"`)
+	}
+	decls := map[string]string{}
+	for _, prefix := range valuePrefixes(n) {
+		if space, ok := n.NS[prefix]; ok && prefix != "" {
+			decls[prefix] = space
+		}
+	}
+	if prefix := originPrefix(n); prefix != "" {
+		if space, ok := n.NS[prefix]; ok {
+			decls[prefix] = space
+		}
+	}
+	var attrs []string
+	for _, a := range n.Attr {
+		name := a.Name.Local
+		if a.Name.Space != "" {
+			prefix := attrPrefix(n, a.Name.Space, decls)
+			decls[prefix] = a.Name.Space
+			name = prefix + ":" + name
+		}
+		attrs = append(attrs, " "+name+`="`+attrEscaper.Replace(a.Value)+`"`)
+	}
+	for _, a := range nsAttrs(decls) {
+		x.write(" ", a.Name.Local, `="`, attrEscaper.Replace(a.Value), `"`)
+	}
+	x.write(attrs...)
+	if len(n.Children) == 0 && n.Value == "" {
+		x.write("/>")
+		return
+	}
+	x.write(">")
+	if len(n.Children) == 0 {
+		x.write(textEscaper.Replace(n.Value))
+	}
+	for _, c := range keysFirst(n) {
+		x.element(c, n.Name.Space)
+	}
+	x.write("</", n.Name.Local, ">")
+}
+
+// keysFirst returns the children of n, with any list keys first
+func keysFirst(n *Node) []*Node {
+	keys := n.Keys()
+	if len(keys) == 0 {
+		return n.Children
+	}
+	children := append([]*Node(nil), keys...)
+	for _, c := range n.Children {
+		if index(keys, c) < 0 {
+			children = append(children, c)
+		}
+	}
+	return children
+}
+
+// attrPrefix returns a prefix for the attribute namespace space of n
+func attrPrefix(n *Node, space string, decls map[string]string) string {
+	for prefix, s := range decls {
+		if s == space {
+			return prefix
+		}
+	}
+	prefix := ""
+	switch space {
+	case netconfNS:
+		prefix = "nc"
+	case ops.DefaultNS:
+		prefix = "wd"
+	default:
+		if m := n.Context().ModuleByNamespace(space); m != nil {
+			prefix = m.Prefix
+		}
+	}
+	for i := 0; prefix == "" || decls[prefix] != ""; i++ {
+		prefix = fmt.Sprintf("ns%d", i)
+	}
+	return prefix
+}
+
+const netconfNS = "urn:ietf:params:xml:ns:netconf:base:1.0"
+
+// valuePrefixes returns the namespace prefixes used in the value of the
+// identityref or instance-identifier node n
+func valuePrefixes(n *Node) (prefixes []string) {
+	if n.Schema == nil || n.Schema.Type == nil || !isValueKind(n.Schema.Type) || len(n.Children) > 0 {
+		return nil
+	}
+	seen := map[string]bool{}
+	for _, name := range qualifiedNames(n.Value) {
+		if i := strings.IndexByte(name, ':'); i > 0 && !seen[name[:i]] {
+			seen[name[:i]] = true
+			prefixes = append(prefixes, name[:i])
+		}
+	}
+	sort.Strings(prefixes)
+	return
+}
+
+// qualifiedNames returns the names (outside of string literals) in the
+// identityref or instance-identifier value s
+func qualifiedNames(s string) (names []string) {
+	mapNames(s, func(name string) string {
+		names = append(names, name)
+		return name
+	})
+	return
+}
+
+// mapNames returns s, with each name (outside of string literals) in
+// the identityref or instance-identifier value s replaced by f(name)
+func mapNames(s string, f func(name string) string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\'' || c == '"':
+			j := strings.IndexByte(s[i+1:], c)
+			if j < 0 {
+				b.WriteString(s[i:])
+				return b.String()
+			}
+			b.WriteString(s[i : i+j+2])
+			i += j + 2
+		case isNameStart(c):
+			j := i
+			for j < len(s) && (isNameChar(s[j]) || s[j] == ':') {
+				j++
+			}
+			b.WriteString(f(s[i:j]))
+			i = j
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.String()
+}
+
+// isValueKind returns true for types whose values hold qualified names
+func isValueKind(t *yang.Type) bool {
+	switch t.Kind {
+	case yang.TypeIdentityref, yang.TypeInstanceIdentifier:
+		return true
+	case yang.TypeUnion:
+		for _, m := range t.Union {
+			if isValueKind(m) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,925 @@
+package data
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// XPath is a compiled XPath 1.0 expression, evaluated over data trees
+// with the YANG XPath function library (RFC7950 section 10).
+//
+// Expressions are evaluated as for YANG must and when statements: the
+// root node is the tree's root node, and names without a prefix are in
+// the namespace of the initial context node (current()), unless a
+// default namespace is declared with an empty prefix.
+type XPath struct {
+	expr string
+	ns   map[string]string
+	root xexpr
+}
+
+// CompileXPath compiles expr, whose prefixes are declared in ns
+func CompileXPath(expr string, ns map[string]string) (*XPath, error) {
+	toks, err := lexXPath(expr)
+	if err != nil {
+		return nil, fmt.Errorf("xpath %q: %v", expr, err)
+	}
+	x := &XPath{expr: expr, ns: ns}
+	p := &xparser{toks: toks, x: x}
+	root, err := p.expr()
+	if err == nil && p.peek().kind != tokEOF {
+		err = fmt.Errorf("unexpected %q", p.peek().val)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("xpath %q: %v", expr, err)
+	}
+	x.root = root
+	return x, nil
+}
+
+func (x *XPath) String() string { return x.expr }
+
+// Eval evaluates x with the context node n, returning a node-set
+// ([]*Node), string, number (float64) or boolean result.
+func (x *XPath) Eval(n *Node) (interface{}, error) {
+	return x.root.eval(&xctx{node: n, pos: 1, size: 1, current: n})
+}
+
+// Bool evaluates x with the context node n, converting the result to a boolean
+func (x *XPath) Bool(n *Node) (bool, error) {
+	v, err := x.Eval(n)
+	return toBool(v), err
+}
+
+// Select evaluates x with the context node n, returning the resulting
+// node-set in document order
+func (x *XPath) Select(n *Node) ([]*Node, error) {
+	v, err := x.Eval(n)
+	if err != nil {
+		return nil, err
+	}
+	nodes, ok := v.([]*Node)
+	if !ok {
+		return nil, fmt.Errorf("xpath %q: result is not a node-set", x.expr)
+	}
+	return nodes, nil
+}
+
+// lexer
+
+const (
+	tokEOF = iota
+	tokName
+	tokOp
+	tokNumber
+	tokLiteral
+)
+
+type xtoken struct {
+	kind int
+	val  string
+}
+
+func isNameStart(r byte) bool { return r == '_' || r >= 0x80 || unicode.IsLetter(rune(r)) }
+
+func isNameChar(r byte) bool {
+	return isNameStart(r) || r == '-' || r == '.' || (r >= '0' && r <= '9')
+}
+
+func lexXPath(s string) ([]xtoken, error) {
+	var toks []xtoken
+	// operand reports whether the previous token ends an operand, in
+	// which case * and names are operators (XPath 1.0 section 3.7)
+	operand := func() bool {
+		if len(toks) == 0 {
+			return false
+		}
+		t := toks[len(toks)-1]
+		switch t.kind {
+		case tokOp:
+			return t.val == ")" || t.val == "]" || t.val == "." || t.val == ".."
+		}
+		return true
+	}
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(s) && s[i+1] >= '0' && s[i+1] <= '9':
+			j := i
+			for j < len(s) && (s[j] >= '0' && s[j] <= '9' || s[j] == '.') {
+				j++
+			}
+			toks = append(toks, xtoken{tokNumber, s[i:j]})
+			i = j
+		case c == '"' || c == '\'':
+			j := strings.IndexByte(s[i+1:], c)
+			if j < 0 {
+				return nil, fmt.Errorf("unterminated string literal")
+			}
+			toks = append(toks, xtoken{tokLiteral, s[i+1 : i+1+j]})
+			i += j + 2
+		case c == '*' && operand():
+			toks = append(toks, xtoken{tokOp, "*"})
+			i++
+		case c == '*':
+			toks = append(toks, xtoken{tokName, "*"})
+			i++
+		case isNameStart(c):
+			j := i
+			for j < len(s) && isNameChar(s[j]) {
+				j++
+			}
+			if j+1 < len(s) && s[j] == ':' && s[j+1] != ':' {
+				// QName or prefix:*
+				if s[j+1] == '*' {
+					j += 2
+				} else if isNameStart(s[j+1]) {
+					j++
+					for j < len(s) && isNameChar(s[j]) {
+						j++
+					}
+				}
+			}
+			name := s[i:j]
+			switch {
+			case operand() && (name == "and" || name == "or" || name == "div" || name == "mod"):
+				toks = append(toks, xtoken{tokOp, name})
+			case operand():
+				return nil, fmt.Errorf("unexpected %q", name)
+			default:
+				toks = append(toks, xtoken{tokName, name})
+			}
+			i = j
+		default:
+			op := string(c)
+			if i+1 < len(s) {
+				switch two := s[i : i+2]; two {
+				case "//", "..", "::", "!=", "<=", ">=":
+					op = two
+				}
+			}
+			switch op {
+			case "/", "//", ".", "..", "::", "!=", "<=", ">=", "(", ")", "[", "]", "@", ",", "|", "+", "-", "=", "<", ">":
+			default:
+				return nil, fmt.Errorf("unexpected %q", op)
+			}
+			toks = append(toks, xtoken{tokOp, op})
+			i += len(op)
+		}
+	}
+	return append(toks, xtoken{kind: tokEOF}), nil
+}
+
+// parser
+
+type xparser struct {
+	toks []xtoken
+	pos  int
+	x    *XPath
+}
+
+func (p *xparser) peek() xtoken { return p.toks[p.pos] }
+
+func (p *xparser) peekN(n int) xtoken {
+	if p.pos+n < len(p.toks) {
+		return p.toks[p.pos+n]
+	}
+	return xtoken{kind: tokEOF}
+}
+
+func (p *xparser) next() xtoken {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *xparser) isOp(ops ...string) bool {
+	t := p.peek()
+	if t.kind != tokOp {
+		return false
+	}
+	for _, op := range ops {
+		if t.val == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *xparser) expect(op string) error {
+	if !p.isOp(op) {
+		if t := p.peek(); t.kind != tokEOF {
+			return fmt.Errorf("expected %q, found %q", op, t.val)
+		}
+		return fmt.Errorf("expected %q", op)
+	}
+	p.next()
+	return nil
+}
+
+// binary parses left-associative binary operator expressions
+func (p *xparser) binary(operand func() (xexpr, error), ops ...string) (xexpr, error) {
+	l, err := operand()
+	for err == nil && p.isOp(ops...) {
+		op := p.next().val
+		var r xexpr
+		if r, err = operand(); err == nil {
+			l = &binaryExpr{op: op, l: l, r: r}
+		}
+	}
+	return l, err
+}
+
+func (p *xparser) expr() (xexpr, error) { return p.binary(p.and, "or") }
+func (p *xparser) and() (xexpr, error)  { return p.binary(p.equality, "and") }
+func (p *xparser) equality() (xexpr, error) {
+	return p.binary(p.relational, "=", "!=")
+}
+func (p *xparser) relational() (xexpr, error) {
+	return p.binary(p.additive, "<", "<=", ">", ">=")
+}
+func (p *xparser) additive() (xexpr, error) { return p.binary(p.multiplicative, "+", "-") }
+func (p *xparser) multiplicative() (xexpr, error) {
+	return p.binary(p.unary, "*", "div", "mod")
+}
+
+func (p *xparser) unary() (xexpr, error) {
+	if p.isOp("-") {
+		p.next()
+		e, err := p.unary()
+		return &negExpr{e}, err
+	}
+	return p.binary(p.path, "|")
+}
+
+// path parses a PathExpr
+func (p *xparser) path() (xexpr, error) {
+	t := p.peek()
+	var filter xexpr
+	switch {
+	case t.kind == tokLiteral:
+		p.next()
+		filter = literalExpr(t.val)
+	case t.kind == tokNumber:
+		p.next()
+		f, err := strconv.ParseFloat(t.val, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", t.val)
+		}
+		filter = numberExpr(f)
+	case t.kind == tokOp && t.val == "(":
+		p.next()
+		e, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		filter = e
+	case t.kind == tokName && p.peekN(1).kind == tokOp && p.peekN(1).val == "(" && !isNodeType(t.val):
+		f, err := p.function()
+		if err != nil {
+			return nil, err
+		}
+		filter = f
+	}
+	if filter != nil {
+		preds, err := p.predicates()
+		if err != nil {
+			return nil, err
+		}
+		if len(preds) > 0 {
+			filter = &filterExpr{e: filter, preds: preds}
+		}
+		if !p.isOp("/", "//") {
+			return filter, nil
+		}
+		pe := &pathExpr{filter: filter}
+		return pe, p.steps(pe, true)
+	}
+	pe := &pathExpr{}
+	switch {
+	case p.isOp("/"):
+		pe.abs = true
+		p.next()
+		// a lone "/" selects the root node
+		if t := p.peek(); t.kind != tokName && !p.isOp(".", "..", "@") {
+			return pe, nil
+		}
+		return pe, p.steps(pe, false)
+	case p.isOp("//"):
+		pe.abs = true
+		return pe, p.steps(pe, true)
+	}
+	return pe, p.steps(pe, false)
+}
+
+// steps parses the steps of a relative location path into pe. If sep is
+// set, the path begins with a "/" or "//" separator.
+func (p *xparser) steps(pe *pathExpr, sep bool) error {
+	for {
+		if sep {
+			if p.isOp("//") {
+				pe.steps = append(pe.steps, &step{axis: "descendant-or-self", test: nodeTest{kind: "node"}})
+			}
+			p.next()
+		}
+		s, err := p.step()
+		if err != nil {
+			return err
+		}
+		pe.steps = append(pe.steps, s)
+		if !p.isOp("/", "//") {
+			return nil
+		}
+		sep = true
+	}
+}
+
+func (p *xparser) step() (*step, error) {
+	switch {
+	case p.isOp("."):
+		p.next()
+		return &step{axis: "self", test: nodeTest{kind: "node"}}, nil
+	case p.isOp(".."):
+		p.next()
+		return &step{axis: "parent", test: nodeTest{kind: "node"}}, nil
+	}
+	s := &step{axis: "child"}
+	if p.isOp("@") {
+		p.next()
+		s.axis = "attribute"
+	} else if t := p.peek(); t.kind == tokName && p.peekN(1).kind == tokOp && p.peekN(1).val == "::" {
+		switch t.val {
+		case "ancestor", "ancestor-or-self", "attribute", "child", "descendant", "descendant-or-self",
+			"following", "following-sibling", "namespace", "parent", "preceding", "preceding-sibling", "self":
+		default:
+			return nil, fmt.Errorf("unknown axis %q", t.val)
+		}
+		s.axis = t.val
+		p.next()
+		p.next()
+	}
+	t := p.next()
+	if t.kind != tokName {
+		if t.kind == tokEOF {
+			return nil, fmt.Errorf("unexpected end of expression")
+		}
+		return nil, fmt.Errorf("unexpected %q", t.val)
+	}
+	if isNodeType(t.val) && p.isOp("(") {
+		p.next()
+		if t.val == "processing-instruction" && p.peek().kind == tokLiteral {
+			p.next()
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		s.test = nodeTest{kind: t.val}
+	} else {
+		prefix, local := "", t.val
+		if i := strings.IndexByte(t.val, ':'); i >= 0 {
+			prefix, local = t.val[:i], t.val[i+1:]
+		}
+		s.test = nodeTest{local: local}
+		switch {
+		case prefix != "":
+			ns, ok := p.x.ns[prefix]
+			if !ok {
+				return nil, fmt.Errorf("unknown prefix %q", prefix)
+			}
+			s.test.space = ns
+		case local == "*":
+			s.test.anySpace = true
+		default:
+			if ns, ok := p.x.ns[""]; ok {
+				s.test.space = ns
+			} else {
+				s.test.current = true
+			}
+		}
+	}
+	preds, err := p.predicates()
+	s.preds = preds
+	return s, err
+}
+
+func (p *xparser) predicates() (preds []xexpr, err error) {
+	for p.isOp("[") {
+		p.next()
+		e, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		preds = append(preds, e)
+	}
+	return
+}
+
+func (p *xparser) function() (xexpr, error) {
+	name := p.next().val
+	p.next() // (
+	f := &funcExpr{name: name}
+	for !p.isOp(")") {
+		if len(f.args) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		arg, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		f.args = append(f.args, arg)
+	}
+	p.next()
+	def, ok := functions[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s()", name)
+	}
+	if len(f.args) < def.min || (def.max >= 0 && len(f.args) > def.max) {
+		return nil, fmt.Errorf("wrong number of arguments to %s()", name)
+	}
+	f.fn = def.fn
+	if name == "derived-from" || name == "derived-from-or-self" {
+		f.ns = p.x.ns
+	}
+	return f, nil
+}
+
+func isNodeType(name string) bool {
+	switch name {
+	case "node", "text", "comment", "processing-instruction":
+		return true
+	}
+	return false
+}
+
+// evaluation
+
+type xctx struct {
+	node      *Node
+	pos, size int
+	current   *Node
+}
+
+type xexpr interface {
+	eval(c *xctx) (interface{}, error)
+}
+
+type literalExpr string
+
+func (e literalExpr) eval(*xctx) (interface{}, error) { return string(e), nil }
+
+type numberExpr float64
+
+func (e numberExpr) eval(*xctx) (interface{}, error) { return float64(e), nil }
+
+type negExpr struct{ e xexpr }
+
+func (e *negExpr) eval(c *xctx) (interface{}, error) {
+	v, err := e.e.eval(c)
+	return -toNumber(v), err
+}
+
+type binaryExpr struct {
+	op   string
+	l, r xexpr
+}
+
+func (e *binaryExpr) eval(c *xctx) (interface{}, error) {
+	l, err := e.l.eval(c)
+	if err != nil {
+		return nil, err
+	}
+	switch e.op {
+	case "and", "or":
+		if toBool(l) == (e.op == "or") {
+			return e.op == "or", nil
+		}
+		r, err := e.r.eval(c)
+		return toBool(r), err
+	}
+	r, err := e.r.eval(c)
+	if err != nil {
+		return nil, err
+	}
+	switch e.op {
+	case "|":
+		ln, lok := l.([]*Node)
+		rn, rok := r.([]*Node)
+		if !lok || !rok {
+			return nil, fmt.Errorf("union of non node-sets")
+		}
+		return docOrder(append(append([]*Node(nil), ln...), rn...)), nil
+	case "+":
+		return toNumber(l) + toNumber(r), nil
+	case "-":
+		return toNumber(l) - toNumber(r), nil
+	case "*":
+		return toNumber(l) * toNumber(r), nil
+	case "div":
+		return toNumber(l) / toNumber(r), nil
+	case "mod":
+		return math.Mod(toNumber(l), toNumber(r)), nil
+	}
+	return compare(e.op, l, r), nil
+}
+
+// compare compares l and r per XPath 1.0 section 3.4
+func compare(op string, l, r interface{}) bool {
+	if ln, ok := l.([]*Node); ok {
+		if rn, ok := r.([]*Node); ok {
+			for _, a := range ln {
+				for _, b := range rn {
+					if compare(op, stringValue(a), stringValue(b)) {
+						return true
+					}
+				}
+			}
+			return false
+		}
+		if b, ok := r.(bool); ok {
+			return compare(op, len(ln) > 0, b)
+		}
+		for _, a := range ln {
+			var v interface{} = stringValue(a)
+			if _, ok := r.(float64); ok {
+				v = toNumber(v)
+			}
+			if compare(op, v, r) {
+				return true
+			}
+		}
+		return false
+	}
+	if _, ok := r.([]*Node); ok {
+		return compare(swap[op], r, l)
+	}
+	switch op {
+	case "=", "!=":
+		var eq bool
+		_, lb := l.(bool)
+		_, rb := r.(bool)
+		_, lf := l.(float64)
+		_, rf := r.(float64)
+		switch {
+		case lb || rb:
+			eq = toBool(l) == toBool(r)
+		case lf || rf:
+			eq = toNumber(l) == toNumber(r)
+		default:
+			eq = toString(l) == toString(r)
+		}
+		return eq == (op == "=")
+	}
+	a, b := toNumber(l), toNumber(r)
+	switch op {
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	}
+	return false
+}
+
+var swap = map[string]string{"=": "=", "!=": "!=", "<": ">", "<=": ">=", ">": "<", ">=": "<="}
+
+type filterExpr struct {
+	e     xexpr
+	preds []xexpr
+}
+
+func (e *filterExpr) eval(c *xctx) (interface{}, error) {
+	v, err := e.e.eval(c)
+	if err != nil {
+		return nil, err
+	}
+	nodes, ok := v.([]*Node)
+	if !ok {
+		return nil, fmt.Errorf("predicate applied to a non node-set")
+	}
+	for _, pred := range e.preds {
+		if nodes, err = filter(c, nodes, pred); err != nil {
+			return nil, err
+		}
+	}
+	return nodes, nil
+}
+
+// filter returns the nodes satisfying pred, in the order given
+func filter(c *xctx, nodes []*Node, pred xexpr) ([]*Node, error) {
+	var out []*Node
+	for i, n := range nodes {
+		v, err := pred.eval(&xctx{node: n, pos: i + 1, size: len(nodes), current: c.current})
+		if err != nil {
+			return nil, err
+		}
+		if f, ok := v.(float64); ok {
+			if f == float64(i+1) {
+				out = append(out, n)
+			}
+		} else if toBool(v) {
+			out = append(out, n)
+		}
+	}
+	return out, nil
+}
+
+type pathExpr struct {
+	filter xexpr
+	abs    bool
+	steps  []*step
+}
+
+type step struct {
+	axis  string
+	test  nodeTest
+	preds []xexpr
+}
+
+type nodeTest struct {
+	// kind is the node type test (e.g., "node"), or empty for name tests
+	kind     string
+	space    string
+	local    string
+	anySpace bool
+	// current is set for names without a prefix, in the namespace of
+	// the current node
+	current bool
+}
+
+func (t *nodeTest) match(c *xctx, n *Node) bool {
+	switch t.kind {
+	case "node":
+		return true
+	case "":
+	default:
+		// text, comment and processing-instruction nodes are not modelled
+		return false
+	}
+	if n.Parent == nil {
+		return false
+	}
+	if t.local != "*" && t.local != n.Name.Local {
+		return false
+	}
+	switch {
+	case t.anySpace:
+		return true
+	case t.current:
+		return n.Name.Space == c.current.Name.Space
+	}
+	return n.Name.Space == t.space
+}
+
+func (e *pathExpr) eval(c *xctx) (interface{}, error) {
+	var nodes []*Node
+	switch {
+	case e.filter != nil:
+		v, err := e.filter.eval(c)
+		if err != nil {
+			return nil, err
+		}
+		var ok bool
+		if nodes, ok = v.([]*Node); !ok {
+			return nil, fmt.Errorf("path applied to a non node-set")
+		}
+	case e.abs:
+		nodes = []*Node{c.node.Root()}
+	default:
+		nodes = []*Node{c.node}
+	}
+	for _, s := range e.steps {
+		var out []*Node
+		for _, n := range nodes {
+			var candidates []*Node
+			for _, a := range axis(s.axis, n) {
+				if s.test.match(c, a) {
+					candidates = append(candidates, a)
+				}
+			}
+			for _, pred := range s.preds {
+				var err error
+				if candidates, err = filter(c, candidates, pred); err != nil {
+					return nil, err
+				}
+			}
+			out = append(out, candidates...)
+		}
+		nodes = docOrder(out)
+	}
+	return nodes, nil
+}
+
+// axis returns the nodes of axis from n, in axis (proximity) order
+func axis(name string, n *Node) (nodes []*Node) {
+	switch name {
+	case "child":
+		return n.Children
+	case "descendant":
+		return descendants(n, nil)
+	case "descendant-or-self":
+		return descendants(n, []*Node{n})
+	case "self":
+		return []*Node{n}
+	case "parent":
+		if n.Parent != nil {
+			return []*Node{n.Parent}
+		}
+	case "ancestor", "ancestor-or-self":
+		if name == "ancestor-or-self" {
+			nodes = append(nodes, n)
+		}
+		for a := n.Parent; a != nil; a = a.Parent {
+			nodes = append(nodes, a)
+		}
+	case "following-sibling", "preceding-sibling":
+		if n.Parent == nil {
+			return nil
+		}
+		siblings := n.Parent.Children
+		i := index(siblings, n)
+		if i < 0 {
+			return nil
+		}
+		if name == "following-sibling" {
+			return siblings[i+1:]
+		}
+		for j := i - 1; j >= 0; j-- {
+			nodes = append(nodes, siblings[j])
+		}
+	case "following":
+		for a := n; a.Parent != nil; a = a.Parent {
+			for _, s := range axis("following-sibling", a) {
+				nodes = append(nodes, descendants(s, []*Node{s})...)
+			}
+		}
+		return docOrder(nodes)
+	case "preceding":
+		for a := n; a.Parent != nil; a = a.Parent {
+			for _, s := range axis("preceding-sibling", a) {
+				nodes = append(nodes, descendants(s, []*Node{s})...)
+			}
+		}
+		nodes = docOrder(nodes)
+		for i, j := 0, len(nodes)-1; i < j; i, j = i+1, j-1 {
+			nodes[i], nodes[j] = nodes[j], nodes[i]
+		}
+	}
+	// attribute and namespace nodes are not modelled
+	return nodes
+}
+
+func descendants(n *Node, nodes []*Node) []*Node {
+	for _, c := range n.Children {
+		nodes = descendants(c, append(nodes, c))
+	}
+	return nodes
+}
+
+func index(nodes []*Node, n *Node) int {
+	for i, c := range nodes {
+		if c == n {
+			return i
+		}
+	}
+	return -1
+}
+
+// docOrder sorts nodes into document order, removing duplicates
+func docOrder(nodes []*Node) []*Node {
+	if len(nodes) < 2 {
+		return nodes
+	}
+	seen := map[*Node]bool{}
+	type entry struct {
+		n    *Node
+		path []int
+	}
+	entries := make([]entry, 0, len(nodes))
+	for _, n := range nodes {
+		if seen[n] {
+			continue
+		}
+		seen[n] = true
+		var path []int
+		for a := n; a.Parent != nil; a = a.Parent {
+			path = append([]int{index(a.Parent.Children, a)}, path...)
+		}
+		entries = append(entries, entry{n, path})
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i].path, entries[j].path
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+	out := make([]*Node, len(entries))
+	for i, e := range entries {
+		out[i] = e.n
+	}
+	return out
+}
+
+// conversions
+
+// stringValue returns the XPath string-value of n
+func stringValue(n *Node) string {
+	if len(n.Children) == 0 {
+		return n.Value
+	}
+	var b strings.Builder
+	for _, d := range descendants(n, nil) {
+		if len(d.Children) == 0 {
+			b.WriteString(d.Value)
+		}
+	}
+	return b.String()
+}
+
+func toString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		switch {
+		case math.IsNaN(v):
+			return "NaN"
+		case math.IsInf(v, 1):
+			return "Infinity"
+		case math.IsInf(v, -1):
+			return "-Infinity"
+		case v == math.Trunc(v) && math.Abs(v) < 1e15:
+			return strconv.FormatInt(int64(v), 10)
+		}
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []*Node:
+		if len(v) > 0 {
+			return stringValue(v[0])
+		}
+	}
+	return ""
+}
+
+func toNumber(v interface{}) float64 {
+	switch v := v.(type) {
+	case float64:
+		return v
+	case bool:
+		if v {
+			return 1
+		}
+		return 0
+	}
+	s := strings.TrimSpace(toString(v))
+	valid := s != "" && s != "-" && s != "." && s != "-."
+	for i, c := range s {
+		if !(c >= '0' && c <= '9' || c == '.' || c == '-' && i == 0) {
+			valid = false
+		}
+	}
+	if !valid || strings.Count(s, ".") > 1 {
+		return math.NaN()
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return math.NaN()
+	}
+	return f
+}
+
+func toBool(v interface{}) bool {
+	switch v := v.(type) {
+	case bool:
+		return v
+	case float64:
+		return v != 0 && !math.IsNaN(v)
+	case string:
+		return v != ""
+	case []*Node:
+		return len(v) > 0
+	}
+	return false
+}
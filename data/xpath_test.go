@@ -0,0 +1,111 @@
+package data
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestXPath(t *testing.T) {
+	root, err := Parse(strings.NewReader(`<system xmlns="urn:example:data" xmlns:ex="urn:example:data">`+
+		`<hostname>r1</hostname><mtu>1500</mtu><mode>manual</mode><flags>a</flags><proto>ex:tcp</proto>`+
+		`<server><name>a</name><port>1</port><weight>3</weight></server>`+
+		`<server><name>b</name><port>2</port><weight>4</weight></server>`+
+		`<server><name>c</name><port>3</port></server>`+
+		`<primary>b</primary><dns>x</dns><dns>y</dns></system>`), loadSchema(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	system := root.Children[0]
+	ns := map[string]string{"ex": "urn:example:data"}
+	for _, tc := range []struct {
+		expr    string
+		context *Node
+		want    interface{}
+		wantErr string
+	}{
+		{expr: "/ex:system/ex:hostname", want: "r1"},
+		{expr: "hostname", context: system, want: "r1"},
+		{expr: "count(ex:server)", context: system, want: 3.0},
+		{expr: "count(//ex:port)", want: 3.0},
+		{expr: "count(/ex:system/ex:server/descendant-or-self::node())", want: 11.0},
+		{expr: "server[2]/name", context: system, want: "b"},
+		{expr: "server[last()]/name", context: system, want: "c"},
+		{expr: "server[weight > 3]/name", context: system, want: "b"},
+		{expr: "server[name = current()/primary]/port", context: system, want: "2"},
+		{expr: "count(server[port >= 2 and port < 3] | server[name = 'c'])", context: system, want: 2.0},
+		{expr: "sum(server/weight) * 2", context: system, want: 14.0},
+		{expr: "server/weight div 2", context: system, want: 1.5},
+		{expr: "7 mod 3 - -1", want: 2.0},
+		{expr: "dns = 'y'", context: system, want: true},
+		{expr: "dns != 'x'", context: system, want: true},
+		{expr: "not(dns = 'z')", context: system, want: true},
+		{expr: "mtu > 1000 and mtu < 2000", context: system, want: true},
+		{expr: "../ex:system/./mtu = 1500.0", context: system, want: true},
+		{expr: "local-name(server[1]/..)", context: system, want: "system"},
+		{expr: "name(*[1])", context: system, want: "ex:hostname"},
+		{expr: "namespace-uri(.)", context: system, want: "urn:example:data"},
+		{expr: "concat(hostname, '-', substring('abcdef', 2, 3))", context: system, want: "r1-bcd"},
+		{expr: "substring-before('a=b', '=')", want: "a"},
+		{expr: "substring-after('a=b', '=')", want: "b"},
+		{expr: "translate('abc', 'abc', 'AB')", want: "AB"},
+		{expr: "normalize-space('  a   b ')", want: "a b"},
+		{expr: "string-length(hostname)", context: system, want: 2.0},
+		{expr: "starts-with(hostname, 'r') and contains(hostname, '1')", context: system, want: true},
+		{expr: "round(2.5) + floor(1.9) + ceiling(1.1)", want: 6.0},
+		{expr: "number('x')", want: math.NaN()},
+		{expr: "string(1 div 0)", want: "Infinity"},
+		{expr: "boolean('') or boolean(0)", want: false},
+		{expr: "re-match(hostname, '[a-z][0-9]')", context: system, want: true},
+		{expr: "deref(primary)/../port", context: system, want: "2"},
+		{expr: "derived-from(proto, 'ex:protocol')", context: system, want: true},
+		{expr: "derived-from(proto, 'ex:tcp')", context: system, want: false},
+		{expr: "derived-from-or-self(proto, 'ex:tcp')", context: system, want: true},
+		{expr: "enum-value(mode)", context: system, want: 5.0},
+		{expr: "bit-is-set(flags, 'a') and not(bit-is-set(flags, 'b'))", context: system, want: true},
+		{expr: "count(/)", want: 1.0},
+		{expr: "count(server/following-sibling::ex:server)", context: system, want: 2.0},
+		{expr: "count(primary/preceding-sibling::*)", context: system, want: 8.0},
+		{expr: "count(ancestor-or-self::node())", context: system.Children[0], want: 3.0},
+		{expr: "count(ex:*)", context: system, want: 11.0},
+		{expr: "foo()", wantErr: `xpath "foo()": unknown function foo()`},
+		{expr: "count(", wantErr: `xpath "count(": unexpected end of expression`},
+		{expr: "x:y", wantErr: `xpath "x:y": unknown prefix "x"`},
+		{expr: "a[1", wantErr: `xpath "a[1": expected "]"`},
+		{expr: "1 2", wantErr: `xpath "1 2": unexpected "2"`},
+		{expr: "'abc", wantErr: `xpath "'abc": unterminated string literal`},
+		{expr: "count()", wantErr: `xpath "count()": wrong number of arguments to count()`},
+	} {
+		t.Run(tc.expr, func(t *testing.T) {
+			a := assert.New(t)
+			x, err := CompileXPath(tc.expr, ns)
+			if tc.wantErr != "" {
+				a.EqualError(err, tc.wantErr)
+				return
+			}
+			if !a.NoError(err) {
+				return
+			}
+			context := tc.context
+			if context == nil {
+				context = root
+			}
+			got, err := x.Eval(context)
+			if !a.NoError(err) {
+				return
+			}
+			switch want := tc.want.(type) {
+			case string:
+				got = toString(got)
+			case float64:
+				if math.IsNaN(want) {
+					a.True(math.IsNaN(toNumber(got)), "got %v", got)
+					return
+				}
+			}
+			a.Equal(tc.want, got)
+		})
+	}
+}
@@ -4,5 +4,8 @@ Package framing offers RFC6242 end-of-message and chunked framing decoders.
 The functions in this package return bufio.SplitFunc for use with a *bufio.Scanner.
 These functions will return io.ErrUnexpectedEOF when input terminates other
 than at the end of a message.
+
+A Tracker uses these decoders to observe message boundaries in a transport
+stream, without consuming the stream's data.
 */
 package framing
@@ -91,7 +91,7 @@ func SplitChunked(endOfMessage func()) bufio.SplitFunc {
 		for cur := b[advance:]; err == nil && advance < len(b); cur = b[advance:] {
 			// Each chunk header is at least 4 bytes, so ask for at least that
 			// (unless we're at EOF, in which case we check length again later)
-			if len(cur) < 4 && !atEOF {
+			if len(cur) < 4 && !atEOF && state == headerStart {
 				return
 			}
 			// chunked message decoding state machine
@@ -164,13 +164,19 @@ func SplitChunked(endOfMessage func()) bufio.SplitFunc {
 					if endOfMessage != nil {
 						endOfMessage()
 					}
+					// return a (possibly empty) token at the end of message, so the
+					// scanner returns rather than blocking to read the next message
+					if token == nil {
+						token = []byte{}
+					}
+					return
 				default:
 					err = ErrBadChunk{Message: "invalid chunk terminator"}
 				}
 			}
 		}
 		// catch unexpected EOF conditions
-		if err == nil && (atEOF && dataleft > 0 || state != headerStart) {
+		if err == nil && atEOF && (dataleft > 0 || state != headerStart) {
 			err = io.ErrUnexpectedEOF
 		}
 		return
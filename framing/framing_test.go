@@ -2,7 +2,10 @@ package framing
 
 import (
 	"fmt"
+	"io"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"bufio"
 
@@ -171,6 +174,35 @@ func TestFramingChunked(t *testing.T) {
 	}
 }
 
+func TestFramingChunkedStreaming(t *testing.T) {
+	// the scanner must return at the end of each message, rather than
+	// blocking to read the next message from the input
+	a := assert.New(t)
+	r, w := io.Pipe()
+	done := make(chan struct{})
+	var timedOut int32
+	go func() {
+		w.Write([]byte("\n#1\na\n##\n"))
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			atomic.StoreInt32(&timedOut, 1)
+		}
+		w.Close()
+	}()
+	var gotCB int
+	scanner := bufio.NewScanner(r)
+	scanner.Split(SplitChunked(func() { gotCB++ }))
+	var got string
+	for gotCB == 0 && scanner.Scan() {
+		got += scanner.Text()
+	}
+	close(done)
+	a.Equal(int32(0), atomic.LoadInt32(&timedOut))
+	a.Equal(1, gotCB)
+	a.Equal("a", got)
+}
+
 func BenchmarkFramingChunked(b *testing.B) {
 	for _, tc := range []struct {
 		input  string
@@ -211,3 +243,93 @@ func BenchmarkFramingChunked(b *testing.B) {
 		})
 	}
 }
+
+func TestTracker(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		input       []string
+		wantEnds    [][]int
+		wantChunked bool
+		wantErr     bool
+	}{
+		{
+			name:     "end of message",
+			input:    []string{"<hello/>]]>]]>foo]]", ">]]>bar]]>]]>baz"},
+			wantEnds: [][]int{{14}, {4, 13}},
+		},
+		{
+			name:        "chunked",
+			input:       []string{"<hello/>]]>]]>\n#3\nfoo\n#", "1\na\n##\n\n#3\nbar\n##\n"},
+			wantEnds:    [][]int{{14}, {7, 18}},
+			wantChunked: true,
+		},
+		{
+			name:        "chunked split marker",
+			input:       []string{"<hello/>]]>]]>\n#3\nfoo\n#", "#", "\n"},
+			wantEnds:    [][]int{{14}, nil, {1}},
+			wantChunked: true,
+		},
+		{
+			name:        "framing error",
+			input:       []string{"<hello/>]]>]]>\n#x\nfoo\n##\n", "\n#3\nfoo\n##\n"},
+			wantEnds:    [][]int{{14}, nil},
+			wantChunked: true,
+			wantErr:     true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			a := assert.New(t)
+			tr := NewTracker()
+			var sawErr bool
+			for i, in := range tc.input {
+				ends, err := tr.Feed([]byte(in))
+				sawErr = sawErr || err != nil
+				a.Equal(tc.wantEnds[i], ends, "input %d", i)
+			}
+			a.Equal(tc.wantErr, sawErr)
+			a.Equal(tc.wantChunked, tr.Chunked())
+		})
+	}
+}
+
+func TestFramingLenient(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		input        string
+		skip         bool
+		want         string
+		wantChunked  bool
+		wantSkipped  int
+		wantMessages int
+	}{
+		{name: "chunked", input: "\n#3\nfoo\n##\n", want: "foo", wantChunked: true, wantMessages: 1},
+		{name: "eom", input: "foo]]>]]>bar]]>]]>", want: "foobar", wantMessages: 2},
+		{name: "chunked garbage", input: "\r\n\x00\n\n#3\nfoo\n##\n", skip: true, want: "foo", wantChunked: true, wantSkipped: 4, wantMessages: 1},
+		{name: "eom garbage", input: "  \x00<foo/>]]>]]>", skip: true, want: "<foo/>", wantSkipped: 3, wantMessages: 1},
+	} {
+		for bsize := 16; bsize < 24; bsize++ {
+			t.Run(fmt.Sprintf("%s/%d", tc.name, bsize), func(t *testing.T) {
+				a := assert.New(t)
+				var messages, skipped int
+				var chunked bool
+				eom := func() { messages++ }
+				split := SplitDetect(SplitEOM(eom), SplitChunked(eom), func(c bool) { chunked = c })
+				if tc.skip {
+					split = SkipTo(split, func(n int) { skipped = n }, []byte("\n#"), []byte("<"))
+				}
+				scanner := bufio.NewScanner(strings.NewReader(tc.input))
+				scanner.Buffer(make([]byte, bsize), bsize*2)
+				scanner.Split(split)
+				var got string
+				for scanner.Scan() {
+					got += scanner.Text()
+				}
+				a.NoError(scanner.Err())
+				a.Equal(tc.want, got)
+				a.Equal(tc.wantChunked, chunked)
+				a.Equal(tc.wantSkipped, skipped)
+				a.Equal(tc.wantMessages, messages)
+			})
+		}
+	}
+}
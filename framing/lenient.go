@@ -0,0 +1,76 @@
+package framing
+
+import (
+	"bufio"
+	"bytes"
+)
+
+// SplitDetect returns a bufio.SplitFunc detecting the framing mode of
+// the stream from the start of its first message: if the stream begins
+// with a chunk header, the chunked split function is used, otherwise
+// the eom split function is used.
+//
+// detected, if non-nil, is called once with the framing mode detected.
+func SplitDetect(eom, chunked bufio.SplitFunc, detected func(chunked bool)) bufio.SplitFunc {
+	var split bufio.SplitFunc
+	return func(b []byte, atEOF bool) (advance int, token []byte, err error) {
+		if split == nil {
+			if len(b) < 2 && !atEOF {
+				return 0, nil, nil
+			}
+			isChunked := bytes.HasPrefix(b, []byte("\n#"))
+			if split = eom; isChunked {
+				split = chunked
+			}
+			if detected != nil {
+				detected(isChunked)
+			}
+		}
+		return split(b, atEOF)
+	}
+}
+
+// SkipTo returns a bufio.SplitFunc discarding data at the start of the
+// stream preceding the first of the markers, before using split for the
+// remainder of the stream (including the marker).
+//
+// skipped, if non-nil, is called once with the number of bytes discarded
+// if any were.
+func SkipTo(split bufio.SplitFunc, skipped func(n int), markers ...[]byte) bufio.SplitFunc {
+	var found bool
+	var total int
+	return func(b []byte, atEOF bool) (advance int, token []byte, err error) {
+		if found {
+			return split(b, atEOF)
+		}
+		idx := -1
+		var keep int
+		for _, m := range markers {
+			if i := bytes.Index(b, m); i > -1 && (idx == -1 || i < idx) {
+				idx = i
+			}
+			if len(m)-1 > keep {
+				keep = len(m) - 1
+			}
+		}
+		switch {
+		case idx > -1:
+			found = true
+			if total += idx; total > 0 && skipped != nil {
+				skipped(total)
+			}
+			if idx == 0 {
+				return split(b, atEOF)
+			}
+			// discard the leading data
+			return idx, nil, nil
+		case atEOF:
+			return split(b, atEOF)
+		case len(b) > keep:
+			// discard all but a possible partial marker at the end of b
+			total += len(b) - keep
+			return len(b) - keep, nil, nil
+		}
+		return 0, nil, nil
+	}
+}
@@ -0,0 +1,81 @@
+package framing
+
+import (
+	"bufio"
+	"bytes"
+)
+
+// Tracker tracks NETCONF message boundaries in one direction of a
+// transport stream, such as for observing a session's traffic without
+// decoding it.
+//
+// Streams start in end-of-message framing mode. After the first
+// message (the <hello>), chunked framing is detected by the presence
+// of a chunk header at the start of the next message. If a framing
+// error is seen, no further boundaries are reported.
+type Tracker struct {
+	split   bufio.SplitFunc
+	buf     []byte
+	pos     int
+	msgs    int
+	ended   bool
+	decided bool
+	chunked bool
+	failed  bool
+}
+
+// NewTracker returns a new Tracker for the start of a transport stream
+func NewTracker() *Tracker {
+	t := &Tracker{}
+	t.split = SplitEOM(t.onEndOfMessage)
+	return t
+}
+
+func (t *Tracker) onEndOfMessage() { t.ended = true }
+
+// Feed consumes the next data b from the stream, returning the offsets
+// in b immediately after which a message ended. Returns a framing error,
+// once, if one was seen.
+func (t *Tracker) Feed(b []byte) (ends []int, err error) {
+	if t.failed {
+		return nil, nil
+	}
+	start := t.pos + len(t.buf)
+	t.buf = append(t.buf, b...)
+	for len(t.buf) > 0 {
+		if t.msgs > 0 && !t.decided {
+			if len(t.buf) < 2 {
+				break
+			}
+			if t.chunked = bytes.HasPrefix(t.buf, []byte("\n#")); t.chunked {
+				t.split = SplitChunked(t.onEndOfMessage)
+			}
+			t.decided = true
+		}
+		advance, _, serr := t.split(t.buf, false)
+		if serr != nil {
+			t.failed, t.buf = true, nil
+			return ends, serr
+		}
+		if advance == 0 {
+			break
+		}
+		t.buf = t.buf[advance:]
+		t.pos += advance
+		if t.ended {
+			t.ended = false
+			t.msgs++
+			ends = append(ends, t.pos-start)
+		}
+	}
+	if len(t.buf) == 0 {
+		t.buf = nil
+	}
+	return ends, nil
+}
+
+// Messages returns the number of messages ended so far
+func (t *Tracker) Messages() int { return t.msgs }
+
+// Chunked returns true if chunked framing has been detected
+func (t *Tracker) Chunked() bool { return t.chunked }
@@ -11,10 +11,12 @@ import (
 //
 // D must point to an initialized *transport.Reader, while OnEOF must
 // be a non-nil function, called at end of stream, when the underlying
-// transport sees EOF.
+// transport sees EOF. If Tee is non-nil, data read is also written
+// to Tee (errors writing to Tee are ignored).
 type Decoder struct {
 	D      *transport.Reader
 	OnEOF  func()
+	Tee    io.Writer
 	closed bool
 	first  bool
 }
@@ -33,7 +35,11 @@ func (d *Decoder) Read(p []byte) (n int, err error) {
 		return 0, io.EOF
 	}
 	defer func() { d.first = false }()
-	if n, err = d.D.Read(p); err == io.EOF {
+	n, err = d.D.Read(p)
+	if n > 0 && d.Tee != nil {
+		_, _ = d.Tee.Write(p[:n])
+	}
+	if err == io.EOF {
 		d.closed = true
 		if d.first {
 			err = ErrEndOfStream
@@ -51,10 +57,14 @@ func (d *Decoder) Close() error {
 	return nil
 }
 
-// Encoder is a NETCONF message encoder, implementing io.WriteCloser
+// Encoder is a NETCONF message encoder, implementing io.WriteCloser.
+//
+// If Tee is non-nil, data written is also written to Tee (errors
+// writing to Tee are ignored).
 type Encoder struct {
 	E        *transport.Writer
 	OnClosed func()
+	Tee      io.Writer
 	written  bool
 }
 
@@ -65,7 +75,11 @@ func (e *Encoder) Write(b []byte) (int, error) {
 		return 0, io.ErrClosedPipe
 	}
 	e.written = true
-	return e.E.Write(b)
+	n, err := e.E.Write(b)
+	if n > 0 && e.Tee != nil {
+		_, _ = e.Tee.Write(b[:n])
+	}
+	return n, err
 }
 
 // Close closes the message, causing the end of message token
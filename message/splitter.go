@@ -1,6 +1,8 @@
 package message
 
 import (
+	"io"
+
 	"github.com/andaru/netconf/transport"
 )
 
@@ -24,13 +26,15 @@ type Splitter struct {
 	enc    *Encoder
 	newDec bool
 	newEnc bool
+	rtee   io.Writer
+	wtee   io.Writer
 }
 
 // Reader returns the current message's reader (implementing io.Reader),
 // or creates a new message reader and returns that.
 func (s *Splitter) Reader() *Decoder {
 	if s.dec == nil || s.newDec {
-		s.dec = &Decoder{D: s.R, OnEOF: s.FinishReader, first: true}
+		s.dec = &Decoder{D: s.R, OnEOF: s.FinishReader, Tee: s.rtee, first: true}
 		s.newDec = false
 	}
 	return s.dec
@@ -39,7 +43,7 @@ func (s *Splitter) Reader() *Decoder {
 // Writer returns the current message's writer (implementing io.WriteCloser)
 func (s *Splitter) Writer() *Encoder {
 	if s.enc == nil || s.newEnc {
-		s.enc = &Encoder{E: s.W, OnClosed: s.FinishWriter}
+		s.enc = &Encoder{E: s.W, OnClosed: s.FinishWriter, Tee: s.wtee}
 		s.newEnc = false
 	}
 	return s.enc
@@ -50,3 +54,15 @@ func (s *Splitter) FinishWriter() { s.newEnc = true }
 
 // FinishReader emits a new reader on the next call(s) to Reader
 func (s *Splitter) FinishReader() { s.newDec = true }
+
+// SetTee sets the writers receiving a copy of data read from (r) and
+// written to (w) the current and future messages. Either may be nil.
+func (s *Splitter) SetTee(r, w io.Writer) {
+	s.rtee, s.wtee = r, w
+	if s.dec != nil {
+		s.dec.Tee = r
+	}
+	if s.enc != nil {
+		s.enc.Tee = w
+	}
+}
@@ -0,0 +1,178 @@
+package nacm
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+// NS is the namespace of the ietf-netconf-acm module
+const NS = "urn:ietf:params:xml:ns:yang:ietf-netconf-acm"
+
+// Values of the action of a Rule, and of the default access of a Config
+const (
+	Permit = "permit"
+	Deny   = "deny"
+)
+
+// Config is the ietf-netconf-acm <nacm> container, holding the access
+// control configuration and its statistics. It may be encoded and decoded
+// with encoding/xml.
+type Config struct {
+	XMLName              xml.Name `xml:"urn:ietf:params:xml:ns:yang:ietf-netconf-acm nacm"`
+	EnableNACM           bool     `xml:"enable-nacm"`
+	ReadDefault          string   `xml:"read-default"`
+	WriteDefault         string   `xml:"write-default"`
+	ExecDefault          string   `xml:"exec-default"`
+	EnableExternalGroups bool     `xml:"enable-external-groups"`
+	// Denied counters are state data, set by Engine.Config
+	DeniedOperations    uint32     `xml:"denied-operations"`
+	DeniedDataWrites    uint32     `xml:"denied-data-writes"`
+	DeniedNotifications uint32     `xml:"denied-notifications"`
+	Groups              []Group    `xml:"groups>group"`
+	RuleLists           []RuleList `xml:"rule-list"`
+}
+
+// NewConfig returns a Config with the ietf-netconf-acm default values:
+// access control enabled, read and exec access permitted, and write
+// access denied.
+func NewConfig() *Config {
+	return &Config{
+		EnableNACM:           true,
+		ReadDefault:          Permit,
+		WriteDefault:         Deny,
+		ExecDefault:          Permit,
+		EnableExternalGroups: true,
+	}
+}
+
+// ParseConfig decodes a <nacm> element read from r, with default values
+// for its missing leafs as for NewConfig.
+func ParseConfig(r io.Reader) (*Config, error) {
+	c := NewConfig()
+	if err := xml.NewDecoder(r).Decode(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Group is an administrative group of users
+type Group struct {
+	Name      string   `xml:"name"`
+	UserNames []string `xml:"user-name"`
+}
+
+// RuleList is an ordered list of rules, applying to the users of its
+// groups ("*" applying to all users)
+type RuleList struct {
+	Name   string   `xml:"name"`
+	Groups []string `xml:"group"`
+	Rules  []Rule   `xml:"rule"`
+}
+
+// Rule is an access control rule.
+//
+// At most one of RPCName, NotificationName and Path is set, selecting the
+// rule's type; a rule with none of them set applies to all requests.
+type Rule struct {
+	Name string `xml:"name"`
+	// ModuleName is the name of the module the rule applies to, or "*"
+	ModuleName       string `xml:"module-name,omitempty"`
+	RPCName          string `xml:"rpc-name,omitempty"`
+	NotificationName string `xml:"notification-name,omitempty"`
+	// Path is an instance identifier selecting the data nodes (along with
+	// their descendants) the rule applies to
+	Path *Path `xml:"path,omitempty"`
+	// AccessOperations holds the space separated access operations the
+	// rule applies to (e.g., "read update"), or "*" (the default)
+	AccessOperations string `xml:"access-operations,omitempty"`
+	Action           string `xml:"action"`
+	Comment          string `xml:"comment,omitempty"`
+}
+
+// Path is a data node instance identifier, with any prefixes it uses
+// declared in Attrs
+type Path struct {
+	Attrs []xml.Attr `xml:",any,attr"`
+	Expr  string     `xml:",chardata"`
+}
+
+// MarshalXML implements xml.Marshaler, re-encoding decoded namespace
+// declarations
+func (p *Path) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	for _, a := range p.Attrs {
+		if a.Name.Space == "xmlns" {
+			a.Name = xml.Name{Local: "xmlns:" + a.Name.Local}
+		}
+		start.Attr = append(start.Attr, a)
+	}
+	return e.EncodeElement(p.Expr, start)
+}
+
+// Namespaces returns the namespace prefixes declared in p's Attrs
+func (p *Path) Namespaces() map[string]string {
+	ns := map[string]string{}
+	for _, a := range p.Attrs {
+		switch {
+		case a.Name.Space == "xmlns":
+			ns[a.Name.Local] = a.Value
+		case a.Name.Space == "" && strings.HasPrefix(a.Name.Local, "xmlns:"):
+			ns[strings.TrimPrefix(a.Name.Local, "xmlns:")] = a.Value
+		}
+	}
+	return ns
+}
+
+// Access is a set of access operations
+type Access uint8
+
+// Access operations
+const (
+	Create Access = 1 << iota
+	Read
+	Update
+	Delete
+	Exec
+
+	All = Create | Read | Update | Delete | Exec
+)
+
+var accessNames = []string{"create", "read", "update", "delete", "exec"}
+
+func (a Access) String() string {
+	if a == All {
+		return "*"
+	}
+	var names []string
+	for i, name := range accessNames {
+		if a&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, " ")
+}
+
+// ParseAccess parses an access-operations value, such as "read update"
+// or "*". Unknown operation names are ignored.
+func ParseAccess(s string) Access {
+	var a Access
+	for _, f := range strings.Fields(s) {
+		if f == "*" {
+			return All
+		}
+		for i, name := range accessNames {
+			if f == name {
+				a |= 1 << i
+			}
+		}
+	}
+	return a
+}
+
+// access returns the rule's access operations
+func (r *Rule) access() Access {
+	if r.AccessOperations == "" {
+		return All
+	}
+	return ParseAccess(r.AccessOperations)
+}
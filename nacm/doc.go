@@ -0,0 +1,31 @@
+/*
+Package nacm enforces the NETCONF Access Control Model (RFC8341) for
+NETCONF servers.
+
+An Engine evaluates the rule-lists of a Config, the ietf-netconf-acm
+<nacm> configuration, for a User: the username of a server session (see
+session.Config.Username), along with its configured and external groups.
+
+	e := nacm.New(config, schema)
+	u := nacm.SessionUser(s)
+
+Access control is not applied to a recovery session (RFC8341 section
+3.4), whose User has Recovery set, such that the configuration may be
+repaired should it deny all access.
+
+Servers check each <rpc>'s operation with CheckOperation, and each data
+node written by an edit with CheckWrite, replying with the access-denied
+rpc-error returned if denied. The data of <get> and <get-config> replies
+is pruned of the nodes the user may not read by FilterRead, while
+notifications denied by CheckNotification are dropped:
+
+	if err := e.CheckOperation(u, xml.Name{Space: ops.NS, Local: "edit-config"}); err != nil {
+		reply.Errors = append(reply.Errors, *err.(*ops.RPCError))
+	}
+
+The nacm:default-deny-write and nacm:default-deny-all extensions of the
+schema are honoured when no rule matches a request. Engine.Data returns
+the configuration, with its denied request counters, as ietf-netconf-acm
+data, such that it may be read with <get>.
+*/
+package nacm
@@ -0,0 +1,384 @@
+package nacm
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/andaru/netconf/data"
+	"github.com/andaru/netconf/ops"
+	"github.com/andaru/netconf/session"
+	"github.com/andaru/netconf/yang"
+)
+
+// User is a user requesting access, being the NETCONF username of a
+// session along with any groups assigned to it externally, such as by
+// the session's SSH or TLS authentication.
+type User struct {
+	Name   string
+	Groups []string
+	// Recovery is true for a recovery session (RFC8341 section 3.4),
+	// such as a session of the system's superuser, to which access
+	// control is not applied
+	Recovery bool
+}
+
+// SessionUser returns the User of the server session s, whose username
+// is its Config.Username, with the external groups.
+func SessionUser(s *session.Session, groups ...string) User {
+	return User{Name: s.Config.Username, Groups: groups}
+}
+
+// Engine enforces the access control rules of a Config (RFC8341) on
+// requests for protocol operations, data nodes and notifications. It is
+// safe for concurrent use.
+type Engine struct {
+	schema *yang.Context
+
+	mu     sync.RWMutex
+	config *Config
+
+	deniedOperations, deniedDataWrites, deniedNotifications uint32
+}
+
+// New returns a new Engine enforcing config, for the data and operations
+// of schema. A nil config uses the defaults of NewConfig.
+func New(config *Config, schema *yang.Context) *Engine {
+	if config == nil {
+		config = NewConfig()
+	}
+	if schema == nil {
+		schema = yang.NewContext()
+	}
+	return &Engine{schema: schema, config: config}
+}
+
+// SetConfig replaces the engine's configuration, such as after an edit
+// of its ietf-netconf-acm data. The denied counters are retained.
+func (e *Engine) SetConfig(config *Config) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.config = config
+}
+
+// Config returns a shallow copy of the engine's configuration, with
+// its denied counters set.
+func (e *Engine) Config() *Config {
+	e.mu.RLock()
+	c := *e.config
+	e.mu.RUnlock()
+	c.DeniedOperations = atomic.LoadUint32(&e.deniedOperations)
+	c.DeniedDataWrites = atomic.LoadUint32(&e.deniedDataWrites)
+	c.DeniedNotifications = atomic.LoadUint32(&e.deniedNotifications)
+	return &c
+}
+
+// Data returns the engine's configuration and statistics as an
+// ietf-netconf-acm data tree, bound to the engine's schema, to be
+// included in the server's replies.
+func (e *Engine) Data() (*data.Node, error) {
+	b, err := xml.Marshal(e.Config())
+	if err != nil {
+		return nil, err
+	}
+	return data.Parse(bytes.NewReader(b), e.schema)
+}
+
+// CheckOperation checks that u may execute the protocol operation name
+// (e.g., an <rpc>'s operation element name), returning an access-denied
+// *ops.RPCError if not. The <close-session> operation is always permitted.
+func (e *Engine) CheckOperation(u User, name xml.Name) error {
+	if name == (xml.Name{Space: ops.NS, Local: "close-session"}) {
+		return nil
+	}
+	r := e.request(u)
+	if r == nil {
+		return nil
+	}
+	permit, ok := r.decide(e.moduleName(name.Space), Exec, func(rule *Rule) bool {
+		return rule.NotificationName == "" && rule.Path == nil && nameMatch(rule.RPCName, name.Local)
+	})
+	if !ok {
+		permit = !e.operationDenyAll(name) && r.config.ExecDefault == Permit
+	}
+	if permit {
+		return nil
+	}
+	atomic.AddUint32(&e.deniedOperations, 1)
+	return &ops.RPCError{Type: ops.ErrorTypeProtocol, Tag: ops.ErrorTagAccessDenied, Severity: ops.SeverityError,
+		Message: fmt.Sprintf("access to operation %q denied", name.Local)}
+}
+
+// CheckNotification checks that u may receive the notification whose
+// event element is name, returning an access-denied *ops.RPCError if
+// not. Denied notifications are to be silently dropped.
+func (e *Engine) CheckNotification(u User, name xml.Name) error {
+	r := e.request(u)
+	if r == nil {
+		return nil
+	}
+	permit, ok := r.decide(e.moduleName(name.Space), Read, func(rule *Rule) bool {
+		return rule.RPCName == "" && rule.Path == nil && nameMatch(rule.NotificationName, name.Local)
+	})
+	if !ok {
+		permit = !e.notificationDenyAll(name) && r.config.ReadDefault == Permit
+	}
+	if permit {
+		return nil
+	}
+	atomic.AddUint32(&e.deniedNotifications, 1)
+	return &ops.RPCError{Type: ops.ErrorTypeProtocol, Tag: ops.ErrorTagAccessDenied, Severity: ops.SeverityError,
+		Message: fmt.Sprintf("access to notification %q denied", name.Local)}
+}
+
+// CheckWrite checks that u may perform the write access (Create, Update
+// or Delete) on the data node n and its descendants, such as the nodes
+// of an <edit-config> being created. The first node denied is reported
+// as an access-denied *ops.RPCError, with its error-path.
+func (e *Engine) CheckWrite(u User, n *data.Node, access Access) error {
+	r := e.request(u)
+	if r == nil {
+		return nil
+	}
+	if denied := r.write(n, access); denied != nil {
+		atomic.AddUint32(&e.deniedDataWrites, 1)
+		path, ns := denied.Path()
+		return &ops.RPCError{Type: ops.ErrorTypeApplication, Tag: ops.ErrorTagAccessDenied, Severity: ops.SeverityError,
+			Path: path, PathNS: ns, Message: fmt.Sprintf("%s access to %q denied", access, denied.Name.Local)}
+	}
+	return nil
+}
+
+// FilterRead removes the nodes of the tree root that u may not read,
+// along with their descendants, as for the data of a <get> or
+// <get-config> reply.
+func (e *Engine) FilterRead(u User, root *data.Node) {
+	if r := e.request(u); r != nil {
+		r.read(root)
+	}
+}
+
+// request holds the rules applying to a user's requests
+type request struct {
+	e      *Engine
+	config *Config
+	rules  []*Rule
+	// paths holds the nodes selected by the path of data node rules
+	paths map[*Rule]map[*data.Node]bool
+}
+
+// request returns the rules applying to u, or nil if access control is
+// not enabled or u is a recovery session
+func (e *Engine) request(u User) *request {
+	e.mu.RLock()
+	config := e.config
+	e.mu.RUnlock()
+	if !config.EnableNACM || u.Recovery {
+		return nil
+	}
+	groups := map[string]bool{}
+	for _, g := range config.Groups {
+		for _, name := range g.UserNames {
+			if name == u.Name {
+				groups[g.Name] = true
+			}
+		}
+	}
+	if config.EnableExternalGroups {
+		for _, g := range u.Groups {
+			groups[g] = true
+		}
+	}
+	r := &request{e: e, config: config, paths: map[*Rule]map[*data.Node]bool{}}
+	for i := range config.RuleLists {
+		rl := &config.RuleLists[i]
+		for _, g := range rl.Groups {
+			if g == "*" || groups[g] {
+				for j := range rl.Rules {
+					r.rules = append(r.rules, &rl.Rules[j])
+				}
+				break
+			}
+		}
+	}
+	return r
+}
+
+// decide returns whether the first rule for the module and access
+// matched by match permits the request, and false if no rule matches.
+func (r *request) decide(module string, access Access, match func(*Rule) bool) (permit, ok bool) {
+	for _, rule := range r.rules {
+		if rule.access()&access == 0 || !nameMatch(rule.ModuleName, module) || !match(rule) {
+			continue
+		}
+		return rule.Action == Permit, true
+	}
+	return false, false
+}
+
+// nameMatch returns true if the rule's name pattern (a name, "*" or
+// empty) matches name
+func nameMatch(pattern, name string) bool {
+	return pattern == "" || pattern == "*" || pattern == name
+}
+
+// data returns true if access to the data node n is permitted
+func (r *request) data(n *data.Node, access Access) bool {
+	permit, ok := r.decide(r.e.moduleName(n.Name.Space), access, func(rule *Rule) bool {
+		return rule.RPCName == "" && rule.NotificationName == "" && (rule.Path == nil || r.pathMatch(rule, n))
+	})
+	if ok {
+		return permit
+	}
+	deny := dataDenyAll(n.Schema, access)
+	if access == Read {
+		return !deny && r.config.ReadDefault == Permit
+	}
+	return !deny && r.config.WriteDefault == Permit
+}
+
+// write returns the first of n and its descendants to which access is
+// denied, or nil
+func (r *request) write(n *data.Node, access Access) *data.Node {
+	if !r.data(n, access) {
+		return n
+	}
+	for _, c := range n.Children {
+		if denied := r.write(c, access); denied != nil {
+			return denied
+		}
+	}
+	return nil
+}
+
+// read removes the descendants of n which may not be read
+func (r *request) read(n *data.Node) {
+	for _, c := range append([]*data.Node(nil), n.Children...) {
+		if !r.data(c, Read) {
+			c.Remove()
+			continue
+		}
+		r.read(c)
+	}
+}
+
+// pathMatch returns true if n, or one of its ancestors, is selected by
+// the rule's path in n's tree. Invalid paths select no nodes.
+func (r *request) pathMatch(rule *Rule, n *data.Node) bool {
+	selected := r.paths[rule]
+	if selected == nil {
+		selected = map[*data.Node]bool{}
+		r.paths[rule] = selected
+		expr := strings.TrimSpace(rule.Path.Expr)
+		if x, err := data.CompileXPath(expr, rule.Path.Namespaces()); err == nil {
+			if nodes, err := x.Select(n.Root()); err == nil {
+				for _, s := range nodes {
+					selected[s] = true
+				}
+			}
+		}
+	}
+	for ; n != nil; n = n.Parent {
+		if selected[n] {
+			return true
+		}
+	}
+	return false
+}
+
+// moduleName returns the name of the module with namespace space
+func (e *Engine) moduleName(space string) string {
+	if m := e.schema.ModuleByNamespace(space); m != nil {
+		return m.Name
+	}
+	if space == ops.NS {
+		return "ietf-netconf"
+	}
+	return ""
+}
+
+// operationDenyAll returns true if the operation name is defined with
+// the nacm:default-deny-all extension
+func (e *Engine) operationDenyAll(name xml.Name) bool {
+	if name.Space == ops.NS {
+		// as defined by ietf-netconf (RFC8341 section 3.2.1)
+		switch name.Local {
+		case "kill-session", "delete-config":
+			return true
+		}
+	}
+	if m := e.schema.ModuleByNamespace(name.Space); m != nil && m.Root != nil {
+		if rpc := m.Root.Child(m, name.Local); rpc != nil && rpc.Kind == yang.KindRPC {
+			return hasExtension(rpc, "default-deny-all")
+		}
+	}
+	return false
+}
+
+// notificationDenyAll returns true if the notification name is defined
+// with the nacm:default-deny-all extension
+func (e *Engine) notificationDenyAll(name xml.Name) bool {
+	if m := e.schema.ModuleByNamespace(name.Space); m != nil && m.Root != nil {
+		if n := m.Root.Child(m, name.Local); n != nil && n.Kind == yang.KindNotification {
+			return hasExtension(n, "default-deny-all")
+		}
+	}
+	return false
+}
+
+// dataDenyAll returns true if the schema node s, or one of its
+// ancestors, is defined with the nacm:default-deny-all extension, or
+// with nacm:default-deny-write for write access
+func dataDenyAll(s *yang.Node, access Access) bool {
+	for ; s != nil; s = s.Parent {
+		if hasExtension(s, "default-deny-all") || access != Read && hasExtension(s, "default-deny-write") {
+			return true
+		}
+	}
+	return false
+}
+
+// hasExtension returns true if s has the ietf-netconf-acm extension
+// statement name, its prefix being that of the module's import of
+// ietf-netconf-acm (or ietf-netconf-acm's own prefix)
+func hasExtension(s *yang.Node, name string) bool {
+	m := s.Module
+	if m == nil {
+		return false
+	}
+	acm := importPrefix(m, "ietf-netconf-acm")
+	if m.Name == "ietf-netconf-acm" {
+		acm = m.Prefix
+	}
+	for _, st := range s.Extensions {
+		i := strings.IndexByte(st.Keyword, ':')
+		if i < 0 || st.Keyword[i+1:] != name {
+			continue
+		}
+		if acm != "" && st.Keyword[:i] == acm {
+			return true
+		}
+	}
+	return false
+}
+
+// importPrefix returns the prefix of module's import of the module
+// name, or "" if not imported
+func importPrefix(m *yang.Module, name string) string {
+	if m == nil || m.Stmt == nil {
+		return ""
+	}
+	for _, st := range m.Stmt.Statements {
+		if st.Keyword == "import" && st.Argument == name {
+			for _, sub := range st.Statements {
+				if sub.Keyword == "prefix" {
+					return sub.Argument
+				}
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,201 @@
+package nacm
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/andaru/netconf/data"
+	"github.com/andaru/netconf/ops"
+	"github.com/andaru/netconf/yang"
+	"github.com/stretchr/testify/assert"
+)
+
+func loadSchema(t *testing.T) *yang.Context {
+	t.Helper()
+	c := yang.NewContext("testdata")
+	if err := c.Load("example-acm", "ietf-netconf-acm"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Resolve(); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+const testConfig = `<nacm xmlns="urn:ietf:params:xml:ns:yang:ietf-netconf-acm">
+  <groups>
+    <group><name>admin</name><user-name>alice</user-name></group>
+    <group><name>ops</name><user-name>bob</user-name></group>
+  </groups>
+  <rule-list>
+    <name>admin</name><group>admin</group>
+    <rule><name>all</name><module-name>*</module-name><action>permit</action></rule>
+  </rule-list>
+  <rule-list>
+    <name>ops</name><group>ops</group>
+    <rule><name>hide-location</name><path xmlns:ex="urn:example:acm">/ex:system/ex:location</path>
+      <access-operations>read</access-operations><action>deny</action></rule>
+    <rule><name>edit-hostname</name><path xmlns:ex="urn:example:acm">/ex:system/ex:hostname</path>
+      <access-operations>create update</access-operations><action>permit</action></rule>
+    <rule><name>no-ping</name><module-name>example-acm</module-name><rpc-name>ping</rpc-name><action>deny</action></rule>
+    <rule><name>no-alarms</name><notification-name>alarm</notification-name><action>deny</action></rule>
+  </rule-list>
+</nacm>`
+
+func newEngine(t *testing.T) (*Engine, *yang.Context) {
+	t.Helper()
+	config, err := ParseConfig(strings.NewReader(testConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := loadSchema(t)
+	return New(config, schema), schema
+}
+
+var (
+	alice = User{Name: "alice"}
+	bob   = User{Name: "bob"}
+	carol = User{Name: "carol"}
+)
+
+func TestCheckOperation(t *testing.T) {
+	e, _ := newEngine(t)
+	ping := xml.Name{Space: "urn:example:acm", Local: "ping"}
+	reboot := xml.Name{Space: "urn:example:acm", Local: "reboot"}
+	for _, tc := range []struct {
+		user   User
+		op     xml.Name
+		denied bool
+	}{
+		{user: bob, op: ping, denied: true},
+		{user: bob, op: reboot, denied: true},
+		{user: bob, op: xml.Name{Space: ops.NS, Local: "get"}},
+		{user: carol, op: ping},
+		{user: carol, op: xml.Name{Space: ops.NS, Local: "kill-session"}, denied: true},
+		{user: carol, op: xml.Name{Space: ops.NS, Local: "close-session"}},
+		{user: alice, op: reboot},
+		{user: User{Name: "dave", Groups: []string{"admin"}}, op: reboot},
+	} {
+		err := e.CheckOperation(tc.user, tc.op)
+		if tc.denied {
+			assert.EqualError(t, err, `rpc-error: protocol: access-denied: access to operation "`+tc.op.Local+`" denied`, "%s %s", tc.user.Name, tc.op.Local)
+		} else {
+			assert.NoError(t, err, "%s %s", tc.user.Name, tc.op.Local)
+		}
+	}
+	assert.Equal(t, uint32(3), e.Config().DeniedOperations)
+
+	// a recovery session bypasses access control
+	recovery := User{Name: "bob", Recovery: true}
+	assert.NoError(t, e.CheckOperation(recovery, reboot))
+	assert.NoError(t, e.CheckOperation(recovery, xml.Name{Space: ops.NS, Local: "kill-session"}))
+	assert.Equal(t, uint32(3), e.Config().DeniedOperations)
+
+	// external groups may be disabled
+	config := e.Config()
+	config.EnableExternalGroups = false
+	e.SetConfig(config)
+	assert.Error(t, e.CheckOperation(User{Name: "dave", Groups: []string{"admin"}}, reboot))
+	// as may access control
+	config.EnableNACM = false
+	e.SetConfig(config)
+	assert.NoError(t, e.CheckOperation(bob, reboot))
+}
+
+func TestData(t *testing.T) {
+	a := assert.New(t)
+	e, schema := newEngine(t)
+	const tree = `<system xmlns="urn:example:acm"><hostname>r1</hostname><location>lab</location>` +
+		`<user><name>root</name><password>secret</password></user></system>`
+	for _, tc := range []struct {
+		user User
+		want string
+	}{
+		{user: alice, want: tree},
+		{user: bob, want: `<system xmlns="urn:example:acm"><hostname>r1</hostname><user><name>root</name></user></system>`},
+		{user: User{Name: "bob", Recovery: true}, want: tree},
+	} {
+		root, err := data.Parse(strings.NewReader(tree), schema)
+		if !a.NoError(err) {
+			return
+		}
+		e.FilterRead(tc.user, root)
+		var b strings.Builder
+		a.NoError(root.WriteXML(&b))
+		a.Equal(tc.want, b.String(), tc.user.Name)
+	}
+
+	edit, err := data.Parse(strings.NewReader(`<system xmlns="urn:example:acm"><hostname>r2</hostname><location>lab</location></system>`), schema)
+	if !a.NoError(err) {
+		return
+	}
+	system := edit.Children[0]
+	a.NoError(e.CheckWrite(bob, system.Children[0], Update))
+	a.EqualError(e.CheckWrite(bob, system.Children[0], Delete), `rpc-error: application: access-denied: /ex:system/ex:hostname: delete access to "hostname" denied`)
+	err = e.CheckWrite(bob, system, Update)
+	if a.IsType(&ops.RPCError{}, err) {
+		a.Equal("/ex:system", err.(*ops.RPCError).Path)
+		a.Equal(map[string]string{"ex": "urn:example:acm"}, err.(*ops.RPCError).PathNS)
+	}
+	a.NoError(e.CheckWrite(alice, system, Update))
+	a.Equal(uint32(2), e.Config().DeniedDataWrites)
+}
+
+func TestCheckNotification(t *testing.T) {
+	e, _ := newEngine(t)
+	alarm := xml.Name{Space: "urn:example:acm", Local: "alarm"}
+	assert.NoError(t, e.CheckNotification(alice, alarm))
+	assert.EqualError(t, e.CheckNotification(bob, alarm), `rpc-error: protocol: access-denied: access to notification "alarm" denied`)
+	assert.Equal(t, uint32(1), e.Config().DeniedNotifications)
+}
+
+func TestConfigData(t *testing.T) {
+	a := assert.New(t)
+	e, _ := newEngine(t)
+	a.Error(e.CheckOperation(bob, xml.Name{Space: "urn:example:acm", Local: "ping"}))
+	// the configuration is readable as ietf-netconf-acm data
+	root, err := e.Data()
+	if !a.NoError(err) {
+		return
+	}
+	if a.Len(root.Children, 1) {
+		a.NotNil(root.Children[0].Schema)
+		a.Equal("1", root.Children[0].Child(xml.Name{Space: NS, Local: "denied-operations"}).Value)
+	}
+	a.NoError(data.Validate(root, data.Options{State: true}))
+	b, err := xml.Marshal(e.Config())
+	a.NoError(err)
+	config, err := ParseConfig(strings.NewReader(string(b)))
+	if a.NoError(err) {
+		a.Equal(e.Config(), config)
+	}
+	// the nacm container is not readable by default
+	e.FilterRead(carol, root)
+	a.Empty(root.Children)
+}
+
+func TestHasExtension(t *testing.T) {
+	a := assert.New(t)
+	c := loadSchema(t)
+	if err := c.Load("example-ext"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Resolve(); err != nil {
+		t.Fatal(err)
+	}
+	acm, ext := c.Module("example-acm"), c.Module("example-ext")
+	password := acm.Root.Child(acm, "system").Child(acm, "user").Child(acm, "password")
+	a.True(hasExtension(password, "default-deny-all"))
+	// another module's extension with the prefix nacm is not ietf-netconf-acm's
+	secret := ext.Root.Child(ext, "settings").Child(ext, "secret")
+	a.False(hasExtension(secret, "default-deny-all"))
+}
+
+func TestAccess(t *testing.T) {
+	a := assert.New(t)
+	a.Equal(Read|Update, ParseAccess("update read"))
+	a.Equal(All, ParseAccess("*"))
+	a.Equal("create delete", (Create | Delete).String())
+	a.Equal("*", All.String())
+}
@@ -0,0 +1,30 @@
+/*
+Package netconftest provides utilities for testing NETCONF applications.
+
+Pipe returns a buffered in-memory transport, suitable for passing to
+session.New. Unlike io.Pipe, writes never block, so both peers may send
+their <hello> message at the same time, as they would over a network.
+
+NewPair returns a connected client and server session.Session pair,
+and Pair.Handshake establishes both sessions concurrently, so a
+Handler implementation may be tested in a few lines:
+
+	p := netconftest.NewPair(session.Config{}, session.Config{})
+	if err := p.Handshake(); err != nil {
+		t.Fatal(err)
+	}
+	go p.Server.Run(&myHandler{})
+	reply, err := netconftest.Call(p.Client, `<rpc message-id="1" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><get/></rpc>`)
+
+A Script is a session.Handler acting as a fake peer, expecting each
+incoming message to be equivalent to the next step's request, replying
+with the step's reply. EqualXML and AssertXMLEqual compare XML documents
+for equivalence, ignoring namespace prefixes, attribute order and
+whitespace-only character data.
+
+Faults injects faults into a session's transport, wrapping the reader
+(FaultReader) or writer (FaultWriter) passed to session.New. Latency,
+arbitrary read splitting, truncation, chunk header corruption and
+dropping the connection after a number of messages are supported.
+*/
+package netconftest
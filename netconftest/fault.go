@@ -0,0 +1,157 @@
+package netconftest
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/andaru/netconf/framing"
+)
+
+// Faults describes faults injected into a transport stream by a
+// FaultReader or FaultWriter, for resilience testing of session
+// handlers and the framing decoders.
+//
+// The zero value injects no faults.
+type Faults struct {
+	// Latency is the delay before each Read or Write
+	Latency time.Duration
+	// ReadSizes, if non-empty, limits the size of successive reads,
+	// cycling through the sizes given. Use this to split reads at
+	// arbitrary byte boundaries (e.g., []int{1} for byte-at-a-time).
+	ReadSizes []int
+	// TruncateAt, if positive, ends the stream after this many bytes,
+	// e.g., mid-chunk.
+	TruncateAt int64
+	// CorruptChunkHeader, if positive, corrupts the size of the Nth
+	// (1-based) chunk header seen in the stream, by replacing the size's
+	// first digit. Note that chunk headers are recognised by the
+	// "\n#<digit>" pattern, which may also appear in message data.
+	CorruptChunkHeader int
+	// DropAfter, if positive, ends the stream after this many messages
+	// (including the <hello>) have passed.
+	DropAfter int
+	// Err is the error returned once the stream has ended due to
+	// truncation or being dropped. Defaults to io.EOF for readers, and
+	// io.ErrClosedPipe for writers.
+	Err error
+}
+
+// FaultReader returns an io.Reader injecting faults into data read from r.
+func (f Faults) FaultReader(r io.Reader) io.Reader {
+	return &faultReader{faulter: newFaulter(f, io.EOF), src: r}
+}
+
+// FaultWriter returns an io.WriteCloser injecting faults into data written to w.
+// When the stream ends due to truncation or being dropped, w is closed.
+func (f Faults) FaultWriter(w io.WriteCloser) io.WriteCloser {
+	return &faultWriter{faulter: newFaulter(f, io.ErrClosedPipe), dst: w}
+}
+
+// faulter holds the fault injection state for a stream
+type faulter struct {
+	Faults
+
+	mu      sync.Mutex
+	tracker *framing.Tracker
+	reads   int
+	offset  int64
+	headers int
+	prev    [2]byte
+	ended   bool
+}
+
+func newFaulter(f Faults, err error) *faulter {
+	if f.Err == nil {
+		f.Err = err
+	}
+	return &faulter{Faults: f, tracker: framing.NewTracker()}
+}
+
+// limit returns the maximum size of the next transfer of size n
+func (f *faulter) limit(n int, read bool) int {
+	if read && len(f.ReadSizes) > 0 {
+		if size := f.ReadSizes[f.reads%len(f.ReadSizes)]; size > 0 && size < n {
+			n = size
+		}
+		f.reads++
+	}
+	if f.TruncateAt > 0 {
+		if left := f.TruncateAt - f.offset; left < int64(n) {
+			n = int(left)
+			f.ended = f.ended || n == 0
+		}
+	}
+	return n
+}
+
+// apply injects faults into b, the next data in the stream, returning the
+// number of bytes of b to transfer before the stream ends.
+func (f *faulter) apply(b []byte) int {
+	if f.CorruptChunkHeader > 0 {
+		for i, c := range b {
+			if f.prev == [2]byte{'\n', '#'} && c >= '1' && c <= '9' {
+				if f.headers++; f.headers == f.CorruptChunkHeader {
+					b[i] = 'x'
+				}
+			}
+			f.prev[0], f.prev[1] = f.prev[1], c
+		}
+	}
+	n := len(b)
+	if f.DropAfter > 0 {
+		msgs := f.tracker.Messages()
+		ends, _ := f.tracker.Feed(b)
+		if left := f.DropAfter - msgs; left <= len(ends) {
+			n = ends[left-1]
+			f.ended = true
+		}
+	}
+	f.offset += int64(n)
+	if f.TruncateAt > 0 && f.offset >= f.TruncateAt {
+		f.ended = true
+	}
+	return n
+}
+
+type faultReader struct {
+	*faulter
+	src io.Reader
+}
+
+func (r *faultReader) Read(p []byte) (n int, err error) {
+	time.Sleep(r.Latency)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.ended {
+		return 0, r.Err
+	}
+	limit := r.limit(len(p), true)
+	if r.ended {
+		return 0, r.Err
+	}
+	n, err = r.src.Read(p[:limit])
+	return r.apply(p[:n]), err
+}
+
+type faultWriter struct {
+	*faulter
+	dst io.WriteCloser
+}
+
+func (w *faultWriter) Write(b []byte) (n int, err error) {
+	time.Sleep(w.Latency)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.ended {
+		return 0, w.Err
+	}
+	data := append([]byte(nil), b[:w.limit(len(b), false)]...)
+	if n, err = w.dst.Write(data[:w.apply(data)]); err == nil && w.ended {
+		w.dst.Close()
+		err = w.Err
+	}
+	return n, err
+}
+
+func (w *faultWriter) Close() error { return w.dst.Close() }
@@ -0,0 +1,192 @@
+package netconftest
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andaru/netconf/framing"
+	"github.com/andaru/netconf/session"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPipe(t *testing.T) {
+	a := assert.New(t)
+	p := NewPipe()
+	n, err := p.Write([]byte("foo"))
+	a.NoError(err)
+	a.Equal(3, n)
+	p.Write([]byte("bar"))
+	a.NoError(p.Close())
+	_, err = p.Write([]byte("baz"))
+	a.Equal(io.ErrClosedPipe, err)
+	b, err := io.ReadAll(p)
+	a.NoError(err)
+	a.Equal("foobar", string(b))
+}
+
+func TestEqualXML(t *testing.T) {
+	for _, tc := range []struct {
+		a, b  string
+		equal bool
+	}{
+		{a: `<a/>`, b: `<a></a>`, equal: true},
+		{a: `<a x="1" y="2"/>`, b: `<a y="2" x="1"/>`, equal: true},
+		{a: `<a xmlns="urn:x"><b>1</b></a>`, b: `<p:a xmlns:p="urn:x"><p:b> 1 </p:b></p:a>`, equal: true},
+		{a: "<a>\n\t<b/>\n</a>", b: `<a><b/><!-- comment --></a>`, equal: true},
+		{a: `<a xmlns="urn:x"/>`, b: `<a xmlns="urn:y"/>`},
+		{a: `<a x="1"/>`, b: `<a x="2"/>`},
+		{a: `<a><b/><c/></a>`, b: `<a><c/><b/></a>`},
+		{a: `<a>1</a>`, b: `<a>2</a>`},
+	} {
+		t.Run(tc.a, func(t *testing.T) {
+			equal, err := EqualXML(tc.a, tc.b)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.equal, equal)
+		})
+	}
+	_, err := EqualXML(`<a>`, `<a/>`)
+	assert.Error(t, err)
+}
+
+func TestPair(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		client  session.Config
+		server  session.Config
+		wantErr bool
+	}{
+		{name: "defaults"},
+		{name: "base 1.0", client: session.Config{Capabilities: session.Capabilities{capBase10}}},
+		{
+			name:    "no common framing",
+			client:  session.Config{Capabilities: session.Capabilities{capBase10}},
+			server:  session.Config{Capabilities: session.Capabilities{capBase11}},
+			wantErr: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			a := assert.New(t)
+			p := NewPair(tc.client, tc.server)
+			if err := p.Handshake(); tc.wantErr {
+				a.Error(err)
+				return
+			} else if !a.NoError(err) {
+				return
+			}
+			a.Equal(uint32(1), p.Client.State.ID)
+			a.Equal(session.StatusEstablished, p.Client.State.Status)
+			a.Equal(session.StatusEstablished, p.Server.State.Status)
+		})
+	}
+}
+
+func TestScriptedServer(t *testing.T) {
+	a := assert.New(t)
+	client, script := ScriptedServer(t, session.Config{}, session.Config{ID: 7},
+		Step{
+			Request: `<rpc message-id="1" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><get/></rpc>`,
+			Reply:   `<rpc-reply message-id="1" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><data/></rpc-reply>`,
+		},
+		Step{
+			Request: `<rpc message-id="2" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><close-session/></rpc>`,
+			Reply:   `<rpc-reply message-id="2" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><ok/></rpc-reply>`,
+		},
+	)
+	a.Equal(uint32(7), client.State.ID)
+	reply, err := Call(client, `<nc:rpc message-id="1" xmlns:nc="urn:ietf:params:xml:ns:netconf:base:1.0"><nc:get/></nc:rpc>`)
+	a.NoError(err)
+	AssertXMLEqual(t, `<rpc-reply message-id="1" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><data/></rpc-reply>`, reply)
+	reply, err = Call(client, `<rpc message-id="2" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><close-session/></rpc>`)
+	a.NoError(err)
+	AssertXMLEqual(t, `<rpc-reply message-id="2" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><ok/></rpc-reply>`, reply)
+	client.Close()
+	script.Wait()
+}
+
+const testHello = `<hello xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><capabilities>` +
+	`<capability>urn:ietf:params:netconf:base:1.0</capability>` +
+	`<capability>urn:ietf:params:netconf:base:1.1</capability>` +
+	`</capabilities><session-id>1</session-id></hello>]]>]]>`
+
+func TestFaultReader(t *testing.T) {
+	const input = testHello + "\n#4\n<rpc\n#3\n/>\n\n##\n\n#7\n<rpc/>\n\n##\n"
+	for _, tc := range []struct {
+		name     string
+		faults   Faults
+		wantMsgs int
+		wantErr  error
+	}{
+		{name: "no faults", wantMsgs: 2, wantErr: session.ErrEndOfStream},
+		{name: "byte at a time", faults: Faults{ReadSizes: []int{1}}, wantMsgs: 2, wantErr: session.ErrEndOfStream},
+		{name: "odd read sizes", faults: Faults{ReadSizes: []int{3, 1, 7}}, wantMsgs: 2, wantErr: session.ErrEndOfStream},
+		{name: "latency", faults: Faults{Latency: time.Millisecond}, wantMsgs: 2, wantErr: session.ErrEndOfStream},
+		{name: "truncate mid-chunk", faults: Faults{TruncateAt: int64(len(testHello)) + 6}, wantErr: io.ErrUnexpectedEOF},
+		{name: "corrupt chunk header", faults: Faults{CorruptChunkHeader: 2}, wantErr: framing.ErrBadChunk{Message: "invalid chunk size"}},
+		{name: "drop after hello", faults: Faults{DropAfter: 1}, wantErr: session.ErrEndOfStream},
+		{name: "drop after message", faults: Faults{DropAfter: 2}, wantMsgs: 1, wantErr: session.ErrEndOfStream},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			a := assert.New(t)
+			s := session.New(tc.faults.FaultReader(strings.NewReader(input)), NewPipe(), session.Config{
+				Capabilities: session.Capabilities{capBase10, capBase11},
+			})
+			start := time.Now()
+			if !a.True(s.InitialHandshake(), "%v", s.Errors()) {
+				return
+			}
+			var msgs int
+			var err error
+			for err == nil {
+				var b []byte
+				if b, err = io.ReadAll(s.Incoming()); err == nil {
+					a.Equal("<rpc/>\n", string(b))
+					msgs++
+				}
+			}
+			a.Equal(tc.wantMsgs, msgs)
+			a.Equal(tc.wantErr, err)
+			a.GreaterOrEqual(time.Since(start), tc.faults.Latency)
+		})
+	}
+}
+
+func TestFaultWriter(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		faults  Faults
+		wantErr error
+	}{
+		{name: "no faults"},
+		{name: "drop after hello", faults: Faults{DropAfter: 1}, wantErr: session.ErrEndOfStream},
+		{name: "truncate", faults: Faults{TruncateAt: int64(len(testHello)) + 10}, wantErr: io.ErrUnexpectedEOF},
+		{name: "corrupt chunk header", faults: Faults{CorruptChunkHeader: 1}, wantErr: framing.ErrBadChunk{Message: "invalid chunk size"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			a := assert.New(t)
+			toServer, toClient := NewPipe(), NewPipe()
+			client := session.New(toClient, toServer, session.Config{})
+			server := session.New(toServer, tc.faults.FaultWriter(toClient), session.Config{
+				ID:           1,
+				Capabilities: session.Capabilities{capBase10, capBase11},
+			})
+			go func() {
+				if server.InitialHandshake() {
+					io.WriteString(server.Outgoing(), "<rpc-reply/>")
+					server.Outgoing().Close()
+				}
+				server.Close()
+			}()
+			client.Config.Capabilities = session.Capabilities{capBase10, capBase11}
+			if !a.True(client.InitialHandshake(), "%v", client.Errors()) {
+				return
+			}
+			b, err := io.ReadAll(client.Incoming())
+			a.Equal(tc.wantErr, err)
+			if err == nil {
+				a.Equal("<rpc-reply/>", string(b))
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package netconftest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/andaru/netconf/session"
+)
+
+// Pair is a client and server session.Session pair, connected by Pipes
+type Pair struct {
+	Client *session.Session
+	Server *session.Session
+}
+
+// NewPair returns a new Pair of connected sessions, using the client and
+// server session configuration.
+//
+// If no capabilities are configured for a session, it uses :base:1.0 and
+// :base:1.1. The client's ID is always 0, while the server's ID defaults to 1.
+func NewPair(client, server session.Config) *Pair {
+	client.ID = 0
+	if server.ID == 0 {
+		server.ID = 1
+	}
+	for _, c := range []*session.Config{&client, &server} {
+		if len(c.Capabilities) == 0 {
+			c.Capabilities = session.Capabilities{capBase10, capBase11}
+		}
+	}
+	toServer, toClient := NewPipe(), NewPipe()
+	return &Pair{
+		Client: session.New(toClient, toServer, client),
+		Server: session.New(toServer, toClient, server),
+	}
+}
+
+// Handshake performs the initial handshake for both sessions concurrently,
+// returning an error if either session failed to establish.
+func (p *Pair) Handshake() error {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if !p.Server.InitialHandshake() {
+			// unblock the client's handshake
+			p.Server.Close()
+		}
+	}()
+	clientOK := p.Client.InitialHandshake()
+	if !clientOK {
+		p.Client.Close()
+	}
+	wg.Wait()
+	var errs []string
+	for _, s := range []struct {
+		name string
+		s    *session.Session
+	}{{"client", p.Client}, {"server", p.Server}} {
+		for _, err := range s.s.Errors() {
+			errs = append(errs, fmt.Sprintf("%s: %v", s.name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New("handshake failed: " + fmt.Sprint(errs))
+	}
+	return nil
+}
+
+// Call sends the request message on the session s, and returns the next
+// message received.
+func Call(s *session.Session, request string) (reply string, err error) {
+	if _, err = io.WriteString(s.Outgoing(), request); err == nil {
+		err = s.Outgoing().Close()
+	}
+	if err != nil {
+		return "", err
+	}
+	b, err := io.ReadAll(s.Incoming())
+	return string(b), err
+}
+
+const (
+	capBase10 = "urn:ietf:params:netconf:base:1.0"
+	capBase11 = "urn:ietf:params:netconf:base:1.1"
+)
@@ -0,0 +1,62 @@
+package netconftest
+
+import (
+	"io"
+	"sync"
+)
+
+// Pipe is a buffered in-memory pipe, implementing io.Reader and io.WriteCloser.
+//
+// Writes append to the pipe's buffer and never block, while reads block
+// until data is available or the pipe is closed.
+type Pipe struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	buf    []byte
+	closed bool
+}
+
+// NewPipe returns a new, empty Pipe
+func NewPipe() *Pipe {
+	p := &Pipe{}
+	p.cond = sync.NewCond(&p.mu)
+	return p
+}
+
+// Read reads data written to the pipe, returning io.EOF once the pipe
+// has been closed and all data has been read.
+func (p *Pipe) Read(b []byte) (n int, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for len(p.buf) == 0 && !p.closed {
+		p.cond.Wait()
+	}
+	if len(p.buf) == 0 {
+		return 0, io.EOF
+	}
+	n = copy(b, p.buf)
+	p.buf = p.buf[n:]
+	return n, nil
+}
+
+// Write writes b to the pipe, returning io.ErrClosedPipe if the pipe is closed.
+func (p *Pipe) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return 0, io.ErrClosedPipe
+	}
+	p.buf = append(p.buf, b...)
+	p.cond.Broadcast()
+	return len(b), nil
+}
+
+// Close closes the pipe for writing. Reads return any remaining data
+// before returning io.EOF.
+func (p *Pipe) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	p.cond.Broadcast()
+	return nil
+}
@@ -0,0 +1,108 @@
+package netconftest
+
+import (
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/andaru/netconf/session"
+)
+
+// Step is a single Script step
+type Step struct {
+	// Request is the message expected from the peer. If empty,
+	// any message is accepted.
+	Request string
+	// Reply is the message sent in response, if non-empty.
+	Reply string
+}
+
+// Script is a session.Handler acting as a scripted fake peer.
+//
+// For each incoming message, the Script asserts the message is equivalent
+// (see EqualXML) to the next Step's Request, and sends its Reply. Messages
+// received after the last step cause a test failure. Scripts must be
+// created with NewScript.
+type Script struct {
+	T     testing.TB
+	Steps []Step
+
+	mu   sync.Mutex
+	done chan struct{}
+	next int
+}
+
+// NewScript returns a new Script reporting failures to t
+func NewScript(t testing.TB, steps ...Step) *Script {
+	return &Script{T: t, Steps: steps, done: make(chan struct{})}
+}
+
+// ScriptedServer returns an established client session connected to a
+// server session running script in a new goroutine. The client and server
+// configuration are as per NewPair.
+func ScriptedServer(t testing.TB, client, server session.Config, steps ...Step) (*session.Session, *Script) {
+	t.Helper()
+	p := NewPair(client, server)
+	if err := p.Handshake(); err != nil {
+		t.Fatal(err)
+	}
+	script := NewScript(t, steps...)
+	go p.Server.Run(script)
+	return p.Client, script
+}
+
+// OnEstablish implements session.Handler
+func (sc *Script) OnEstablish(s *session.Session) {}
+
+// OnMessage implements session.Handler
+func (sc *Script) OnMessage(s *session.Session) {
+	b, err := io.ReadAll(s.Incoming())
+	if err == session.ErrEndOfStream {
+		s.SetStatus(session.StatusClosed)
+		return
+	} else if err != nil {
+		sc.T.Errorf("script: read error: %v", err)
+		s.AddError(err)
+		s.SetStatus(session.StatusError)
+		return
+	}
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if sc.next >= len(sc.Steps) {
+		sc.T.Errorf("script: unexpected message after %d steps: %s", len(sc.Steps), b)
+		return
+	}
+	step := sc.Steps[sc.next]
+	sc.next++
+	if step.Request != "" {
+		AssertXMLEqual(sc.T, step.Request, string(b), "script step %d", sc.next)
+	}
+	if step.Reply != "" {
+		if _, err = io.WriteString(s.Outgoing(), step.Reply); err == nil {
+			err = s.Outgoing().Close()
+		}
+		if err != nil {
+			sc.T.Errorf("script: write error: %v", err)
+		}
+	}
+}
+
+// OnError implements session.Handler
+func (sc *Script) OnError(s *session.Session) {}
+
+// OnClose implements session.Handler
+func (sc *Script) OnClose(s *session.Session) { close(sc.done) }
+
+// Wait waits for the script's session to close, then asserts all
+// steps were run.
+func (sc *Script) Wait() {
+	sc.T.Helper()
+	<-sc.done
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if sc.next < len(sc.Steps) {
+		sc.T.Errorf("script: %d of %d steps were not run", len(sc.Steps)-sc.next, len(sc.Steps))
+	}
+}
+
+var _ session.Handler = &Script{}
@@ -0,0 +1,95 @@
+package netconftest
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Canonical returns a canonical text representation of the XML document
+// doc, suitable for comparison with another document's.
+//
+// Elements and attributes are named by namespace URI and local name
+// (prefixes and namespace declarations are ignored), attributes are sorted,
+// and character data is trimmed of surrounding whitespace, with
+// whitespace-only character data, comments and processing instructions
+// being ignored.
+func Canonical(doc string) (string, error) {
+	var sb strings.Builder
+	var depth int
+	dec := xml.NewDecoder(strings.NewReader(doc))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return "", err
+		}
+		indent := strings.Repeat("  ", depth)
+		switch t := tok.(type) {
+		case xml.StartElement:
+			fmt.Fprintf(&sb, "%s<%s", indent, name(t.Name))
+			var attrs []string
+			for _, attr := range t.Attr {
+				if attr.Name.Space == "xmlns" || attr.Name.Space == "" && attr.Name.Local == "xmlns" {
+					continue
+				}
+				attrs = append(attrs, fmt.Sprintf("%s=%q", name(attr.Name), attr.Value))
+			}
+			sort.Strings(attrs)
+			for _, attr := range attrs {
+				sb.WriteString(" " + attr)
+			}
+			sb.WriteString(">\n")
+			depth++
+		case xml.EndElement:
+			depth--
+		case xml.CharData:
+			if text := strings.TrimSpace(string(t)); text != "" {
+				fmt.Fprintf(&sb, "%s%q\n", indent, text)
+			}
+		}
+	}
+	return sb.String(), nil
+}
+
+// EqualXML returns true if the XML documents a and b are equivalent.
+// See Canonical for the equivalence rules.
+func EqualXML(a, b string) (bool, error) {
+	ca, err := Canonical(a)
+	if err != nil {
+		return false, err
+	}
+	cb, err := Canonical(b)
+	if err != nil {
+		return false, err
+	}
+	return ca == cb, nil
+}
+
+// AssertXMLEqual asserts that the XML documents want and got are equivalent,
+// reporting the difference in their canonical forms to t if not.
+func AssertXMLEqual(t testing.TB, want, got string, msgAndArgs ...interface{}) bool {
+	t.Helper()
+	cw, err := Canonical(want)
+	if err != nil {
+		return assert.Fail(t, "invalid expected XML: "+err.Error(), msgAndArgs...)
+	}
+	cg, err := Canonical(got)
+	if err != nil {
+		return assert.Fail(t, "invalid XML: "+err.Error(), msgAndArgs...)
+	}
+	return assert.Equal(t, cw, cg, msgAndArgs...)
+}
+
+func name(n xml.Name) string {
+	if n.Space == "" {
+		return n.Local
+	}
+	return "{" + n.Space + "}" + n.Local
+}
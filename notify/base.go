@@ -0,0 +1,256 @@
+package notify
+
+import (
+	"encoding/xml"
+	"time"
+
+	"github.com/andaru/netconf/ops"
+	"github.com/andaru/netconf/session"
+)
+
+// BaseNS is the namespace of the ietf-netconf-notifications module
+// (RFC6470)
+const BaseNS = "urn:ietf:params:xml:ns:yang:ietf-netconf-notifications"
+
+// Values of the ConfirmEvent of a ConfirmedCommit
+const (
+	ConfirmStart    = "start"
+	ConfirmCancel   = "cancel"
+	ConfirmTimeout  = "timeout"
+	ConfirmExtend   = "extend"
+	ConfirmComplete = "complete"
+)
+
+// SessionParams identifies the session causing an event
+type SessionParams struct {
+	Username   string `xml:"username"`
+	SessionID  uint32 `xml:"session-id"`
+	SourceHost string `xml:"source-host,omitempty"`
+}
+
+// ChangedBy identifies the cause of a change: the server itself, if
+// Server is set, or else a session
+type ChangedBy struct {
+	Server *ops.Empty `xml:"server,omitempty"`
+	*SessionParams
+}
+
+// Edit is an edit of a <netconf-config-change> event
+type Edit struct {
+	// Target is the instance identifier of the data node edited
+	Target string
+	// TargetNS maps the prefixes used in Target to their namespaces
+	TargetNS map[string]string
+	// Operation is the edit operation, e.g. ops.OperationMerge
+	Operation string
+}
+
+// MarshalXML implements xml.Marshaler, declaring the target's namespaces
+// on the <target> element
+func (e *Edit) MarshalXML(enc *xml.Encoder, start xml.StartElement) error {
+	target := xml.StartElement{Name: xml.Name{Local: "target"}}
+	for prefix, ns := range e.TargetNS {
+		target.Attr = append(target.Attr, xml.Attr{Name: xml.Name{Local: "xmlns:" + prefix}, Value: ns})
+	}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := enc.EncodeElement(e.Target, target); err != nil {
+		return err
+	}
+	if e.Operation != "" {
+		if err := enc.EncodeElement(e.Operation, xml.StartElement{Name: xml.Name{Local: "operation"}}); err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
+
+// UnmarshalXML implements xml.Unmarshaler, recording the namespaces
+// declared on the <target> element
+func (e *Edit) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var v struct {
+		Target struct {
+			Attrs []xml.Attr `xml:",any,attr"`
+			Value string     `xml:",chardata"`
+		} `xml:"target"`
+		Operation string `xml:"operation"`
+	}
+	if err := d.DecodeElement(&v, &start); err != nil {
+		return err
+	}
+	e.Target, e.Operation, e.TargetNS = v.Target.Value, v.Operation, nil
+	for _, a := range v.Target.Attrs {
+		if a.Name.Space == "xmlns" {
+			if e.TargetNS == nil {
+				e.TargetNS = map[string]string{}
+			}
+			e.TargetNS[a.Name.Local] = a.Value
+		}
+	}
+	return nil
+}
+
+// ConfigChange is the <netconf-config-change> event, generated when the
+// running (or another) datastore is changed
+type ConfigChange struct {
+	XMLName   xml.Name  `xml:"urn:ietf:params:xml:ns:yang:ietf-netconf-notifications netconf-config-change"`
+	ChangedBy ChangedBy `xml:"changed-by"`
+	// Datastore is the datastore changed, "running" or "startup"
+	Datastore string `xml:"datastore,omitempty"`
+	Edits     []Edit `xml:"edit"`
+}
+
+// CapabilityChange is the <netconf-capability-change> event, generated
+// when the server's capabilities change
+type CapabilityChange struct {
+	XMLName            xml.Name  `xml:"urn:ietf:params:xml:ns:yang:ietf-netconf-notifications netconf-capability-change"`
+	ChangedBy          ChangedBy `xml:"changed-by"`
+	AddedCapability    []string  `xml:"added-capability"`
+	DeletedCapability  []string  `xml:"deleted-capability"`
+	ModifiedCapability []string  `xml:"modified-capability"`
+}
+
+// SessionStart is the <netconf-session-start> event, generated when a
+// session is established
+type SessionStart struct {
+	XMLName xml.Name `xml:"urn:ietf:params:xml:ns:yang:ietf-netconf-notifications netconf-session-start"`
+	SessionParams
+}
+
+// SessionEnd is the <netconf-session-end> event, generated when an
+// established session ends
+type SessionEnd struct {
+	XMLName xml.Name `xml:"urn:ietf:params:xml:ns:yang:ietf-netconf-notifications netconf-session-end"`
+	SessionParams
+	// KilledBy is the session-id of the session terminating the session
+	// with <kill-session>, if its TerminationReason is killed
+	KilledBy uint32 `xml:"killed-by,omitempty"`
+	// TerminationReason is one of the session.Termination* values
+	TerminationReason string `xml:"termination-reason"`
+}
+
+// ConfirmedCommit is the <netconf-confirmed-commit> event, generated
+// at each step of a confirmed commit procedure
+type ConfirmedCommit struct {
+	XMLName xml.Name `xml:"urn:ietf:params:xml:ns:yang:ietf-netconf-notifications netconf-confirmed-commit"`
+	// SessionParams identifies the session, unless the event is the
+	// server's (a ConfirmTimeout)
+	*SessionParams
+	// ConfirmEvent is one of the Confirm* values
+	ConfirmEvent string `xml:"confirm-event"`
+	// Timeout is the confirm timeout in seconds, for ConfirmStart and
+	// ConfirmExtend events
+	Timeout uint32 `xml:"timeout,omitempty"`
+}
+
+// Params returns the SessionParams identifying the server session s,
+// from its Config.Username and Config.SourceHost.
+func Params(s *session.Session) *SessionParams {
+	id := s.State.ID
+	if id == 0 {
+		id = s.Config.ID
+	}
+	return &SessionParams{Username: s.Config.Username, SessionID: id, SourceHost: s.Config.SourceHost}
+}
+
+// changedBy returns the ChangedBy of a change by the session s, or by
+// the server if s is nil
+func changedBy(s *session.Session) ChangedBy {
+	if s == nil {
+		return ChangedBy{Server: &ops.Empty{}}
+	}
+	return ChangedBy{SessionParams: Params(s)}
+}
+
+// Base generates the base notifications (RFC6470) of a server on its
+// NETCONF stream. Session lifecycle events are generated by
+// OnStatusChange, to be used as (or called by) the Config.OnStatusChange
+// of the server's sessions (see SessionConfig). The library does not
+// manage datastores, so servers call the other methods as their
+// datastores, capabilities and confirmed commits change.
+type Base struct {
+	Stream *Stream
+}
+
+// NewBase returns a new Base generating notifications on stream
+func NewBase(stream *Stream) *Base {
+	return &Base{Stream: stream}
+}
+
+// OnStatusChange generates <netconf-session-start> when the session s
+// is established, and <netconf-session-end> when an established session
+// ends, with its termination reason.
+func (b *Base) OnStatusChange(s *session.Session, old, new session.Status) {
+	switch {
+	case new == session.StatusEstablished:
+		b.Stream.Publish(&SessionStart{SessionParams: *Params(s)})
+	case old == session.StatusEstablished && (new == session.StatusClosed || new == session.StatusError):
+		b.Stream.Publish(&SessionEnd{
+			SessionParams:     *Params(s),
+			KilledBy:          s.KilledBy(),
+			TerminationReason: s.TerminationReason(),
+		})
+	}
+}
+
+// SessionConfig returns config with an OnStatusChange hook calling, in
+// turn, config's own hook and the OnStatusChange methods of base and m,
+// either of which may be nil. Server sessions run with the config (see
+// session.Run) generate their <netconf-session-start> and
+// <netconf-session-end> notifications, and end their subscriptions,
+// as they are established and end.
+func SessionConfig(config session.Config, base *Base, m *Manager) session.Config {
+	hooks := []func(*session.Session, session.Status, session.Status){config.OnStatusChange}
+	if base != nil {
+		hooks = append(hooks, base.OnStatusChange)
+	}
+	if m != nil {
+		hooks = append(hooks, m.OnStatusChange)
+	}
+	config.OnStatusChange = session.OnStatusChanges(hooks...)
+	return config
+}
+
+// ConfigChange generates <netconf-config-change> for the commit of the
+// edits to datastore by the session s, or by the server if s is nil.
+func (b *Base) ConfigChange(s *session.Session, datastore string, edits ...Edit) error {
+	return b.Stream.Publish(&ConfigChange{ChangedBy: changedBy(s), Datastore: datastore, Edits: edits})
+}
+
+// CapabilityChange generates <netconf-capability-change> for the change
+// of the server's capabilities by the session s, or by the server if s
+// is nil.
+func (b *Base) CapabilityChange(s *session.Session, added, deleted, modified []string) error {
+	return b.Stream.Publish(&CapabilityChange{ChangedBy: changedBy(s),
+		AddedCapability: added, DeletedCapability: deleted, ModifiedCapability: modified})
+}
+
+// ConfirmedCommit generates <netconf-confirmed-commit> for the confirm
+// event (one of the Confirm* values) of a confirmed commit by the session
+// s, or by the server if s is nil (as for ConfirmTimeout). The timeout is
+// reported for ConfirmStart and ConfirmExtend events.
+func (b *Base) ConfirmedCommit(s *session.Session, event string, timeout time.Duration) error {
+	c := &ConfirmedCommit{ConfirmEvent: event}
+	if s != nil {
+		c.SessionParams = Params(s)
+	}
+	if event == ConfirmStart || event == ConfirmExtend {
+		c.Timeout = uint32(timeout / time.Second)
+	}
+	return b.Stream.Publish(c)
+}
+
+func init() {
+	for name, new := range map[string]func() interface{}{
+		"netconf-config-change":     func() interface{} { return &ConfigChange{} },
+		"netconf-capability-change": func() interface{} { return &CapabilityChange{} },
+		"netconf-session-start":     func() interface{} { return &SessionStart{} },
+		"netconf-session-end":       func() interface{} { return &SessionEnd{} },
+		"netconf-confirmed-commit":  func() interface{} { return &ConfirmedCommit{} },
+	} {
+		ops.RegisterEvent(xml.Name{Space: BaseNS, Local: name}, new)
+	}
+	ops.RegisterEvent(xml.Name{Space: NS, Local: "replayComplete"}, func() interface{} { return &ReplayComplete{} })
+	ops.RegisterEvent(xml.Name{Space: NS, Local: "notificationComplete"}, func() interface{} { return &NotificationComplete{} })
+}
@@ -0,0 +1,51 @@
+/*
+Package notify provides NETCONF event notifications (RFC5277) for
+servers, along with the base notifications of RFC6470.
+
+A Stream delivers the events published on it to its subscriptions, each
+a server session subscribed with Subscribe (e.g., for a
+<create-subscription> request). Events are sent as <notification>
+messages using Session.Send, so they are interleaved with the session's
+replies, but never within one. A stream retaining its latest events
+supports replay, for subscriptions with a start time:
+
+	stream := notify.NewStream(notify.NETCONF, 1000)
+	sub, err := stream.Subscribe(s, notify.Options{StartTime: *req.StartTime})
+
+Base generates the RFC6470 notifications on the server's NETCONF stream.
+Its OnStatusChange method, called by each server session's
+Config.OnStatusChange, generates <netconf-session-start> and
+<netconf-session-end> as sessions run (see session.Run), the latter
+with the session's TerminationReason and KilledBy. SessionConfig wires
+a session's Config to a Base, and to a Manager (see below). The library
+does not manage datastores, so <netconf-config-change>,
+<netconf-capability-change> and <netconf-confirmed-commit> are not
+generated automatically: servers call ConfigChange, CapabilityChange and
+ConfirmedCommit as their datastores are committed:
+
+	base := notify.NewBase(stream)
+	config := notify.SessionConfig(session.Config{ID: id, Username: user}, base, nil)
+	...
+	base.ConfigChange(s, "running", notify.Edit{Target: "/ex:system", TargetNS: ns, Operation: ops.OperationMerge})
+
+A Manager handles the subscription operations of server sessions:
+<create-subscription>, and the dynamic subscriptions (RFC8639 and
+RFC8640) made with <establish-subscription>, which have a
+subscription-id, an optional subtree or XPath stream filter (see
+SubtreeFilter and XPathFilter) and optional replay. They are managed with
+<modify-subscription>, <delete-subscription> and <kill-subscription>,
+and report their state with the <subscription-started>,
+<subscription-modified>, <subscription-terminated>, <replay-completed>
+and <subscription-completed> notifications. Manager.Handle sends each
+reply on the subscription's outgoing message path, ordered with its
+notifications, so the server handles the other operations itself:
+
+	m := notify.NewManager(schema, stream)
+	config := notify.SessionConfig(session.Config{ID: id, Username: user}, base, m)
+	...
+	rpc, err := ops.DecodeRPC(s.Incoming())
+	if handled, err := m.Handle(s, rpc); !handled {
+		// reply to the other operations using s.Send
+	}
+*/
+package notify
@@ -0,0 +1,73 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/andaru/netconf/data"
+	"github.com/andaru/netconf/ops"
+	"github.com/andaru/netconf/xmlutil"
+	"github.com/andaru/netconf/yang"
+)
+
+// SubtreeFilter returns an event filter (see Options) selecting the
+// events matched by the subtree filter content, such as the content of a
+// <filter type="subtree"> or <stream-subtree-filter> element. Events are
+// filtered as data trees using schema, which may be nil. Matching events
+// are delivered whole.
+func SubtreeFilter(content []byte, schema *yang.Context) (func(*Event) bool, error) {
+	if _, err := data.Parse(bytes.NewReader(content), schema); err != nil {
+		return nil, err
+	}
+	return func(ev *Event) bool {
+		root, err := ev.tree(schema)
+		if err != nil {
+			return false
+		}
+		selected, err := data.SubtreeFilter(root, bytes.NewReader(content))
+		return err == nil && len(selected.Children) > 0
+	}, nil
+}
+
+// XPathFilter returns an event filter (see Options) selecting the events
+// for which the XPath expression expr, whose prefixes are declared in
+// ns, is true (e.g., selects a node), evaluated with the root of the
+// event's data tree as the context node. Events are filtered as data
+// trees using schema, which may be nil.
+func XPathFilter(expr string, ns map[string]string, schema *yang.Context) (func(*Event) bool, error) {
+	x, err := data.CompileXPath(strings.TrimSpace(expr), ns)
+	if err != nil {
+		return nil, err
+	}
+	return func(ev *Event) bool {
+		root, err := ev.tree(schema)
+		if err != nil {
+			return false
+		}
+		ok, err := x.Bool(root)
+		return ok && err == nil
+	}, nil
+}
+
+// NewFilter returns the event filter of the <filter> of a
+// <create-subscription> request, using schema, which may be nil.
+func NewFilter(f *ops.Filter, schema *yang.Context) (func(*Event) bool, error) {
+	switch f.Type {
+	case "", "subtree":
+		return SubtreeFilter(f.Content, schema)
+	case "xpath":
+		return XPathFilter(f.Select, xmlutil.NewPrefixMap(f.Attrs...), schema)
+	}
+	return nil, fmt.Errorf("unsupported filter type %q", f.Type)
+}
+
+// tree returns the data tree holding ev's event element
+func (ev *Event) tree(schema *yang.Context) (*data.Node, error) {
+	b, err := xml.Marshal(ev.Event)
+	if err != nil {
+		return nil, err
+	}
+	return data.Parse(bytes.NewReader(b), schema)
+}
@@ -0,0 +1,434 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/andaru/netconf/ops"
+	"github.com/andaru/netconf/session"
+	"github.com/andaru/netconf/yang"
+)
+
+// SubscriptionStarted is the <subscription-started> state notification
+// (RFC8639), sent following the reply to <establish-subscription>
+type SubscriptionStarted struct {
+	XMLName xml.Name `xml:"urn:ietf:params:xml:ns:yang:ietf-subscribed-notifications subscription-started"`
+	ID      uint32   `xml:"id"`
+	Stream  string   `xml:"stream"`
+	ops.StreamFilter
+	ReplayStartTime *time.Time `xml:"replay-start-time,omitempty"`
+	StopTime        *time.Time `xml:"stop-time,omitempty"`
+}
+
+// SubscriptionModified is the <subscription-modified> state notification
+// (RFC8639), sent following the reply to <modify-subscription>
+type SubscriptionModified struct {
+	XMLName xml.Name `xml:"urn:ietf:params:xml:ns:yang:ietf-subscribed-notifications subscription-modified"`
+	ID      uint32   `xml:"id"`
+	Stream  string   `xml:"stream"`
+	ops.StreamFilter
+	ReplayStartTime *time.Time `xml:"replay-start-time,omitempty"`
+	StopTime        *time.Time `xml:"stop-time,omitempty"`
+}
+
+// SubscriptionTerminated is the <subscription-terminated> state
+// notification (RFC8639), sent when a subscription is ended by the
+// server or by <kill-subscription>
+type SubscriptionTerminated struct {
+	XMLName xml.Name `xml:"urn:ietf:params:xml:ns:yang:ietf-subscribed-notifications subscription-terminated"`
+	ID      uint32   `xml:"id"`
+	// Reason is an identity such as ops.SubscriptionErrorNoSuchSubscription
+	Reason ops.Identity `xml:"reason"`
+}
+
+// ReplayCompleted is the <replay-completed> state notification (RFC8639)
+type ReplayCompleted struct {
+	XMLName xml.Name `xml:"urn:ietf:params:xml:ns:yang:ietf-subscribed-notifications replay-completed"`
+	ID      uint32   `xml:"id"`
+}
+
+// SubscriptionCompleted is the <subscription-completed> state
+// notification (RFC8639), sent once a subscription's stop time is reached
+type SubscriptionCompleted struct {
+	XMLName xml.Name `xml:"urn:ietf:params:xml:ns:yang:ietf-subscribed-notifications subscription-completed"`
+	ID      uint32   `xml:"id"`
+}
+
+// Manager manages the subscriptions of a server's sessions to its
+// streams: RFC5277 subscriptions made with <create-subscription>, and
+// the dynamic subscriptions (RFC8639) made with <establish-subscription>
+// and managed with <modify-subscription>, <delete-subscription> and
+// <kill-subscription>. It is safe for concurrent use.
+type Manager struct {
+	schema *yang.Context
+
+	mu      sync.Mutex
+	streams map[string]*Stream
+	lastID  uint32
+	dynamic map[uint32]*dynamic
+	created map[*session.Session]*Subscription
+}
+
+// dynamic is a dynamic subscription
+type dynamic struct {
+	id  uint32
+	sub *Subscription
+}
+
+// NewManager returns a new Manager of subscriptions to streams, whose
+// event filters use schema, which may be nil.
+func NewManager(schema *yang.Context, streams ...*Stream) *Manager {
+	m := &Manager{
+		schema:  schema,
+		streams: map[string]*Stream{},
+		dynamic: map[uint32]*dynamic{},
+		created: map[*session.Session]*Subscription{},
+	}
+	for _, st := range streams {
+		m.streams[st.Name] = st
+	}
+	return m
+}
+
+// Stream returns the stream named name, or nil
+func (m *Manager) Stream(name string) *Stream {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.streams[name]
+}
+
+// Handle handles the rpc received on the server session s, if a
+// subscription operation, returning false otherwise. The reply is sent
+// using s.Send, ahead of the subscription's notifications, such that
+// the <subscription-started> and <subscription-modified> notifications
+// follow their operation's reply, and no notifications follow the reply
+// to <delete-subscription>. Servers must use Send for their other
+// replies. An error is returned if the reply could not be sent.
+func (m *Manager) Handle(s *session.Session, rpc *ops.RPC) (handled bool, err error) {
+	switch op := rpc.Operation.(type) {
+	case *ops.CreateSubscription:
+		err = m.create(s, rpc, op)
+	case *ops.EstablishSubscription:
+		err = m.establish(s, rpc, op)
+	case *ops.ModifySubscription:
+		err = m.modify(s, rpc, op)
+	case *ops.DeleteSubscription:
+		err = m.delete(s, rpc, op)
+	case *ops.KillSubscription:
+		err = m.kill(s, rpc, op)
+	default:
+		return false, nil
+	}
+	if err != nil {
+		rpcErr, ok := err.(*ops.RPCError)
+		if !ok {
+			rpcErr = &ops.RPCError{Type: ops.ErrorTypeApplication, Tag: ops.ErrorTagOperationFailed,
+				Severity: ops.SeverityError, Message: err.Error()}
+		}
+		reply := newReply(rpc)
+		reply.Errors = []ops.RPCError{*rpcErr}
+		err = send(s, reply)
+	}
+	return true, err
+}
+
+// OnStatusChange ends the subscriptions of the session s once it has
+// ended, to be called by the Config.OnStatusChange of server sessions.
+func (m *Manager) OnStatusChange(s *session.Session, old, new session.Status) {
+	if new != session.StatusClosed && new != session.StatusError {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, d := range m.dynamic {
+		if d.sub.s == s {
+			d.sub.Cancel()
+			delete(m.dynamic, id)
+		}
+	}
+	if sub := m.created[s]; sub != nil {
+		sub.Cancel()
+		delete(m.created, s)
+	}
+}
+
+// create handles <create-subscription>, of which a session may have one
+// active at a time
+func (m *Manager) create(s *session.Session, rpc *ops.RPC, op *ops.CreateSubscription) error {
+	name := op.Stream
+	if name == "" {
+		name = NETCONF
+	}
+	st := m.Stream(name)
+	if st == nil {
+		return rpcError(ops.ErrorTagInvalidValue, "stream", fmt.Sprintf("no such stream %q", name))
+	}
+	var opts Options
+	if op.StartTime != nil {
+		opts.StartTime = *op.StartTime
+	}
+	if op.StopTime != nil {
+		opts.StopTime = *op.StopTime
+	}
+	if op.Filter != nil {
+		filter, err := NewFilter(op.Filter, m.schema)
+		if err != nil {
+			return rpcError(ops.ErrorTagInvalidValue, "filter", err.Error())
+		}
+		opts.Filter = filter
+	}
+	if err := st.check(opts); err != nil {
+		return err
+	}
+	reply := newReply(rpc)
+	reply.OK = &ops.Empty{}
+	msg, err := message(reply)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if sub := m.created[s]; sub != nil && !ended(sub) {
+		return &ops.RPCError{Type: ops.ErrorTypeProtocol, Tag: ops.ErrorTagOperationFailed, Severity: ops.SeverityError,
+			Message: "a subscription is already active on the session"}
+	}
+	m.created[s] = st.subscribe(s, opts, notices{}, msg)
+	return nil
+}
+
+// establish handles <establish-subscription>
+func (m *Manager) establish(s *session.Session, rpc *ops.RPC, op *ops.EstablishSubscription) error {
+	st := m.Stream(op.Stream)
+	if st == nil {
+		return subscriptionError(ops.SubscriptionErrorStreamUnavailable, "stream", fmt.Sprintf("no such stream %q", op.Stream))
+	}
+	if op.Encoding != nil && *op.Encoding != ops.EncodeXML {
+		return subscriptionError(ops.SubscriptionErrorEncodingUnsupported, "encoding", fmt.Sprintf("unsupported encoding %q", op.Encoding.Local))
+	}
+	var opts Options
+	var err error
+	if opts.Filter, err = m.filter(op.StreamFilter); err != nil {
+		return err
+	}
+	now := time.Now()
+	if t := op.ReplayStartTime; t != nil {
+		switch {
+		case !st.Replay():
+			return subscriptionError(ops.SubscriptionErrorReplayUnsupported, "replay-start-time", "replay is not supported by stream "+st.Name)
+		case t.After(now):
+			return rpcError(ops.ErrorTagInvalidValue, "replay-start-time", "replay start time is in the future")
+		}
+		opts.StartTime = *t
+	}
+	if t := op.StopTime; t != nil {
+		if t.Before(now) && op.ReplayStartTime == nil || t.Before(opts.StartTime) {
+			return rpcError(ops.ErrorTagInvalidValue, "stop-time", "stop time has passed")
+		}
+		opts.StopTime = *t
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lastID++
+	id := m.lastID
+	reply := newReply(rpc)
+	reply.Output = []ops.Inline{{XMLName: xml.Name{Space: ops.SubscriptionsNS, Local: "id"}, Content: []byte(strconv.FormatUint(uint64(id), 10))}}
+	if revision, ok := st.replayStart(opts.StartTime); ok && op.ReplayStartTime != nil {
+		reply.Output = append(reply.Output, ops.Inline{XMLName: xml.Name{Space: ops.SubscriptionsNS, Local: "replay-start-time-revision"},
+			Content: []byte(revision.Format(time.RFC3339Nano))})
+	}
+	msg, err := message(reply)
+	if err != nil {
+		return err
+	}
+	started := notice(&SubscriptionStarted{ID: id, Stream: st.Name, StreamFilter: op.StreamFilter,
+		ReplayStartTime: op.ReplayStartTime, StopTime: op.StopTime})
+	m.dynamic[id] = &dynamic{id: id, sub: st.subscribe(s, opts, notices{
+		replayComplete: func() interface{} { return &ReplayCompleted{ID: id} },
+		complete:       func() interface{} { return &SubscriptionCompleted{ID: id} },
+	}, msg, started)}
+	return nil
+}
+
+// modify handles <modify-subscription>, replacing the filter and stop
+// time of the subscription if given
+func (m *Manager) modify(s *session.Session, rpc *ops.RPC, op *ops.ModifySubscription) error {
+	d := m.lookup(s, op.ID)
+	if d == nil {
+		return noSuchSubscription(op.ID)
+	}
+	filter, err := m.filter(op.StreamFilter)
+	if err != nil {
+		return err
+	}
+	if t := op.StopTime; t != nil && t.Before(time.Now()) {
+		return rpcError(ops.ErrorTagInvalidValue, "stop-time", "stop time has passed")
+	}
+	reply := newReply(rpc)
+	reply.OK = &ops.Empty{}
+	msg, err := message(reply)
+	if err != nil {
+		return err
+	}
+	sub := d.sub
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	if op.StreamFilter != (ops.StreamFilter{}) {
+		sub.opts.Filter = filter
+	}
+	if op.StopTime != nil {
+		sub.setStopTime(*op.StopTime)
+	}
+	modified := &SubscriptionModified{ID: d.id, Stream: sub.stream.Name, StreamFilter: op.StreamFilter, StopTime: op.StopTime}
+	if !sub.opts.StartTime.IsZero() {
+		modified.ReplayStartTime = &sub.opts.StartTime
+	}
+	sub.enqueueLocked(msg, notice(modified))
+	return nil
+}
+
+// delete handles <delete-subscription>, ending a subscription of the
+// session once its reply has been sent
+func (m *Manager) delete(s *session.Session, rpc *ops.RPC, op *ops.DeleteSubscription) error {
+	d := m.lookup(s, op.ID)
+	if d == nil {
+		return noSuchSubscription(op.ID)
+	}
+	reply := newReply(rpc)
+	reply.OK = &ops.Empty{}
+	msg, err := message(reply)
+	if err != nil {
+		return err
+	}
+	m.remove(d)
+	if !d.sub.stop(msg, true) {
+		return noSuchSubscription(op.ID)
+	}
+	return nil
+}
+
+// kill handles <kill-subscription>, ending a subscription of any session
+// with a <subscription-terminated> notification
+func (m *Manager) kill(s *session.Session, rpc *ops.RPC, op *ops.KillSubscription) error {
+	d := m.lookup(nil, op.ID)
+	if d == nil {
+		return noSuchSubscription(op.ID)
+	}
+	m.remove(d)
+	terminated := &SubscriptionTerminated{ID: d.id, Reason: ops.Identity{Space: ops.SubscriptionsNS, Local: "no-such-subscription"}}
+	if !d.sub.stop(notice(terminated), true) {
+		return noSuchSubscription(op.ID)
+	}
+	reply := newReply(rpc)
+	reply.OK = &ops.Empty{}
+	return send(s, reply)
+}
+
+// lookup returns the active dynamic subscription id of the session s,
+// or of any session if s is nil
+func (m *Manager) lookup(s *session.Session, id uint32) *dynamic {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	d := m.dynamic[id]
+	switch {
+	case d == nil:
+		return nil
+	case ended(d.sub):
+		delete(m.dynamic, id)
+		return nil
+	case s != nil && d.sub.s != s:
+		return nil
+	}
+	return d
+}
+
+// remove removes the dynamic subscription d
+func (m *Manager) remove(d *dynamic) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.dynamic[d.id] == d {
+		delete(m.dynamic, d.id)
+	}
+}
+
+// filter returns the event filter of f, or nil if f is empty. Named
+// filters are not supported.
+func (m *Manager) filter(f ops.StreamFilter) (func(*Event) bool, error) {
+	switch {
+	case f.StreamFilterName != "":
+		return nil, subscriptionError(ops.SubscriptionErrorFilterUnavailable, "stream-filter-name",
+			fmt.Sprintf("no such filter %q", f.StreamFilterName))
+	case f.StreamSubtreeFilter != nil:
+		filter, err := SubtreeFilter(f.StreamSubtreeFilter.Content, m.schema)
+		if err != nil {
+			return nil, subscriptionError(ops.SubscriptionErrorFilterUnsupported, "stream-subtree-filter", err.Error())
+		}
+		return filter, nil
+	case f.StreamXPathFilter != nil:
+		filter, err := XPathFilter(f.StreamXPathFilter.Select, f.StreamXPathFilter.Namespaces(), m.schema)
+		if err != nil {
+			return nil, subscriptionError(ops.SubscriptionErrorFilterUnsupported, "stream-xpath-filter", err.Error())
+		}
+		return filter, nil
+	}
+	return nil, nil
+}
+
+// ended returns true if sub has ended, or is stopping
+func ended(sub *Subscription) bool {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	return sub.stopped || sub.ended
+}
+
+// newReply returns a reply to rpc, with its attributes
+func newReply(rpc *ops.RPC) *ops.RPCReply {
+	return &ops.RPCReply{MessageID: rpc.MessageID, Attrs: rpc.Attrs}
+}
+
+// message returns the message of reply, to be queued for delivery
+func message(reply *ops.RPCReply) (*Event, error) {
+	b, err := xml.Marshal(reply)
+	if err != nil {
+		return nil, err
+	}
+	return &Event{Message: b}, nil
+}
+
+// send sends reply to the session s
+func send(s *session.Session, reply *ops.RPCReply) error {
+	b, err := xml.Marshal(reply)
+	if err != nil {
+		return err
+	}
+	return s.Send(context.Background(), bytes.NewReader(b))
+}
+
+// subscriptionError returns an invalid-value error with the error-app-tag
+// of an RFC8639 error identity, such as ops.SubscriptionErrorReplayUnsupported
+func subscriptionError(appTag, element, message string) *ops.RPCError {
+	err := rpcError(ops.ErrorTagInvalidValue, element, message)
+	err.AppTag = appTag
+	return err
+}
+
+func noSuchSubscription(id uint32) *ops.RPCError {
+	return subscriptionError(ops.SubscriptionErrorNoSuchSubscription, "id", fmt.Sprintf("no such subscription %d", id))
+}
+
+func init() {
+	for name, new := range map[string]func() interface{}{
+		"subscription-started":    func() interface{} { return &SubscriptionStarted{} },
+		"subscription-modified":   func() interface{} { return &SubscriptionModified{} },
+		"subscription-terminated": func() interface{} { return &SubscriptionTerminated{} },
+		"replay-completed":        func() interface{} { return &ReplayCompleted{} },
+		"subscription-completed":  func() interface{} { return &SubscriptionCompleted{} },
+	} {
+		ops.RegisterEvent(xml.Name{Space: ops.SubscriptionsNS, Local: name}, new)
+	}
+}
@@ -0,0 +1,211 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andaru/netconf/netconftest"
+	"github.com/andaru/netconf/ops"
+	"github.com/andaru/netconf/session"
+	"github.com/stretchr/testify/assert"
+)
+
+// request sends the operation op from the client of p, handling it with
+// m on the server
+func request(t *testing.T, m *Manager, p *netconftest.Pair, op string) {
+	t.Helper()
+	w := p.Client.Outgoing()
+	io.WriteString(w, `<rpc message-id="1" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">`+op+`</rpc>`)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	rpc, err := ops.DecodeRPC(p.Server.Incoming())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if handled, err := m.Handle(p.Server, rpc); !handled || err != nil {
+		t.Fatal("request not handled", err)
+	}
+}
+
+// recvReply returns the next message received by s, an <rpc-reply>
+func recvReply(t *testing.T, s *session.Session) *ops.RPCReply {
+	t.Helper()
+	b, err := io.ReadAll(s.Incoming())
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply, err := ops.DecodeReply(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err, string(b))
+	}
+	return reply
+}
+
+func alarm(severity string) *ops.Inline {
+	return &ops.Inline{XMLName: xml.Name{Space: "urn:example", Local: "alarm"}, Content: []byte("<severity>" + severity + "</severity>")}
+}
+
+func severity(t *testing.T, event interface{}) string {
+	t.Helper()
+	if ev, ok := event.(*ops.Inline); ok {
+		return strings.TrimSuffix(strings.TrimPrefix(string(ev.Content), "<severity>"), "</severity>")
+	}
+	t.Fatalf("unexpected event %#v", event)
+	return ""
+}
+
+const sn = `xmlns="urn:ietf:params:xml:ns:yang:ietf-subscribed-notifications"`
+
+func TestManager(t *testing.T) {
+	a := assert.New(t)
+	example, live := NewStream("example", 16), NewStream("live", 0)
+	m := NewManager(nil, NewStream(NETCONF, 16), example, live)
+	p := netconftest.NewPair(session.Config{}, session.Config{ID: 1, OnStatusChange: m.OnStatusChange})
+	q := netconftest.NewPair(session.Config{}, session.Config{ID: 2, OnStatusChange: m.OnStatusChange})
+	for _, pair := range []*netconftest.Pair{p, q} {
+		if !a.NoError(pair.Handshake()) {
+			return
+		}
+	}
+	a.NoError(example.Publish(alarm("major")))
+	a.NoError(example.Publish(alarm("minor")))
+
+	// the reply precedes the started notification and replay
+	start := time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+	request(t, m, p, `<establish-subscription `+sn+`><stream>example</stream>`+
+		`<stream-xpath-filter xmlns:ex="urn:example">/ex:alarm[ex:severity='major']</stream-xpath-filter>`+
+		`<replay-start-time>`+start+`</replay-start-time></establish-subscription>`)
+	reply := recvReply(t, p.Client)
+	a.Equal([]ops.Inline{{XMLName: xml.Name{Space: ops.SubscriptionsNS, Local: "id"}, Content: []byte("1")}}, reply.Output)
+	if ev, ok := recv(t, p.Client).(*SubscriptionStarted); a.True(ok) {
+		a.Equal(uint32(1), ev.ID)
+		a.Equal("example", ev.Stream)
+		if a.NotNil(ev.StreamXPathFilter) {
+			a.Equal("/ex:alarm[ex:severity='major']", ev.StreamXPathFilter.Select)
+		}
+	}
+	a.Equal("major", severity(t, recv(t, p.Client)))
+	a.Equal(&ReplayCompleted{XMLName: xml.Name{Space: ops.SubscriptionsNS, Local: "replay-completed"}, ID: 1}, recv(t, p.Client))
+	a.NoError(example.Publish(alarm("minor")))
+	a.NoError(example.Publish(alarm("critical")))
+	a.NoError(example.Publish(alarm("major")))
+	a.Equal("major", severity(t, recv(t, p.Client)))
+
+	// the filter is replaced
+	request(t, m, p, `<modify-subscription `+sn+`><id>1</id>`+
+		`<stream-subtree-filter><alarm xmlns="urn:example"><severity>minor</severity></alarm></stream-subtree-filter></modify-subscription>`)
+	a.NotNil(recvReply(t, p.Client).OK)
+	if ev, ok := recv(t, p.Client).(*SubscriptionModified); a.True(ok) {
+		a.Equal(uint32(1), ev.ID)
+		a.NotNil(ev.StreamSubtreeFilter)
+	}
+	a.NoError(example.Publish(alarm("major")))
+	a.NoError(example.Publish(alarm("minor")))
+	a.Equal("minor", severity(t, recv(t, p.Client)))
+
+	// another session may kill, but not delete or modify, the subscription
+	request(t, m, q, `<delete-subscription `+sn+`><id>1</id></delete-subscription>`)
+	a.EqualError(recvReply(t, q.Client).Err(), "rpc-error: protocol: invalid-value: no such subscription 1")
+	request(t, m, q, `<modify-subscription `+sn+`><id>1</id></modify-subscription>`)
+	if err, ok := recvReply(t, q.Client).Err().(*ops.RPCError); a.True(ok) {
+		a.Equal(ops.SubscriptionErrorNoSuchSubscription, err.AppTag)
+	}
+	request(t, m, q, `<kill-subscription `+sn+`><id>1</id></kill-subscription>`)
+	a.NotNil(recvReply(t, q.Client).OK)
+	a.Equal(&SubscriptionTerminated{XMLName: xml.Name{Space: ops.SubscriptionsNS, Local: "subscription-terminated"}, ID: 1,
+		Reason: ops.Identity{Space: ops.SubscriptionsNS, Local: "no-such-subscription"}}, recv(t, p.Client))
+
+	// no notifications follow the reply to delete-subscription
+	request(t, m, p, `<establish-subscription `+sn+`><stream>example</stream></establish-subscription>`)
+	a.Equal("2", string(recvReply(t, p.Client).Output[0].Content))
+	a.IsType(&SubscriptionStarted{}, recv(t, p.Client))
+	sub := m.lookup(p.Server, 2).sub
+	request(t, m, p, `<delete-subscription `+sn+`><id>2</id></delete-subscription>`)
+	a.NotNil(recvReply(t, p.Client).OK)
+	<-sub.Done()
+	a.NoError(example.Publish(alarm("minor")))
+	request(t, m, p, `<delete-subscription `+sn+`><id>2</id></delete-subscription>`)
+	a.NotNil(recvReply(t, p.Client).Err())
+
+	for _, tc := range []struct {
+		op, appTag string
+	}{
+		{op: `<stream>nope</stream>`, appTag: ops.SubscriptionErrorStreamUnavailable},
+		{op: `<stream>live</stream><replay-start-time>` + start + `</replay-start-time>`, appTag: ops.SubscriptionErrorReplayUnsupported},
+		{op: `<stream>live</stream><stream-xpath-filter>/a[</stream-xpath-filter>`, appTag: ops.SubscriptionErrorFilterUnsupported},
+		{op: `<stream>live</stream><stream-filter-name>f</stream-filter-name>`, appTag: ops.SubscriptionErrorFilterUnavailable},
+		{op: `<stream>live</stream><encoding>sn:encode-json</encoding>`, appTag: ops.SubscriptionErrorEncodingUnsupported},
+	} {
+		request(t, m, p, `<establish-subscription `+sn+`>`+tc.op+`</establish-subscription>`)
+		if err, ok := recvReply(t, p.Client).Err().(*ops.RPCError); a.True(ok, tc.op) {
+			a.Equal(tc.appTag, err.AppTag, tc.op)
+		}
+	}
+
+	// subscriptions end with their session
+	request(t, m, q, `<establish-subscription `+sn+`><stream>live</stream></establish-subscription>`)
+	a.Equal("3", string(recvReply(t, q.Client).Output[0].Content))
+	a.IsType(&SubscriptionStarted{}, recv(t, q.Client))
+	sub = m.lookup(q.Server, 3).sub
+	a.NoError(q.Server.SetStatus(session.StatusClosed))
+	<-sub.Done()
+	a.Nil(m.lookup(nil, 3))
+}
+
+func TestCreateSubscription(t *testing.T) {
+	a := assert.New(t)
+	m := NewManager(nil, NewStream(NETCONF, 16))
+	p := netconftest.NewPair(session.Config{}, session.Config{})
+	if !a.NoError(p.Handshake()) {
+		return
+	}
+	request(t, m, p, `<create-subscription xmlns="urn:ietf:params:xml:ns:netconf:notification:1.0">`+
+		`<filter type="subtree"><alarm xmlns="urn:example"/></filter></create-subscription>`)
+	a.NotNil(recvReply(t, p.Client).OK)
+	request(t, m, p, `<create-subscription xmlns="urn:ietf:params:xml:ns:netconf:notification:1.0"/>`)
+	a.EqualError(recvReply(t, p.Client).Err(), "rpc-error: protocol: operation-failed: a subscription is already active on the session")
+
+	st := m.Stream(NETCONF)
+	a.NoError(st.Publish(&ops.Inline{XMLName: xml.Name{Space: "urn:example", Local: "other"}}))
+	a.NoError(st.Publish(alarm("major")))
+	a.Equal("major", severity(t, recv(t, p.Client)))
+}
+
+func TestSessionConfig(t *testing.T) {
+	a := assert.New(t)
+	stream := NewStream(NETCONF, 16)
+	base, m := NewBase(stream), NewManager(nil, stream)
+	var changes int
+	config := SessionConfig(session.Config{ID: 1, OnStatusChange: func(*session.Session, session.Status, session.Status) { changes++ }}, base, m)
+	p := netconftest.NewPair(session.Config{}, config)
+	if !a.NoError(p.Handshake()) {
+		return
+	}
+	request(t, m, p, `<establish-subscription `+sn+`><stream>NETCONF</stream></establish-subscription>`)
+	a.Equal("1", string(recvReply(t, p.Client).Output[0].Content))
+	a.IsType(&SubscriptionStarted{}, recv(t, p.Client))
+
+	// base notifications are generated, and subscriptions end, as sessions run
+	config.ID = 2
+	q := netconftest.NewPair(session.Config{}, config)
+	if !a.NoError(q.Handshake()) {
+		return
+	}
+	if ev, ok := recv(t, p.Client).(*SessionStart); a.True(ok) {
+		a.Equal(uint32(2), ev.SessionID)
+	}
+	a.NoError(q.Server.SetStatus(session.StatusClosed))
+	if ev, ok := recv(t, p.Client).(*SessionEnd); a.True(ok) {
+		a.Equal(uint32(2), ev.SessionID)
+	}
+	sub := m.lookup(p.Server, 1).sub
+	a.NoError(p.Server.SetStatus(session.StatusClosed))
+	<-sub.Done()
+	a.Nil(m.lookup(nil, 1))
+	a.Equal(6, changes)
+}
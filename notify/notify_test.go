@@ -0,0 +1,149 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/andaru/netconf/netconftest"
+	"github.com/andaru/netconf/ops"
+	"github.com/andaru/netconf/session"
+	"github.com/stretchr/testify/assert"
+)
+
+// recv returns the event of the next notification received by s
+func recv(t *testing.T, s *session.Session) interface{} {
+	t.Helper()
+	b, err := io.ReadAll(s.Incoming())
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := ops.DecodeNotification(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n.Event
+}
+
+func newPair(t *testing.T, id uint32, base *Base) *netconftest.Pair {
+	t.Helper()
+	p := netconftest.NewPair(session.Config{},
+		session.Config{ID: id, Username: "alice", SourceHost: "192.0.2.1", OnStatusChange: base.OnStatusChange})
+	if err := p.Handshake(); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestBase(t *testing.T) {
+	a := assert.New(t)
+	base := NewBase(NewStream(NETCONF, 16))
+	p := newPair(t, 1, base)
+	sub, err := base.Stream.Subscribe(p.Server, Options{StartTime: time.Now().Add(-time.Minute)})
+	if !a.NoError(err) {
+		return
+	}
+	defer sub.Cancel()
+	alice := SessionParams{Username: "alice", SessionID: 1, SourceHost: "192.0.2.1"}
+
+	// the session's start is replayed
+	a.Equal(&SessionStart{XMLName: xml.Name{Space: BaseNS, Local: "netconf-session-start"}, SessionParams: alice}, recv(t, p.Client))
+	a.IsType(&ReplayComplete{}, recv(t, p.Client))
+
+	a.NoError(base.ConfigChange(p.Server, "running",
+		Edit{Target: "/ex:system/ex:hostname", TargetNS: map[string]string{"ex": "urn:example"}, Operation: ops.OperationMerge}))
+	a.Equal(&ConfigChange{
+		XMLName:   xml.Name{Space: BaseNS, Local: "netconf-config-change"},
+		ChangedBy: ChangedBy{SessionParams: &alice},
+		Datastore: "running",
+		Edits:     []Edit{{Target: "/ex:system/ex:hostname", TargetNS: map[string]string{"ex": "urn:example"}, Operation: "merge"}},
+	}, recv(t, p.Client))
+
+	a.NoError(base.CapabilityChange(nil, []string{"urn:example:cap"}, nil, nil))
+	if ev, ok := recv(t, p.Client).(*CapabilityChange); a.True(ok) {
+		a.NotNil(ev.ChangedBy.Server)
+		a.Equal([]string{"urn:example:cap"}, ev.AddedCapability)
+	}
+
+	a.NoError(base.ConfirmedCommit(p.Server, ConfirmStart, 10*time.Minute))
+	a.Equal(&ConfirmedCommit{XMLName: xml.Name{Space: BaseNS, Local: "netconf-confirmed-commit"},
+		SessionParams: &alice, ConfirmEvent: ConfirmStart, Timeout: 600}, recv(t, p.Client))
+	a.NoError(base.ConfirmedCommit(nil, ConfirmTimeout, 0))
+	a.Equal(&ConfirmedCommit{XMLName: xml.Name{Space: BaseNS, Local: "netconf-confirmed-commit"},
+		ConfirmEvent: ConfirmTimeout}, recv(t, p.Client))
+
+	// another session starts, then is killed by the first
+	q := newPair(t, 2, base)
+	if ev, ok := recv(t, p.Client).(*SessionStart); a.True(ok) {
+		a.Equal(uint32(2), ev.SessionID)
+	}
+	q.Server.Kill(1)
+	for range q.Server.Messages(context.Background()) {
+	}
+	a.Equal(&SessionEnd{
+		XMLName:           xml.Name{Space: BaseNS, Local: "netconf-session-end"},
+		SessionParams:     SessionParams{Username: "alice", SessionID: 2, SourceHost: "192.0.2.1"},
+		KilledBy:          1,
+		TerminationReason: session.TerminationKilled,
+	}, recv(t, p.Client))
+}
+
+func TestEventXML(t *testing.T) {
+	a := assert.New(t)
+	ev, err := NewEvent(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), &SessionEnd{
+		SessionParams:     SessionParams{Username: "bob", SessionID: 3},
+		TerminationReason: session.TerminationDropped,
+	})
+	if !a.NoError(err) {
+		return
+	}
+	a.Equal(xml.Name{Space: BaseNS, Local: "netconf-session-end"}, ev.Name)
+	a.Equal(`<notification xmlns="urn:ietf:params:xml:ns:netconf:notification:1.0"><eventTime>2024-01-02T03:04:05Z</eventTime>`+
+		`<netconf-session-end xmlns="urn:ietf:params:xml:ns:yang:ietf-netconf-notifications"><username>bob</username><session-id>3</session-id>`+
+		`<termination-reason>dropped</termination-reason></netconf-session-end></notification>`, string(ev.Message))
+
+	b, err := xml.Marshal(&Edit{Target: "/ex:a", TargetNS: map[string]string{"ex": "urn:example"}})
+	a.NoError(err)
+	a.Equal(`<Edit><target xmlns:ex="urn:example">/ex:a</target></Edit>`, string(b))
+}
+
+func TestSubscribe(t *testing.T) {
+	a := assert.New(t)
+	st := NewStream("example", 0)
+	p := netconftest.NewPair(session.Config{}, session.Config{})
+	if !a.NoError(p.Handshake()) {
+		return
+	}
+	now := time.Now()
+	_, err := st.Subscribe(p.Server, Options{StartTime: now.Add(-time.Minute)})
+	a.EqualError(err, "rpc-error: protocol: bad-element: replay is not supported by stream example")
+
+	st = NewStream("example", 2)
+	_, err = st.Subscribe(p.Server, Options{StopTime: now})
+	a.EqualError(err, "rpc-error: protocol: missing-element: stop time requires a start time")
+	_, err = st.Subscribe(p.Server, Options{StartTime: now.Add(-time.Minute), StopTime: now.Add(-time.Hour)})
+	a.EqualError(err, "rpc-error: protocol: bad-element: stop time is before the start time")
+
+	// the replay buffer retains the latest events
+	for _, name := range []string{"a", "b", "c"} {
+		a.NoError(st.Publish(&ops.Inline{XMLName: xml.Name{Space: "urn:example", Local: name}}))
+	}
+	sub, err := st.Subscribe(p.Server, Options{
+		StartTime: now.Add(-time.Minute),
+		StopTime:  time.Now().Add(50 * time.Millisecond),
+		Filter:    func(ev *Event) bool { return ev.Name.Local != "c" },
+	})
+	if !a.NoError(err) {
+		return
+	}
+	if ev, ok := recv(t, p.Client).(*ops.Inline); a.True(ok) {
+		a.Equal(xml.Name{Space: "urn:example", Local: "b"}, ev.XMLName)
+	}
+	a.IsType(&ReplayComplete{}, recv(t, p.Client))
+	a.IsType(&NotificationComplete{}, recv(t, p.Client))
+	<-sub.Done()
+	a.NoError(sub.Err())
+}
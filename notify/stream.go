@@ -0,0 +1,351 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"sync"
+	"time"
+
+	"github.com/andaru/netconf/ops"
+	"github.com/andaru/netconf/session"
+)
+
+// NETCONF is the name of the default event stream (RFC5277), carrying
+// the base notifications of RFC6470
+const NETCONF = "NETCONF"
+
+// NS is the namespace of the RFC5277 replayComplete and
+// notificationComplete notifications
+const NS = "urn:ietf:params:xml:ns:netmod:notification"
+
+// ReplayComplete is the <replayComplete> notification, sent once the
+// replay of a subscription with a start time has completed
+type ReplayComplete struct {
+	XMLName xml.Name `xml:"urn:ietf:params:xml:ns:netmod:notification replayComplete"`
+}
+
+// NotificationComplete is the <notificationComplete> notification, sent
+// once a subscription's stop time has been reached
+type NotificationComplete struct {
+	XMLName xml.Name `xml:"urn:ietf:params:xml:ns:netmod:notification notificationComplete"`
+}
+
+// Event is a notification published on a Stream
+type Event struct {
+	*ops.Notification
+	// Name is the event's element name
+	Name xml.Name
+	// Message is the encoded <notification> message
+	Message []byte
+}
+
+// NewEvent returns the Event of the notification of event at time t
+func NewEvent(t time.Time, event interface{}) (*Event, error) {
+	n := &ops.Notification{EventTime: t, Event: event}
+	b, err := xml.Marshal(n)
+	if err != nil {
+		return nil, err
+	}
+	return &Event{Notification: n, Name: n.Name(), Message: b}, nil
+}
+
+// Stream is an event stream, delivering the events published on it to
+// its subscriptions. A stream may retain its latest events for replay to
+// subscriptions with a start time. It is safe for concurrent use.
+type Stream struct {
+	// Name is the stream's name, such as NETCONF
+	Name string
+
+	mu     sync.Mutex
+	replay int
+	events []*Event
+	subs   map[*Subscription]bool
+}
+
+// NewStream returns a new Stream, retaining up to replay of its latest
+// events for replay. Replay is not supported by streams with no events
+// retained.
+func NewStream(name string, replay int) *Stream {
+	return &Stream{Name: name, replay: replay, subs: map[*Subscription]bool{}}
+}
+
+// Replay returns true if the stream supports replay
+func (st *Stream) Replay() bool { return st.replay > 0 }
+
+// Publish publishes the notification of event, timestamped now, to the
+// stream's subscriptions.
+func (st *Stream) Publish(event interface{}) error {
+	ev, err := NewEvent(time.Now(), event)
+	if err != nil {
+		return err
+	}
+	st.PublishEvent(ev)
+	return nil
+}
+
+// PublishEvent publishes ev to the stream's subscriptions
+func (st *Stream) PublishEvent(ev *Event) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.replay > 0 {
+		if len(st.events) == st.replay {
+			st.events = append(st.events[:0], st.events[1:]...)
+		}
+		st.events = append(st.events, ev)
+	}
+	for sub := range st.subs {
+		sub.push(ev)
+	}
+}
+
+// Options are the options of a subscription to a Stream
+type Options struct {
+	// StartTime, if non-zero, requests the replay of the retained events
+	// since the start time, followed by a <replayComplete> notification
+	StartTime time.Time
+	// StopTime, if non-zero, ends the subscription with a
+	// <notificationComplete> notification once reached
+	StopTime time.Time
+	// Filter, if non-nil, selects the events delivered
+	Filter func(*Event) bool
+}
+
+// Subscribe subscribes the session s to the stream's events, which are
+// sent to the peer as <notification> messages using s.Send, interleaved
+// with the session's other outgoing messages.
+//
+// Invalid options are reported as an *ops.RPCError: a start time for a
+// stream without replay, a start time in the future or a stop time
+// without a start time or before it.
+func (st *Stream) Subscribe(s *session.Session, opts Options) (*Subscription, error) {
+	if err := st.check(opts); err != nil {
+		return nil, err
+	}
+	return st.subscribe(s, opts, notices{}), nil
+}
+
+// check checks the options of an RFC5277 subscription
+func (st *Stream) check(opts Options) error {
+	switch {
+	case !opts.StartTime.IsZero() && !st.Replay():
+		return rpcError(ops.ErrorTagBadElement, "startTime", "replay is not supported by stream "+st.Name)
+	case opts.StartTime.After(time.Now()):
+		return rpcError(ops.ErrorTagBadElement, "startTime", "start time is in the future")
+	case !opts.StopTime.IsZero() && opts.StartTime.IsZero():
+		return rpcError(ops.ErrorTagMissingElement, "startTime", "stop time requires a start time")
+	case !opts.StopTime.IsZero() && opts.StopTime.Before(opts.StartTime):
+		return rpcError(ops.ErrorTagBadElement, "stopTime", "stop time is before the start time")
+	}
+	return nil
+}
+
+// notices returns the events sent by a subscription once its replay has
+// completed and once its stop time is reached, being the RFC5277
+// <replayComplete> and <notificationComplete> if nil
+type notices struct {
+	replayComplete, complete func() interface{}
+}
+
+// subscribe subscribes s to the stream without checking opts, first
+// sending the messages pre
+func (st *Stream) subscribe(s *session.Session, opts Options, n notices, pre ...*Event) *Subscription {
+	if n.replayComplete == nil {
+		n.replayComplete = func() interface{} { return &ReplayComplete{} }
+	}
+	if n.complete == nil {
+		n.complete = func() interface{} { return &NotificationComplete{} }
+	}
+	sub := &Subscription{stream: st, s: s, opts: opts, complete: n.complete, done: make(chan struct{})}
+	sub.cond = sync.NewCond(&sub.mu)
+	sub.enqueue(pre...)
+	st.mu.Lock()
+	if !opts.StartTime.IsZero() {
+		for _, ev := range st.events {
+			if !ev.EventTime.Before(opts.StartTime) {
+				sub.push(ev)
+			}
+		}
+		sub.enqueue(notice(n.replayComplete()))
+	}
+	st.subs[sub] = true
+	st.mu.Unlock()
+	sub.mu.Lock()
+	sub.setStopTime(opts.StopTime)
+	sub.mu.Unlock()
+	go sub.run()
+	return sub
+}
+
+// replayStart returns the time of the earliest event retained for
+// replay since t, if any event since t is no longer retained
+func (st *Stream) replayStart(t time.Time) (time.Time, bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if len(st.events) < st.replay || len(st.events) == 0 || !st.events[0].EventTime.After(t) {
+		return time.Time{}, false
+	}
+	return st.events[0].EventTime, true
+}
+
+// unsubscribe removes sub from the stream
+func (st *Stream) unsubscribe(sub *Subscription) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	delete(st.subs, sub)
+}
+
+// Subscription is a session's subscription to a Stream
+type Subscription struct {
+	stream   *Stream
+	s        *session.Session
+	complete func() interface{}
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	opts    Options
+	timer   *time.Timer
+	queue   []*Event
+	final   *Event // sent once the queue is delivered, after stopping
+	stopped bool
+	ended   bool
+	err     error
+	done    chan struct{}
+}
+
+// Stream returns the subscription's stream
+func (sub *Subscription) Stream() *Stream { return sub.stream }
+
+// Session returns the subscribed session
+func (sub *Subscription) Session() *session.Session { return sub.s }
+
+// Cancel ends the subscription, without sending the events queued for
+// delivery.
+func (sub *Subscription) Cancel() {
+	sub.mu.Lock()
+	sub.ended = true
+	sub.mu.Unlock()
+	sub.cond.Signal()
+}
+
+// Done returns a channel closed once the subscription has ended, either
+// when cancelled, after its stop time or after failing to send to the
+// session.
+func (sub *Subscription) Done() <-chan struct{} { return sub.done }
+
+// Err returns the error ending the subscription, if sending to the
+// session failed.
+func (sub *Subscription) Err() error {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	return sub.err
+}
+
+// push queues ev for delivery, if selected by the filter and not after
+// the stop time
+func (sub *Subscription) push(ev *Event) {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	if sub.opts.Filter != nil && !sub.opts.Filter(ev) {
+		return
+	}
+	if stop := sub.opts.StopTime; !stop.IsZero() && ev.EventTime.After(stop) {
+		return
+	}
+	sub.enqueueLocked(ev)
+}
+
+// enqueue queues the messages evs for delivery, unfiltered
+func (sub *Subscription) enqueue(evs ...*Event) {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	sub.enqueueLocked(evs...)
+}
+
+func (sub *Subscription) enqueueLocked(evs ...*Event) {
+	if sub.stopped || sub.ended {
+		return
+	}
+	sub.queue = append(sub.queue, evs...)
+	sub.cond.Signal()
+}
+
+// stop stops the subscription, sending final once the events queued
+// have been delivered, or discarding them if drop is true. Returns false
+// if the subscription had already stopped.
+func (sub *Subscription) stop(final *Event, drop bool) bool {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	if sub.stopped || sub.ended {
+		return false
+	}
+	sub.stopped, sub.final = true, final
+	if drop {
+		sub.queue = nil
+	}
+	sub.cond.Signal()
+	return true
+}
+
+// setStopTime (re)starts the timer stopping the subscription at the stop
+// time t, or stops it if t is zero. It must be called with sub.mu held.
+func (sub *Subscription) setStopTime(t time.Time) {
+	sub.opts.StopTime = t
+	if sub.timer != nil {
+		sub.timer.Stop()
+		sub.timer = nil
+	}
+	if !t.IsZero() {
+		sub.timer = time.AfterFunc(time.Until(t), func() {
+			sub.stop(notice(sub.complete()), false)
+		})
+	}
+}
+
+// run delivers the subscription's messages until it ends
+func (sub *Subscription) run() {
+	defer close(sub.done)
+	defer sub.stream.unsubscribe(sub)
+	for {
+		sub.mu.Lock()
+		for len(sub.queue) == 0 && !sub.stopped && !sub.ended {
+			sub.cond.Wait()
+		}
+		var ev *Event
+		switch {
+		case sub.ended:
+		case len(sub.queue) > 0:
+			ev = sub.queue[0]
+			sub.queue = sub.queue[1:]
+		default:
+			ev, sub.final = sub.final, nil
+			sub.ended = true
+		}
+		if sub.ended && sub.timer != nil {
+			sub.timer.Stop()
+		}
+		sub.mu.Unlock()
+		if ev == nil {
+			return
+		}
+		if err := sub.s.Send(context.Background(), bytes.NewReader(ev.Message)); err != nil {
+			sub.mu.Lock()
+			sub.ended, sub.err = true, err
+			sub.mu.Unlock()
+		}
+	}
+}
+
+// notice returns the Event of the state notification event, timestamped
+// now. State notifications always encode, so errors are not reported.
+func notice(event interface{}) *Event {
+	ev, _ := NewEvent(time.Now(), event)
+	return ev
+}
+
+// rpcError returns a protocol error with tag, reporting the element
+// name as its bad-element
+func rpcError(tag, element, message string) *ops.RPCError {
+	return &ops.RPCError{Type: ops.ErrorTypeProtocol, Tag: tag, Severity: ops.SeverityError, Message: message,
+		Info: &ops.Inline{Content: []byte("<bad-element>" + element + "</bad-element>")}}
+}
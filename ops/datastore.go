@@ -0,0 +1,77 @@
+package ops
+
+import "encoding/xml"
+
+// Datastore is a configuration datastore choice, as used in <source>
+// and <target> elements. One of its fields should be set.
+type Datastore struct {
+	// Name is the name of a datastore (e.g., "running")
+	Name string
+	// URL is a datastore URL (requires the :url capability)
+	URL string
+	// Config is an inline configuration (valid as a <copy-config> or
+	// <validate> source only)
+	Config *Inline
+}
+
+// Standard datastores
+var (
+	Running   = Datastore{Name: "running"}
+	Candidate = Datastore{Name: "candidate"}
+	Startup   = Datastore{Name: "startup"}
+)
+
+// URL returns a Datastore for the URL u
+func URL(u string) Datastore { return Datastore{URL: u} }
+
+// MarshalXML implements xml.Marshaler
+func (d Datastore) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	var err error
+	switch {
+	case d.URL != "":
+		err = e.EncodeElement(d.URL, xml.StartElement{Name: xml.Name{Local: "url"}})
+	case d.Config != nil:
+		err = e.EncodeElement(d.Config, xml.StartElement{Name: xml.Name{Local: "config"}})
+	case d.Name != "":
+		se := xml.StartElement{Name: xml.Name{Local: d.Name}}
+		if err = e.EncodeToken(se); err == nil {
+			err = e.EncodeToken(se.End())
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return e.EncodeToken(start.End())
+}
+
+// UnmarshalXML implements xml.Unmarshaler
+func (d *Datastore) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
+	*d = Datastore{}
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "url":
+				err = dec.DecodeElement(&d.URL, &t)
+			case "config":
+				d.Config = &Inline{}
+				err = dec.DecodeElement(d.Config, &t)
+			default:
+				d.Name = t.Name.Local
+				err = dec.Skip()
+			}
+			if err != nil {
+				return err
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
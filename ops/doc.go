@@ -0,0 +1,46 @@
+/*
+Package ops provides encoding/xml types for the RFC6241 NETCONF base
+protocol operations and their replies.
+
+RPC and RPCReply are the <rpc> and <rpc-reply> message envelopes, the
+former carrying one of the operation types (such as Get, GetConfig or
+EditConfig) as its Operation. All types are in the NETCONF base
+namespace (NS).
+
+Use Encode to send a message to a session's Outgoing message, and
+DecodeRPC or DecodeReply to read one from a session's Incoming message:
+
+	err := ops.Encode(s.Outgoing(), &ops.RPC{
+		MessageID: "101",
+		Operation: &ops.GetConfig{Source: ops.Running},
+	})
+
+	rpc, err := ops.DecodeRPC(s.Incoming())
+	switch op := rpc.Operation.(type) {
+	case *ops.GetConfig:
+		// ...
+	}
+
+GetData and EditData are the NMDA operations of RFC8526, selecting a
+datastore such as DSOperational by its Identity. Their replies' Data
+element is in the NMDA namespace.
+
+The WithDefaults parameter of Get, GetConfig and CopyConfig requires
+the RFC6243 :with-defaults capability. WithDefaultsMode chooses a mode
+the peer supports:
+
+	mode := ops.WithDefaultsMode(s.State.Capabilities, ops.WithDefaultsReportAll)
+	op := &ops.Get{WithDefaults: mode}
+
+Notification is the RFC5277 <notification> message, decoded with
+DecodeNotification; its Event is decoded as a type registered with
+RegisterEvent, or as an *Inline. CreateSubscription is the RFC5277
+subscription operation, while EstablishSubscription, ModifySubscription,
+DeleteSubscription and KillSubscription manage the dynamic subscriptions
+of RFC8639. The <id> of an <establish-subscription> reply, like other
+operation output, is held by the reply's Output.
+
+Operations not known to this package are decoded as *RawOperation,
+unless registered with RegisterOperation.
+*/
+package ops
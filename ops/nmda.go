@@ -0,0 +1,194 @@
+package ops
+
+import (
+	"encoding/xml"
+	"strconv"
+	"strings"
+)
+
+// Namespaces of the RFC8526 NMDA operations and the RFC8342 datastore
+// and origin identities
+const (
+	NMDANS       = "urn:ietf:params:xml:ns:yang:ietf-netconf-nmda"
+	DatastoresNS = "urn:ietf:params:xml:ns:yang:ietf-datastores"
+	OriginNS     = "urn:ietf:params:xml:ns:yang:ietf-origin"
+)
+
+// GetData is the NMDA <get-data> operation (RFC8526).
+//
+// At most one of SubtreeFilter and XPathFilter, and one of OriginFilter
+// and NegatedOriginFilter, may be set. The origin parameters are valid
+// for the operational datastore only. The reply's Data element is in
+// the NMDA namespace.
+type GetData struct {
+	XMLName   xml.Name `xml:"urn:ietf:params:xml:ns:yang:ietf-netconf-nmda get-data"`
+	Datastore Identity `xml:"datastore"`
+	// SubtreeFilter holds an RFC6241 subtree filter
+	SubtreeFilter *Inline      `xml:"subtree-filter,omitempty"`
+	XPathFilter   *XPathFilter `xml:"xpath-filter,omitempty"`
+	// ConfigFilter selects configuration nodes only if true, or state
+	// nodes only if false
+	ConfigFilter        *bool      `xml:"config-filter,omitempty"`
+	MaxDepth            Depth      `xml:"max-depth,omitempty"`
+	OriginFilter        []Identity `xml:"origin-filter"`
+	NegatedOriginFilter []Identity `xml:"negated-origin-filter"`
+	// WithOrigin requests or:origin annotations on the returned data
+	WithOrigin *Empty `xml:"with-origin,omitempty"`
+}
+
+// EditData is the NMDA <edit-data> operation (RFC8526), editing a
+// configuration datastore such as DSRunning or DSCandidate.
+//
+// One of Config or URL must be set.
+type EditData struct {
+	XMLName          xml.Name `xml:"urn:ietf:params:xml:ns:yang:ietf-netconf-nmda edit-data"`
+	Datastore        Identity `xml:"datastore"`
+	DefaultOperation string   `xml:"default-operation,omitempty"`
+	Config           *Inline  `xml:"config,omitempty"`
+	URL              string   `xml:"url,omitempty"`
+}
+
+// Identity is a YANG identityref value, such as an NMDA datastore,
+// origin or subscribed notifications identity.
+//
+// When encoded, the identity's namespace prefix is declared on its
+// element. When decoded, the prefix is resolved using the declarations
+// of the element itself, falling back to the conventional prefixes "ds",
+// "or" and "sn" of the datastore, origin and subscribed notifications
+// identities.
+type Identity xml.Name
+
+// NMDA datastores (RFC8342)
+var (
+	DSRunning     = Identity{Space: DatastoresNS, Local: "running"}
+	DSCandidate   = Identity{Space: DatastoresNS, Local: "candidate"}
+	DSStartup     = Identity{Space: DatastoresNS, Local: "startup"}
+	DSIntended    = Identity{Space: DatastoresNS, Local: "intended"}
+	DSOperational = Identity{Space: DatastoresNS, Local: "operational"}
+)
+
+// Origins of operational data (RFC8342)
+var (
+	OriginIntended = Identity{Space: OriginNS, Local: "intended"}
+	OriginDynamic  = Identity{Space: OriginNS, Local: "dynamic"}
+	OriginSystem   = Identity{Space: OriginNS, Local: "system"}
+	OriginLearned  = Identity{Space: OriginNS, Local: "learned"}
+	OriginDefault  = Identity{Space: OriginNS, Local: "default"}
+	OriginUnknown  = Identity{Space: OriginNS, Local: "unknown"}
+)
+
+// identityPrefixes holds the conventional prefixes of identity namespaces
+var identityPrefixes = map[string]string{
+	"ds": DatastoresNS,
+	"or": OriginNS,
+	"sn": SubscriptionsNS,
+}
+
+func (i Identity) prefix() string {
+	for prefix, space := range identityPrefixes {
+		if space == i.Space {
+			return prefix
+		}
+	}
+	return "id"
+}
+
+// MarshalXML implements xml.Marshaler
+func (i Identity) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if i.Space == "" {
+		return e.EncodeElement(i.Local, start)
+	}
+	prefix := i.prefix()
+	// encoding/xml does not encode attributes in the xmlns namespace
+	start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "xmlns:" + prefix}, Value: i.Space})
+	return e.EncodeElement(prefix+":"+i.Local, start)
+}
+
+// UnmarshalXML implements xml.Unmarshaler
+func (i *Identity) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var s string
+	if err := d.DecodeElement(&s, &start); err != nil {
+		return err
+	}
+	s = strings.TrimSpace(s)
+	prefix, local := "", s
+	if j := strings.IndexByte(s, ':'); j >= 0 {
+		prefix, local = s[:j], s[j+1:]
+	}
+	space, declared := "", false
+	for _, a := range start.Attr {
+		if a.Name.Space == "xmlns" && a.Name.Local == prefix || prefix == "" && a.Name.Space == "" && a.Name.Local == "xmlns" {
+			space, declared = a.Value, true
+		}
+	}
+	if !declared && prefix != "" {
+		space = identityPrefixes[prefix]
+	}
+	*i = Identity{Space: space, Local: local}
+	return nil
+}
+
+// XPathFilter is an <xpath-filter> expression, with any prefixes it uses
+// declared in Attrs
+type XPathFilter struct {
+	Attrs  []xml.Attr `xml:",any,attr"`
+	Select string     `xml:",chardata"`
+}
+
+// MarshalXML implements xml.Marshaler, re-encoding decoded namespace
+// declarations
+func (f *XPathFilter) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	for _, a := range f.Attrs {
+		if a.Name.Space == "xmlns" {
+			a.Name = xml.Name{Local: "xmlns:" + a.Name.Local}
+		}
+		start.Attr = append(start.Attr, a)
+	}
+	return e.EncodeElement(f.Select, start)
+}
+
+// Namespaces returns the namespace prefixes declared in f's Attrs
+func (f *XPathFilter) Namespaces() map[string]string {
+	ns := map[string]string{}
+	for _, a := range f.Attrs {
+		switch {
+		case a.Name.Space == "xmlns":
+			ns[a.Name.Local] = a.Value
+		case a.Name.Space == "" && strings.HasPrefix(a.Name.Local, "xmlns:"):
+			ns[strings.TrimPrefix(a.Name.Local, "xmlns:")] = a.Value
+		}
+	}
+	return ns
+}
+
+// Depth is a <max-depth> value, the zero value being "unbounded"
+type Depth uint16
+
+// MarshalText implements encoding.TextMarshaler
+func (d Depth) MarshalText() ([]byte, error) {
+	if d == 0 {
+		return []byte("unbounded"), nil
+	}
+	return []byte(strconv.Itoa(int(d))), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (d *Depth) UnmarshalText(b []byte) error {
+	s := strings.TrimSpace(string(b))
+	if s == "unbounded" {
+		*d = 0
+		return nil
+	}
+	v, err := strconv.ParseUint(s, 10, 16)
+	if err != nil || v == 0 {
+		return &RPCError{Type: ErrorTypeProtocol, Tag: ErrorTagInvalidValue, Severity: SeverityError,
+			Message: "invalid max-depth " + strconv.Quote(s)}
+	}
+	*d = Depth(v)
+	return nil
+}
+
+func init() {
+	RegisterOperation(xml.Name{Space: NMDANS, Local: "get-data"}, func() interface{} { return &GetData{} })
+	RegisterOperation(xml.Name{Space: NMDANS, Local: "edit-data"}, func() interface{} { return &EditData{} })
+}
@@ -0,0 +1,146 @@
+package ops
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"sync"
+	"time"
+)
+
+// NotificationNS is the namespace of the RFC5277 <notification> message
+// and <create-subscription> operation
+const NotificationNS = "urn:ietf:params:xml:ns:netconf:notification:1.0"
+
+// Notification is a <notification> message (RFC5277)
+type Notification struct {
+	XMLName   xml.Name  `xml:"urn:ietf:params:xml:ns:netconf:notification:1.0 notification"`
+	EventTime time.Time `xml:"eventTime"`
+	// Event is the notification's event, a pointer to an event type
+	// registered with RegisterEvent, or an *Inline holding the element
+	// of an unregistered event.
+	Event interface{}
+}
+
+// MarshalXML implements xml.Marshaler, encoding the event after the
+// <eventTime> element.
+func (n *Notification) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Name = xml.Name{Space: NotificationNS, Local: "notification"}
+	start.Attr = nil
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	t := n.EventTime.Format(time.RFC3339Nano)
+	if err := e.EncodeElement(t, xml.StartElement{Name: xml.Name{Local: "eventTime"}}); err != nil {
+		return err
+	}
+	if n.Event != nil {
+		if err := e.Encode(n.Event); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// UnmarshalXML implements xml.Unmarshaler, decoding the first element
+// following <eventTime> as the event.
+func (n *Notification) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	n.XMLName = start.Name
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch {
+			case t.Name.Local == "eventTime" && t.Name.Space == NotificationNS:
+				err = d.DecodeElement(&n.EventTime, &t)
+			case n.Event != nil:
+				err = d.Skip()
+			default:
+				event := newEvent(t.Name)
+				if err = d.DecodeElement(event, &t); err == nil {
+					n.Event = event
+				}
+			}
+			if err != nil {
+				return err
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// Name returns the element name of the notification's event, or the
+// zero Name if it has none.
+func (n *Notification) Name() xml.Name {
+	switch ev := n.Event.(type) {
+	case nil:
+		return xml.Name{}
+	case *Inline:
+		return ev.XMLName
+	default:
+		b, err := xml.Marshal(ev)
+		if err != nil {
+			return xml.Name{}
+		}
+		d := xml.NewDecoder(bytes.NewReader(b))
+		for {
+			tok, err := d.Token()
+			if err != nil {
+				return xml.Name{}
+			}
+			if t, ok := tok.(xml.StartElement); ok {
+				return t.Name
+			}
+		}
+	}
+}
+
+// CreateSubscription is the <create-subscription> operation (RFC5277)
+type CreateSubscription struct {
+	XMLName xml.Name `xml:"urn:ietf:params:xml:ns:netconf:notification:1.0 create-subscription"`
+	// Stream is the name of the event stream, "NETCONF" if empty
+	Stream    string     `xml:"stream,omitempty"`
+	Filter    *Filter    `xml:"filter,omitempty"`
+	StartTime *time.Time `xml:"startTime,omitempty"`
+	StopTime  *time.Time `xml:"stopTime,omitempty"`
+}
+
+var (
+	eventMu sync.RWMutex
+	events  = map[xml.Name]func() interface{}{}
+)
+
+// RegisterEvent registers the event element name, such that
+// DecodeNotification decodes events of that name into the (pointer)
+// value returned by new. It is safe to call concurrently.
+func RegisterEvent(name xml.Name, new func() interface{}) {
+	eventMu.Lock()
+	defer eventMu.Unlock()
+	events[name] = new
+}
+
+func newEvent(name xml.Name) interface{} {
+	eventMu.RLock()
+	defer eventMu.RUnlock()
+	if f := events[name]; f != nil {
+		return f()
+	}
+	return &Inline{}
+}
+
+// DecodeNotification decodes a <notification> message from r
+func DecodeNotification(r io.Reader) (*Notification, error) {
+	n := &Notification{}
+	if err := decode(r, n); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func init() {
+	RegisterOperation(xml.Name{Space: NotificationNS, Local: "create-subscription"}, func() interface{} { return &CreateSubscription{} })
+}
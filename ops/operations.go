@@ -0,0 +1,150 @@
+package ops
+
+import "encoding/xml"
+
+// Get is the <get> operation.
+//
+// WithDefaults requires the :with-defaults capability.
+type Get struct {
+	XMLName      xml.Name `xml:"urn:ietf:params:xml:ns:netconf:base:1.0 get"`
+	Filter       *Filter  `xml:"filter,omitempty"`
+	WithDefaults string   `xml:"urn:ietf:params:xml:ns:yang:ietf-netconf-with-defaults with-defaults,omitempty"`
+}
+
+// GetConfig is the <get-config> operation.
+//
+// WithDefaults requires the :with-defaults capability.
+type GetConfig struct {
+	XMLName      xml.Name  `xml:"urn:ietf:params:xml:ns:netconf:base:1.0 get-config"`
+	Source       Datastore `xml:"source"`
+	Filter       *Filter   `xml:"filter,omitempty"`
+	WithDefaults string    `xml:"urn:ietf:params:xml:ns:yang:ietf-netconf-with-defaults with-defaults,omitempty"`
+}
+
+// EditConfig is the <edit-config> operation.
+//
+// One of Config or URL must be set.
+type EditConfig struct {
+	XMLName          xml.Name  `xml:"urn:ietf:params:xml:ns:netconf:base:1.0 edit-config"`
+	Target           Datastore `xml:"target"`
+	DefaultOperation string    `xml:"default-operation,omitempty"`
+	TestOption       string    `xml:"test-option,omitempty"`
+	ErrorOption      string    `xml:"error-option,omitempty"`
+	Config           *Inline   `xml:"config,omitempty"`
+	URL              string    `xml:"url,omitempty"`
+}
+
+// CopyConfig is the <copy-config> operation.
+//
+// WithDefaults requires the :with-defaults capability, and applies to
+// copies to a URL target.
+type CopyConfig struct {
+	XMLName      xml.Name  `xml:"urn:ietf:params:xml:ns:netconf:base:1.0 copy-config"`
+	Target       Datastore `xml:"target"`
+	Source       Datastore `xml:"source"`
+	WithDefaults string    `xml:"urn:ietf:params:xml:ns:yang:ietf-netconf-with-defaults with-defaults,omitempty"`
+}
+
+// DeleteConfig is the <delete-config> operation
+type DeleteConfig struct {
+	XMLName xml.Name  `xml:"urn:ietf:params:xml:ns:netconf:base:1.0 delete-config"`
+	Target  Datastore `xml:"target"`
+}
+
+// Lock is the <lock> operation
+type Lock struct {
+	XMLName xml.Name  `xml:"urn:ietf:params:xml:ns:netconf:base:1.0 lock"`
+	Target  Datastore `xml:"target"`
+}
+
+// Unlock is the <unlock> operation
+type Unlock struct {
+	XMLName xml.Name  `xml:"urn:ietf:params:xml:ns:netconf:base:1.0 unlock"`
+	Target  Datastore `xml:"target"`
+}
+
+// CloseSession is the <close-session> operation
+type CloseSession struct {
+	XMLName xml.Name `xml:"urn:ietf:params:xml:ns:netconf:base:1.0 close-session"`
+}
+
+// KillSession is the <kill-session> operation
+type KillSession struct {
+	XMLName   xml.Name `xml:"urn:ietf:params:xml:ns:netconf:base:1.0 kill-session"`
+	SessionID uint32   `xml:"session-id"`
+}
+
+// Commit is the <commit> operation (requires the :candidate capability).
+//
+// Confirmed commit parameters require the :confirmed-commit capability.
+type Commit struct {
+	XMLName        xml.Name `xml:"urn:ietf:params:xml:ns:netconf:base:1.0 commit"`
+	Confirmed      *Empty   `xml:"confirmed,omitempty"`
+	ConfirmTimeout uint32   `xml:"confirm-timeout,omitempty"`
+	Persist        string   `xml:"persist,omitempty"`
+	PersistID      string   `xml:"persist-id,omitempty"`
+}
+
+// DiscardChanges is the <discard-changes> operation (requires the :candidate capability)
+type DiscardChanges struct {
+	XMLName xml.Name `xml:"urn:ietf:params:xml:ns:netconf:base:1.0 discard-changes"`
+}
+
+// CancelCommit is the <cancel-commit> operation (requires the :confirmed-commit capability)
+type CancelCommit struct {
+	XMLName   xml.Name `xml:"urn:ietf:params:xml:ns:netconf:base:1.0 cancel-commit"`
+	PersistID string   `xml:"persist-id,omitempty"`
+}
+
+// Validate is the <validate> operation (requires the :validate capability)
+type Validate struct {
+	XMLName xml.Name  `xml:"urn:ietf:params:xml:ns:netconf:base:1.0 validate"`
+	Source  Datastore `xml:"source"`
+}
+
+// Filter is a <filter> element, as used by <get> and <get-config>.
+//
+// Subtree filters (Type "subtree" or empty) carry the filter in Content,
+// while XPath filters (Type "xpath", requiring the :xpath capability)
+// use Select, with any prefixes used declared in Attrs.
+type Filter struct {
+	Type    string     `xml:"type,attr,omitempty"`
+	Select  string     `xml:"select,attr,omitempty"`
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Content []byte     `xml:",innerxml"`
+}
+
+// Values for EditConfig fields
+const (
+	OperationMerge   = "merge"
+	OperationReplace = "replace"
+	OperationNone    = "none"
+
+	TestOptionTestThenSet = "test-then-set"
+	TestOptionSet         = "set"
+	TestOptionTestOnly    = "test-only"
+
+	ErrorOptionStopOnError     = "stop-on-error"
+	ErrorOptionContinueOnError = "continue-on-error"
+	ErrorOptionRollbackOnError = "rollback-on-error"
+)
+
+func init() {
+	for name, f := range map[string]func() interface{}{
+		"get":             func() interface{} { return &Get{} },
+		"get-config":      func() interface{} { return &GetConfig{} },
+		"edit-config":     func() interface{} { return &EditConfig{} },
+		"copy-config":     func() interface{} { return &CopyConfig{} },
+		"delete-config":   func() interface{} { return &DeleteConfig{} },
+		"lock":            func() interface{} { return &Lock{} },
+		"unlock":          func() interface{} { return &Unlock{} },
+		"close-session":   func() interface{} { return &CloseSession{} },
+		"kill-session":    func() interface{} { return &KillSession{} },
+		"commit":          func() interface{} { return &Commit{} },
+		"discard-changes": func() interface{} { return &DiscardChanges{} },
+		"cancel-commit":   func() interface{} { return &CancelCommit{} },
+		"validate":        func() interface{} { return &Validate{} },
+	} {
+		RegisterOperation(xml.Name{Space: NS, Local: name}, f)
+	}
+}
@@ -0,0 +1,170 @@
+package ops
+
+import (
+	"encoding/xml"
+	"io"
+	"sync"
+)
+
+// NS is the NETCONF base namespace
+const NS = "urn:ietf:params:xml:ns:netconf:base:1.0"
+
+// RPC is an <rpc> message
+type RPC struct {
+	XMLName   xml.Name   `xml:"urn:ietf:params:xml:ns:netconf:base:1.0 rpc"`
+	MessageID string     `xml:"message-id,attr,omitempty"`
+	Attrs     []xml.Attr `xml:",any,attr"`
+	// Operation is the RPC's operation, a pointer to an operation type
+	// (e.g., *Get) or a *RawOperation.
+	Operation interface{}
+}
+
+// RawOperation is an operation not registered with this package
+type RawOperation struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Content []byte     `xml:",innerxml"`
+}
+
+// Empty is an XML element with no content, such as <ok/>
+type Empty struct{}
+
+// Inline is an XML element containing arbitrary XML content, such as <config>.
+//
+// Any namespace declarations needed by the content must be made in the content.
+type Inline struct {
+	// XMLName is the element's name when decoded. If set, it overrides
+	// the name of the field holding the element when encoded, such as
+	// for the <data> element of a <get-data> reply, in the NMDA namespace.
+	XMLName xml.Name
+	Content []byte `xml:",innerxml"`
+}
+
+// MarshalXML implements xml.Marshaler
+func (i *Inline) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if i.XMLName.Local != "" {
+		start.Name = i.XMLName
+	}
+	// encoding/xml would undeclare the default namespace of an element
+	// whose XMLName has none, so encode the content without it
+	return e.EncodeElement(struct {
+		Content []byte `xml:",innerxml"`
+	}{i.Content}, start)
+}
+
+// MarshalXML implements xml.Marshaler, encoding the operation as the
+// <rpc> element's only child.
+func (r *RPC) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Name = xml.Name{Space: NS, Local: "rpc"}
+	start.Attr = r.Attrs
+	if r.MessageID != "" {
+		start.Attr = append([]xml.Attr{{Name: xml.Name{Local: "message-id"}, Value: r.MessageID}}, r.Attrs...)
+	}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	if r.Operation != nil {
+		if err := e.Encode(r.Operation); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// UnmarshalXML implements xml.Unmarshaler, decoding the <rpc> element's
+// first child element as its operation.
+func (r *RPC) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	r.XMLName = start.Name
+	r.Attrs = nil
+	for _, attr := range start.Attr {
+		switch {
+		case attr.Name.Space == "" && attr.Name.Local == "message-id":
+			r.MessageID = attr.Value
+		case attr.Name.Space == "xmlns", attr.Name.Space == "" && attr.Name.Local == "xmlns":
+		default:
+			r.Attrs = append(r.Attrs, attr)
+		}
+	}
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if r.Operation != nil {
+				if err := d.Skip(); err != nil {
+					return err
+				}
+				continue
+			}
+			op := newOperation(t.Name)
+			if err := d.DecodeElement(op, &t); err != nil {
+				return err
+			}
+			r.Operation = op
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+var (
+	opMu       sync.RWMutex
+	operations = map[xml.Name]func() interface{}{}
+)
+
+// RegisterOperation registers the operation element name, such that
+// DecodeRPC decodes operations of that name into the (pointer) value
+// returned by new. It is safe to call concurrently.
+func RegisterOperation(name xml.Name, new func() interface{}) {
+	opMu.Lock()
+	defer opMu.Unlock()
+	operations[name] = new
+}
+
+func newOperation(name xml.Name) interface{} {
+	opMu.RLock()
+	defer opMu.RUnlock()
+	if f := operations[name]; f != nil {
+		return f()
+	}
+	return &RawOperation{}
+}
+
+// Encode encodes v (e.g., an *RPC or *RPCReply) as a single message to w,
+// then closes w, ending the message.
+func Encode(w io.WriteCloser, v interface{}) error {
+	err := xml.NewEncoder(w).Encode(v)
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// DecodeRPC decodes an <rpc> message from r
+func DecodeRPC(r io.Reader) (*RPC, error) {
+	rpc := &RPC{}
+	if err := decode(r, rpc); err != nil {
+		return nil, err
+	}
+	return rpc, nil
+}
+
+// DecodeReply decodes an <rpc-reply> message from r
+func DecodeReply(r io.Reader) (*RPCReply, error) {
+	reply := &RPCReply{}
+	if err := decode(r, reply); err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+// decode decodes v from r, then reads the remainder of the message
+func decode(r io.Reader, v interface{}) error {
+	if err := xml.NewDecoder(r).Decode(v); err != nil {
+		return err
+	}
+	_, err := io.Copy(io.Discard, r)
+	return err
+}
@@ -0,0 +1,346 @@
+package ops
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/andaru/netconf/netconftest"
+	"github.com/andaru/netconf/session"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRPCRoundTrip(t *testing.T) {
+	for _, tc := range []struct {
+		op   interface{}
+		want string
+	}{
+		{
+			op:   &Get{},
+			want: `<rpc message-id="1" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><get/></rpc>`,
+		},
+		{
+			op:   &Get{Filter: &Filter{Type: "subtree", Content: []byte(`<top xmlns="urn:example"/>`)}},
+			want: `<rpc message-id="1" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><get><filter type="subtree"><top xmlns="urn:example"/></filter></get></rpc>`,
+		},
+		{
+			op: &Get{WithDefaults: WithDefaultsReportAllTagged},
+			want: `<rpc message-id="1" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><get>` +
+				`<with-defaults xmlns="urn:ietf:params:xml:ns:yang:ietf-netconf-with-defaults">report-all-tagged</with-defaults></get></rpc>`,
+		},
+		{
+			op:   &GetConfig{Source: Running, Filter: &Filter{Type: "xpath", Select: "/top"}},
+			want: `<rpc message-id="1" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><get-config><source><running/></source><filter type="xpath" select="/top"/></get-config></rpc>`,
+		},
+		{
+			op: &EditConfig{
+				Target:           Candidate,
+				DefaultOperation: OperationReplace,
+				TestOption:       TestOptionTestThenSet,
+				ErrorOption:      ErrorOptionRollbackOnError,
+				Config:           &Inline{Content: []byte(`<top xmlns="urn:example"><a>1</a></top>`)},
+			},
+			want: `<rpc message-id="1" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><edit-config><target><candidate/></target>` +
+				`<default-operation>replace</default-operation><test-option>test-then-set</test-option>` +
+				`<error-option>rollback-on-error</error-option><config><top xmlns="urn:example"><a>1</a></top></config></edit-config></rpc>`,
+		},
+		{
+			op:   &EditConfig{Target: Running, URL: "file:///cfg.xml"},
+			want: `<rpc message-id="1" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><edit-config><target><running/></target><url>file:///cfg.xml</url></edit-config></rpc>`,
+		},
+		{
+			op:   &CopyConfig{Target: URL("ftp://example.com/cfg"), Source: Startup},
+			want: `<rpc message-id="1" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><copy-config><target><url>ftp://example.com/cfg</url></target><source><startup/></source></copy-config></rpc>`,
+		},
+		{
+			op:   &CopyConfig{Target: Running, Source: Datastore{Config: &Inline{Content: []byte(`<top xmlns="urn:example"/>`)}}},
+			want: `<rpc message-id="1" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><copy-config><target><running/></target><source><config><top xmlns="urn:example"/></config></source></copy-config></rpc>`,
+		},
+		{
+			op:   &DeleteConfig{Target: Startup},
+			want: `<rpc message-id="1" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><delete-config><target><startup/></target></delete-config></rpc>`,
+		},
+		{
+			op:   &Lock{Target: Running},
+			want: `<rpc message-id="1" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><lock><target><running/></target></lock></rpc>`,
+		},
+		{
+			op:   &Unlock{Target: Running},
+			want: `<rpc message-id="1" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><unlock><target><running/></target></unlock></rpc>`,
+		},
+		{
+			op:   &CloseSession{},
+			want: `<rpc message-id="1" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><close-session/></rpc>`,
+		},
+		{
+			op:   &KillSession{SessionID: 4},
+			want: `<rpc message-id="1" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><kill-session><session-id>4</session-id></kill-session></rpc>`,
+		},
+		{
+			op:   &Commit{Confirmed: &Empty{}, ConfirmTimeout: 120, Persist: "abc"},
+			want: `<rpc message-id="1" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><commit><confirmed/><confirm-timeout>120</confirm-timeout><persist>abc</persist></commit></rpc>`,
+		},
+		{
+			op:   &Commit{PersistID: "abc"},
+			want: `<rpc message-id="1" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><commit><persist-id>abc</persist-id></commit></rpc>`,
+		},
+		{
+			op:   &DiscardChanges{},
+			want: `<rpc message-id="1" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><discard-changes/></rpc>`,
+		},
+		{
+			op:   &CancelCommit{PersistID: "abc"},
+			want: `<rpc message-id="1" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><cancel-commit><persist-id>abc</persist-id></cancel-commit></rpc>`,
+		},
+		{
+			op:   &Validate{Source: Candidate},
+			want: `<rpc message-id="1" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><validate><source><candidate/></source></validate></rpc>`,
+		},
+		{
+			op: &GetData{
+				Datastore:    DSOperational,
+				XPathFilter:  &XPathFilter{Select: "/ex:top", Attrs: []xml.Attr{{Name: xml.Name{Local: "xmlns:ex"}, Value: "urn:example"}}},
+				ConfigFilter: new(bool),
+				MaxDepth:     2,
+				OriginFilter: []Identity{OriginIntended, OriginSystem},
+				WithOrigin:   &Empty{},
+			},
+			want: `<rpc message-id="1" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><get-data xmlns="urn:ietf:params:xml:ns:yang:ietf-netconf-nmda">` +
+				`<datastore xmlns:ds="urn:ietf:params:xml:ns:yang:ietf-datastores">ds:operational</datastore>` +
+				`<xpath-filter xmlns:ex="urn:example">/ex:top</xpath-filter><config-filter>false</config-filter><max-depth>2</max-depth>` +
+				`<origin-filter xmlns:or="urn:ietf:params:xml:ns:yang:ietf-origin">or:intended</origin-filter>` +
+				`<origin-filter xmlns:or="urn:ietf:params:xml:ns:yang:ietf-origin">or:system</origin-filter><with-origin/></get-data></rpc>`,
+		},
+		{
+			op: &GetData{Datastore: DSRunning, SubtreeFilter: &Inline{Content: []byte(`<top xmlns="urn:example"/>`)}},
+			want: `<rpc message-id="1" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><get-data xmlns="urn:ietf:params:xml:ns:yang:ietf-netconf-nmda">` +
+				`<datastore xmlns:ds="urn:ietf:params:xml:ns:yang:ietf-datastores">ds:running</datastore>` +
+				`<subtree-filter><top xmlns="urn:example"/></subtree-filter></get-data></rpc>`,
+		},
+		{
+			op: &EditData{Datastore: DSCandidate, DefaultOperation: OperationReplace, Config: &Inline{Content: []byte(`<top xmlns="urn:example"/>`)}},
+			want: `<rpc message-id="1" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><edit-data xmlns="urn:ietf:params:xml:ns:yang:ietf-netconf-nmda">` +
+				`<datastore xmlns:ds="urn:ietf:params:xml:ns:yang:ietf-datastores">ds:candidate</datastore>` +
+				`<default-operation>replace</default-operation><config><top xmlns="urn:example"/></config></edit-data></rpc>`,
+		},
+	} {
+		t.Run(reflect.TypeOf(tc.op).Elem().Name(), func(t *testing.T) {
+			a := assert.New(t)
+			p := netconftest.NewPair(session.Config{}, session.Config{})
+			if err := p.Handshake(); err != nil {
+				t.Fatal(err)
+			}
+			sent := &RPC{MessageID: "1", Operation: tc.op}
+			// capture the encoded message on the server side
+			buf := &bytes.Buffer{}
+			a.NoError(Encode(p.Client.Outgoing(), sent))
+			rpc, err := DecodeRPC(io.TeeReader(p.Server.Incoming(), buf))
+			if !a.NoError(err) {
+				return
+			}
+			netconftest.AssertXMLEqual(t, tc.want, buf.String())
+			a.Equal("1", rpc.MessageID)
+			a.Equal(xml.Name{Space: NS, Local: "rpc"}, rpc.XMLName)
+			a.IsType(tc.op, rpc.Operation)
+			// re-encoding the decoded rpc produces the same message
+			reencoded, err := xml.Marshal(rpc)
+			a.NoError(err)
+			netconftest.AssertXMLEqual(t, tc.want, string(reencoded))
+		})
+	}
+}
+
+func TestWithDefaultsCapability(t *testing.T) {
+	a := assert.New(t)
+	c := WithDefaultsCapability{BasicMode: WithDefaultsExplicit, AlsoSupported: []string{WithDefaultsReportAll, WithDefaultsTrim}}
+	a.Equal("urn:ietf:params:netconf:capability:with-defaults:1.0?basic-mode=explicit&also-supported=report-all,trim", c.String())
+	caps := []string{"urn:ietf:params:netconf:base:1.1", c.String()}
+	parsed, ok := ParseWithDefaultsCapability(caps)
+	if a.True(ok) {
+		a.Equal(c, parsed)
+	}
+	a.Equal(WithDefaultsTrim, WithDefaultsMode(caps, WithDefaultsReportAllTagged, WithDefaultsTrim))
+	a.Equal(WithDefaultsExplicit, WithDefaultsMode(caps, WithDefaultsExplicit))
+	a.Equal("", WithDefaultsMode(caps, WithDefaultsReportAllTagged))
+	a.Equal("", WithDefaultsMode(caps[:1], WithDefaultsReportAll))
+	_, ok = ParseWithDefaultsCapability([]string{CapWithDefaults})
+	a.False(ok)
+}
+
+func TestDecodeRawOperation(t *testing.T) {
+	a := assert.New(t)
+	rpc, err := DecodeRPC(bytes.NewBufferString(`<rpc message-id="7" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" xmlns:ex="urn:example" ex:foo="bar"><action xmlns="urn:example"><x/></action></rpc>`))
+	if !a.NoError(err) {
+		return
+	}
+	a.Equal("7", rpc.MessageID)
+	a.Equal([]xml.Attr{{Name: xml.Name{Space: "urn:example", Local: "foo"}, Value: "bar"}}, rpc.Attrs)
+	if raw, ok := rpc.Operation.(*RawOperation); a.True(ok) {
+		a.Equal(xml.Name{Space: "urn:example", Local: "action"}, raw.XMLName)
+		a.Equal("<x/>", string(raw.Content))
+	}
+}
+
+func TestDecodeGetData(t *testing.T) {
+	a := assert.New(t)
+	rpc, err := DecodeRPC(bytes.NewBufferString(`<rpc message-id="1" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">` +
+		`<get-data xmlns="urn:ietf:params:xml:ns:yang:ietf-netconf-nmda" xmlns:ds="urn:ietf:params:xml:ns:yang:ietf-datastores">` +
+		`<datastore>ds:intended</datastore><max-depth>unbounded</max-depth>` +
+		`<negated-origin-filter xmlns:o="urn:ietf:params:xml:ns:yang:ietf-origin">o:learned</negated-origin-filter></get-data></rpc>`))
+	if !a.NoError(err) {
+		return
+	}
+	if op, ok := rpc.Operation.(*GetData); a.True(ok) {
+		a.Equal(DSIntended, op.Datastore)
+		a.Equal(Depth(0), op.MaxDepth)
+		a.Equal([]Identity{OriginLearned}, op.NegatedOriginFilter)
+	}
+	_, err = DecodeRPC(bytes.NewBufferString(`<rpc xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">` +
+		`<get-data xmlns="urn:ietf:params:xml:ns:yang:ietf-netconf-nmda"><max-depth>0</max-depth></get-data></rpc>`))
+	a.EqualError(err, `rpc-error: protocol: invalid-value: invalid max-depth "0"`)
+}
+
+func TestNotification(t *testing.T) {
+	a := assert.New(t)
+	const msg = `<notification xmlns="urn:ietf:params:xml:ns:netconf:notification:1.0"><eventTime>2024-01-02T03:04:05.5Z</eventTime>` +
+		`<alarm xmlns="urn:example"><severity>major</severity></alarm></notification>`
+	n, err := DecodeNotification(bytes.NewBufferString(msg))
+	if !a.NoError(err) {
+		return
+	}
+	a.Equal(time.Date(2024, 1, 2, 3, 4, 5, 5e8, time.UTC), n.EventTime)
+	a.Equal(xml.Name{Space: "urn:example", Local: "alarm"}, n.Name())
+	b, err := xml.Marshal(n)
+	a.NoError(err)
+	a.Equal(msg, string(b))
+
+	rpc, err := DecodeRPC(bytes.NewBufferString(`<rpc message-id="1" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">` +
+		`<create-subscription xmlns="urn:ietf:params:xml:ns:netconf:notification:1.0"><startTime>2024-01-02T00:00:00Z</startTime></create-subscription></rpc>`))
+	if !a.NoError(err) {
+		return
+	}
+	if op, ok := rpc.Operation.(*CreateSubscription); a.True(ok) && a.NotNil(op.StartTime) {
+		a.Equal("", op.Stream)
+		a.Equal(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), *op.StartTime)
+	}
+}
+
+func TestEstablishSubscription(t *testing.T) {
+	a := assert.New(t)
+	rpc, err := DecodeRPC(bytes.NewBufferString(`<rpc message-id="1" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">` +
+		`<establish-subscription xmlns="urn:ietf:params:xml:ns:yang:ietf-subscribed-notifications"><stream>NETCONF</stream>` +
+		`<stream-xpath-filter xmlns:ex="urn:example">/ex:alarm</stream-xpath-filter><encoding>sn:encode-xml</encoding></establish-subscription></rpc>`))
+	if !a.NoError(err) {
+		return
+	}
+	if op, ok := rpc.Operation.(*EstablishSubscription); a.True(ok) && a.NotNil(op.StreamXPathFilter) && a.NotNil(op.Encoding) {
+		a.Equal("NETCONF", op.Stream)
+		a.Equal("/ex:alarm", op.StreamXPathFilter.Select)
+		a.Equal(map[string]string{"ex": "urn:example"}, op.StreamXPathFilter.Namespaces())
+		a.Equal(EncodeXML, *op.Encoding)
+	}
+
+	const msg = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="1">` +
+		`<id xmlns="urn:ietf:params:xml:ns:yang:ietf-subscribed-notifications">7</id></rpc-reply>`
+	reply, err := DecodeReply(bytes.NewBufferString(msg))
+	if a.NoError(err) && a.Len(reply.Output, 1) {
+		a.Equal(Inline{XMLName: xml.Name{Space: SubscriptionsNS, Local: "id"}, Content: []byte("7")}, reply.Output[0])
+	}
+	b, err := xml.Marshal(&RPCReply{MessageID: "1", Output: []Inline{{XMLName: xml.Name{Space: SubscriptionsNS, Local: "id"}, Content: []byte("7")}}})
+	a.NoError(err)
+	a.Equal(msg, string(b))
+}
+
+func TestReplyRoundTrip(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		reply   *RPCReply
+		want    string
+		wantErr string
+	}{
+		{
+			name:  "ok",
+			reply: &RPCReply{MessageID: "1", OK: &Empty{}},
+			want:  `<rpc-reply message-id="1" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><ok/></rpc-reply>`,
+		},
+		{
+			name:  "data",
+			reply: &RPCReply{MessageID: "2", Data: &Inline{Content: []byte(`<top xmlns="urn:example"/>`)}},
+			want:  `<rpc-reply message-id="2" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><data><top xmlns="urn:example"/></data></rpc-reply>`,
+		},
+		{
+			name: "errors",
+			reply: &RPCReply{MessageID: "3", Errors: []RPCError{
+				{Type: ErrorTypeApplication, Tag: ErrorTagInvalidValue, Severity: SeverityWarning},
+				{Type: ErrorTypeProtocol, Tag: ErrorTagLockDenied, Severity: SeverityError, Message: "lock held",
+					Info: &Inline{Content: []byte(`<session-id>4</session-id>`)}},
+			}},
+			want: `<rpc-reply message-id="3" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">` +
+				`<rpc-error><error-type>application</error-type><error-tag>invalid-value</error-tag><error-severity>warning</error-severity></rpc-error>` +
+				`<rpc-error><error-type>protocol</error-type><error-tag>lock-denied</error-tag><error-severity>error</error-severity>` +
+				`<error-message>lock held</error-message><error-info><session-id>4</session-id></error-info></rpc-error></rpc-reply>`,
+			wantErr: "rpc-error: protocol: lock-denied: lock held",
+		},
+		{
+			name: "error path namespaces",
+			reply: &RPCReply{MessageID: "4", Errors: []RPCError{
+				{Type: ErrorTypeApplication, Tag: ErrorTagInvalidValue, Severity: SeverityError, AppTag: "bad-mtu",
+					Path: "/ex:top/ex:mtu", PathNS: map[string]string{"ex": "urn:example"}},
+			}},
+			want: `<rpc-reply message-id="4" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">` +
+				`<rpc-error><error-type>application</error-type><error-tag>invalid-value</error-tag><error-severity>error</error-severity>` +
+				`<error-app-tag>bad-mtu</error-app-tag><error-path xmlns:ex="urn:example">/ex:top/ex:mtu</error-path></rpc-error></rpc-reply>`,
+			wantErr: "rpc-error: application: invalid-value: /ex:top/ex:mtu",
+		},
+		{
+			name:  "nmda data",
+			reply: &RPCReply{MessageID: "5", Data: &Inline{XMLName: xml.Name{Space: NMDANS, Local: "data"}, Content: []byte(`<top xmlns="urn:example"/>`)}},
+			want: `<rpc-reply message-id="5" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">` +
+				`<data xmlns="urn:ietf:params:xml:ns:yang:ietf-netconf-nmda"><top xmlns="urn:example"/></data></rpc-reply>`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			a := assert.New(t)
+			p := netconftest.NewPair(session.Config{}, session.Config{})
+			if err := p.Handshake(); err != nil {
+				t.Fatal(err)
+			}
+			buf := &bytes.Buffer{}
+			a.NoError(Encode(p.Server.Outgoing(), tc.reply))
+			reply, err := DecodeReply(io.TeeReader(p.Client.Incoming(), buf))
+			if !a.NoError(err) {
+				return
+			}
+			netconftest.AssertXMLEqual(t, tc.want, buf.String())
+			a.Equal(tc.reply.MessageID, reply.MessageID)
+			if err := reply.Err(); tc.wantErr != "" {
+				a.EqualError(err, tc.wantErr)
+			} else {
+				a.NoError(err)
+			}
+		})
+	}
+}
+
+func TestRejectRPCError(t *testing.T) {
+	a := assert.New(t)
+	p := netconftest.NewPair(session.Config{}, session.Config{})
+	if err := p.Handshake(); err != nil {
+		t.Fatal(err)
+	}
+	a.NoError(Encode(p.Client.Outgoing(), &RPC{MessageID: "7", Operation: &Lock{Target: Running}}))
+	a.NoError(p.Server.Reject(&RPCError{Type: ErrorTypeProtocol, Tag: ErrorTagLockDenied, Severity: SeverityError,
+		Info: &Inline{Content: []byte(`<session-id>4</session-id>`)}}))
+	reply, err := DecodeReply(p.Client.Incoming())
+	if !a.NoError(err) {
+		return
+	}
+	a.Equal("7", reply.MessageID)
+	a.EqualError(reply.Err(), "rpc-error: protocol: lock-denied")
+	if a.Len(reply.Errors, 1) && a.NotNil(reply.Errors[0].Info) {
+		a.Equal(`<session-id>4</session-id>`, string(reply.Errors[0].Info.Content))
+	}
+}
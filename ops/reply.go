@@ -0,0 +1,128 @@
+package ops
+
+import (
+	"encoding/xml"
+	"sort"
+	"strings"
+)
+
+// RPCReply is an <rpc-reply> message
+type RPCReply struct {
+	XMLName   xml.Name   `xml:"urn:ietf:params:xml:ns:netconf:base:1.0 rpc-reply"`
+	MessageID string     `xml:"message-id,attr,omitempty"`
+	Attrs     []xml.Attr `xml:",any,attr"`
+	// Errors holds any <rpc-error> elements
+	Errors []RPCError `xml:"rpc-error"`
+	// OK is non-nil if the reply contained <ok/>
+	OK *Empty `xml:"ok"`
+	// Data holds the <data> element of <get> and <get-config> replies
+	Data *Inline `xml:"data"`
+	// Output holds the other output elements of the reply, such as the
+	// <id> of an <establish-subscription> reply
+	Output []Inline `xml:",any"`
+}
+
+// Err returns the reply's first error-severity rpc-error, or nil if none.
+func (r *RPCReply) Err() error {
+	for i := range r.Errors {
+		if r.Errors[i].Severity != SeverityWarning {
+			return &r.Errors[i]
+		}
+	}
+	return nil
+}
+
+// RPCError is an <rpc-error> element, implementing error
+type RPCError struct {
+	Type     string  `xml:"error-type"`
+	Tag      string  `xml:"error-tag"`
+	Severity string  `xml:"error-severity"`
+	AppTag   string  `xml:"error-app-tag,omitempty"`
+	Path     string  `xml:"error-path,omitempty"`
+	Message  string  `xml:"error-message,omitempty"`
+	Info     *Inline `xml:"error-info,omitempty"`
+	// PathNS maps the prefixes used in Path to their namespaces, which are
+	// declared on the <error-path> element when encoded
+	PathNS map[string]string `xml:"-"`
+}
+
+func (e *RPCError) Error() string {
+	msg := []string{"rpc-error", e.Type, e.Tag}
+	if e.Path != "" {
+		msg = append(msg, e.Path)
+	}
+	if e.Message != "" {
+		msg = append(msg, e.Message)
+	}
+	return strings.Join(msg, ": ")
+}
+
+// MarshalXML encodes the error as the element start, allowing it to
+// be sent as an <rpc-error> by session.Session.Reject.
+func (e *RPCError) MarshalXML(enc *xml.Encoder, start xml.StartElement) error {
+	type rpcError RPCError
+	if len(e.PathNS) == 0 || e.Path == "" {
+		return enc.EncodeElement((*rpcError)(e), start)
+	}
+	// encode the error path with its namespace declarations
+	type errorPath struct {
+		Attrs []xml.Attr `xml:",any,attr"`
+		Path  string     `xml:",chardata"`
+	}
+	v := struct {
+		Type     string     `xml:"error-type"`
+		Tag      string     `xml:"error-tag"`
+		Severity string     `xml:"error-severity"`
+		AppTag   string     `xml:"error-app-tag,omitempty"`
+		Path     *errorPath `xml:"error-path"`
+		Message  string     `xml:"error-message,omitempty"`
+		Info     *Inline    `xml:"error-info,omitempty"`
+	}{e.Type, e.Tag, e.Severity, e.AppTag, &errorPath{Path: e.Path}, e.Message, e.Info}
+	prefixes := make([]string, 0, len(e.PathNS))
+	for prefix := range e.PathNS {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	for _, prefix := range prefixes {
+		// encoding/xml does not encode attributes in the xmlns namespace
+		v.Path.Attrs = append(v.Path.Attrs, xml.Attr{Name: xml.Name{Local: "xmlns:" + prefix}, Value: e.PathNS[prefix]})
+	}
+	return enc.EncodeElement(&v, start)
+}
+
+// Values for RPCError Type
+const (
+	ErrorTypeTransport   = "transport"
+	ErrorTypeRPC         = "rpc"
+	ErrorTypeProtocol    = "protocol"
+	ErrorTypeApplication = "application"
+)
+
+// Values for RPCError Severity
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Values for RPCError Tag (see RFC6241 Appendix A)
+const (
+	ErrorTagInUse                 = "in-use"
+	ErrorTagInvalidValue          = "invalid-value"
+	ErrorTagTooBig                = "too-big"
+	ErrorTagMissingAttribute      = "missing-attribute"
+	ErrorTagBadAttribute          = "bad-attribute"
+	ErrorTagUnknownAttribute      = "unknown-attribute"
+	ErrorTagMissingElement        = "missing-element"
+	ErrorTagBadElement            = "bad-element"
+	ErrorTagUnknownElement        = "unknown-element"
+	ErrorTagUnknownNamespace      = "unknown-namespace"
+	ErrorTagAccessDenied          = "access-denied"
+	ErrorTagLockDenied            = "lock-denied"
+	ErrorTagResourceDenied        = "resource-denied"
+	ErrorTagRollbackFailed        = "rollback-failed"
+	ErrorTagDataExists            = "data-exists"
+	ErrorTagDataMissing           = "data-missing"
+	ErrorTagOperationNotSupported = "operation-not-supported"
+	ErrorTagOperationFailed       = "operation-failed"
+	ErrorTagMalformedMessage      = "malformed-message"
+)
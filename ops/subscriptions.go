@@ -0,0 +1,89 @@
+package ops
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// SubscriptionsNS is the namespace of the ietf-subscribed-notifications
+// module (RFC8639)
+const SubscriptionsNS = "urn:ietf:params:xml:ns:yang:ietf-subscribed-notifications"
+
+// EncodeXML is the XML encoding of subscribed notifications (RFC8639),
+// the only encoding of NETCONF subscriptions (RFC8640)
+var EncodeXML = Identity{Space: SubscriptionsNS, Local: "encode-xml"}
+
+// StreamFilter is the filter of a dynamic subscription: a reference to a
+// configured filter, a subtree filter or an XPath filter. At most one
+// field is set; an empty StreamFilter selects all events.
+type StreamFilter struct {
+	StreamFilterName    string       `xml:"urn:ietf:params:xml:ns:yang:ietf-subscribed-notifications stream-filter-name,omitempty"`
+	StreamSubtreeFilter *Inline      `xml:"urn:ietf:params:xml:ns:yang:ietf-subscribed-notifications stream-subtree-filter,omitempty"`
+	StreamXPathFilter   *XPathFilter `xml:"urn:ietf:params:xml:ns:yang:ietf-subscribed-notifications stream-xpath-filter,omitempty"`
+}
+
+// EstablishSubscription is the <establish-subscription> operation
+// (RFC8639), establishing a dynamic subscription to an event stream.
+//
+// The reply's output holds the subscription's <id>, along with a
+// <replay-start-time-revision> if replay starts later than requested.
+type EstablishSubscription struct {
+	XMLName xml.Name `xml:"urn:ietf:params:xml:ns:yang:ietf-subscribed-notifications establish-subscription"`
+	Stream  string   `xml:"stream"`
+	StreamFilter
+	// ReplayStartTime, if set, requests the replay of the stream's
+	// events since the start time
+	ReplayStartTime *time.Time `xml:"replay-start-time,omitempty"`
+	StopTime        *time.Time `xml:"stop-time,omitempty"`
+	// Encoding is the encoding requested, EncodeXML if nil
+	Encoding *Identity `xml:"encoding,omitempty"`
+}
+
+// ModifySubscription is the <modify-subscription> operation (RFC8639),
+// replacing the filter and stop time of a dynamic subscription of the
+// session
+type ModifySubscription struct {
+	XMLName xml.Name `xml:"urn:ietf:params:xml:ns:yang:ietf-subscribed-notifications modify-subscription"`
+	ID      uint32   `xml:"id"`
+	StreamFilter
+	StopTime *time.Time `xml:"stop-time,omitempty"`
+}
+
+// DeleteSubscription is the <delete-subscription> operation (RFC8639),
+// ending a dynamic subscription of the session
+type DeleteSubscription struct {
+	XMLName xml.Name `xml:"urn:ietf:params:xml:ns:yang:ietf-subscribed-notifications delete-subscription"`
+	ID      uint32   `xml:"id"`
+}
+
+// KillSubscription is the <kill-subscription> operation (RFC8639),
+// ending a dynamic subscription of any session
+type KillSubscription struct {
+	XMLName xml.Name `xml:"urn:ietf:params:xml:ns:yang:ietf-subscribed-notifications kill-subscription"`
+	ID      uint32   `xml:"id"`
+}
+
+// Errors of the subscription operations (RFC8639), reported as the
+// error-app-tag of their rpc-errors (RFC8640), and reasons for the
+// termination of subscriptions
+const (
+	SubscriptionErrorDSCPUnavailable       = "ietf-subscribed-notifications:dscp-unavailable"
+	SubscriptionErrorEncodingUnsupported   = "ietf-subscribed-notifications:encoding-unsupported"
+	SubscriptionErrorFilterUnavailable     = "ietf-subscribed-notifications:filter-unavailable"
+	SubscriptionErrorFilterUnsupported     = "ietf-subscribed-notifications:filter-unsupported"
+	SubscriptionErrorInsufficientResources = "ietf-subscribed-notifications:insufficient-resources"
+	SubscriptionErrorNoSuchSubscription    = "ietf-subscribed-notifications:no-such-subscription"
+	SubscriptionErrorReplayUnsupported     = "ietf-subscribed-notifications:replay-unsupported"
+	SubscriptionErrorStreamUnavailable     = "ietf-subscribed-notifications:stream-unavailable"
+)
+
+func init() {
+	for name, f := range map[string]func() interface{}{
+		"establish-subscription": func() interface{} { return &EstablishSubscription{} },
+		"modify-subscription":    func() interface{} { return &ModifySubscription{} },
+		"delete-subscription":    func() interface{} { return &DeleteSubscription{} },
+		"kill-subscription":      func() interface{} { return &KillSubscription{} },
+	} {
+		RegisterOperation(xml.Name{Space: SubscriptionsNS, Local: name}, f)
+	}
+}
@@ -0,0 +1,98 @@
+package ops
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Namespaces of the RFC6243 <with-defaults> parameter and the wd:default
+// attribute of report-all-tagged mode
+const (
+	WithDefaultsNS = "urn:ietf:params:xml:ns:yang:ietf-netconf-with-defaults"
+	DefaultNS      = "urn:ietf:params:xml:ns:netconf:default:1.0"
+)
+
+// CapWithDefaults is the :with-defaults capability URI, without parameters
+const CapWithDefaults = "urn:ietf:params:netconf:capability:with-defaults:1.0"
+
+// Values for the WithDefaults field of Get, GetConfig and CopyConfig
+const (
+	WithDefaultsReportAll       = "report-all"
+	WithDefaultsReportAllTagged = "report-all-tagged"
+	WithDefaultsTrim            = "trim"
+	WithDefaultsExplicit        = "explicit"
+)
+
+// WithDefaultsCapability is the :with-defaults capability (RFC6243),
+// advertising a server's basic-mode and any other retrieval modes it
+// supports.
+type WithDefaultsCapability struct {
+	// BasicMode is one of WithDefaultsReportAll, WithDefaultsTrim or
+	// WithDefaultsExplicit
+	BasicMode     string
+	AlsoSupported []string
+}
+
+// String returns the capability's URI, with its parameters
+func (c WithDefaultsCapability) String() string {
+	uri := CapWithDefaults + "?basic-mode=" + c.BasicMode
+	if len(c.AlsoSupported) > 0 {
+		uri += "&also-supported=" + strings.Join(c.AlsoSupported, ",")
+	}
+	return uri
+}
+
+// Supports returns true if mode may be requested of the server
+func (c WithDefaultsCapability) Supports(mode string) bool {
+	if mode == c.BasicMode {
+		return true
+	}
+	for _, m := range c.AlsoSupported {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseWithDefaultsCapability returns the :with-defaults capability in
+// caps (such as a session's State.Capabilities), and false if caps does
+// not include it or its basic-mode parameter is missing.
+func ParseWithDefaultsCapability(caps []string) (WithDefaultsCapability, bool) {
+	for _, uri := range caps {
+		params := ""
+		if i := strings.IndexByte(uri, '?'); i >= 0 {
+			uri, params = uri[:i], uri[i+1:]
+		}
+		if uri != CapWithDefaults {
+			continue
+		}
+		q, err := url.ParseQuery(params)
+		if err != nil || q.Get("basic-mode") == "" {
+			return WithDefaultsCapability{}, false
+		}
+		c := WithDefaultsCapability{BasicMode: q.Get("basic-mode")}
+		if also := q.Get("also-supported"); also != "" {
+			c.AlsoSupported = strings.Split(also, ",")
+		}
+		return c, true
+	}
+	return WithDefaultsCapability{}, false
+}
+
+// WithDefaultsMode returns the first of modes supported by the peer
+// advertising caps, or "" if the peer does not support the
+// :with-defaults capability or any of modes, in which case the
+// <with-defaults> parameter should be omitted.
+func WithDefaultsMode(caps []string, modes ...string) string {
+	c, ok := ParseWithDefaultsCapability(caps)
+	if !ok {
+		return ""
+	}
+	for _, mode := range modes {
+		if c.Supports(mode) {
+			return mode
+		}
+	}
+	return ""
+}
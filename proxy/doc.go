@@ -0,0 +1,18 @@
+/*
+Package proxy provides a NETCONF framing transcoding relay.
+
+A Proxy sits between a downstream peer (typically a NETCONF client
+application) and an upstream peer (typically a device). It negotiates
+a session independently with each side, so a downstream client may use
+:base:1.1 chunked framing while the upstream device only supports
+:base:1.0 end-of-message framing, or vice versa.
+
+Once both sessions are established, messages are relayed in both
+directions while streaming, re-framing each message for the
+destination session's framing mode and preserving message boundaries.
+
+The upstream session is established first, so that the downstream
+<hello> can carry the upstream peer's session-id and (rewritten)
+capabilities.
+*/
+package proxy
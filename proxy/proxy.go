@@ -0,0 +1,141 @@
+package proxy
+
+import (
+	"io"
+	"sync"
+
+	"github.com/andaru/netconf/session"
+)
+
+// Config contains Proxy configuration
+type Config struct {
+	// Upstream holds the capabilities sent to the upstream peer.
+	// If empty, both :base:1.0 and :base:1.1 are offered.
+	Upstream session.Capabilities
+	// Downstream holds the framing capabilities offered to the downstream
+	// peer, in addition to the upstream peer's non-framing capabilities.
+	// If empty, both :base:1.0 and :base:1.1 are offered.
+	Downstream session.Capabilities
+	// Rewrite, if non-nil, is called with the capabilities to be sent
+	// to the downstream peer, and returns the capabilities to send instead.
+	Rewrite func(session.Capabilities) session.Capabilities
+}
+
+// Proxy is a NETCONF relay between a downstream and an upstream peer
+type Proxy struct {
+	Config Config
+
+	// Up is the upstream (client) session, and Down is the downstream
+	// (server) session. Down is nil until the upstream session is established.
+	Up   *session.Session
+	Down *session.Session
+
+	downR io.Reader
+	downW io.WriteCloser
+	upW   io.WriteCloser
+}
+
+// New returns a new Proxy relaying between the downstream peer (reading
+// from downR and writing to downW) and the upstream peer (reading from upR
+// and writing to upW).
+func New(downR io.Reader, downW io.WriteCloser, upR io.Reader, upW io.WriteCloser, config Config) *Proxy {
+	if len(config.Upstream) == 0 {
+		config.Upstream = session.Capabilities{capBase10, capBase11}
+	}
+	if len(config.Downstream) == 0 {
+		config.Downstream = session.Capabilities{capBase10, capBase11}
+	}
+	return &Proxy{
+		Config: config,
+		Up:     session.New(upR, upW, session.Config{Capabilities: config.Upstream}),
+		downR:  downR,
+		downW:  downW,
+		upW:    upW,
+	}
+}
+
+// Run establishes both sessions and relays messages between them until
+// either peer's stream ends, then closes both sessions.
+//
+// Returns false if either session failed to establish or a relay error
+// occurred, in which case the session's Errors will be non-nil.
+func (p *Proxy) Run() (ok bool) {
+	if !p.Up.InitialHandshake() {
+		p.Up.Close()
+		p.downW.Close()
+		return false
+	}
+	p.Down = session.New(p.downR, p.downW, session.Config{
+		ID:           p.Up.State.ID,
+		Capabilities: p.downstreamCapabilities(),
+	})
+	if !p.Down.InitialHandshake() {
+		p.Down.Close()
+		p.Up.Close()
+		return false
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		relay(p.Up, p.Down)
+		p.upW.Close()
+	}()
+	go func() {
+		defer wg.Done()
+		relay(p.Down, p.Up)
+		p.downW.Close()
+	}()
+	wg.Wait()
+
+	ok = len(p.Up.Errors()) == 0 && len(p.Down.Errors()) == 0
+	for _, s := range []*session.Session{p.Up, p.Down} {
+		if s.State.Status == session.StatusEstablished {
+			s.SetStatus(session.StatusClosed)
+		}
+	}
+	return ok
+}
+
+// downstreamCapabilities returns the capabilities to send to the
+// downstream peer, being the configured downstream framing capabilities
+// and the upstream peer's non-framing capabilities.
+func (p *Proxy) downstreamCapabilities() session.Capabilities {
+	caps := append(session.Capabilities{}, p.Config.Downstream...)
+	for _, c := range p.Up.State.Capabilities {
+		if !isFraming(c) && !caps.Has(c) {
+			caps = append(caps, c)
+		}
+	}
+	if p.Config.Rewrite != nil {
+		caps = p.Config.Rewrite(caps)
+	}
+	return caps
+}
+
+// relay copies each message read from src to dst, until src's stream
+// ends. Errors are recorded on src, the session read by the caller.
+func relay(dst, src *session.Session) {
+	for {
+		_, err := io.Copy(dst.Outgoing(), src.Incoming())
+		if cerr := dst.Outgoing().Close(); err == nil {
+			err = cerr
+		}
+		switch {
+		case err == session.ErrEndOfStream:
+			return
+		case err != nil:
+			src.AddError(err)
+			src.SetStatus(session.StatusError)
+			return
+		}
+	}
+}
+
+func isFraming(uri string) bool { return uri == capBase10 || uri == capBase11 }
+
+const (
+	capBase10 = "urn:ietf:params:netconf:base:1.0"
+	capBase11 = "urn:ietf:params:netconf:base:1.1"
+)
@@ -0,0 +1,142 @@
+package proxy
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/andaru/netconf/session"
+	"github.com/stretchr/testify/assert"
+)
+
+// echoDevice is a session handler replying to each message with an <rpc-reply>
+type echoDevice struct{ rpcs []string }
+
+func (d *echoDevice) OnEstablish(s *session.Session) {}
+func (d *echoDevice) OnMessage(s *session.Session) {
+	b, err := io.ReadAll(s.Incoming())
+	if err != nil {
+		s.State.Status = session.StatusClosed
+		return
+	}
+	d.rpcs = append(d.rpcs, string(b))
+	s.Outgoing().Write([]byte(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><ok/></rpc-reply>`))
+	s.Outgoing().Close()
+}
+func (d *echoDevice) OnError(s *session.Session) {}
+func (d *echoDevice) OnClose(s *session.Session) {}
+
+// tapWriter records all bytes written to the underlying io.WriteCloser
+type tapWriter struct {
+	io.WriteCloser
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (t *tapWriter) Write(b []byte) (int, error) {
+	t.mu.Lock()
+	t.buf.Write(b)
+	t.mu.Unlock()
+	return t.WriteCloser.Write(b)
+}
+
+func (t *tapWriter) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.buf.String()
+}
+
+func pipe(t *testing.T) (*os.File, *os.File) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r, w
+}
+
+func TestProxy(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		deviceCaps  session.Capabilities
+		clientCaps  session.Capabilities
+		config      Config
+		wantDevice  string
+		wantClient  string
+		wantClientC string
+	}{
+		{
+			name:       "1.1 client to 1.0 device",
+			deviceCaps: session.Capabilities{capBase10, "urn:example:device"},
+			clientCaps: session.Capabilities{capBase10, capBase11},
+			wantDevice: "]]>]]>",
+			wantClient: "\n##\n",
+		},
+		{
+			name:       "1.0 client to 1.1 device",
+			deviceCaps: session.Capabilities{capBase11, "urn:example:device"},
+			clientCaps: session.Capabilities{capBase10},
+			wantDevice: "\n##\n",
+			wantClient: "]]>]]>",
+		},
+		{
+			name:        "rewritten capabilities",
+			deviceCaps:  session.Capabilities{capBase10, "urn:example:device"},
+			clientCaps:  session.Capabilities{capBase11},
+			config:      Config{Rewrite: func(c session.Capabilities) session.Capabilities { return append(c, "urn:example:proxy") }},
+			wantDevice:  "]]>]]>",
+			wantClient:  "\n##\n",
+			wantClientC: "urn:example:proxy",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			a := assert.New(t)
+			// device <-> proxy upstream
+			devR, upW := pipe(t)
+			upR, devW := pipe(t)
+			// proxy downstream <-> client
+			cliR, downW := pipe(t)
+			downR, cliW := pipe(t)
+
+			devTap := &tapWriter{WriteCloser: upW}
+			cliTap := &tapWriter{WriteCloser: downW}
+			p := New(downR, cliTap, upR, devTap, tc.config)
+
+			device := &echoDevice{}
+			devSession := session.New(devR, devW, session.Config{ID: 42, Capabilities: tc.deviceCaps})
+			var wg sync.WaitGroup
+			wg.Add(2)
+			go func() { defer wg.Done(); devSession.Run(device) }()
+			var proxyOK bool
+			go func() { defer wg.Done(); proxyOK = p.Run() }()
+
+			client := session.New(cliR, cliW, session.Config{Capabilities: tc.clientCaps})
+			if !a.True(client.InitialHandshake(), "%v", client.Errors()) {
+				return
+			}
+			a.Equal(uint32(42), client.State.ID)
+			a.True(client.State.Capabilities.Has("urn:example:device"))
+			if tc.wantClientC != "" {
+				a.True(client.State.Capabilities.Has(tc.wantClientC))
+			}
+			for _, rpc := range []string{"one", "two", "three"} {
+				req := `<rpc xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><` + rpc + `/></rpc>`
+				client.Outgoing().Write([]byte(req))
+				a.NoError(client.Outgoing().Close())
+				reply, err := io.ReadAll(client.Incoming())
+				a.NoError(err)
+				a.Contains(string(reply), "<ok/>")
+			}
+			client.Close()
+			wg.Wait()
+
+			a.True(proxyOK, "up=%v down=%v", p.Up.Errors(), p.Down.Errors())
+			a.Len(device.rpcs, 3)
+			a.True(strings.HasSuffix(device.rpcs[2], "<three/></rpc>"))
+			a.Contains(devTap.String(), tc.wantDevice)
+			a.Contains(cliTap.String(), tc.wantClient)
+		})
+	}
+}
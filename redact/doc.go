@@ -0,0 +1,26 @@
+/*
+Package redact removes secrets from NETCONF message XML streams.
+
+A Redactor is configured with a set of element names or simple XPath
+location paths identifying secret elements, such as passwords and keys:
+
+	r, err := redact.New(
+		"{urn:ietf:params:xml:ns:yang:ietf-system}password",
+		"//keystore/asymmetric-keys/asymmetric-key/cleartext-private-key",
+	)
+
+The content of each matching element is replaced with a placeholder, while
+the remainder of the document is passed through verbatim. Redaction is
+performed as data streams through the Redactor, buffering no more than a
+single tag (or CDATA section) at a time, so it may wrap a session's
+Incoming and Outgoing messages for logging, tracing or capture:
+
+	msg := r.Reader(s.Incoming())
+	log := r.Writer(os.Stderr)
+
+Use Bytes to redact a complete message, e.g., as session.Config.LogRedact.
+
+Redaction applies to message content, as read from and written to a
+session's messages, not to framed transport traffic.
+*/
+package redact
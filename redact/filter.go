@@ -0,0 +1,233 @@
+package redact
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+)
+
+// filter is the redaction state of a stream
+type filter struct {
+	r *Redactor
+	// markup holds the markup (e.g., tag) being read, or nil in text
+	markup []byte
+	quote  byte
+	// elems holds the names of the open elements, and scopes
+	// their namespace declarations
+	elems  []xml.Name
+	scopes []map[string]string
+	// depth is the element depth within a redacted element,
+	// being zero when not redacting
+	depth int
+	// pending is set if the redacted element's placeholder is unwritten
+	pending bool
+}
+
+var (
+	commentStart = []byte("<!--")
+	cdataStart   = []byte("<![CDATA[")
+	piStart      = []byte("<?")
+	declStart    = []byte("<!")
+)
+
+// process writes the redacted form of b to out
+func (f *filter) process(b []byte, out *bytes.Buffer) {
+	for len(b) > 0 {
+		if f.markup == nil {
+			i := bytes.IndexByte(b, '<')
+			if i < 0 {
+				f.text(b, out)
+				return
+			}
+			f.text(b[:i], out)
+			b = b[i:]
+			f.markup = make([]byte, 0, 64)
+		}
+		for len(b) > 0 {
+			c := b[0]
+			b = b[1:]
+			f.markup = append(f.markup, c)
+			if f.complete(c) {
+				f.element(out)
+				f.markup, f.quote = nil, 0
+				break
+			}
+		}
+	}
+}
+
+// flush writes any incomplete markup at the end of the stream
+func (f *filter) flush(out *bytes.Buffer) {
+	if f.markup != nil {
+		f.text(f.markup, out)
+		f.markup = nil
+	}
+}
+
+// complete returns true if the markup is complete after reading c
+func (f *filter) complete(c byte) bool {
+	m := f.markup
+	switch {
+	case len(m) < len(commentStart) && bytes.HasPrefix(commentStart, m),
+		len(m) < len(cdataStart) && bytes.HasPrefix(cdataStart, m):
+		// undecided between comment, CDATA and other declarations
+		return false
+	case bytes.HasPrefix(m, commentStart):
+		return len(m) >= 7 && bytes.HasSuffix(m, []byte("-->"))
+	case bytes.HasPrefix(m, cdataStart):
+		return len(m) >= 12 && bytes.HasSuffix(m, []byte("]]>"))
+	case bytes.HasPrefix(m, piStart):
+		return len(m) >= 4 && bytes.HasSuffix(m, []byte("?>"))
+	case bytes.HasPrefix(m, declStart):
+		return c == '>'
+	}
+	// element tag, in which '>' may be quoted in attribute values
+	switch {
+	case f.quote != 0:
+		if c == f.quote {
+			f.quote = 0
+		}
+	case c == '"' || c == '\'':
+		f.quote = c
+	case c == '>':
+		return true
+	}
+	return false
+}
+
+// text handles character data b
+func (f *filter) text(b []byte, out *bytes.Buffer) {
+	if len(b) == 0 {
+		return
+	}
+	if f.depth > 0 {
+		f.placeholder(out)
+		return
+	}
+	out.Write(b)
+}
+
+// placeholder writes the placeholder for the redacted element, once
+func (f *filter) placeholder(out *bytes.Buffer) {
+	if f.pending {
+		f.pending = false
+		_ = xml.EscapeText(out, []byte(f.r.Placeholder))
+	}
+}
+
+// element handles the complete markup
+func (f *filter) element(out *bytes.Buffer) {
+	m := f.markup
+	isTag := len(m) > 1 && m[1] != '!' && m[1] != '?'
+	switch {
+	case !isTag:
+		// comments, CDATA, processing instructions and declarations
+		f.text(m, out)
+	case m[1] == '/':
+		// end tag
+		if f.depth > 0 {
+			if f.depth--; f.depth > 0 {
+				return
+			}
+		}
+		f.pop()
+		out.Write(m)
+	case f.depth > 0:
+		// start tag within a redacted element
+		f.placeholder(out)
+		if !bytes.HasSuffix(m, []byte("/>")) {
+			f.depth++
+		}
+	default:
+		out.Write(m)
+		empty := bytes.HasSuffix(m, []byte("/>"))
+		f.push(m)
+		if f.r.matches(f.elems) && !empty {
+			f.depth, f.pending = 1, true
+		}
+		if empty {
+			f.pop()
+		}
+	}
+}
+
+// push pushes the element of start tag m
+func (f *filter) push(m []byte) {
+	name, attrs := parseTag(string(bytes.TrimSuffix(m[1:len(m)-1], []byte("/"))))
+	var scope map[string]string
+	for _, a := range attrs {
+		switch {
+		case a[0] == "xmlns":
+			scope = bind(scope, "", a[1])
+		case strings.HasPrefix(a[0], "xmlns:"):
+			scope = bind(scope, a[0][6:], a[1])
+		}
+	}
+	f.scopes = append(f.scopes, scope)
+	prefix, local := "", name
+	if i := strings.IndexByte(name, ':'); i >= 0 {
+		prefix, local = name[:i], name[i+1:]
+	}
+	space, ok := f.lookup(prefix)
+	if !ok && prefix != "" {
+		// as per encoding/xml, unbound prefixes are used as the namespace
+		space = prefix
+	}
+	f.elems = append(f.elems, xml.Name{Space: space, Local: local})
+}
+
+func (f *filter) pop() {
+	if n := len(f.elems); n > 0 {
+		f.elems, f.scopes = f.elems[:n-1], f.scopes[:n-1]
+	}
+}
+
+func bind(scope map[string]string, prefix, space string) map[string]string {
+	if scope == nil {
+		scope = map[string]string{}
+	}
+	scope[prefix] = space
+	return scope
+}
+
+// lookup returns the namespace bound to prefix in the current scope
+func (f *filter) lookup(prefix string) (string, bool) {
+	for i := len(f.scopes) - 1; i >= 0; i-- {
+		if space, ok := f.scopes[i][prefix]; ok {
+			return space, true
+		}
+	}
+	return "", false
+}
+
+// parseTag returns the element name and attributes of the start tag s
+// (without its angle brackets), tolerating malformed attributes.
+func parseTag(s string) (name string, attrs [][2]string) {
+	end := strings.IndexAny(s, " \t\r\n")
+	if end < 0 {
+		return s, nil
+	}
+	name, s = s[:end], s[end:]
+	for {
+		s = strings.TrimLeft(s, " \t\r\n")
+		eq := strings.IndexByte(s, '=')
+		if eq < 0 {
+			return
+		}
+		attr := strings.TrimSpace(s[:eq])
+		s = strings.TrimLeft(s[eq+1:], " \t\r\n")
+		if s == "" || (s[0] != '"' && s[0] != '\'') {
+			return
+		}
+		close := strings.IndexByte(s[1:], s[0])
+		if close < 0 {
+			return
+		}
+		attrs = append(attrs, [2]string{attr, unescape(s[1 : close+1])})
+		s = s[close+2:]
+	}
+}
+
+var unescaper = strings.NewReplacer("&lt;", "<", "&gt;", ">", "&amp;", "&", "&apos;", "'", "&quot;", `"`)
+
+func unescape(s string) string { return unescaper.Replace(s) }
@@ -0,0 +1,110 @@
+package redact
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// path is a location path of element name steps
+type path []step
+
+// step is a single location path step
+type step struct {
+	// descendant is set for steps following "//"
+	descendant bool
+	// qualified is set if space must match the element's namespace
+	qualified bool
+	space     string
+	// local is the local name to match, or "*" to match any
+	local string
+}
+
+func (st step) match(n xml.Name) bool {
+	return (st.local == "*" || st.local == n.Local) && (!st.qualified || st.space == n.Space)
+}
+
+// parsePath parses expr, being either an element name, or a location
+// path of child ("/") and descendant ("//") steps. Names are given in
+// the form {namespace}local, or as local (matching any namespace), or
+// as "*" (matching any element). Relative paths and bare names match
+// at any depth.
+func parsePath(expr string) (path, error) {
+	var p path
+	rest := strings.TrimSpace(expr)
+	descendant := true
+	switch {
+	case strings.HasPrefix(rest, "//"):
+		rest = rest[2:]
+	case strings.HasPrefix(rest, "/"):
+		rest, descendant = rest[1:], false
+	}
+	for {
+		name, tail, err := splitStep(rest)
+		if err != nil {
+			return nil, fmt.Errorf("redact: invalid path %q: %v", expr, err)
+		}
+		st := step{descendant: descendant, local: name}
+		if strings.HasPrefix(name, "{") {
+			end := strings.IndexByte(name, '}')
+			st.qualified, st.space, st.local = true, name[1:end], name[end+1:]
+		}
+		if st.local == "" || strings.ContainsAny(st.local, "{}:[]()@") {
+			return nil, fmt.Errorf("redact: invalid path %q: invalid name %q", expr, name)
+		}
+		p = append(p, st)
+		switch {
+		case tail == "":
+			return p, nil
+		case strings.HasPrefix(tail, "//"):
+			rest, descendant = tail[2:], true
+		default:
+			rest, descendant = tail[1:], false
+		}
+	}
+}
+
+// splitStep returns the name step at the start of s, and the remainder
+// of s (being empty, or beginning with "/").
+func splitStep(s string) (name, tail string, err error) {
+	i := 0
+	if strings.HasPrefix(s, "{") {
+		end := strings.IndexByte(s, '}')
+		if end < 0 {
+			return "", "", fmt.Errorf("unterminated namespace")
+		}
+		i = end + 1
+	}
+	if slash := strings.IndexByte(s[i:], '/'); slash >= 0 {
+		i += slash
+	} else {
+		i = len(s)
+	}
+	if i == 0 {
+		return "", "", fmt.Errorf("empty step")
+	}
+	return s[:i], s[i:], nil
+}
+
+// matches returns true if p matches the element at the end of elems,
+// being the names of the element and its ancestors, from the root.
+func (p path) matches(elems []xml.Name) bool { return p.match(0, elems) }
+
+func (p path) match(i int, elems []xml.Name) bool {
+	if i == len(p) {
+		return len(elems) == 0
+	}
+	if len(elems) == 0 {
+		return false
+	}
+	st := p[i]
+	if !st.descendant {
+		return st.match(elems[0]) && p.match(i+1, elems[1:])
+	}
+	for k := range elems {
+		if st.match(elems[k]) && p.match(i+1, elems[k+1:]) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package redact
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+)
+
+// DefaultPlaceholder is the default text replacing redacted content
+const DefaultPlaceholder = "[redacted]"
+
+// Redactor replaces the content of secret elements in XML documents.
+// Redactors are safe for concurrent use, while the readers and writers
+// they return are not.
+type Redactor struct {
+	// Placeholder replaces the content of each redacted element,
+	// and is escaped as XML character data when written.
+	Placeholder string
+
+	paths []path
+}
+
+// New returns a new Redactor, redacting the content of elements matching
+// any of the expressions exprs, each being either an element name in the
+// form {namespace}local (or local, in any namespace), or a location path
+// of such names (or "*", matching any element), separated by child ("/")
+// or descendant ("//") steps, e.g., "/rpc/edit-config//{urn:example}key".
+// Paths not beginning with "/" (including bare names) match at any depth.
+func New(exprs ...string) (*Redactor, error) {
+	r := &Redactor{Placeholder: DefaultPlaceholder}
+	for _, expr := range exprs {
+		p, err := parsePath(expr)
+		if err != nil {
+			return nil, err
+		}
+		r.paths = append(r.paths, p)
+	}
+	return r, nil
+}
+
+// Writer returns an io.WriteCloser writing documents written to it to w,
+// with secrets redacted. Closing the writer closes w, if w is an io.Closer.
+func (r *Redactor) Writer(w io.Writer) io.WriteCloser { return &writer{f: filter{r: r}, w: w} }
+
+// Reader returns an io.Reader reading documents from src, with secrets redacted
+func (r *Redactor) Reader(src io.Reader) io.Reader {
+	return &reader{f: filter{r: r}, src: src, buf: make([]byte, 4096)}
+}
+
+// Bytes returns the document b with secrets redacted
+func (r *Redactor) Bytes(b []byte) []byte {
+	f := filter{r: r}
+	var out bytes.Buffer
+	f.process(b, &out)
+	f.flush(&out)
+	return out.Bytes()
+}
+
+func (r *Redactor) matches(elems []xml.Name) bool {
+	for _, p := range r.paths {
+		if p.matches(elems) {
+			return true
+		}
+	}
+	return false
+}
+
+type writer struct {
+	f   filter
+	w   io.Writer
+	out bytes.Buffer
+}
+
+func (w *writer) Write(b []byte) (int, error) {
+	w.f.process(b, &w.out)
+	return len(b), w.drain()
+}
+
+func (w *writer) Close() error {
+	w.f.flush(&w.out)
+	err := w.drain()
+	if c, ok := w.w.(io.Closer); ok {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+func (w *writer) drain() error {
+	if w.out.Len() == 0 {
+		return nil
+	}
+	_, err := w.w.Write(w.out.Bytes())
+	w.out.Reset()
+	return err
+}
+
+type reader struct {
+	f   filter
+	src io.Reader
+	buf []byte
+	out bytes.Buffer
+	err error
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	for r.out.Len() == 0 && r.err == nil {
+		var n int
+		n, r.err = r.src.Read(r.buf)
+		r.f.process(r.buf[:n], &r.out)
+		if r.err != nil {
+			r.f.flush(&r.out)
+		}
+	}
+	if r.out.Len() > 0 {
+		return r.out.Read(p)
+	}
+	return 0, r.err
+}
@@ -0,0 +1,106 @@
+package redact
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const sysNS = "urn:ietf:params:xml:ns:yang:ietf-system"
+
+func TestRedact(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		exprs []string
+		in    string
+		want  string
+	}{
+		{
+			name:  "qualified name",
+			exprs: []string{"{" + sysNS + "}password"},
+			in: `<rpc xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="1"><edit-config><config>` +
+				`<system xmlns="` + sysNS + `"><authentication><user><name>joe</name><password>$0$s3cr3t</password></user>` +
+				`</authentication></system><password xmlns="urn:other">public</password></config></edit-config></rpc>`,
+			want: `<rpc xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="1"><edit-config><config>` +
+				`<system xmlns="` + sysNS + `"><authentication><user><name>joe</name><password>[redacted]</password></user>` +
+				`</authentication></system><password xmlns="urn:other">public</password></config></edit-config></rpc>`,
+		},
+		{
+			name:  "prefixed element",
+			exprs: []string{"{" + sysNS + "}password"},
+			in:    `<x xmlns:sys="` + sysNS + `"><sys:password a='>'>secret</sys:password><sys:password/></x>`,
+			want:  `<x xmlns:sys="` + sysNS + `"><sys:password a='>'>[redacted]</sys:password><sys:password/></x>`,
+		},
+		{
+			name:  "unqualified name in any namespace",
+			exprs: []string{"key"},
+			in:    `<a xmlns="urn:a"><key>k1</key><b xmlns="urn:b"><key>k2</key></b></a>`,
+			want:  `<a xmlns="urn:a"><key>[redacted]</key><b xmlns="urn:b"><key>[redacted]</key></b></a>`,
+		},
+		{
+			name:  "absolute path",
+			exprs: []string{"/a/key"},
+			in:    `<a><key>k1</key><b><key>k2</key></b></a>`,
+			want:  `<a><key>[redacted]</key><b><key>k2</key></b></a>`,
+		},
+		{
+			name:  "descendant path with wildcard",
+			exprs: []string{"/a//*/{urn:k}key"},
+			in:    `<a><key xmlns="urn:k">k1</key><b><c><key xmlns="urn:k">k2</key></c></b></a>`,
+			want:  `<a><key xmlns="urn:k">k1</key><b><c><key xmlns="urn:k">[redacted]</key></c></b></a>`,
+		},
+		{
+			name:  "nested content, comments and cdata",
+			exprs: []string{"secret"},
+			in:    "<?xml version=\"1.0\"?><!-- <secret> --><a><secret>\n <k><![CDATA[</secret>]]></k><!-- x --></secret><c><![CDATA[<ok>]]></c></a>",
+			want:  "<?xml version=\"1.0\"?><!-- <secret> --><a><secret>[redacted]</secret><c><![CDATA[<ok>]]></c></a>",
+		},
+		{
+			name:  "empty element",
+			exprs: []string{"secret"},
+			in:    `<a><secret></secret></a>`,
+			want:  `<a><secret></secret></a>`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			a := assert.New(t)
+			r, err := New(tc.exprs...)
+			if !a.NoError(err) {
+				return
+			}
+			a.Equal(tc.want, string(r.Bytes([]byte(tc.in))))
+
+			// streaming a byte at a time produces the same result
+			got, err := io.ReadAll(r.Reader(iotest.OneByteReader(strings.NewReader(tc.in))))
+			a.NoError(err)
+			a.Equal(tc.want, string(got))
+
+			out := &bytes.Buffer{}
+			w := r.Writer(out)
+			for i := range tc.in {
+				_, err = w.Write([]byte{tc.in[i]})
+				a.NoError(err)
+			}
+			a.NoError(w.Close())
+			a.Equal(tc.want, out.String())
+		})
+	}
+}
+
+func TestRedactPlaceholder(t *testing.T) {
+	r, err := New("pw")
+	assert.NoError(t, err)
+	r.Placeholder = "<hidden>"
+	assert.Equal(t, `<pw>&lt;hidden&gt;</pw>`, string(r.Bytes([]byte(`<pw>x</pw>`))))
+}
+
+func TestNewInvalid(t *testing.T) {
+	for _, expr := range []string{"", "/", "a//", "{urn:x", "p:local", "a/b[1]"} {
+		_, err := New(expr)
+		assert.Error(t, err, expr)
+	}
+}
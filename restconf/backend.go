@@ -0,0 +1,133 @@
+package restconf
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"strconv"
+	"sync"
+
+	"github.com/andaru/netconf/ops"
+	"github.com/andaru/netconf/session"
+)
+
+// Capabilities selecting the datastore edited
+const (
+	capWritableRunning = "urn:ietf:params:netconf:capability:writable-running:1.0"
+	capCandidate       = "urn:ietf:params:netconf:capability:candidate:1.0"
+)
+
+// errClosed is returned for requests once the backend session has ended
+var errClosed = errors.New("backend session closed")
+
+// backend sends RPCs on a NETCONF client session, one at a time
+type backend struct {
+	s    *session.Session
+	msgs <-chan session.Message
+	// caps holds the peer's capabilities, read before the session's
+	// State is owned by its Messages goroutine
+	caps session.Capabilities
+
+	mu sync.Mutex
+	id uint64
+}
+
+func newBackend(s *session.Session) *backend {
+	caps := append(session.Capabilities(nil), s.State.Capabilities...)
+	return &backend{s: s, caps: caps, msgs: s.Messages(context.Background())}
+}
+
+// call sends the operation op in an <rpc>, and returns the reply, along
+// with its raw message. Messages other than the reply (such as
+// notifications, and the replies of earlier cancelled calls) are skipped.
+// Replies containing errors are returned with a nil error.
+func (b *backend) call(ctx context.Context, op interface{}) (*ops.RPCReply, []byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.id++
+	id := strconv.FormatUint(b.id, 10)
+	var req bytes.Buffer
+	if err := xml.NewEncoder(&req).Encode(&ops.RPC{MessageID: id, Operation: op}); err != nil {
+		return nil, nil, err
+	}
+	if err := b.s.Send(ctx, &req); err != nil {
+		return nil, nil, err
+	}
+	for {
+		select {
+		case m, ok := <-b.msgs:
+			if !ok {
+				return nil, nil, errClosed
+			}
+			if m.Err != nil {
+				return nil, nil, m.Err
+			}
+			reply, err := ops.DecodeReply(bytes.NewReader(m.Data))
+			if err != nil || reply.MessageID != id {
+				continue
+			}
+			return reply, m.Data, nil
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+	}
+}
+
+// edit applies the configuration content to the running datastore, via
+// the candidate datastore and a commit if running is not writable. The
+// running and candidate datastores are locked for the edit and commit,
+// such that the changes of other sessions are not committed or discarded.
+func (b *backend) edit(ctx context.Context, content []byte, defaultOperation string) (reply *ops.RPCReply, err error) {
+	candidate := !b.caps.Has(capWritableRunning) && b.caps.Has(capCandidate)
+	if !candidate {
+		reply, _, err = b.call(ctx, &ops.EditConfig{
+			Target:           ops.Running,
+			DefaultOperation: defaultOperation,
+			Config:           &ops.Inline{Content: content},
+		})
+		return reply, err
+	}
+	for i, target := range []ops.Datastore{ops.Running, ops.Candidate} {
+		if reply, _, err = b.call(ctx, &ops.Lock{Target: target}); err != nil || reply.Err() != nil {
+			b.unlock(i)
+			return reply, err
+		}
+	}
+	defer func() {
+		if uerr := b.unlock(2); err == nil {
+			err = uerr
+		}
+	}()
+	reply, _, err = b.call(ctx, &ops.EditConfig{
+		Target:           ops.Candidate,
+		DefaultOperation: defaultOperation,
+		Config:           &ops.Inline{Content: content},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reply.Err() == nil {
+		reply, _, err = b.call(ctx, &ops.Commit{})
+		if err != nil || reply.Err() == nil {
+			return reply, err
+		}
+	}
+	// discard the failed changes, reporting the original error
+	if _, _, err := b.call(ctx, &ops.DiscardChanges{}); err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+// unlock releases the first n of the locks taken by edit, in reverse
+// order. The locks are released even once the request's context is done.
+func (b *backend) unlock(n int) error {
+	var err error
+	for _, target := range []ops.Datastore{ops.Candidate, ops.Running}[2-n:] {
+		if _, _, uerr := b.call(context.Background(), &ops.Unlock{Target: target}); err == nil {
+			err = uerr
+		}
+	}
+	return err
+}
@@ -0,0 +1,17 @@
+/*
+Package restconf provides a RESTCONF (RFC8040) gateway, serving the
+RESTCONF API over HTTP by NETCONF operations on a backend session.
+
+New returns an http.Handler using an established client session, and the
+YANG schema of the backend's data:
+
+	h := restconf.New(s, schema)
+	http.ListenAndServe(addr, h)
+
+Data resources are addressed by RFC8040 api-paths, such as
+/restconf/data/example-sys:system/server=s1, and encoded as XML or JSON
+per the request's Accept and Content-Type headers. Edits are validated
+against the schema before being sent to the backend, and rpc-errors are
+returned as ietf-restconf errors, with their HTTP status codes.
+*/
+package restconf
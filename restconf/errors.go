@@ -0,0 +1,113 @@
+package restconf
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/andaru/netconf/ops"
+)
+
+// statuses maps rpc-error error-tags to HTTP status codes (RFC8040 section 7)
+var statuses = map[string]int{
+	ops.ErrorTagInUse:                 http.StatusConflict,
+	ops.ErrorTagInvalidValue:          http.StatusBadRequest,
+	ops.ErrorTagTooBig:                http.StatusRequestEntityTooLarge,
+	ops.ErrorTagMissingAttribute:      http.StatusBadRequest,
+	ops.ErrorTagBadAttribute:          http.StatusBadRequest,
+	ops.ErrorTagUnknownAttribute:      http.StatusBadRequest,
+	ops.ErrorTagMissingElement:        http.StatusBadRequest,
+	ops.ErrorTagBadElement:            http.StatusBadRequest,
+	ops.ErrorTagUnknownElement:        http.StatusBadRequest,
+	ops.ErrorTagUnknownNamespace:      http.StatusBadRequest,
+	ops.ErrorTagAccessDenied:          http.StatusForbidden,
+	ops.ErrorTagLockDenied:            http.StatusConflict,
+	ops.ErrorTagResourceDenied:        http.StatusConflict,
+	ops.ErrorTagRollbackFailed:        http.StatusInternalServerError,
+	ops.ErrorTagDataExists:            http.StatusConflict,
+	ops.ErrorTagDataMissing:           http.StatusConflict,
+	ops.ErrorTagOperationNotSupported: http.StatusNotImplemented,
+	ops.ErrorTagOperationFailed:       http.StatusInternalServerError,
+	ops.ErrorTagMalformedMessage:      http.StatusBadRequest,
+}
+
+// status returns the HTTP status code for the errors, from the first
+// error-severity error's tag
+func status(errs []ops.RPCError) int {
+	for _, e := range errs {
+		if e.Severity != ops.SeverityWarning {
+			if code, ok := statuses[e.Tag]; ok {
+				return code
+			}
+			break
+		}
+	}
+	return http.StatusInternalServerError
+}
+
+// newError returns an error-severity rpc-error
+func newError(typ, tag, msg string) ops.RPCError {
+	return ops.RPCError{Type: typ, Tag: tag, Severity: ops.SeverityError, Message: msg}
+}
+
+// writeErrors writes the errors as an ietf-restconf errors container,
+// with the HTTP status code
+func (rs *response) writeErrors(code int, errs ...ops.RPCError) {
+	var b bytes.Buffer
+	if rs.json {
+		type jsonError struct {
+			Type    string `json:"error-type"`
+			Tag     string `json:"error-tag"`
+			AppTag  string `json:"error-app-tag,omitempty"`
+			Path    string `json:"error-path,omitempty"`
+			Message string `json:"error-message,omitempty"`
+			Info    string `json:"error-info,omitempty"`
+		}
+		var v struct {
+			Errors struct {
+				Error []jsonError `json:"error"`
+			} `json:"ietf-restconf:errors"`
+		}
+		for _, e := range errs {
+			je := jsonError{Type: e.Type, Tag: e.Tag, AppTag: e.AppTag, Path: rs.jsonPath(e), Message: e.Message}
+			if e.Info != nil {
+				// error-info content is anydata; its XML is passed as a string
+				je.Info = string(bytes.TrimSpace(e.Info.Content))
+			}
+			v.Errors.Error = append(v.Errors.Error, je)
+		}
+		enc := json.NewEncoder(&b)
+		enc.SetEscapeHTML(false)
+		enc.Encode(&v)
+	} else {
+		b.WriteString(`<errors xmlns="` + NS + `">`)
+		enc := xml.NewEncoder(&b)
+		for i := range errs {
+			enc.EncodeElement(&errs[i], xml.StartElement{Name: xml.Name{Local: "error"}})
+		}
+		enc.Flush()
+		b.WriteString(`</errors>`)
+	}
+	rs.write(code, b.Bytes())
+}
+
+// jsonPath returns the error-path of e, with the prefixes declared by
+// e.PathNS replaced by module names, as required by RFC7951
+func (rs *response) jsonPath(e ops.RPCError) string {
+	if len(e.PathNS) == 0 || rs.schema == nil {
+		return e.Path
+	}
+	return pathPrefix.ReplaceAllStringFunc(e.Path, func(m string) string {
+		prefix := strings.TrimRight(m[1:], ":")
+		if mod := rs.schema.ModuleByNamespace(e.PathNS[prefix]); mod != nil {
+			return m[:1] + mod.Name + ":"
+		}
+		return m
+	})
+}
+
+// pathPrefix matches the prefixes of an error-path's node names
+var pathPrefix = regexp.MustCompile(`[/\[][A-Za-z_][A-Za-z0-9_.-]*:`)
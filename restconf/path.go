@@ -0,0 +1,166 @@
+package restconf
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/andaru/netconf/data"
+	"github.com/andaru/netconf/yang"
+)
+
+// step is a step of a RESTCONF api-path (RFC8040 section 3.5.3),
+// identifying a schema node and, for list entries and leaf-list values,
+// the list's key values or the leaf-list value.
+type step struct {
+	schema *yang.Node
+	keys   []string
+}
+
+// parsePath parses the percent-encoded api-path p (without its leading
+// "/") into steps, using the schema of c
+func parsePath(c *yang.Context, p string) ([]step, error) {
+	if p == "" {
+		return nil, nil
+	}
+	var steps []step
+	var parent *yang.Node
+	var m *yang.Module
+	segs := strings.Split(p, "/")
+	for i, seg := range segs {
+		name, keys, hasKeys := seg, "", false
+		if j := strings.IndexByte(seg, '='); j >= 0 {
+			name, keys, hasKeys = seg[:j], seg[j+1:], true
+		}
+		name, err := url.PathUnescape(name)
+		if err != nil {
+			return nil, err
+		}
+		if j := strings.IndexByte(name, ':'); j >= 0 {
+			if m = c.Module(name[:j]); m == nil {
+				return nil, fmt.Errorf("unknown module %q", name[:j])
+			}
+			name = name[j+1:]
+		} else if m == nil {
+			return nil, fmt.Errorf("%q is not module qualified", name)
+		}
+		var s *yang.Node
+		if parent == nil {
+			s = m.Root.DataChild(m, name)
+		} else {
+			s = parent.DataChild(m, name)
+		}
+		if s == nil {
+			return nil, fmt.Errorf("unknown resource %q", seg)
+		}
+		st := step{schema: s}
+		if hasKeys {
+			for _, k := range strings.Split(keys, ",") {
+				v, err := url.PathUnescape(k)
+				if err != nil {
+					return nil, err
+				}
+				st.keys = append(st.keys, v)
+			}
+		}
+		switch {
+		case s.Kind == yang.KindList && hasKeys && len(st.keys) != len(s.Keys):
+			return nil, fmt.Errorf("list %q requires %d key values", name, len(s.Keys))
+		case s.Kind == yang.KindLeafList && hasKeys && len(st.keys) != 1:
+			return nil, fmt.Errorf("leaf-list %q requires a single value", name)
+		case hasKeys && s.Kind != yang.KindList && s.Kind != yang.KindLeafList:
+			return nil, fmt.Errorf("%s %q has no keys", s.Kind, name)
+		case (s.Kind == yang.KindList || s.Kind == yang.KindLeafList) && !hasKeys && i < len(segs)-1:
+			return nil, fmt.Errorf("%s %q requires key values", s.Kind, name)
+		}
+		steps = append(steps, st)
+		parent = s
+	}
+	return steps, nil
+}
+
+// build appends the data nodes identified by steps (with their list keys
+// or leaf-list values) to n, returning the last
+func build(n *data.Node, steps []step) *data.Node {
+	for _, st := range steps {
+		c := &data.Node{Name: name(st.schema)}
+		n.Append(c)
+		switch st.schema.Kind {
+		case yang.KindList:
+			for i, k := range st.keys {
+				c.Append(&data.Node{Name: xml.Name{Space: c.Name.Space, Local: st.schema.Keys[i]}, Value: k})
+			}
+		case yang.KindLeafList:
+			if len(st.keys) > 0 {
+				c.Value = st.keys[0]
+			}
+		}
+		n = c
+	}
+	return n
+}
+
+// find returns the nodes of the tree root identified by steps
+func find(root *data.Node, steps []step) []*data.Node {
+	nodes := []*data.Node{root}
+	for _, st := range steps {
+		var next []*data.Node
+		for _, n := range nodes {
+			for _, c := range n.Children {
+				if c.Schema == st.schema && matches(c, st) {
+					next = append(next, c)
+				}
+			}
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+// matches returns true if the node n has the keys or value of st
+func matches(n *data.Node, st step) bool {
+	if st.keys == nil {
+		return true
+	}
+	if st.schema.Kind == yang.KindLeafList {
+		return n.Value == st.keys[0]
+	}
+	for i, k := range st.schema.Keys {
+		c := n.Child(xml.Name{Space: n.Name.Space, Local: k})
+		if c == nil || c.Value != st.keys[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// segment returns the api-path segment identifying n, whose parent's
+// namespace is parentNS
+func segment(n *data.Node, parentNS string) string {
+	seg := n.Name.Local
+	if n.Name.Space != parentNS && n.Schema != nil {
+		seg = n.Schema.Module.Name + ":" + seg
+	}
+	var keys []string
+	switch {
+	case n.Schema == nil:
+	case n.Schema.Kind == yang.KindList:
+		for _, k := range n.Keys() {
+			keys = append(keys, k.Value)
+		}
+	case n.Schema.Kind == yang.KindLeafList:
+		keys = []string{n.Value}
+	}
+	if keys == nil {
+		return seg
+	}
+	for i, k := range keys {
+		keys[i] = strings.ReplaceAll(url.PathEscape(k), ",", "%2C")
+	}
+	return seg + "=" + strings.Join(keys, ",")
+}
+
+func name(s *yang.Node) xml.Name {
+	return xml.Name{Space: s.Module.Namespace, Local: s.Name}
+}
@@ -0,0 +1,615 @@
+package restconf
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/andaru/netconf/data"
+	"github.com/andaru/netconf/ops"
+	"github.com/andaru/netconf/session"
+	"github.com/andaru/netconf/yang"
+)
+
+// NS is the ietf-restconf module namespace
+const NS = "urn:ietf:params:xml:ns:yang:ietf-restconf"
+
+// Media types of YANG data (RFC8040 section 11.3)
+const (
+	MediaTypeXML  = "application/yang-data+xml"
+	MediaTypeJSON = "application/yang-data+json"
+)
+
+// YANGLibraryVersion is the ietf-yang-library revision reported by the
+// yang-library-version resource
+const YANGLibraryVersion = "2019-01-04"
+
+// Handler is an http.Handler serving the RESTCONF (RFC8040) API
+// resources, being the datastore (/restconf/data), operations
+// (/restconf/operations) and yang-library-version resources, by NETCONF
+// operations on a backend client session:
+//
+//	GET, HEAD  /restconf/data/...  <get> (or <get-config>, for content=config)
+//	POST       /restconf/data/...  <edit-config> creating a child resource
+//	PUT        /restconf/data/...  <edit-config> replacing the resource
+//	PATCH      /restconf/data/...  <edit-config> merging into the resource
+//	DELETE     /restconf/data/...  <edit-config> deleting the resource
+//	POST       /restconf/operations/module:rpc  the rpc
+//
+// Edits are made to the running datastore, or to the candidate
+// datastore and committed, if the backend does not support
+// :writable-running.
+//
+// Requests and responses are encoded as YANG data in XML or JSON (RFC7951),
+// per the Content-Type and Accept headers, defaulting to JSON. The root
+// resource is discoverable at /.well-known/host-meta.
+type Handler struct {
+	schema  *yang.Context
+	backend *backend
+}
+
+// New returns a new Handler sending requests on the established client
+// session s, whose data is modelled by the resolved schema. The Handler
+// owns the session's incoming messages (see session.Session.Messages).
+func New(s *session.Session, schema *yang.Context) *Handler {
+	return &Handler{schema: schema, backend: newBackend(s)}
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rs := &response{w: w, json: wantJSON(r), schema: h.schema}
+	path := r.URL.EscapedPath()
+	switch {
+	case path == "/.well-known/host-meta":
+		if rs.allow(r, http.MethodGet, http.MethodHead) {
+			w.Header().Set("Content-Type", "application/xrd+xml")
+			io.WriteString(w, `<XRD xmlns="http://docs.oasis-open.org/ns/xri/xrd-1.0"><Link rel="restconf" href="/restconf"/></XRD>`)
+		}
+	case path == "/restconf" || path == "/restconf/":
+		if rs.allow(r, http.MethodGet, http.MethodHead) {
+			rs.root()
+		}
+	case path == "/restconf/yang-library-version":
+		if rs.allow(r, http.MethodGet, http.MethodHead) {
+			rs.leaf("yang-library-version", YANGLibraryVersion)
+		}
+	case path == "/restconf/data" || strings.HasPrefix(path, "/restconf/data/"):
+		h.data(rs, r, strings.TrimPrefix(strings.TrimPrefix(path, "/restconf/data"), "/"))
+	case path == "/restconf/operations":
+		if rs.allow(r, http.MethodGet, http.MethodHead) {
+			h.operations(rs)
+		}
+	case strings.HasPrefix(path, "/restconf/operations/"):
+		if rs.allow(r, http.MethodPost) {
+			h.invoke(rs, r, strings.TrimPrefix(path, "/restconf/operations/"))
+		}
+	default:
+		rs.writeErrors(http.StatusNotFound, newError(ops.ErrorTypeProtocol, ops.ErrorTagInvalidValue, "unknown resource"))
+	}
+}
+
+// response writes a response in the requested encoding
+type response struct {
+	w      http.ResponseWriter
+	json   bool
+	schema *yang.Context
+}
+
+// wantJSON returns true if the JSON encoding is preferred by r's Accept
+// header, or else by its Content-Type
+func wantJSON(r *http.Request) bool {
+	for _, h := range []string{r.Header.Get("Accept"), r.Header.Get("Content-Type")} {
+		j, x := strings.Index(h, "json"), strings.Index(h, "xml")
+		switch {
+		case j >= 0 && (x < 0 || j < x):
+			return true
+		case x >= 0:
+			return false
+		}
+	}
+	return true
+}
+
+func (rs *response) write(code int, body []byte) {
+	if len(body) > 0 {
+		if rs.json {
+			rs.w.Header().Set("Content-Type", MediaTypeJSON)
+		} else {
+			rs.w.Header().Set("Content-Type", MediaTypeXML)
+		}
+	}
+	rs.w.WriteHeader(code)
+	rs.w.Write(body)
+}
+
+// allow returns true if r's method is one of methods, otherwise writing
+// an error response
+func (rs *response) allow(r *http.Request, methods ...string) bool {
+	for _, m := range methods {
+		if r.Method == m {
+			return true
+		}
+	}
+	rs.w.Header().Set("Allow", strings.Join(methods, ", "))
+	rs.writeErrors(http.StatusMethodNotAllowed,
+		newError(ops.ErrorTypeProtocol, ops.ErrorTagOperationNotSupported, "method not allowed"))
+	return false
+}
+
+// root writes the API root resource
+func (rs *response) root() {
+	if rs.json {
+		rs.write(http.StatusOK, []byte(`{"ietf-restconf:restconf":{"data":{},"operations":{},"yang-library-version":"`+YANGLibraryVersion+`"}}`))
+		return
+	}
+	rs.write(http.StatusOK, []byte(`<restconf xmlns="`+NS+`"><data/><operations/><yang-library-version>`+YANGLibraryVersion+`</yang-library-version></restconf>`))
+}
+
+// leaf writes the ietf-restconf leaf name's value
+func (rs *response) leaf(name, value string) {
+	if rs.json {
+		b, _ := json.Marshal(map[string]string{"ietf-restconf:" + name: value})
+		rs.write(http.StatusOK, b)
+		return
+	}
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(value))
+	rs.write(http.StatusOK, []byte(`<`+name+` xmlns="`+NS+`">`+b.String()+`</`+name+`>`))
+}
+
+// nodes writes the data nodes, or the datastore contents in a data
+// container if datastore is set
+func (rs *response) nodes(code int, nodes []*data.Node, datastore bool) {
+	var b bytes.Buffer
+	var err error
+	switch {
+	case rs.json && datastore:
+		b.WriteString(`{"ietf-restconf:data":`)
+		err = data.EncodeJSON(&b, nodes)
+		b.WriteString(`}`)
+	case rs.json:
+		err = data.EncodeJSON(&b, nodes)
+	case datastore:
+		b.WriteString(`<data xmlns="` + NS + `">`)
+		err = data.EncodeXML(&b, nodes)
+		b.WriteString(`</data>`)
+	default:
+		err = data.EncodeXML(&b, nodes)
+	}
+	if err != nil {
+		rs.writeErrors(http.StatusInternalServerError, newError(ops.ErrorTypeApplication, ops.ErrorTagOperationFailed, err.Error()))
+		return
+	}
+	rs.write(code, b.Bytes())
+}
+
+// call calls op on the backend, writing an error response and returning
+// nil if the call or operation failed
+func (h *Handler) call(rs *response, ctx context.Context, op interface{}) (*ops.RPCReply, []byte) {
+	reply, raw, err := h.backend.call(ctx, op)
+	if !rs.ok(reply, err) {
+		return nil, nil
+	}
+	return reply, raw
+}
+
+// ok returns true if the reply was received without error, otherwise
+// writing an error response
+func (rs *response) ok(reply *ops.RPCReply, err error) bool {
+	switch {
+	case err != nil:
+		rs.writeErrors(http.StatusInternalServerError, newError(ops.ErrorTypeTransport, ops.ErrorTagOperationFailed, err.Error()))
+		return false
+	case reply.Err() != nil:
+		rs.writeErrors(status(reply.Errors), reply.Errors...)
+		return false
+	}
+	return true
+}
+
+// data serves the datastore resource at the api-path p
+func (h *Handler) data(rs *response, r *http.Request, p string) {
+	steps, err := parsePath(h.schema, p)
+	if err != nil {
+		rs.writeErrors(http.StatusBadRequest, newError(ops.ErrorTypeProtocol, ops.ErrorTagInvalidValue, err.Error()))
+		return
+	}
+	for _, st := range steps {
+		if !st.schema.IsData() {
+			rs.writeErrors(http.StatusBadRequest, newError(ops.ErrorTypeProtocol, ops.ErrorTagOperationNotSupported,
+				fmt.Sprintf("%s %q is not a data resource", st.schema.Kind, st.schema.Name)))
+			return
+		}
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		h.get(rs, r, steps)
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		h.edit(rs, r, steps)
+	default:
+		rs.allow(r, http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete)
+	}
+}
+
+// get serves GET requests for the datastore resource identified by steps
+func (h *Handler) get(rs *response, r *http.Request, steps []step) {
+	q := r.URL.Query()
+	depth := 0
+	if d := q.Get("depth"); d != "" && d != "unbounded" {
+		var err error
+		if depth, err = strconv.Atoi(d); err != nil || depth < 1 || depth > 65535 {
+			rs.writeErrors(http.StatusBadRequest, newError(ops.ErrorTypeProtocol, ops.ErrorTagInvalidValue, "invalid depth "+strconv.Quote(d)))
+			return
+		}
+	}
+	var filter *ops.Filter
+	if len(steps) > 0 {
+		var b bytes.Buffer
+		root := data.NewRoot(h.schema)
+		build(root, steps)
+		data.EncodeXML(&b, root.Children)
+		filter = &ops.Filter{Type: "subtree", Content: b.Bytes()}
+	}
+	var op interface{}
+	switch content := q.Get("content"); content {
+	case "", "all", "nonconfig":
+		op = &ops.Get{Filter: filter}
+	case "config":
+		op = &ops.GetConfig{Source: ops.Running, Filter: filter}
+	default:
+		rs.writeErrors(http.StatusBadRequest, newError(ops.ErrorTypeProtocol, ops.ErrorTagInvalidValue, "invalid content "+strconv.Quote(content)))
+		return
+	}
+	reply, _ := h.call(rs, r.Context(), op)
+	if reply == nil {
+		return
+	}
+	tree := data.NewRoot(h.schema)
+	if reply.Data != nil {
+		if err := tree.DecodeXML(bytes.NewReader(reply.Data.Content)); err != nil {
+			rs.writeErrors(http.StatusInternalServerError, newError(ops.ErrorTypeApplication, ops.ErrorTagOperationFailed, err.Error()))
+			return
+		}
+	}
+	if q.Get("content") == "nonconfig" {
+		pruneConfig(tree)
+	}
+	targets := []*data.Node{tree}
+	if len(steps) > 0 {
+		if targets = find(tree, steps); len(targets) == 0 {
+			rs.writeErrors(http.StatusNotFound, newError(ops.ErrorTypeProtocol, ops.ErrorTagInvalidValue, "resource not found"))
+			return
+		}
+	}
+	if depth > 0 {
+		for _, t := range targets {
+			t.LimitDepth(depth)
+		}
+	}
+	if len(steps) == 0 {
+		rs.nodes(http.StatusOK, tree.Children, true)
+		return
+	}
+	rs.nodes(http.StatusOK, targets, false)
+}
+
+// pruneConfig removes configuration nodes with no state data descendants
+// from n, returning false if n has no state data. List keys are kept.
+func pruneConfig(n *data.Node) bool {
+	if n.Schema != nil && !n.Schema.Config {
+		return true
+	}
+	var children []*data.Node
+	for _, c := range n.Children {
+		if pruneConfig(c) {
+			children = append(children, c)
+		}
+	}
+	keep := len(children) > 0
+	if keep && n.Schema != nil && n.Schema.Kind == yang.KindList {
+		children = append(n.Keys(), children...)
+	}
+	n.Children = children
+	return keep
+}
+
+// edit serves requests editing the datastore resource identified by steps
+func (h *Handler) edit(rs *response, r *http.Request, steps []step) {
+	badRequest := func(msg string) {
+		rs.writeErrors(http.StatusBadRequest, newError(ops.ErrorTypeProtocol, ops.ErrorTagInvalidValue, msg))
+	}
+	if len(steps) > 0 {
+		last := steps[len(steps)-1].schema
+		if (last.Kind == yang.KindList || last.Kind == yang.KindLeafList) && steps[len(steps)-1].keys == nil && r.Method != http.MethodPost {
+			badRequest(fmt.Sprintf("%s %q requires key values", last.Kind, last.Name))
+			return
+		}
+	}
+	root := data.NewRoot(h.schema)
+	var target *data.Node
+	operation, defaultOperation := "", ""
+	switch r.Method {
+	case http.MethodDelete:
+		if len(steps) == 0 {
+			rs.allow(r, http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch)
+			return
+		}
+		target, operation = build(root, steps), "delete"
+	case http.MethodPost:
+		parent := build(root, steps)
+		if err := h.decode(parent, r, false); err != nil {
+			badRequest(err.Error())
+			return
+		}
+		if len(children(parent, steps)) != 1 {
+			badRequest("request must contain a single resource")
+			return
+		}
+		target, operation = children(parent, steps)[0], "create"
+	default:
+		operation = "merge"
+		if r.Method == http.MethodPut {
+			operation = "replace"
+		}
+		if len(steps) == 0 {
+			// the entire datastore
+			if err := h.decode(root, r, true); err != nil {
+				badRequest(err.Error())
+				return
+			}
+			defaultOperation = operation
+			break
+		}
+		parent := build(root, steps[:len(steps)-1])
+		if err := h.decode(parent, r, false); err != nil {
+			badRequest(err.Error())
+			return
+		}
+		found := children(parent, steps[:len(steps)-1])
+		if len(found) != 1 || found[0].Schema != steps[len(steps)-1].schema {
+			badRequest("request must contain the target resource")
+			return
+		}
+		if !matches(found[0], steps[len(steps)-1]) {
+			badRequest("request keys do not match the target resource")
+			return
+		}
+		target = found[0]
+	}
+	if target != nil {
+		target.Attr = append(target.Attr, xml.Attr{Name: xml.Name{Space: ops.NS, Local: "operation"}, Value: operation})
+	}
+	if err := data.Validate(root, data.Options{Partial: true}); err != nil {
+		if errs, ok := err.(data.Errors); ok {
+			rs.writeErrors(status(errs), errs...)
+			return
+		}
+		badRequest(err.Error())
+		return
+	}
+
+	code := http.StatusNoContent
+	if r.Method == http.MethodPost {
+		code = http.StatusCreated
+	} else if r.Method == http.MethodPut && len(steps) > 0 {
+		exists, ok := h.exists(rs, r.Context(), steps)
+		if !ok {
+			return
+		}
+		if !exists {
+			code = http.StatusCreated
+		}
+	}
+	var b bytes.Buffer
+	data.EncodeXML(&b, root.Children)
+	reply, err := h.backend.edit(r.Context(), b.Bytes(), defaultOperation)
+	if !rs.ok(reply, err) {
+		return
+	}
+	switch {
+	case r.Method == http.MethodPost:
+		rs.w.Header().Set("Location", location(r, target, steps))
+	case code == http.StatusCreated:
+		rs.w.Header().Set("Location", r.URL.EscapedPath())
+	}
+	rs.write(code, nil)
+}
+
+// children returns the children of the node parent, decoded from a
+// request body, which was built from the request path steps
+func children(parent *data.Node, steps []step) []*data.Node {
+	if len(steps) == 0 || steps[len(steps)-1].schema.Kind != yang.KindList {
+		return parent.Children
+	}
+	// exclude the list keys from the path
+	keys := parent.Keys()
+	var nodes []*data.Node
+	for _, c := range parent.Children {
+		key := false
+		for _, k := range keys {
+			key = key || c == k
+		}
+		if !key {
+			nodes = append(nodes, c)
+		}
+	}
+	return nodes
+}
+
+// location returns the URL path of the resource target, created by the
+// request r for the path steps
+func location(r *http.Request, target *data.Node, steps []step) string {
+	parentNS := ""
+	if len(steps) > 0 {
+		parentNS = target.Parent.Name.Space
+	}
+	return strings.TrimSuffix(r.URL.EscapedPath(), "/") + "/" + segment(target, parentNS)
+}
+
+// decode decodes the request body (if any) into n, per its Content-Type.
+// If datastore is set, the body holds the entire datastore, in a data
+// container.
+func (h *Handler) decode(n *data.Node, r *http.Request, datastore bool) error {
+	body, err := io.ReadAll(r.Body)
+	if err != nil || len(bytes.TrimSpace(body)) == 0 {
+		return err
+	}
+	ct := r.Header.Get("Content-Type")
+	isJSON := strings.Contains(ct, "json")
+	if !isJSON && !strings.Contains(ct, "xml") {
+		return fmt.Errorf("unsupported content type %q", ct)
+	}
+	if datastore {
+		if body, err = unwrap(body, isJSON); err != nil {
+			return err
+		}
+	}
+	if isJSON {
+		return n.DecodeJSON(bytes.NewReader(body))
+	}
+	return n.DecodeXML(bytes.NewReader(body))
+}
+
+// unwrap returns the content of the datastore resource's data container
+func unwrap(body []byte, isJSON bool) ([]byte, error) {
+	if isJSON {
+		var v map[string]json.RawMessage
+		if err := json.Unmarshal(body, &v); err != nil {
+			return nil, err
+		}
+		content, ok := v["ietf-restconf:data"]
+		if !ok || len(v) != 1 {
+			return nil, fmt.Errorf(`request must contain a single "ietf-restconf:data" member`)
+		}
+		return content, nil
+	}
+	var v struct {
+		XMLName xml.Name
+		Content []byte `xml:",innerxml"`
+	}
+	if err := xml.Unmarshal(body, &v); err != nil {
+		return nil, err
+	}
+	if v.XMLName != (xml.Name{Space: NS, Local: "data"}) {
+		return nil, fmt.Errorf("request must contain a data element")
+	}
+	return v.Content, nil
+}
+
+// exists returns true if the resource identified by steps exists in the
+// running datastore
+func (h *Handler) exists(rs *response, ctx context.Context, steps []step) (exists, ok bool) {
+	var b bytes.Buffer
+	root := data.NewRoot(h.schema)
+	build(root, steps)
+	if err := data.EncodeXML(&b, root.Children); err != nil {
+		rs.writeErrors(http.StatusInternalServerError, newError(ops.ErrorTypeApplication, ops.ErrorTagOperationFailed, err.Error()))
+		return false, false
+	}
+	reply, _ := h.call(rs, ctx, &ops.GetConfig{Source: ops.Running, Filter: &ops.Filter{Type: "subtree", Content: b.Bytes()}})
+	if reply == nil {
+		return false, false
+	}
+	tree := data.NewRoot(h.schema)
+	if reply.Data != nil {
+		if err := tree.DecodeXML(bytes.NewReader(reply.Data.Content)); err != nil {
+			rs.writeErrors(http.StatusInternalServerError, newError(ops.ErrorTypeApplication, ops.ErrorTagOperationFailed, err.Error()))
+			return false, false
+		}
+	}
+	return len(find(tree, steps)) > 0, true
+}
+
+// operations serves the operations resource, listing the schema's rpcs
+func (h *Handler) operations(rs *response) {
+	names := make([]string, 0, len(h.schema.Modules))
+	for name := range h.schema.Modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b bytes.Buffer
+	if rs.json {
+		b.WriteString(`{"ietf-restconf:operations":{`)
+	} else {
+		b.WriteString(`<operations xmlns="` + NS + `">`)
+	}
+	n := 0
+	for _, name := range names {
+		m := h.schema.Modules[name]
+		for _, c := range m.Root.Children {
+			if c.Kind != yang.KindRPC {
+				continue
+			}
+			if rs.json {
+				if n > 0 {
+					b.WriteByte(',')
+				}
+				fmt.Fprintf(&b, `"%s:%s":[null]`, m.Name, c.Name)
+			} else {
+				fmt.Fprintf(&b, `<%s xmlns="%s">/restconf/operations/%s:%s</%[1]s>`, c.Name, m.Namespace, m.Name, c.Name)
+			}
+			n++
+		}
+	}
+	if rs.json {
+		b.WriteString(`}}`)
+	} else {
+		b.WriteString(`</operations>`)
+	}
+	rs.write(http.StatusOK, b.Bytes())
+}
+
+// invoke serves POST requests invoking the rpc at the api-path p
+func (h *Handler) invoke(rs *response, r *http.Request, p string) {
+	steps, err := parsePath(h.schema, p)
+	if err == nil && (len(steps) != 1 || steps[0].schema.Kind != yang.KindRPC) {
+		err = fmt.Errorf("%q is not an operation", p)
+	}
+	if err != nil {
+		rs.writeErrors(http.StatusBadRequest, newError(ops.ErrorTypeProtocol, ops.ErrorTagInvalidValue, err.Error()))
+		return
+	}
+	root := data.NewRoot(h.schema)
+	rpc := build(root, steps)
+	if err := h.decode(rpc, r, false); err != nil {
+		rs.writeErrors(http.StatusBadRequest, newError(ops.ErrorTypeProtocol, ops.ErrorTagInvalidValue, err.Error()))
+		return
+	}
+	op := &ops.RawOperation{XMLName: rpc.Name}
+	if input := rpc.Child(xml.Name{Space: rpc.Name.Space, Local: "input"}); input != nil {
+		var b bytes.Buffer
+		data.EncodeXML(&b, input.Children)
+		op.Content = b.Bytes()
+	}
+	reply, raw := h.call(rs, r.Context(), op)
+	if reply == nil {
+		return
+	}
+	var content struct {
+		Content []byte `xml:",innerxml"`
+	}
+	if err := xml.Unmarshal(raw, &content); err != nil {
+		rs.writeErrors(http.StatusInternalServerError, newError(ops.ErrorTypeApplication, ops.ErrorTagOperationFailed, err.Error()))
+		return
+	}
+	output := &data.Node{Name: xml.Name{Space: rpc.Name.Space, Local: "output"}}
+	rpc.Append(output)
+	if reply.OK == nil {
+		if err := output.DecodeXML(bytes.NewReader(content.Content)); err != nil {
+			rs.writeErrors(http.StatusInternalServerError, newError(ops.ErrorTypeApplication, ops.ErrorTagOperationFailed, err.Error()))
+			return
+		}
+	}
+	if len(output.Children) == 0 {
+		rs.write(http.StatusNoContent, nil)
+		return
+	}
+	rs.nodes(http.StatusOK, []*data.Node{output}, false)
+}
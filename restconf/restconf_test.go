@@ -0,0 +1,565 @@
+package restconf
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/andaru/netconf/data"
+	"github.com/andaru/netconf/netconftest"
+	"github.com/andaru/netconf/ops"
+	"github.com/andaru/netconf/session"
+	"github.com/andaru/netconf/yang"
+	"github.com/stretchr/testify/assert"
+)
+
+func loadSchema(t *testing.T) *yang.Context {
+	t.Helper()
+	c := yang.NewContext("testdata")
+	if err := c.Load("example-sys"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Resolve(); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// server is an in-process NETCONF server, holding its datastores as data trees
+type server struct {
+	schema             *yang.Context
+	running, candidate *data.Node
+	// lockDenied holds the datastore locks to deny
+	lockDenied map[string]bool
+	mu         sync.Mutex
+	// ops holds the names of the operations received
+	ops []string
+}
+
+func newServer(t *testing.T, schema *yang.Context, config string) *server {
+	running, err := data.Parse(strings.NewReader(config), schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &server{schema: schema, running: running}
+}
+
+// serve answers the requests received on s
+func (srv *server) serve(s *session.Session) {
+	ctx := context.Background()
+	for m := range s.Messages(ctx) {
+		rpc, err := ops.DecodeRPC(bytes.NewReader(m.Data))
+		if err != nil {
+			return
+		}
+		b, _ := xml.Marshal(srv.handle(rpc))
+		s.Send(ctx, bytes.NewReader(b))
+	}
+}
+
+func (srv *server) operations() []string {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	return append([]string(nil), srv.ops...)
+}
+
+type rawReply struct {
+	XMLName   xml.Name `xml:"urn:ietf:params:xml:ns:netconf:base:1.0 rpc-reply"`
+	MessageID string   `xml:"message-id,attr"`
+	Content   []byte   `xml:",innerxml"`
+}
+
+// handle returns the reply to rpc, an *ops.RPCReply or *rawReply
+func (srv *server) handle(rpc *ops.RPC) interface{} {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	reply := &ops.RPCReply{MessageID: rpc.MessageID}
+	fail := func(tag, msg string) *ops.RPCReply {
+		reply.Errors = []ops.RPCError{{Type: ops.ErrorTypeApplication, Tag: tag, Severity: ops.SeverityError, Message: msg}}
+		return reply
+	}
+	switch op := rpc.Operation.(type) {
+	case *ops.Get, *ops.GetConfig:
+		tree := clone(srv.running, nil)
+		if _, ok := op.(*ops.Get); ok {
+			srv.ops = append(srv.ops, "get")
+			// add state data
+			if system := tree.Child(xml.Name{Space: "urn:example:sys", Local: "system"}); system != nil {
+				system.Append(&data.Node{Name: xml.Name{Space: "urn:example:sys", Local: "uptime"}, Value: "100"})
+			}
+		} else {
+			srv.ops = append(srv.ops, "get-config")
+		}
+		var b bytes.Buffer
+		data.EncodeXML(&b, tree.Children)
+		reply.Data = &ops.Inline{Content: b.Bytes()}
+	case *ops.EditConfig:
+		srv.ops = append(srv.ops, "edit-config "+op.Target.Name)
+		edit, err := data.Parse(bytes.NewReader(op.Config.Content), srv.schema)
+		if err != nil {
+			return fail(ops.ErrorTagMalformedMessage, err.Error())
+		}
+		target := srv.running
+		if op.Target.Name == "candidate" {
+			if srv.candidate == nil {
+				srv.candidate = clone(srv.running, nil)
+			}
+			target = srv.candidate
+		}
+		defaultOp := op.DefaultOperation
+		if defaultOp == "" {
+			defaultOp = "merge"
+		}
+		if tag, msg := apply(target, edit, defaultOp); tag != "" {
+			return fail(tag, msg)
+		}
+		reply.OK = &ops.Empty{}
+	case *ops.Lock:
+		srv.ops = append(srv.ops, "lock "+op.Target.Name)
+		if srv.lockDenied[op.Target.Name] {
+			return fail(ops.ErrorTagLockDenied, "lock held by another session")
+		}
+		reply.OK = &ops.Empty{}
+	case *ops.Unlock:
+		srv.ops = append(srv.ops, "unlock "+op.Target.Name)
+		reply.OK = &ops.Empty{}
+	case *ops.Commit:
+		srv.ops = append(srv.ops, "commit")
+		srv.running, srv.candidate = srv.candidate, nil
+		reply.OK = &ops.Empty{}
+	case *ops.DiscardChanges:
+		srv.ops = append(srv.ops, "discard-changes")
+		srv.candidate = nil
+		reply.OK = &ops.Empty{}
+	case *ops.RawOperation:
+		srv.ops = append(srv.ops, op.XMLName.Local+" "+string(op.Content))
+		switch op.XMLName.Local {
+		case "ping":
+			return &rawReply{MessageID: rpc.MessageID, Content: []byte(`<rtt xmlns="urn:example:sys">42</rtt>`)}
+		case "reset":
+			reply.OK = &ops.Empty{}
+		default:
+			return fail(ops.ErrorTagOperationNotSupported, "unknown operation")
+		}
+	}
+	return reply
+}
+
+// apply applies the edit-config content src to dst, returning an
+// error-tag and message on failure
+func apply(dst, src *data.Node, op string) (string, string) {
+	for _, c := range src.Children {
+		cop := op
+		for _, a := range c.Attr {
+			if a.Name == (xml.Name{Space: ops.NS, Local: "operation"}) {
+				cop = a.Value
+			}
+		}
+		existing := lookup(dst, c)
+		switch cop {
+		case "create":
+			if existing != nil {
+				return ops.ErrorTagDataExists, "data exists"
+			}
+			clone(c, dst)
+		case "delete", "remove":
+			if existing == nil {
+				if cop == "delete" {
+					return ops.ErrorTagDataMissing, "data missing"
+				}
+				continue
+			}
+			existing.Remove()
+		case "replace":
+			if existing != nil {
+				existing.Remove()
+			}
+			clone(c, dst)
+		default:
+			if existing == nil {
+				existing = &data.Node{Name: c.Name}
+				dst.Append(existing)
+			}
+			if len(c.Children) == 0 {
+				existing.Value = c.Value
+			}
+			if tag, msg := apply(existing, c, cop); tag != "" {
+				return tag, msg
+			}
+		}
+	}
+	return "", ""
+}
+
+// lookup returns the child of dst matching n, by name and list keys or
+// leaf-list value
+func lookup(dst, n *data.Node) *data.Node {
+	for _, c := range dst.Children {
+		if c.Name != n.Name {
+			continue
+		}
+		if n.Schema != nil && n.Schema.Kind == yang.KindLeafList && c.Value != n.Value {
+			continue
+		}
+		match := true
+		for _, k := range n.Keys() {
+			if ck := c.Child(k.Name); ck == nil || ck.Value != k.Value {
+				match = false
+			}
+		}
+		if match {
+			return c
+		}
+	}
+	return nil
+}
+
+// clone returns a copy of n appended to parent, or a new root if parent is nil
+func clone(n, parent *data.Node) *data.Node {
+	c := data.NewRoot(n.Context())
+	if parent != nil {
+		c = &data.Node{Name: n.Name, Value: n.Value, NS: n.NS}
+		parent.Append(c)
+	}
+	for _, child := range n.Children {
+		clone(child, c)
+	}
+	return c
+}
+
+// setup returns a RESTCONF test server, backed by an in-process NETCONF
+// server with the configuration and capabilities
+func setup(t *testing.T, config string, caps ...string) (*httptest.Server, *server) {
+	schema := loadSchema(t)
+	srv := newServer(t, schema, config)
+	toServer, toClient := netconftest.NewPipe(), netconftest.NewPipe()
+	p := &netconftest.Pair{
+		Client: session.New(toClient, toServer, session.Config{Capabilities: session.Capabilities{capBase11}}),
+		Server: session.New(toServer, toClient, session.Config{ID: 1, Capabilities: append(session.Capabilities{capBase11}, caps...)}),
+	}
+	if err := p.Handshake(); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		srv.serve(p.Server)
+	}()
+	ts := httptest.NewServer(New(p.Client, schema))
+	t.Cleanup(func() {
+		ts.Close()
+		// end both sessions' message streams
+		toServer.Close()
+		toClient.Close()
+		<-done
+	})
+	return ts, srv
+}
+
+const capBase11 = "urn:ietf:params:netconf:base:1.1"
+
+const initialConfig = `<system xmlns="urn:example:sys"><hostname>r1</hostname><dns>a</dns><dns>b</dns>` +
+	`<server><name>s1</name><port>830</port></server></system>`
+
+type request struct {
+	method, path, accept, contentType, body string
+}
+
+func do(t *testing.T, ts *httptest.Server, r request) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequest(r.method, ts.URL+r.path, strings.NewReader(r.body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.accept != "" {
+		req.Header.Set("Accept", r.accept)
+	}
+	if r.contentType != "" {
+		req.Header.Set("Content-Type", r.contentType)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	return resp, string(b)
+}
+
+func TestGet(t *testing.T) {
+	ts, _ := setup(t, initialConfig, capWritableRunning)
+	for _, tc := range []struct {
+		name     string
+		path     string
+		accept   string
+		wantCode int
+		want     string
+	}{
+		{
+			name: "root", path: "/restconf", wantCode: http.StatusOK,
+			want: `{"ietf-restconf:restconf":{"data":{},"operations":{},"yang-library-version":"2019-01-04"}}`,
+		},
+		{
+			name: "yang library version xml", path: "/restconf/yang-library-version", accept: MediaTypeXML, wantCode: http.StatusOK,
+			want: `<yang-library-version xmlns="urn:ietf:params:xml:ns:yang:ietf-restconf">2019-01-04</yang-library-version>`,
+		},
+		{
+			name: "host-meta", path: "/.well-known/host-meta", wantCode: http.StatusOK,
+			want: `<XRD xmlns="http://docs.oasis-open.org/ns/xri/xrd-1.0"><Link rel="restconf" href="/restconf"/></XRD>`,
+		},
+		{
+			name: "datastore", path: "/restconf/data", wantCode: http.StatusOK,
+			want: `{"ietf-restconf:data":{"example-sys:system":{"hostname":"r1","dns":["a","b"],` +
+				`"server":[{"name":"s1","port":830}],"uptime":"100"}}}`,
+		},
+		{
+			name: "container", path: "/restconf/data/example-sys:system?content=config", wantCode: http.StatusOK,
+			want: `{"example-sys:system":{"hostname":"r1","dns":["a","b"],"server":[{"name":"s1","port":830}]}}`,
+		},
+		{
+			name: "container xml", path: "/restconf/data/example-sys:system?content=config", accept: MediaTypeXML, wantCode: http.StatusOK,
+			want: `<system xmlns="urn:example:sys"><hostname>r1</hostname><dns>a</dns><dns>b</dns>` +
+				`<server><name>s1</name><port>830</port></server></system>`,
+		},
+		{
+			name: "list entry", path: "/restconf/data/example-sys:system/server=s1", wantCode: http.StatusOK,
+			want: `{"example-sys:server":[{"name":"s1","port":830}]}`,
+		},
+		{
+			name: "leaf-list value", path: "/restconf/data/example-sys:system/dns=b", wantCode: http.StatusOK,
+			want: `{"example-sys:dns":["b"]}`,
+		},
+		{
+			name: "leaf", path: "/restconf/data/example-sys:system/hostname", wantCode: http.StatusOK,
+			want: `{"example-sys:hostname":"r1"}`,
+		},
+		{
+			name: "depth", path: "/restconf/data/example-sys:system?content=config&depth=1", wantCode: http.StatusOK,
+			want: `{"example-sys:system":{}}`,
+		},
+		{
+			name: "missing", path: "/restconf/data/example-sys:system/server=s9", wantCode: http.StatusNotFound,
+			want: `{"ietf-restconf:errors":{"error":[{"error-type":"protocol","error-tag":"invalid-value","error-message":"resource not found"}]}}`,
+		},
+		{
+			name: "unknown resource xml", path: "/restconf/data/example-sys:bogus", accept: MediaTypeXML, wantCode: http.StatusBadRequest,
+			want: `<errors xmlns="urn:ietf:params:xml:ns:yang:ietf-restconf"><error><error-type>protocol</error-type>` +
+				`<error-tag>invalid-value</error-tag><error-severity>error</error-severity>` +
+				`<error-message>unknown resource "example-sys:bogus"</error-message></error></errors>`,
+		},
+		{
+			name: "operations", path: "/restconf/operations", wantCode: http.StatusOK,
+			want: `{"ietf-restconf:operations":{"example-sys:ping":[null],"example-sys:reset":[null]}}`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			resp, body := do(t, ts, request{method: http.MethodGet, path: tc.path, accept: tc.accept})
+			assert.Equal(tc.wantCode, resp.StatusCode)
+			if strings.HasPrefix(tc.want, "<") {
+				netconftest.AssertXMLEqual(t, tc.want, body)
+			} else {
+				assert.Equal(tc.want, strings.TrimSpace(body))
+			}
+		})
+	}
+}
+
+func TestEdit(t *testing.T) {
+	ts, srv := setup(t, initialConfig, capWritableRunning)
+	for _, tc := range []struct {
+		name         string
+		req          request
+		wantCode     int
+		wantLocation string
+		want         string
+		// wantConfig is the resulting running configuration, if the request succeeded
+		wantConfig string
+	}{
+		{
+			name: "create list entry",
+			req: request{method: http.MethodPost, path: "/restconf/data/example-sys:system", contentType: MediaTypeJSON,
+				body: `{"example-sys:server":[{"name":"s 2","port":22}]}`},
+			wantCode:     http.StatusCreated,
+			wantLocation: "/restconf/data/example-sys:system/server=s%202",
+			wantConfig: `<system xmlns="urn:example:sys"><hostname>r1</hostname><dns>a</dns><dns>b</dns>` +
+				`<server><name>s1</name><port>830</port></server><server><name>s 2</name><port>22</port></server></system>`,
+		},
+		{
+			name: "create existing",
+			req: request{method: http.MethodPost, path: "/restconf/data/example-sys:system", contentType: MediaTypeJSON,
+				body: `{"example-sys:server":[{"name":"s1"}]}`},
+			wantCode: http.StatusConflict,
+			want:     `{"ietf-restconf:errors":{"error":[{"error-type":"application","error-tag":"data-exists","error-message":"data exists"}]}}`,
+		},
+		{
+			name: "replace leaf xml",
+			req: request{method: http.MethodPut, path: "/restconf/data/example-sys:system/hostname", contentType: MediaTypeXML,
+				body: `<hostname xmlns="urn:example:sys">r2</hostname>`},
+			wantCode: http.StatusNoContent,
+			wantConfig: `<system xmlns="urn:example:sys"><dns>a</dns><dns>b</dns>` +
+				`<server><name>s1</name><port>830</port></server><server><name>s 2</name><port>22</port></server>` +
+				`<hostname>r2</hostname></system>`,
+		},
+		{
+			name: "put new list entry",
+			req: request{method: http.MethodPut, path: "/restconf/data/example-sys:system/server=s3", contentType: MediaTypeJSON,
+				body: `{"example-sys:server":[{"name":"s3","port":3}]}`},
+			wantCode:     http.StatusCreated,
+			wantLocation: "/restconf/data/example-sys:system/server=s3",
+		},
+		{
+			name: "put mismatched key",
+			req: request{method: http.MethodPut, path: "/restconf/data/example-sys:system/server=s3", contentType: MediaTypeJSON,
+				body: `{"example-sys:server":[{"name":"s4"}]}`},
+			wantCode: http.StatusBadRequest,
+			want:     `{"ietf-restconf:errors":{"error":[{"error-type":"protocol","error-tag":"invalid-value","error-message":"request keys do not match the target resource"}]}}`,
+		},
+		{
+			name: "merge",
+			req: request{method: http.MethodPatch, path: "/restconf/data/example-sys:system/server=s3", contentType: MediaTypeJSON,
+				body: `{"example-sys:server":[{"name":"s3","port":33}]}`},
+			wantCode: http.StatusNoContent,
+		},
+		{
+			name:     "delete",
+			req:      request{method: http.MethodDelete, path: "/restconf/data/example-sys:system/server=s3"},
+			wantCode: http.StatusNoContent,
+		},
+		{
+			name:     "delete missing",
+			req:      request{method: http.MethodDelete, path: "/restconf/data/example-sys:system/server=s3"},
+			wantCode: http.StatusConflict,
+		},
+		{
+			name: "state data",
+			req: request{method: http.MethodPatch, path: "/restconf/data/example-sys:system", contentType: MediaTypeJSON,
+				body: `{"example-sys:system":{"uptime":"5"}}`},
+			wantCode: http.StatusBadRequest,
+			want: `{"ietf-restconf:errors":{"error":[{"error-type":"application","error-tag":"unknown-element",` +
+				`"error-path":"/example-sys:system/example-sys:uptime","error-message":"state data \"uptime\" is not permitted in configuration",` +
+				`"error-info":"<bad-element>uptime</bad-element>"}]}}`,
+		},
+		{
+			name: "replace datastore",
+			req: request{method: http.MethodPut, path: "/restconf/data", contentType: MediaTypeJSON,
+				body: `{"ietf-restconf:data":{"example-sys:system":{"hostname":"r3"}}}`},
+			wantCode:   http.StatusNoContent,
+			wantConfig: `<system xmlns="urn:example:sys"><hostname>r3</hostname></system>`,
+		},
+		{
+			name:     "delete datastore",
+			req:      request{method: http.MethodDelete, path: "/restconf/data"},
+			wantCode: http.StatusMethodNotAllowed,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			resp, body := do(t, ts, tc.req)
+			assert.Equal(tc.wantCode, resp.StatusCode, body)
+			assert.Equal(tc.wantLocation, resp.Header.Get("Location"))
+			if tc.want != "" {
+				assert.Equal(tc.want, strings.TrimSpace(body))
+			}
+			if tc.wantConfig != "" {
+				var b bytes.Buffer
+				srv.mu.Lock()
+				data.EncodeXML(&b, srv.running.Children)
+				srv.mu.Unlock()
+				netconftest.AssertXMLEqual(t, tc.wantConfig, b.String())
+			}
+		})
+	}
+}
+
+func TestEditCandidate(t *testing.T) {
+	assert := assert.New(t)
+	ts, srv := setup(t, initialConfig, capCandidate)
+	resp, _ := do(t, ts, request{method: http.MethodPatch, path: "/restconf/data/example-sys:system/hostname",
+		contentType: MediaTypeJSON, body: `{"example-sys:hostname":"r2"}`})
+	assert.Equal(http.StatusNoContent, resp.StatusCode)
+	resp, _ = do(t, ts, request{method: http.MethodDelete, path: "/restconf/data/example-sys:system/server=s9"})
+	assert.Equal(http.StatusConflict, resp.StatusCode)
+	assert.Equal([]string{
+		"lock running", "lock candidate", "edit-config candidate", "commit", "unlock candidate", "unlock running",
+		"lock running", "lock candidate", "edit-config candidate", "discard-changes", "unlock candidate", "unlock running",
+	}, srv.operations())
+	_, body := do(t, ts, request{method: http.MethodGet, path: "/restconf/data/example-sys:system/hostname"})
+	assert.Equal(`{"example-sys:hostname":"r2"}`, body)
+
+	// the candidate is not edited while locked by another session
+	srv.mu.Lock()
+	srv.ops, srv.lockDenied = nil, map[string]bool{"candidate": true}
+	srv.mu.Unlock()
+	resp, _ = do(t, ts, request{method: http.MethodPatch, path: "/restconf/data/example-sys:system/hostname",
+		contentType: MediaTypeJSON, body: `{"example-sys:hostname":"r3"}`})
+	assert.Equal(http.StatusConflict, resp.StatusCode)
+	assert.Equal([]string{"lock running", "lock candidate", "unlock running"}, srv.operations())
+}
+
+func TestOperations(t *testing.T) {
+	ts, srv := setup(t, initialConfig, capWritableRunning)
+	for _, tc := range []struct {
+		name     string
+		req      request
+		wantCode int
+		want     string
+		wantOp   string
+	}{
+		{
+			name: "output json",
+			req: request{method: http.MethodPost, path: "/restconf/operations/example-sys:ping", contentType: MediaTypeJSON,
+				body: `{"example-sys:input":{"host":"h1"}}`},
+			wantCode: http.StatusOK,
+			want:     `{"example-sys:output":{"rtt":42}}`,
+			wantOp:   `ping <host xmlns="urn:example:sys">h1</host>`,
+		},
+		{
+			name: "output xml",
+			req: request{method: http.MethodPost, path: "/restconf/operations/example-sys:ping", contentType: MediaTypeXML,
+				body: `<input xmlns="urn:example:sys"><host>h2</host></input>`},
+			wantCode: http.StatusOK,
+			want:     `<output xmlns="urn:example:sys"><rtt>42</rtt></output>`,
+			wantOp:   `ping <host xmlns="urn:example:sys">h2</host>`,
+		},
+		{
+			name:     "no output",
+			req:      request{method: http.MethodPost, path: "/restconf/operations/example-sys:reset"},
+			wantCode: http.StatusNoContent,
+			wantOp:   "reset ",
+		},
+		{
+			name:     "not an operation",
+			req:      request{method: http.MethodPost, path: "/restconf/operations/example-sys:system"},
+			wantCode: http.StatusBadRequest,
+			want:     `{"ietf-restconf:errors":{"error":[{"error-type":"protocol","error-tag":"invalid-value","error-message":"\"example-sys:system\" is not an operation"}]}}`,
+		},
+		{
+			name:     "method not allowed",
+			req:      request{method: http.MethodGet, path: "/restconf/operations/example-sys:reset"},
+			wantCode: http.StatusMethodNotAllowed,
+			want:     `{"ietf-restconf:errors":{"error":[{"error-type":"protocol","error-tag":"operation-not-supported","error-message":"method not allowed"}]}}`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			before := len(srv.operations())
+			resp, body := do(t, ts, tc.req)
+			assert.Equal(tc.wantCode, resp.StatusCode, body)
+			if strings.HasPrefix(tc.want, "<") {
+				netconftest.AssertXMLEqual(t, tc.want, body)
+			} else {
+				assert.Equal(tc.want, strings.TrimSpace(body))
+			}
+			if ops := srv.operations(); tc.wantOp != "" && assert.Len(ops, before+1) {
+				assert.Equal(tc.wantOp, ops[before])
+			}
+		})
+	}
+}
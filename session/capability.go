@@ -5,13 +5,28 @@ import "strings"
 // Capabilities is a slice of strings denoting NETCONF capability URIs
 type Capabilities []string
 
-// Has returns true if uri is in the capabilities set
+// Has returns true if uri is in the capabilities set.
+// Any URI parameters (following "?") are ignored in the comparison.
 func (c Capabilities) Has(uri string) bool {
-	uri = strings.SplitAfterN(uri, "?", 2)[0]
+	uri = stripParams(uri)
 	for _, cap := range c {
-		if uri == cap {
+		if uri == stripParams(cap) {
 			return true
 		}
 	}
 	return false
 }
+
+// Shared returns the capabilities in the peer capabilities also in c,
+// ignoring any URI parameters. The peer's capability URIs are returned.
+func (c Capabilities) Shared(peer Capabilities) (shared Capabilities) {
+	for _, cap := range peer {
+		if c.Has(cap) {
+			shared = append(shared, cap)
+		}
+	}
+	return shared
+}
+
+// stripParams returns the capability uri without any parameters
+func stripParams(uri string) string { return strings.SplitN(uri, "?", 2)[0] }
@@ -0,0 +1,114 @@
+package session
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync/atomic"
+)
+
+// Message is a complete NETCONF message received by a session
+// (see Session.Messages).
+type Message struct {
+	// Data is the message's content
+	Data []byte
+	// Err is the error ending the session's message stream, if any,
+	// in which case Data holds any partial message read.
+	Err error
+}
+
+// Messages returns a channel delivering each message received on the
+// established session s, as an alternative to Run and Handler.
+//
+// Messages are read by a new goroutine, which owns the session's Incoming
+// messages and State until the channel is closed. The channel is closed
+// at end of stream, in which case the session status is set to
+// StatusClosed, or after delivering a message with a non-nil Err, in
+// which case the session status is set to StatusError. The channel is
+// also closed once ctx is done and any read in progress has completed.
+//
+// If s is not established, a single message with ErrNotEstablished is
+// delivered.
+func (s *Session) Messages(ctx context.Context) <-chan Message {
+	ch := make(chan Message)
+	go func() {
+		defer close(ch)
+		if s.State.Status != StatusEstablished {
+			deliver(ctx, ch, Message{Err: ErrNotEstablished})
+			return
+		}
+		for {
+			data, err := io.ReadAll(s.Incoming())
+			switch {
+			case err == ErrEndOfStream:
+				// the end of stream follows any timeout, reported instead
+				if !s.checkTimeout() {
+					_ = s.SetStatus(StatusClosed)
+				}
+				return
+			case err != nil:
+				s.AddError(err)
+				_ = s.SetStatus(StatusError)
+				s.checkTimeout()
+				deliver(ctx, ch, Message{Data: data, Err: err})
+				return
+			}
+			if !deliver(ctx, ch, Message{Data: data}) {
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// deliver sends m to ch, returning false if ctx was done first
+func deliver(ctx context.Context, ch chan<- Message, m Message) bool {
+	select {
+	case ch <- m:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Send sends the content read from r as the next message on the
+// established session s, returning after the end of message has been
+// written. Send may be called concurrently with Messages, and by
+// multiple goroutines, but not concurrently with use of Outgoing.
+//
+// The content is read in full before the message is written, so if ctx
+// is done before r is read to EOF, nothing is sent and ctx.Err() is
+// returned. ErrNotEstablished is returned if s has not been established.
+func (s *Session) Send(ctx context.Context, r io.Reader) error {
+	if atomic.LoadUint32(&s.established) == 0 {
+		return ErrNotEstablished
+	}
+	var msg bytes.Buffer
+	if _, err := msg.ReadFrom(&ctxReader{ctx: ctx, r: r}); err != nil {
+		return err
+	}
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	w := s.Outgoing()
+	_, err := msg.WriteTo(w)
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// ctxReader is an io.Reader returning the context's error once it is done
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *ctxReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
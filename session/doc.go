@@ -10,11 +10,10 @@ Session implementation and execution overview
 
 Session for either client or server use are created using
 the New function, providing the input (src) and output (dst)
-along with a session Config.  The config has two fields, at
-least one of which (Capabilities) must be populated for a
-session to establish successfully, so that protocol framing
-capability exchange can occur. See the sections on the
-Session.Config fields below.
+along with a session Config.  At least one Config field
+(Capabilities) must be populated for a session to establish
+successfully, so that protocol framing capability exchange
+can occur. See the sections on the Session.Config fields below.
 
 The Session manages access to the underlying transport through
 an abstraction of the NETCONF message layer.  Each Session has
@@ -44,6 +43,34 @@ This slice field must be populated to define the <capability> URLs
 which will be sent to the peer during session initialization
 and capability exchange.
 
+Session.Config hello hooks
+
+The <hello> exchange may be customized with the Hello, OnHello and
+EnableCapabilities fields, which respectively allow modifying our
+<hello> before it is sent (e.g., adding vendor extension elements),
+inspecting and vetoing the peer's <hello>, and choosing which of the
+capabilities shared by both peers are enabled (State.Enabled). The
+Hello type, along with EncodeHello and DecodeHello, represents <hello>
+messages.
+
+Session.Config strictness and quirks
+
+Sessions are Strict by default, failing when the peer does not
+conform to the specifications. Non-conformant peers' behavior may be
+tolerated by setting Strictness to Lenient (tolerating all known
+quirks), or by enabling individual Quirks. Each quirk exhibited by the
+peer is recorded in State.Quirks and reported to the OnQuirk hook.
+
+Session.Config timeout fields
+
+HelloTimeout, IdleTimeout and MaxLifetime respectively limit the time
+waiting for the peer's <hello>, the time between messages received
+from the peer, and the total session lifetime. A session timing out
+has its transport closed, and the timeout error (ErrHelloTimeout,
+ErrIdleTimeout or ErrLifetimeExceeded) is recorded as the first of
+the session's Errors, before the Handler's OnError and OnClose methods
+are called.
+
 Session execution
 
 The Run function takes a base Session (as created by New) and a
@@ -65,5 +92,59 @@ instead of EOF in case of EOF of the underlying stream (on the
 first call to each Incoming) message, meaning an unexpected EOF
 mid-message will not report ErrEndOfStream until a Read to the
 next Incoming message is made.
+
+If Config.RecoverPanics is set, Run recovers panics in the Handler's
+OnMessage method, recording a *PanicError (with the stack trace) as a
+session error. A request the handler had started reading, but not
+replying to, is answered with an operation-failed <rpc-error>. The
+session then ends with StatusError, unless Config.ContinueAfterPanic
+is set, in which case OnMessage is called for the next message.
+
+As an alternative to Run and a Handler, an established session's
+messages may be received from the channel returned by Messages, and
+sent with Send, allowing applications to select on session messages
+alongside timers and other channels.
+
+Session status
+
+A session's State.Status moves through the lifecycle StatusInactive,
+StatusCapabilitiesExchange and StatusEstablished, ending with
+StatusError or StatusClosed. Handlers should end a session using
+SetStatus, which rejects invalid transitions with ErrStatusTransition.
+Config.OnStatusChange is called for each status change, allowing
+supervisors to react to sessions dropping.
+
+A server ends another session for <kill-session> with Kill, which
+closes its transport. Once a session has ended, TerminationReason
+reports why (e.g., TerminationDropped, TerminationTimeout) and KilledBy
+the session-id of its killer, as for the RFC6470 netconf-session-end
+notification.
+
+Logging
+
+Set Config.Logger (e.g., to a *slog.Logger) to log the session's
+handshake, negotiated framing mode, capability mismatches, framing
+and session errors, message sizes and close reason. Config.LogMessages
+adds each message's content to its debug log record, after applying
+Config.LogRedact, if set.
+
+Middleware
+
+Chain wraps a Handler's OnMessage method with layers of Middleware,
+for concerns common to applications such as logging, metrics or audit.
+Each layer may observe the bytes of the message read and the reply
+written by the layers within it using Session.Tee, and may short-circuit
+the message, replying with an <rpc-error> using Session.Reject instead
+of calling the next layer.
+
+Errors
+
+Errors encountered by a Session are recorded and returned by the
+Errors method, or joined as a single error by the Err method. Problems
+with the peer's <hello> are reported as a *HelloError wrapping one of
+the sentinel errors, such as ErrNoHello or ErrInvalidSessionID, while
+a failure to agree on a framing mode is reported as
+ErrFramingNegotiation. Use errors.Is and errors.As on the result of Err
+to test for these, rather than comparing error strings.
 */
 package session
@@ -0,0 +1,107 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrNoHello indicates the peer's first message was not a <hello>
+	// in the NETCONF namespace.
+	ErrNoHello = errors.New("missing <hello> element")
+	// ErrNoCapabilities indicates the peer's <hello> had no non-empty
+	// <capability> elements.
+	ErrNoCapabilities = errors.New("missing non-empty <capability> element(s)")
+	// ErrMissingSessionIDValue indicates the peer's <hello> had an empty <session-id>.
+	ErrMissingSessionIDValue = errors.New("missing session-id value")
+	// ErrInvalidSessionID indicates the peer's <hello> had an invalid <session-id> value.
+	ErrInvalidSessionID = errors.New("invalid session-id value")
+	// ErrNoSessionID indicates a client session received no <session-id> from the server.
+	ErrNoSessionID = errors.New("no session-id received for client session")
+	// ErrUnexpectedSessionID indicates a server session received a <session-id> from the client.
+	ErrUnexpectedSessionID = errors.New("session-id received from client peer")
+	// ErrFramingNegotiation indicates the peers share no :base:1.x capability.
+	ErrFramingNegotiation = errors.New("session failed to negotiate framing mode")
+	// ErrNotEstablished indicates an operation requiring an established
+	// session was attempted on a session which is not established.
+	ErrNotEstablished = errors.New("session not established")
+)
+
+// HelloError is an error in the peer's <hello> message, wrapping
+// one of the Err* values (e.g. ErrNoHello) with optional detail.
+type HelloError struct {
+	Err    error
+	Detail string
+}
+
+func (e *HelloError) Error() string {
+	if e.Detail == "" {
+		return e.Err.Error()
+	}
+	return e.Err.Error() + ": " + e.Detail
+}
+
+// Unwrap returns the wrapped error
+func (e *HelloError) Unwrap() error { return e.Err }
+
+// PanicError is a panic in a Handler recovered by Run (see
+// Config.RecoverPanics).
+type PanicError struct {
+	// Value is the value passed to panic
+	Value interface{}
+	// Stack is the stack trace of the goroutine which panicked
+	Stack []byte
+}
+
+func (e *PanicError) Error() string { return fmt.Sprintf("session handler panic: %v", e.Value) }
+
+// Unwrap returns the panic value, if it is an error
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// Err returns the session's errors (see Errors) joined as a single error,
+// or nil if there are none. The error returned matches each of the
+// session's errors with errors.Is and errors.As.
+func (s *Session) Err() error {
+	if len(s.State.errs) == 0 {
+		return nil
+	}
+	return joinError(append([]error(nil), s.State.errs...))
+}
+
+// joinError is a list of errors, matching each with errors.Is and errors.As.
+type joinError []error
+
+func (e joinError) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Unwrap returns the errors, for errors.Is and errors.As.
+func (e joinError) Unwrap() []error { return e }
+
+// Is returns true if any error matches target
+func (e joinError) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first error matching target
+func (e joinError) As(target interface{}) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package session
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionErr(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		config     Config
+		input      string
+		wantErr    error
+		wantDetail string
+	}{
+		{
+			name:       "not a hello",
+			input:      `<foo/>]]>]]>`,
+			wantErr:    ErrNoHello,
+			wantDetail: "received <foo>",
+		},
+		{
+			name:    "no capabilities",
+			input:   `<hello xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><capabilities/><session-id>1</session-id></hello>]]>]]>`,
+			wantErr: ErrNoCapabilities,
+		},
+		{
+			name:    "client without session-id",
+			input:   `<hello xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><capabilities><capability>urn:ietf:params:netconf:base:1.0</capability></capabilities></hello>]]>]]>`,
+			wantErr: ErrNoSessionID,
+		},
+		{
+			name:       "server with session-id",
+			config:     Config{ID: 1},
+			input:      `<hello xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><capabilities><capability>urn:ietf:params:netconf:base:1.0</capability></capabilities><session-id>7</session-id></hello>]]>]]>`,
+			wantErr:    ErrUnexpectedSessionID,
+			wantDetail: "7",
+		},
+		{
+			name:    "no shared framing",
+			config:  Config{Capabilities: Capabilities{capBase11}},
+			input:   `<hello xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><capabilities><capability>urn:ietf:params:netconf:base:1.0</capability></capabilities><session-id>1</session-id></hello>]]>]]>`,
+			wantErr: ErrFramingNegotiation,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			a := assert.New(t)
+			config := tc.config
+			if config.Capabilities == nil {
+				config.Capabilities = Capabilities{capBase10, capBase11}
+			}
+			s := New(strings.NewReader(tc.input), closeBuffer{&bytes.Buffer{}}, config)
+			a.False(s.InitialHandshake())
+			err := s.Err()
+			a.ErrorIs(err, tc.wantErr)
+			var herr *HelloError
+			if tc.wantErr == ErrFramingNegotiation {
+				a.False(errors.As(err, &herr))
+				return
+			}
+			if a.ErrorAs(err, &herr) {
+				a.Equal(tc.wantErr, herr.Err)
+				a.Equal(tc.wantDetail, herr.Detail)
+			}
+		})
+	}
+}
+
+func TestSessionErrJoined(t *testing.T) {
+	a := assert.New(t)
+	s := New(strings.NewReader(""), closeBuffer{&bytes.Buffer{}}, Config{})
+	a.NoError(s.Err())
+	s.AddError(&HelloError{Err: ErrNoHello})
+	s.AddError(io.ErrUnexpectedEOF)
+	err := s.Err()
+	a.ErrorIs(err, ErrNoHello)
+	a.ErrorIs(err, io.ErrUnexpectedEOF)
+	a.NotErrorIs(err, ErrNoCapabilities)
+	a.Equal("missing <hello> element\nunexpected EOF", err.Error())
+}
@@ -2,6 +2,7 @@ package session
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"math/rand"
 	"strings"
@@ -131,3 +132,153 @@ func (srv *mockSession) OnError(s *Session) { srv.errs = append(srv.errs, s.Erro
 func (srv *mockSession) OnClose(s *Session) { srv.c, srv.csc = true, s.State.Status == StatusClosed }
 
 var _ Handler = &mockSession{}
+
+func TestSessionChain(t *testing.T) {
+	input := `<hello xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<capabilities><capability>urn:ietf:params:netconf:base:1.0</capability></capabilities>
+</hello>]]>]]><rpc message-id="1" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><get/></rpc>]]>]]>` +
+		`<rpc message-id="2" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><get/></rpc>]]>]]>`
+	dst := closeBuffer{&bytes.Buffer{}}
+	s := New(strings.NewReader(input), dst, Config{ID: 1, Capabilities: Capabilities{capBase10}})
+	a := assert.New(t)
+
+	var order []string
+	var in, out []string
+	observe := func(s *Session, next HandlerFunc) {
+		order = append(order, "observe")
+		var inBuf, outBuf bytes.Buffer
+		untee := s.Tee(&inBuf, &outBuf)
+		next(s)
+		untee()
+		in, out = append(in, inBuf.String()), append(out, outBuf.String())
+	}
+	messages := 0
+	reject := func(s *Session, next HandlerFunc) {
+		order = append(order, "reject")
+		if messages++; messages == 2 {
+			if err := s.Reject(errors.New("too many requests")); err != ErrEndOfStream {
+				a.NoError(err)
+			}
+			return
+		}
+		next(s)
+	}
+	h := &replyHandler{}
+	s.Run(Chain(h, observe, reject))
+
+	a.Equal([]string{"observe", "reject", "observe", "reject", "observe", "reject"}, order)
+	a.Equal(1, h.replies)
+	a.Equal(StatusClosed, s.State.Status)
+	a.Equal([]string{
+		`<rpc message-id="1" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><get/></rpc>`,
+		`<rpc message-id="2" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><get/></rpc>`,
+		``,
+	}, in)
+	wantErrorReply := `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="2">` +
+		`<rpc-error><error-type>application</error-type><error-tag>operation-failed</error-tag>` +
+		`<error-severity>error</error-severity><error-message>too many requests</error-message></rpc-error></rpc-reply>`
+	a.Equal([]string{`<rpc-reply message-id="1"><ok/></rpc-reply>`, wantErrorReply, ``}, out)
+	a.Contains(dst.String(), wantErrorReply+"]]>]]>")
+}
+
+// replyHandler replies <ok/> to each message
+type replyHandler struct {
+	mockSession
+	replies int
+}
+
+func (h *replyHandler) OnMessage(s *Session) {
+	attrs, err := discardMessage(s.Incoming())
+	if err == ErrEndOfStream {
+		s.State.Status = StatusClosed
+		return
+	}
+	h.replies++
+	_, _ = io.WriteString(s.Outgoing(), `<rpc-reply message-id="`+attrs[0].Value+`"><ok/></rpc-reply>`)
+	_ = s.Outgoing().Close()
+}
+
+func TestSessionRecoverPanics(t *testing.T) {
+	input := `<hello xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<capabilities><capability>urn:ietf:params:netconf:base:1.0</capability></capabilities>
+</hello>]]>]]>`
+	for _, id := range []string{"1", "2", "3"} {
+		input += `<rpc message-id="` + id + `" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><get/></rpc>]]>]]>`
+	}
+	errorReply := `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="2">` +
+		`<rpc-error><error-type>application</error-type><error-tag>operation-failed</error-tag>` +
+		`<error-severity>error</error-severity><error-message>internal error</error-message></rpc-error></rpc-reply>]]>]]>`
+	for _, tc := range []struct {
+		name        string
+		config      Config
+		panicAfter  bool
+		wantReplies int
+		wantOutput  string
+		wantOnError bool
+	}{
+		{
+			name:        "continue",
+			config:      Config{RecoverPanics: true, ContinueAfterPanic: true},
+			wantReplies: 2,
+			wantOutput:  errorReply,
+		},
+		{
+			name:        "error",
+			config:      Config{RecoverPanics: true},
+			wantReplies: 1,
+			wantOutput:  errorReply,
+			wantOnError: true,
+		},
+		{
+			name:        "partial reply",
+			config:      Config{RecoverPanics: true, ContinueAfterPanic: true},
+			panicAfter:  true,
+			wantReplies: 3,
+			wantOutput:  `<rpc-reply message-id="2"><ok/></rpc-reply>]]>]]>`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			a := assert.New(t)
+			config := tc.config
+			config.ID, config.Capabilities = 1, Capabilities{capBase10}
+			dst := closeBuffer{&bytes.Buffer{}}
+			s := New(strings.NewReader(input), dst, config)
+			h := &panicHandler{after: tc.panicAfter}
+			s.Run(h)
+			a.Equal(tc.wantReplies, h.replies)
+			a.Contains(dst.String(), tc.wantOutput)
+			var perr *PanicError
+			if a.ErrorAs(s.Err(), &perr) {
+				a.Equal("boom", perr.Value)
+				a.Contains(string(perr.Stack), "panicHandler")
+			}
+			// OnError is called only when the session stops after the panic
+			a.Equal(tc.wantOnError, len(h.errs) > 0)
+		})
+	}
+}
+
+// panicHandler replies <ok/> to each message, panicking on message-id 2
+// before replying (or after partially replying, if after is set)
+type panicHandler struct {
+	replyHandler
+	after bool
+}
+
+func (h *panicHandler) OnMessage(s *Session) {
+	attrs, err := discardMessage(s.Incoming())
+	if err == ErrEndOfStream {
+		s.State.Status = StatusClosed
+		return
+	}
+	id := attrs[0].Value
+	if id == "2" && !h.after {
+		panic("boom")
+	}
+	h.replies++
+	_, _ = io.WriteString(s.Outgoing(), `<rpc-reply message-id="`+id+`"><ok/></rpc-reply>`)
+	if id == "2" {
+		panic("boom")
+	}
+	_ = s.Outgoing().Close()
+}
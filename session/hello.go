@@ -0,0 +1,205 @@
+package session
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/antchfx/xmlquery"
+)
+
+// Hello is a NETCONF <hello> message
+type Hello struct {
+	// Capabilities holds the <capability> URIs
+	Capabilities Capabilities
+	// SessionID is the <session-id> value, sent by servers only.
+	// Zero indicates no session-id.
+	SessionID uint32
+	// Extensions holds any other child elements of the <hello>, such as
+	// vendor extensions, each as a raw XML element. Extensions must
+	// declare any namespaces they use.
+	Extensions []string
+}
+
+// EncodeHello writes the <hello> message h to w
+func EncodeHello(w io.Writer, h *Hello) error {
+	xe := xml.NewEncoder(w)
+	err := xe.EncodeToken(seHello)
+	if err == nil {
+		err = xe.EncodeToken(seCapabilities)
+	}
+	for _, cap := range h.Capabilities {
+		if err != nil {
+			break
+		}
+		_ = xe.EncodeToken(seCapability)
+		_ = xe.EncodeToken(xml.CharData(cap))
+		err = xe.EncodeToken(seCapability.End())
+	}
+	if err == nil {
+		err = xe.EncodeToken(seCapabilities.End())
+	}
+	if err == nil && h.SessionID != 0 {
+		_ = xe.EncodeToken(seSessionID)
+		_ = xe.EncodeToken(xml.CharData(strconv.FormatUint(uint64(h.SessionID), 10)))
+		err = xe.EncodeToken(seSessionID.End())
+	}
+	if err == nil && len(h.Extensions) > 0 {
+		// extensions are written verbatim, inside the <hello> element
+		if err = xe.Flush(); err == nil {
+			_, err = io.WriteString(w, strings.Join(h.Extensions, ""))
+		}
+	}
+	if err == nil {
+		_ = xe.EncodeToken(seHello.End())
+		err = xe.Flush()
+	}
+	return err
+}
+
+// DecodeHello reads a <hello> message from r.
+//
+// Returns a *HelloError if the message is not a <hello> in the NETCONF
+// namespace (ErrNoHello), has no non-empty <capability> elements
+// (ErrNoCapabilities), or has an empty or invalid <session-id>
+// (ErrMissingSessionIDValue, ErrInvalidSessionID).
+func DecodeHello(r io.Reader) (*Hello, error) {
+	return decodeHello(r, 0, nil)
+}
+
+// decodeHello reads a <hello> message from r, as per DecodeHello, but
+// tolerating the <hello> namespace quirks (QuirkHelloNoNamespace and
+// QuirkHelloWrongNamespace) set in tolerate. Each quirk exhibited is
+// reported to quirk, which may be nil if tolerate is zero.
+func decodeHello(r io.Reader, tolerate Quirk, quirk func(q Quirk, detail string)) (*Hello, error) {
+	// parse the incoming message's XML document
+	doc, err := xmlquery.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+	// look for a <hello> element in the NETCONF namespace
+	hello := firstChild(doc, "hello", xmlnsNetconf)
+	if root := firstElement(doc); hello == nil && root != nil && root.Data == "hello" {
+		switch {
+		case root.NamespaceURI == "" && tolerate&QuirkHelloNoNamespace != 0:
+			hello = root
+			quirk(QuirkHelloNoNamespace, "<hello> received without the NETCONF namespace")
+		case root.NamespaceURI != "" && tolerate&QuirkHelloWrongNamespace != 0:
+			hello = root
+			quirk(QuirkHelloWrongNamespace, "<hello> received in namespace "+root.NamespaceURI)
+		}
+	}
+	if hello == nil {
+		herr := &HelloError{Err: ErrNoHello}
+		if root := firstElement(doc); root != nil {
+			herr.Detail = "received <" + root.Data + ">"
+			if root.NamespaceURI != "" {
+				herr.Detail += " in namespace " + root.NamespaceURI
+			}
+		}
+		return nil, herr
+	}
+	// children are in the <hello> namespace, or in no namespace if
+	// tolerated (e.g., for an unprefixed child of a prefixed <hello>)
+	ns := []string{hello.NamespaceURI}
+	if hello.NamespaceURI != "" && tolerate&QuirkHelloNoNamespace != 0 {
+		ns = append(ns, "")
+	}
+	noNamespace := false
+	child := func(n *xmlquery.Node) *xmlquery.Node {
+		if n != nil && n.NamespaceURI != hello.NamespaceURI && !noNamespace {
+			noNamespace = true
+			quirk(QuirkHelloNoNamespace, "<"+n.Data+"> received without the <hello> namespace")
+		}
+		return n
+	}
+
+	h := &Hello{}
+	for caps := child(firstChild(hello, "capabilities", ns...)); caps != nil; caps = child(nextSibling(caps, "capabilities", ns...)) {
+		for c := child(firstChild(caps, "capability", ns...)); c != nil; c = child(nextSibling(c, "capability", ns...)) {
+			if x := strings.TrimSpace(c.InnerText()); x != "" {
+				h.Capabilities = append(h.Capabilities, x)
+			}
+		}
+	}
+	// error if no capabilities were found (we must have seen at least
+	// :base:1.0 or :base:1.1 per the specification)
+	if len(h.Capabilities) == 0 {
+		return nil, &HelloError{Err: ErrNoCapabilities}
+	}
+
+	if sid := child(firstChild(hello, "session-id", ns...)); sid != nil {
+		idVal := strings.TrimSpace(sid.InnerText())
+		if idVal == "" {
+			return nil, &HelloError{Err: ErrMissingSessionIDValue}
+		}
+		v, perr := strconv.ParseUint(idVal, 10, 32)
+		if perr != nil || v == 0 {
+			return nil, &HelloError{Err: ErrInvalidSessionID, Detail: strconv.Quote(idVal)}
+		}
+		h.SessionID = uint32(v)
+	}
+
+	for n := hello.FirstChild; n != nil; n = n.NextSibling {
+		if n.Type != xmlquery.ElementNode {
+			continue
+		}
+		if isElement(n, "capabilities", ns) || isElement(n, "session-id", ns) {
+			continue
+		}
+		h.Extensions = append(h.Extensions, n.OutputXML(true))
+	}
+	return h, nil
+}
+
+// firstElement returns the first child element of n
+func firstElement(n *xmlquery.Node) *xmlquery.Node {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == xmlquery.ElementNode {
+			return c
+		}
+	}
+	return nil
+}
+
+// firstChild returns the first child element of n with the local name,
+// in any of the namespaces
+func firstChild(n *xmlquery.Node, local string, namespaces ...string) *xmlquery.Node {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if isElement(c, local, namespaces) {
+			return c
+		}
+	}
+	return nil
+}
+
+// nextSibling returns the next sibling element of n with the local name,
+// in any of the namespaces
+func nextSibling(n *xmlquery.Node, local string, namespaces ...string) *xmlquery.Node {
+	for c := n.NextSibling; c != nil; c = c.NextSibling {
+		if isElement(c, local, namespaces) {
+			return c
+		}
+	}
+	return nil
+}
+
+func isElement(n *xmlquery.Node, local string, namespaces []string) bool {
+	if n.Type != xmlquery.ElementNode || n.Data != local {
+		return false
+	}
+	for _, ns := range namespaces {
+		if n.NamespaceURI == ns {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	seHello        = xml.StartElement{Name: xn("hello", xmlnsNetconf)}
+	seCapabilities = xml.StartElement{Name: xn("capabilities")}
+	seCapability   = xml.StartElement{Name: xn("capability")}
+	seSessionID    = xml.StartElement{Name: xn("session-id")}
+)
@@ -0,0 +1,152 @@
+package session
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHelloEncodeDecode(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		h    *Hello
+		want string
+	}{
+		{
+			name: "client",
+			h:    &Hello{Capabilities: Capabilities{capBase10, capBase11}},
+			want: `<hello xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><capabilities><capability>urn:ietf:params:netconf:base:1.0</capability><capability>urn:ietf:params:netconf:base:1.1</capability></capabilities></hello>`,
+		},
+		{
+			name: "server with extensions",
+			h: &Hello{
+				Capabilities: Capabilities{capBase11},
+				SessionID:    5,
+				Extensions:   []string{`<ext xmlns="urn:example:vendor"><v>1</v></ext>`},
+			},
+			want: `<hello xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><capabilities><capability>urn:ietf:params:netconf:base:1.1</capability></capabilities><session-id>5</session-id><ext xmlns="urn:example:vendor"><v>1</v></ext></hello>`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			a := assert.New(t)
+			b := &bytes.Buffer{}
+			a.NoError(EncodeHello(b, tc.h))
+			a.Equal(tc.want, b.String())
+			h, err := DecodeHello(b)
+			if a.NoError(err) {
+				a.Equal(tc.h, h)
+			}
+		})
+	}
+
+	_, err := DecodeHello(strings.NewReader(`<hello xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><capabilities><capability>urn:x</capability></capabilities><session-id>0</session-id></hello>`))
+	assert.EqualError(t, err, `invalid session-id value: "0"`)
+	assert.ErrorIs(t, err, ErrInvalidSessionID)
+}
+
+func TestCapabilitiesShared(t *testing.T) {
+	a := assert.New(t)
+	ours := Capabilities{capBase10, capBase11, "urn:ietf:params:netconf:capability:with-defaults:1.0"}
+	peer := Capabilities{capBase11, "urn:ietf:params:netconf:capability:with-defaults:1.0?basic-mode=explicit", "urn:x"}
+	a.True(ours.Has("urn:ietf:params:netconf:capability:with-defaults:1.0?basic-mode=trim"))
+	a.False(ours.Has("urn:x"))
+	a.Equal(Capabilities{capBase11, "urn:ietf:params:netconf:capability:with-defaults:1.0?basic-mode=explicit"}, ours.Shared(peer))
+}
+
+func TestHelloHooks(t *testing.T) {
+	const input = `<hello xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><capabilities>
+<capability>urn:ietf:params:netconf:base:1.0</capability>
+<capability>urn:ietf:params:netconf:base:1.1</capability>
+<capability>urn:example:feature</capability>
+</capabilities><ext xmlns="urn:example:vendor">client</ext></hello>]]>]]>`
+	errVeto := errors.New("vetoed")
+	for _, tc := range []struct {
+		name        string
+		config      Config
+		wantErr     error
+		wantOutput  string
+		wantEnabled Capabilities
+		wantChunked bool
+	}{
+		{
+			name:        "defaults",
+			config:      Config{Capabilities: Capabilities{capBase10, capBase11, "urn:example:feature"}},
+			wantEnabled: Capabilities{capBase10, capBase11, "urn:example:feature"},
+			wantChunked: true,
+		},
+		{
+			name: "veto",
+			config: Config{
+				Capabilities: Capabilities{capBase10, capBase11},
+				OnHello: func(s *Session, h *Hello) error {
+					if len(h.Extensions) == 1 && strings.Contains(h.Extensions[0], ">client</ext>") {
+						return errVeto
+					}
+					return nil
+				},
+			},
+			wantErr: errVeto,
+		},
+		{
+			name: "our extension",
+			config: Config{
+				Capabilities: Capabilities{capBase10},
+				Hello:        func(h *Hello) { h.Extensions = append(h.Extensions, `<ext xmlns="urn:example:vendor">server</ext>`) },
+			},
+			wantOutput:  `<session-id>1</session-id><ext xmlns="urn:example:vendor">server</ext></hello>]]>]]>`,
+			wantEnabled: Capabilities{capBase10},
+		},
+		{
+			name: "hook removes base 1.1",
+			config: Config{
+				Capabilities: Capabilities{capBase10, capBase11, "urn:example:feature"},
+				Hello:        func(h *Hello) { h.Capabilities = Capabilities{capBase10, "urn:example:feature"} },
+			},
+			wantOutput:  `<capabilities><capability>urn:ietf:params:netconf:base:1.0</capability><capability>urn:example:feature</capability></capabilities><session-id>1</session-id></hello>]]>]]>`,
+			wantEnabled: Capabilities{capBase10, "urn:example:feature"},
+		},
+		{
+			name: "disable chunked framing",
+			config: Config{
+				Capabilities: Capabilities{capBase10, capBase11, "urn:example:feature"},
+				EnableCapabilities: func(shared Capabilities) (enabled Capabilities) {
+					for _, c := range shared {
+						if c != capBase11 {
+							enabled = append(enabled, c)
+						}
+					}
+					return enabled
+				},
+			},
+			wantEnabled: Capabilities{capBase10, "urn:example:feature"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			a := assert.New(t)
+			dst := closeBuffer{&bytes.Buffer{}}
+			tc.config.ID = 1
+			s := New(strings.NewReader(input), dst, tc.config)
+			ok := s.InitialHandshake()
+			if tc.wantErr != nil {
+				a.False(ok)
+				a.Equal([]error{tc.wantErr}, s.Errors())
+				return
+			}
+			if !a.True(ok, "%v", s.Errors()) {
+				return
+			}
+			a.Equal(tc.wantEnabled, s.State.Enabled)
+			a.Equal([]string{`<ext xmlns="urn:example:vendor">client</ext>`}, s.State.Hello.Extensions)
+			if tc.wantOutput != "" {
+				a.True(strings.HasSuffix(dst.String(), tc.wantOutput), dst.String())
+			}
+			dst.Reset()
+			s.Outgoing().Write([]byte("<rpc-reply/>"))
+			s.Outgoing().Close()
+			a.Equal(tc.wantChunked, strings.HasPrefix(dst.String(), "\n#"))
+		})
+	}
+}
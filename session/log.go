@@ -0,0 +1,82 @@
+package session
+
+import (
+	"bytes"
+)
+
+// Logger is a leveled, structured logger taking a message and
+// alternating key/value pairs, compatible with *slog.Logger.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// nopLogger discards all log records
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...interface{}) {}
+func (nopLogger) Info(string, ...interface{})  {}
+func (nopLogger) Warn(string, ...interface{})  {}
+func (nopLogger) Error(string, ...interface{}) {}
+
+// logDumpLimit is the maximum message content logged by Config.LogMessages
+const logDumpLimit = 64 * 1024
+
+// log returns the session's logger
+func (s *Session) log() Logger {
+	if s.Config.Logger == nil {
+		return nopLogger{}
+	}
+	return s.Config.Logger
+}
+
+// setupLogging attaches message loggers to the session's transport
+func (s *Session) setupLogging() {
+	if s.Config.Logger == nil {
+		return
+	}
+	s.inLog = &messageLog{s: s, msg: "message received"}
+	out := &messageLog{s: s, msg: "message sent"}
+	s.reader.Tee = s.inLog
+	s.reader.OnError = func(err error) { s.log().Warn("framing error", "error", err) }
+	s.writer.Tee, s.writer.OnEnd = out, out.end
+}
+
+// messageLog logs the size, and optionally the content, of each message
+type messageLog struct {
+	s   *Session
+	msg string
+	n   int
+	buf bytes.Buffer
+}
+
+func (l *messageLog) Write(b []byte) (int, error) {
+	l.n += len(b)
+	if room := logDumpLimit - l.buf.Len(); l.s.Config.LogMessages && room > 0 {
+		if len(b) < room {
+			room = len(b)
+		}
+		l.buf.Write(b[:room])
+	}
+	return len(b), nil
+}
+
+// end logs the message, and resets l for the next message
+func (l *messageLog) end() {
+	args := []interface{}{"session-id", l.s.State.ID, "bytes", l.n}
+	if l.s.Config.LogMessages {
+		content := l.buf.Bytes()
+		if l.s.Config.LogRedact != nil {
+			content = l.s.Config.LogRedact(content)
+		}
+		args = append(args, "xml", string(content))
+		if l.n > logDumpLimit {
+			args = append(args, "truncated", true)
+		}
+	}
+	l.s.log().Debug(l.msg, args...)
+	l.n = 0
+	l.buf.Reset()
+}
@@ -0,0 +1,187 @@
+package session
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+)
+
+// Middleware is a layer of message processing wrapping a Handler's
+// OnMessage method, e.g., for logging, metrics or access control.
+//
+// A Middleware calls next to pass the message on to the next layer
+// (and ultimately, the Handler). To observe the message bytes read
+// and the reply bytes written by the inner layers, the middleware
+// calls Session.Tee before calling next. To short-circuit the message,
+// the middleware does not call next, instead replying with Session.Reject.
+type Middleware func(s *Session, next HandlerFunc)
+
+// Chain returns a Handler calling the methods of h, with its OnMessage
+// method wrapped by the middleware layers mw. The first middleware
+// is the outermost layer, i.e., sees each message first.
+func Chain(h Handler, mw ...Middleware) Handler {
+	next := h.OnMessage
+	for i := len(mw) - 1; i >= 0; i-- {
+		next = wrap(mw[i], next)
+	}
+	return &chain{Handler: h, next: next}
+}
+
+func wrap(mw Middleware, next HandlerFunc) HandlerFunc {
+	return func(s *Session) { mw(s, next) }
+}
+
+type chain struct {
+	Handler
+	next HandlerFunc
+}
+
+func (c *chain) OnMessage(s *Session) { c.next(s) }
+
+// Tee causes data subsequently read from Incoming messages to also be
+// written to in, and data written to Outgoing messages to also be written
+// to out, until the returned untee function is called. Either of in or
+// out may be nil. Errors writing to in or out are ignored.
+func (s *Session) Tee(in, out io.Writer) (untee func()) {
+	t := &tee{in: in, out: out}
+	s.tees = append(s.tees, t)
+	s.setTees()
+	return func() {
+		for i := range s.tees {
+			if s.tees[i] == t {
+				s.tees = append(s.tees[:i], s.tees[i+1:]...)
+				break
+			}
+		}
+		s.setTees()
+	}
+}
+
+type tee struct{ in, out io.Writer }
+
+func (s *Session) setTees() {
+	var in, out multiWriter
+	for _, t := range s.tees {
+		if t.in != nil {
+			in = append(in, t.in)
+		}
+		if t.out != nil {
+			out = append(out, t.out)
+		}
+	}
+	var r, w io.Writer
+	if len(in) > 0 {
+		r = in
+	}
+	if len(out) > 0 {
+		w = out
+	}
+	s.Message.SetTee(r, w)
+}
+
+// multiWriter writes to all of its writers, ignoring their errors
+type multiWriter []io.Writer
+
+func (mw multiWriter) Write(b []byte) (int, error) {
+	for _, w := range mw {
+		_, _ = w.Write(b)
+	}
+	return len(b), nil
+}
+
+// Reject discards the remainder of the Incoming message and sends
+// an <rpc-reply> containing a single <rpc-error> as the Outgoing
+// message, copying the attributes (e.g., message-id) of the incoming
+// <rpc> element.
+//
+// If rpcError (or an error it wraps) implements xml.Marshaler, it is
+// encoded as the <rpc-error> element (e.g., an *ops.RPCError).
+// Otherwise, an operation-failed error with rpcError's message is sent.
+//
+// If the Incoming stream has ended, the session status is set to
+// StatusClosed and ErrEndOfStream is returned without a reply being sent.
+func (s *Session) Reject(rpcError error) error {
+	attrs, err := discardMessage(s.Incoming())
+	if err == ErrEndOfStream {
+		s.setStatus(StatusClosed)
+		return err
+	}
+	// the request may be malformed, but we can still reply
+	return s.replyError(attrs, rpcError)
+}
+
+// discardMessage reads the remainder of the message r, returning
+// the attributes of the message's root element, if any were read.
+func discardMessage(r io.Reader) (attrs []xml.Attr, err error) {
+	dec := xml.NewDecoder(r)
+	for {
+		var tok xml.Token
+		if tok, err = dec.RawToken(); err != nil {
+			break
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			attrs = replyAttrs(start.Attr)
+			break
+		}
+	}
+	if err == nil || err == io.EOF {
+		_, err = io.Copy(io.Discard, r)
+	}
+	return
+}
+
+// replyAttrs returns the attributes of an <rpc> element (as returned
+// by xml.Decoder.RawToken) to be copied to an <rpc-reply>, being the
+// unprefixed attributes other than the namespace declaration.
+func replyAttrs(in []xml.Attr) (out []xml.Attr) {
+	for _, a := range in {
+		if a.Name.Space == "xmlns" || (a.Name.Space == "" && a.Name.Local == "xmlns") {
+			continue
+		}
+		if a.Name.Space != "" {
+			// prefixed attributes are dropped, as their namespace
+			// declarations are not copied
+			continue
+		}
+		out = append(out, a)
+	}
+	return
+}
+
+// operationFailed is an operation-failed <rpc-error>
+type operationFailed struct {
+	Type     string `xml:"error-type"`
+	Tag      string `xml:"error-tag"`
+	Severity string `xml:"error-severity"`
+	Message  string `xml:"error-message,omitempty"`
+}
+
+func newOperationFailed(err error) *operationFailed {
+	return &operationFailed{Type: "application", Tag: "operation-failed", Severity: "error", Message: err.Error()}
+}
+
+// replyError sends an <rpc-reply> containing rpcError, with attrs
+func (s *Session) replyError(attrs []xml.Attr, rpcError error) error {
+	var content interface{} = newOperationFailed(rpcError)
+	var m xml.Marshaler
+	if errors.As(rpcError, &m) {
+		content = m
+	}
+	w := s.Outgoing()
+	enc := xml.NewEncoder(w)
+	reply := xml.StartElement{Name: xn("rpc-reply", xmlnsNetconf), Attr: attrs}
+	err := enc.EncodeToken(reply)
+	if err == nil {
+		err = enc.EncodeElement(content, xml.StartElement{Name: xn("rpc-error")})
+	}
+	if err == nil {
+		err = enc.EncodeToken(reply.End())
+	}
+	if err == nil {
+		err = enc.Flush()
+	}
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
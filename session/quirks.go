@@ -0,0 +1,66 @@
+package session
+
+import "strings"
+
+// Strictness is the session's protocol conformance strictness level
+type Strictness int
+
+const (
+	// Strict sessions require peers to conform to the NETCONF
+	// specifications, except for any Quirks enabled in the Config.
+	Strict Strictness = iota
+	// Lenient sessions tolerate all known non-conformant peer behavior.
+	Lenient
+)
+
+// Quirk is a set of non-conformant peer behaviors (bit flags)
+type Quirk uint
+
+const (
+	// QuirkMissingSessionID tolerates servers omitting the <session-id>
+	// from their <hello> (client sessions only). State.ID remains zero.
+	QuirkMissingSessionID Quirk = 1 << iota
+	// QuirkHelloNoNamespace tolerates a <hello> (and its children) in no
+	// namespace, instead of the NETCONF base namespace, and children of
+	// the <hello> in no namespace.
+	QuirkHelloNoNamespace
+	// QuirkEOMAfterChunked tolerates peers continuing to use end of
+	// message framing after :base:1.1 chunked framing was negotiated.
+	QuirkEOMAfterChunked
+	// QuirkTrailingGarbage tolerates data (e.g., extra newlines) following
+	// the <hello> message's end of message marker, before the next message.
+	QuirkTrailingGarbage
+	// QuirkHelloWrongNamespace tolerates a <hello> (and its children) in a
+	// namespace other than the NETCONF base namespace, such as a
+	// capability URI.
+	QuirkHelloWrongNamespace
+)
+
+var quirkNames = []string{"missing-session-id", "hello-no-namespace", "eom-after-chunked", "trailing-garbage",
+	"hello-wrong-namespace"}
+
+// String returns the names of the quirks set in q, separated by "|"
+func (q Quirk) String() string {
+	var names []string
+	for i, name := range quirkNames {
+		if q&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, "|")
+}
+
+// tolerates returns true if the session tolerates the quirk q
+func (s *Session) tolerates(q Quirk) bool {
+	return s.Config.Strictness == Lenient || s.Config.Quirks&q != 0
+}
+
+// quirk records that the peer exhibited the quirk q, calling the
+// Config.OnQuirk hook if set.
+func (s *Session) quirk(q Quirk, detail string) {
+	s.State.Quirks |= q
+	s.log().Warn("tolerated peer quirk", "session-id", s.State.ID, "quirk", q, "detail", detail)
+	if s.Config.OnQuirk != nil {
+		s.Config.OnQuirk(s, q, detail)
+	}
+}
@@ -0,0 +1,153 @@
+package session
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionQuirks(t *testing.T) {
+	const (
+		caps = `<capabilities><capability>urn:ietf:params:netconf:base:1.0</capability>` +
+			`<capability>urn:ietf:params:netconf:base:1.1</capability></capabilities>`
+		helloSID   = `<hello xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">` + caps + `<session-id>9</session-id></hello>]]>]]>`
+		helloNoSID = `<hello xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">` + caps + `</hello>]]>]]>`
+		helloNoNS  = `<hello>` + caps + `<session-id>9</session-id></hello>]]>]]>`
+		// a capability URI used as the namespace
+		helloWrongNS = `<hello xmlns="urn:ietf:params:netconf:base:1.1">` + caps + `<session-id>9</session-id></hello>]]>]]>`
+		chunkedRPC   = "\n#6\n<rpc/>\n##\n"
+		eomRPC       = "<rpc/>]]>]]>"
+	)
+	for _, tc := range []struct {
+		name       string
+		strictness Strictness
+		quirks     Quirk
+		input      string
+		wantErr    string
+		wantReadOK bool
+		wantQuirks Quirk
+	}{
+		{name: "conformant", input: helloSID + chunkedRPC, wantReadOK: true},
+		{
+			name:    "missing session-id strict",
+			input:   helloNoSID + chunkedRPC,
+			wantErr: "no session-id received for client session",
+		},
+		{
+			name:       "missing session-id tolerated",
+			quirks:     QuirkMissingSessionID,
+			input:      helloNoSID + chunkedRPC,
+			wantReadOK: true,
+			wantQuirks: QuirkMissingSessionID,
+		},
+		{
+			name:    "hello without namespace strict",
+			input:   helloNoNS + chunkedRPC,
+			wantErr: "missing <hello> element: received <hello>",
+		},
+		{
+			name:       "hello without namespace tolerated",
+			quirks:     QuirkHelloNoNamespace,
+			input:      helloNoNS + chunkedRPC,
+			wantReadOK: true,
+			wantQuirks: QuirkHelloNoNamespace,
+		},
+		{
+			name: "prefixed hello",
+			input: `<nc:hello xmlns:nc="urn:ietf:params:xml:ns:netconf:base:1.0"><nc:capabilities>` +
+				"<nc:capability>\n\turn:ietf:params:netconf:base:1.1 </nc:capability></nc:capabilities>" +
+				`<nc:session-id>9</nc:session-id></nc:hello>]]>]]>` + chunkedRPC,
+			wantReadOK: true,
+		},
+		{
+			name:    "hello children without namespace strict",
+			input:   `<nc:hello xmlns:nc="urn:ietf:params:xml:ns:netconf:base:1.0">` + caps + `<session-id>9</session-id></nc:hello>]]>]]>` + chunkedRPC,
+			wantErr: "missing non-empty <capability> element(s)",
+		},
+		{
+			name:       "hello children without namespace tolerated",
+			quirks:     QuirkHelloNoNamespace,
+			input:      `<nc:hello xmlns:nc="urn:ietf:params:xml:ns:netconf:base:1.0">` + caps + `<session-id>9</session-id></nc:hello>]]>]]>` + chunkedRPC,
+			wantReadOK: true,
+			wantQuirks: QuirkHelloNoNamespace,
+		},
+		{
+			name:    "hello in wrong namespace strict",
+			input:   helloWrongNS + chunkedRPC,
+			wantErr: "missing <hello> element: received <hello> in namespace urn:ietf:params:netconf:base:1.1",
+		},
+		{
+			name:       "hello in wrong namespace tolerated",
+			quirks:     QuirkHelloWrongNamespace,
+			input:      helloWrongNS + chunkedRPC,
+			wantReadOK: true,
+			wantQuirks: QuirkHelloWrongNamespace,
+		},
+		{name: "eom after chunked strict", input: helloSID + eomRPC},
+		{
+			name:       "eom after chunked tolerated",
+			quirks:     QuirkEOMAfterChunked,
+			input:      helloSID + eomRPC,
+			wantReadOK: true,
+			wantQuirks: QuirkEOMAfterChunked,
+		},
+		{name: "trailing garbage strict", input: helloSID + "\r\n\x00" + chunkedRPC},
+		{
+			name:       "trailing garbage tolerated",
+			quirks:     QuirkTrailingGarbage,
+			input:      helloSID + "\r\n\x00" + chunkedRPC,
+			wantReadOK: true,
+			wantQuirks: QuirkTrailingGarbage,
+		},
+		{
+			name:       "lenient",
+			strictness: Lenient,
+			input:      strings.TrimSuffix(helloNoNS, "]]>]]>") + "]]>]]>\r\n" + eomRPC,
+			wantReadOK: true,
+			wantQuirks: QuirkHelloNoNamespace | QuirkEOMAfterChunked | QuirkTrailingGarbage,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			a := assert.New(t)
+			var hooked Quirk
+			s := New(strings.NewReader(tc.input), closeBuffer{&bytes.Buffer{}}, Config{
+				Capabilities: Capabilities{capBase10, capBase11},
+				Strictness:   tc.strictness,
+				Quirks:       tc.quirks,
+				OnQuirk: func(s *Session, q Quirk, detail string) {
+					t.Logf("quirk %v: %s", q, detail)
+					a.NotEmpty(detail)
+					hooked |= q
+				},
+			})
+			ok := s.InitialHandshake()
+			if tc.wantErr != "" {
+				if a.False(ok) {
+					a.EqualError(s.Errors()[0], tc.wantErr)
+				}
+				return
+			}
+			if !a.True(ok, "%v", s.Errors()) {
+				return
+			}
+			b, err := io.ReadAll(s.Incoming())
+			if tc.wantReadOK {
+				a.NoError(err)
+				a.Equal("<rpc/>", string(b))
+			} else {
+				a.Error(err)
+			}
+			a.Equal(tc.wantQuirks, s.State.Quirks, "got %v", s.State.Quirks)
+			a.Equal(tc.wantQuirks, hooked)
+		})
+	}
+}
+
+func TestQuirkString(t *testing.T) {
+	assert.Equal(t, "missing-session-id|trailing-garbage", (QuirkMissingSessionID | QuirkTrailingGarbage).String())
+	assert.Equal(t, "hello-no-namespace|hello-wrong-namespace", (QuirkHelloNoNamespace | QuirkHelloWrongNamespace).String())
+	assert.Equal(t, "", Quirk(0).String())
+}
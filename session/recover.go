@@ -0,0 +1,79 @@
+package session
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"runtime/debug"
+
+	"github.com/andaru/netconf/message"
+)
+
+// errInternal is the error reported to the peer for a recovered panic,
+// to avoid leaking details of the panic
+var errInternal = errors.New("internal error")
+
+// sniffLimit is the maximum number of request bytes retained in order
+// to reply to a request in flight when a handler panics
+const sniffLimit = 4096
+
+// onMessage calls h.OnMessage, recovering any panic when configured to
+func (s *Session) onMessage(h Handler) {
+	if !s.Config.RecoverPanics {
+		h.OnMessage(s)
+		return
+	}
+	in, out := s.Incoming(), s.Outgoing()
+	req, written := &sniffer{}, &counter{}
+	untee := s.Tee(req, written)
+	defer func() {
+		untee()
+		if v := recover(); v != nil {
+			s.recovered(&PanicError{Value: v, Stack: debug.Stack()}, in, out, req.Bytes(), written.n)
+		}
+	}()
+	h.OnMessage(s)
+}
+
+// recovered records the handler panic err and replies with an rpc-error
+// if a request was in flight, being one the handler had started reading
+// (req) from in but not yet started replying to on out.
+func (s *Session) recovered(err *PanicError, in io.Reader, out *message.Encoder, req []byte, written int) {
+	s.log().Error("recovered handler panic", "session-id", s.State.ID, "panic", err.Value, "stack", string(err.Stack))
+	s.State.errs = append(s.State.errs, err)
+	switch {
+	case written > 0:
+		// end any partial reply to keep the peer's message framing intact
+		_ = out.Close()
+	case len(req) > 0:
+		if _, derr := io.Copy(io.Discard, in); derr == nil || derr == ErrEndOfStream {
+			attrs, _ := discardMessage(bytes.NewReader(req))
+			s.AddError(s.replyError(attrs, errInternal))
+		}
+	}
+	if !s.Config.ContinueAfterPanic {
+		s.setStatus(StatusError)
+	}
+}
+
+// sniffer retains the first sniffLimit bytes written to it
+type sniffer struct{ bytes.Buffer }
+
+func (s *sniffer) Write(b []byte) (int, error) {
+	if room := sniffLimit - s.Len(); room > 0 {
+		if len(b) > room {
+			_, _ = s.Buffer.Write(b[:room])
+		} else {
+			_, _ = s.Buffer.Write(b)
+		}
+	}
+	return len(b), nil
+}
+
+// counter counts the bytes written to it
+type counter struct{ n int }
+
+func (c *counter) Write(b []byte) (int, error) {
+	c.n += len(b)
+	return len(b), nil
+}
@@ -2,15 +2,14 @@ package session
 
 import (
 	"encoding/xml"
-	"errors"
+	"fmt"
 	"io"
 	"strconv"
-	"strings"
+	"sync"
+	"time"
 
 	"github.com/andaru/netconf/message"
 	"github.com/andaru/netconf/transport"
-	"github.com/antchfx/xmlquery"
-	"github.com/antchfx/xpath"
 )
 
 // New returns a new NETCONF Session reading from src and writing to dst with config.
@@ -21,26 +20,37 @@ func New(src io.Reader, dst io.WriteCloser, config Config) *Session {
 		src:    src,
 		dst:    dst,
 	}
-	s.reader = transport.NewReader(src, s.onEndOfMessage)
+	s.reader = transport.NewReader(&eofReader{r: src, s: s}, s.onEndOfMessage)
 	s.writer = transport.NewWriter(dst)
 	s.Message = &message.Splitter{R: s.reader, W: s.writer}
+	s.setupLogging()
 	return s
 }
 
-// Run executes the Session s, using Handler h
+// Run executes the Session s, using Handler h.
+//
+// The initial handshake is performed unless s is already established.
+// Panics in h.OnMessage are recovered if s.Config.RecoverPanics is set.
 func Run(s *Session, h Handler) {
 	// perform the <hello> and <capabilities> exchange
-	if s.InitialHandshake() {
+	if s.State.Status == StatusEstablished || s.InitialHandshake() {
 		// session was established, run the established callback
 		h.OnEstablish(s)
+		s.timedOutClose()
+		s.syncStatus()
 		// call the session message callback while the session remains established
 		for s.State.Status == StatusEstablished {
-			h.OnMessage(s)
+			s.onMessage(h)
+			s.timedOutClose()
+			s.syncStatus()
 		}
 	}
+	// record any session timeout as the cause of the session ending
+	s.checkTimeout()
 	if s.State.Status == StatusError {
 		// session failed to establish, run the error callback
 		h.OnError(s)
+		s.syncStatus()
 	}
 	// close the session
 	s.Close()
@@ -58,6 +68,18 @@ type Session struct {
 	reader  *transport.Reader
 	writer  *transport.Writer
 	Message *message.Splitter
+
+	timers timers
+	tees   []*tee
+	sendMu sync.Mutex
+	status Status // last status notified to Config.OnStatusChange
+	inLog  *messageLog
+	closed bool
+
+	killedBy uint32 // accessed atomically
+	eof      uint32 // accessed atomically; set at end of the src stream
+	// established is set (atomically) once the session is established
+	established uint32
 }
 
 // Handler is the Session handler interface.
@@ -89,6 +111,76 @@ type Config struct {
 	ID uint32
 	// Capabilities holds our session capabilities
 	Capabilities Capabilities
+	// Username is the NETCONF username of the client of a server
+	// session, as authenticated by the SSH or TLS transport (e.g., for
+	// access control)
+	Username string
+	// SourceHost is the address of the client of a server session
+	// (e.g., as reported in RFC6470 notifications)
+	SourceHost string
+
+	// HelloTimeout, if positive, is the maximum time to wait for the
+	// peer's <hello> message, after which the session fails with
+	// ErrHelloTimeout.
+	HelloTimeout time.Duration
+	// IdleTimeout, if positive, closes an established session with
+	// ErrIdleTimeout when no message has been received from the peer
+	// for this duration (RFC6242 recommends servers close idle sessions).
+	IdleTimeout time.Duration
+	// MaxLifetime, if positive, is the maximum time the session may
+	// remain open, measured from the start of the initial handshake,
+	// after which the session is closed with ErrLifetimeExceeded.
+	MaxLifetime time.Duration
+
+	// Hello, if non-nil, is called with our <hello> message before it
+	// is sent, and may modify it, e.g., to add vendor extensions.
+	// Capabilities are negotiated from those of the modified message.
+	Hello func(*Hello)
+	// OnHello, if non-nil, is called with the peer's <hello> message
+	// once it has been received and validated. Returning a non-nil
+	// error vetoes the session, which fails with that error.
+	OnHello func(*Session, *Hello) error
+	// EnableCapabilities, if non-nil, is called with the capabilities
+	// shared by both peers and returns the capabilities to enable
+	// (see State.Enabled). Framing mode is selected from the :base:1.x
+	// capabilities enabled.
+	EnableCapabilities func(shared Capabilities) Capabilities
+
+	// Strictness is the session's conformance strictness level. Lenient
+	// sessions tolerate all Quirk behaviors of non-conformant peers.
+	Strictness Strictness
+	// Quirks holds the peer behaviors tolerated by Strict sessions
+	Quirks Quirk
+	// OnQuirk, if non-nil, is called each time a tolerated quirk is
+	// exhibited by the peer, with a description of the quirk hit.
+	OnQuirk func(s *Session, q Quirk, detail string)
+
+	// RecoverPanics causes Run to recover panics in the Handler's
+	// OnMessage method, recording them as a *PanicError session error.
+	// If the handler had started reading a request but not writing
+	// its reply, an operation-failed <rpc-error> is sent to the peer.
+	RecoverPanics bool
+	// ContinueAfterPanic causes Run to continue the session after a
+	// recovered panic; otherwise, the session moves to StatusError.
+	ContinueAfterPanic bool
+
+	// OnStatusChange, if non-nil, is called each time the session
+	// status changes, with the previous and new status. Changes made by
+	// direct assignment to State.Status (rather than SetStatus) by a
+	// Handler are notified when the handler method returns.
+	OnStatusChange func(s *Session, old, new Status)
+
+	// Logger, if non-nil, receives the session's log records: handshake
+	// steps, the framing mode negotiated, capability mismatches, framing
+	// errors, session errors, message sizes and the session close reason.
+	Logger Logger
+	// LogMessages causes the content of each message sent and received
+	// to be logged at debug level (up to 64KiB per message).
+	LogMessages bool
+	// LogRedact, if non-nil, is applied to message content before it is
+	// logged, e.g., to remove secrets using the Bytes method of a
+	// redact.Redactor.
+	LogRedact func(content []byte) []byte
 }
 
 // HandlerFunc is a Session handler function
@@ -101,7 +193,17 @@ type State struct {
 	ID uint32
 	// Capabilities holds the remote peer's capabilities
 	Capabilities Capabilities
-	// Status is the session status
+	// Enabled holds the capabilities enabled for the session, being
+	// those shared by both peers (see Config.EnableCapabilities)
+	Enabled Capabilities
+	// Hello is the peer's <hello> message
+	Hello *Hello
+	// SentHello is our <hello> message, as sent (see Config.Hello).
+	// Capabilities are negotiated from those it advertised.
+	SentHello *Hello
+	// Quirks holds the tolerated quirks exhibited by the peer
+	Quirks Quirk
+	// Status is the session status (see Session.SetStatus)
 	Status Status
 	// Counters contains session counters
 	Counters struct {
@@ -158,23 +260,48 @@ func (s *Session) Outgoing() *message.Encoder { return s.Message.Writer() }
 // if an error occurred (for either transport or validation reasons), in
 // which case Session.Errors will return non-nil and the session status will
 // be StatusError.
+//
+// Session timeouts (see Config) are started by InitialHandshake. Timeouts
+// close the session's transport (and src, if it implements io.Closer).
 func (s *Session) InitialHandshake() (ok bool) {
 	if s.State.Status == StatusInactive {
-		s.State.Status = StatusCapabilitiesExchange
+		s.setStatus(StatusCapabilitiesExchange)
+		s.timers.mu.Lock()
+		s.timers.lifetime = s.startTimer(s.Config.MaxLifetime, ErrLifetimeExceeded)
+		s.timers.hello = s.startTimer(s.Config.HelloTimeout, ErrHelloTimeout)
+		s.timers.mu.Unlock()
+		s.log().Debug("sending hello", "session-id", s.Config.ID, "capabilities", len(s.Config.Capabilities))
 		if s.sendHello(); len(s.State.errs) == 0 {
 			s.recvHello()
 		}
-		ok = len(s.State.errs) == 0
+		if s.timers.hello != nil {
+			s.timers.hello.Stop()
+		}
+		ok = !s.checkTimeout() && len(s.State.errs) == 0
+		if ok {
+			s.timers.mu.Lock()
+			s.timers.idle = s.startTimer(s.Config.IdleTimeout, ErrIdleTimeout)
+			s.timers.mu.Unlock()
+		}
 	}
 	if !ok {
-		s.State.Status = StatusError
+		s.setStatus(StatusError)
 	}
 	return ok
 }
 
 // Close closes the Session
 func (s *Session) Close() error {
-	s.State.Status = StatusClosed
+	s.stopTimers()
+	if !s.closed {
+		s.closed = true
+		args := []interface{}{"session-id", s.State.ID, "status", s.State.Status, "received", s.State.Counters.RxMsgs}
+		if len(s.State.errs) > 0 {
+			args = append(args, "reason", s.State.errs[0])
+		}
+		s.log().Info("session closed", args...)
+	}
+	s.setStatus(StatusClosed)
 	s.Outgoing().Close()
 	s.Incoming().Close()
 	err := s.dst.Close()
@@ -188,6 +315,7 @@ func (s *Session) Close() error {
 func (s *Session) AddError(errs ...error) (added int) {
 	for _, err := range errs {
 		if err != nil {
+			s.log().Error("session error", "session-id", s.State.ID, "error", err)
 			s.State.errs = append(s.State.errs, err)
 			added++
 		}
@@ -203,7 +331,11 @@ func (s *Session) Run(handler Handler) { Run(s, handler) }
 
 // onEndOfMessage performs end-of-message handling
 func (s *Session) onEndOfMessage() {
+	if s.inLog != nil {
+		s.inLog.end()
+	}
 	s.State.Counters.RxMsgs++
+	s.resetIdleTimer()
 	// close the incoming message and rotate it at the next read
 	s.Incoming().Close()
 	s.Message.FinishReader()
@@ -211,79 +343,86 @@ func (s *Session) onEndOfMessage() {
 
 // doCapabilitiesExchange performs capability exchange handling,
 // including setting the session framing mode based on the :base:1.x
-// capability seen
+// capability enabled
 func (s *Session) doCapabilitiesExchange() {
-	sc, pc := s.Config.Capabilities, s.State.Capabilities
-	base11 := sc.Has(capBase11) && pc.Has(capBase11)
-	if base10 := sc.Has(capBase10) && pc.Has(capBase10); !(base11 || base10) {
-		s.AddError(errors.New("session failed to negotiate framing mode"))
-		s.State.Status = StatusError
+	local := s.Config.Capabilities
+	if s.State.SentHello != nil {
+		local = s.State.SentHello.Capabilities
+	}
+	enabled := local.Shared(s.State.Capabilities)
+	if s.Config.EnableCapabilities != nil {
+		enabled = s.Config.EnableCapabilities(enabled)
+	}
+	s.State.Enabled = enabled
+	base11 := enabled.Has(capBase11)
+	if base10 := enabled.Has(capBase10); !(base11 || base10) {
+		s.log().Warn("capability mismatch", "session-id", s.State.ID,
+			"local", local, "peer", s.State.Capabilities, "enabled", enabled)
+		s.AddError(ErrFramingNegotiation)
+		s.setStatus(StatusError)
 		return
 	}
-	s.reader.SetFramingMode(base11)
+	s.reader.SetFramingModeLenient(base11, transport.Lenient{
+		EOMAfterChunked: s.tolerates(QuirkEOMAfterChunked),
+		OnEOMAfterChunked: func() {
+			s.quirk(QuirkEOMAfterChunked, "peer uses end of message framing after negotiating chunked framing")
+		},
+		TrailingGarbage: s.tolerates(QuirkTrailingGarbage),
+		OnTrailingGarbage: func(n int) {
+			s.quirk(QuirkTrailingGarbage, fmt.Sprintf("discarded %d bytes following <hello>", n))
+		},
+	})
 	s.writer.SetFramingMode(base11)
-	s.State.Status = StatusEstablished
+	framing := "end-of-message"
+	if base11 {
+		framing = "chunked"
+	}
+	s.setStatus(StatusEstablished)
+	s.log().Info("session established", "session-id", s.State.ID, "framing", framing, "capabilities", len(enabled))
 }
 
 func (s *Session) recvHello() {
-	// parse the incoming message's XML document
-	doc, parseErr := xmlquery.Parse(s.Incoming())
-	if s.AddError(parseErr) > 0 {
-		s.State.Status = StatusError
-		return
-	}
-	// look for a <hello> element in the NETCONF namespace
-	hello := xmlquery.QuerySelector(doc, xpNSetHello)
-	if hello == nil {
-		s.AddError(errors.New("missing <hello> element"))
-		s.State.Status = StatusError
-		return
-	}
-
-	// add capabilities
-	results := xmlquery.QuerySelectorAll(doc, xpNSetCapability)
-	for _, capability := range results {
-		if x := strings.TrimSpace(capability.InnerText()); x != "" {
-			s.State.Capabilities = append(s.State.Capabilities, x)
+	var tolerate Quirk
+	for _, q := range []Quirk{QuirkHelloNoNamespace, QuirkHelloWrongNamespace} {
+		if s.tolerates(q) {
+			tolerate |= q
 		}
 	}
-	// error if no capabilities were found (we must have seen at least
-	// :base:1.0 or :base:1.1 per the specification)
-	if len(s.State.Capabilities) == 0 {
-		s.AddError(errors.New("missing non-empty <capability> element(s)"))
-		s.State.Status = StatusError
+	hello, err := decodeHello(s.Incoming(), tolerate, s.quirk)
+	if s.AddError(err) > 0 {
+		s.setStatus(StatusError)
 		return
 	}
+	s.State.Hello = hello
+	s.State.Capabilities = hello.Capabilities
+	s.log().Debug("received hello", "session-id", hello.SessionID, "capabilities", len(hello.Capabilities))
 
-	// validate the existence and value of any session-id element.
+	// validate the existence of any session-id element.
 	// The spec states only a client should receive a <session-id>
 	// element in the <hello>.  If we have a non-zero s.Config.ID,
 	// we are a server session and should not receive one.
-	var err error
-	switch sid := xmlquery.QuerySelector(doc, xpNSetSessionID); {
-	case sid == nil && s.Config.ID == 0:
+	switch {
+	case hello.SessionID == 0 && s.Config.ID == 0 && s.tolerates(QuirkMissingSessionID):
+		s.quirk(QuirkMissingSessionID, "no session-id received for client session")
+	case hello.SessionID == 0 && s.Config.ID == 0:
 		// no session-id received but we consider ourselves a client
-		err = errors.New("no session-id received for client session")
-	case sid != nil && s.Config.ID != 0:
+		err = &HelloError{Err: ErrNoSessionID}
+	case hello.SessionID != 0 && s.Config.ID != 0:
 		// we are a server session but the peer sent us a session-id
-		err = errors.New("session-id received from client peer")
-	case sid != nil:
+		err = &HelloError{Err: ErrUnexpectedSessionID, Detail: strconv.FormatUint(uint64(hello.SessionID), 10)}
+	case hello.SessionID != 0:
 		// client session: session status takes the received session ID
-		if idVal := strings.TrimSpace(sid.InnerText()); idVal != "" {
-			v, perr := strconv.ParseUint(idVal, 10, 32)
-			if perr != nil {
-				err = errors.New("invalid session-id value")
-			}
-			s.State.ID = uint32(v)
-		} else {
-			err = errors.New("missing session-id value")
-		}
-	case sid == nil:
+		s.State.ID = hello.SessionID
+	default:
 		// server session: the session status takes our configured ID
 		s.State.ID = s.Config.ID
 	}
+	// allow the application to inspect and veto the peer's hello
+	if err == nil && s.Config.OnHello != nil {
+		err = s.Config.OnHello(s, hello)
+	}
 	if s.AddError(err) > 0 {
-		s.State.Status = StatusError
+		s.setStatus(StatusError)
 	}
 
 	// perform capabilities exchange (inc. :base:1.x protocol selection)
@@ -297,31 +436,16 @@ func (s *Session) recvHello() {
 // configured ID is non-zero.
 func (s *Session) sendHello() {
 	defer func() { s.AddError(s.Outgoing().Close()) }()
-	xe := xml.NewEncoder(s.Outgoing())
-	err := xe.EncodeToken(seHello)
-	if err == nil {
-		err = xe.EncodeToken(seCapabilities)
-	}
-	for _, cap := range s.Config.Capabilities {
-		if err != nil {
-			break
-		}
-		_ = xe.EncodeToken(seCapability)
-		_ = xe.EncodeToken(xml.CharData(cap))
-		err = xe.EncodeToken(seCapability.End())
-	}
-	if err == nil && s.Config.ID != 0 {
-		_ = xe.EncodeToken(seSessionID)
-		_ = xe.EncodeToken(xml.CharData(strconv.FormatUint(uint64(s.Config.ID), 10)))
-		err = xe.EncodeToken(seSessionID.End())
+	hello := &Hello{
+		Capabilities: append(Capabilities{}, s.Config.Capabilities...),
+		SessionID:    s.Config.ID,
 	}
-	if err == nil {
-		_ = xe.EncodeToken(seCapabilities.End())
-		_ = xe.EncodeToken(seHello.End())
-		err = xe.Flush()
+	if s.Config.Hello != nil {
+		s.Config.Hello(hello)
 	}
-	if s.AddError(err) > 0 {
-		s.State.Status = StatusError
+	s.State.SentHello = hello
+	if s.AddError(EncodeHello(s.Outgoing(), hello)) > 0 {
+		s.setStatus(StatusError)
 	}
 }
 
@@ -346,14 +470,3 @@ var (
 	// when the final EOF has been reached.
 	ErrEndOfStream = message.ErrEndOfStream
 )
-
-var (
-	xpNSetHello      = xpath.MustCompile(`/hello[namespace-uri()='urn:ietf:params:xml:ns:netconf:base:1.0']`)
-	xpNSetCapability = xpath.MustCompile(`/hello[namespace-uri()='urn:ietf:params:xml:ns:netconf:base:1.0']/capabilities/capability`)
-	xpNSetSessionID  = xpath.MustCompile(`/hello[namespace-uri()='urn:ietf:params:xml:ns:netconf:base:1.0']/session-id`)
-
-	seHello        = xml.StartElement{Name: xn("hello", xmlnsNetconf)}
-	seCapabilities = xml.StartElement{Name: xn("capabilities")}
-	seCapability   = xml.StartElement{Name: xn("capability")}
-	seSessionID    = xml.StartElement{Name: xn("session-id")}
-)
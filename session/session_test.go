@@ -2,8 +2,13 @@ package session
 
 import (
 	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/antchfx/xmlquery"
 	"github.com/stretchr/testify/assert"
@@ -26,7 +31,7 @@ func TestSessionHello(t *testing.T) {
 		{
 			config:  Config{ID: 1},
 			input:   `<foo></foo>]]>]]>`,
-			wantErr: "missing <hello> element",
+			wantErr: "missing <hello> element: received <foo>",
 		},
 		{
 			config: Config{ID: 1},
@@ -60,7 +65,7 @@ func TestSessionHello(t *testing.T) {
 </capabilities>
 <session-id>123</session-id>
 </hello>]]>]]>`,
-			wantErr: "session-id received from client peer",
+			wantErr: "session-id received from client peer: 123",
 		},
 		{
 			config: Config{ID: 1},
@@ -190,7 +195,7 @@ func TestSessionHello(t *testing.T) {
 <session-id>-123</session-id>
 </hello>
 ]]>]]>`,
-			wantErr: "invalid session-id value",
+			wantErr: `invalid session-id value: "-123"`,
 		},
 		{
 			config: Config{Capabilities: Capabilities{capBase11, capBase10}},
@@ -228,6 +233,21 @@ func TestSessionHello(t *testing.T) {
 	}
 }
 
+func TestSessionSendHello(t *testing.T) {
+	a := assert.New(t)
+	dst := closeBuffer{&bytes.Buffer{}}
+	s := New(strings.NewReader(""), dst, Config{ID: 7, Capabilities: Capabilities{capBase10}})
+	a.False(s.InitialHandshake())
+	// the <session-id> follows the <capabilities>, within the <hello>
+	a.Equal(`<hello xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><capabilities>`+
+		`<capability>urn:ietf:params:netconf:base:1.0</capability></capabilities>`+
+		`<session-id>7</session-id></hello>]]>]]>`, dst.String())
+	h, err := DecodeHello(strings.NewReader(strings.TrimSuffix(dst.String(), "]]>]]>")))
+	if a.NoError(err) {
+		a.Equal(uint32(7), h.SessionID)
+	}
+}
+
 func TestSessionEstablished(t *testing.T) {
 	for _, tc := range []struct {
 		name    string
@@ -443,3 +463,328 @@ func TestSessionRun(t *testing.T) {
 type closeBuffer struct{ *bytes.Buffer }
 
 func (cb closeBuffer) Close() error { return nil }
+
+// timeoutHandler reads messages until an error occurs, recording the
+// session errors seen by the OnError and OnClose callbacks
+type timeoutHandler struct {
+	msgs        int
+	errorErrs   []error
+	closeErrs   []error
+	closeStatus Status
+}
+
+func (h *timeoutHandler) OnEstablish(s *Session) {}
+func (h *timeoutHandler) OnMessage(s *Session) {
+	if _, err := io.ReadAll(s.Incoming()); err != nil {
+		s.State.Status = StatusClosed
+		return
+	}
+	h.msgs++
+}
+func (h *timeoutHandler) OnError(s *Session) { h.errorErrs = s.Errors() }
+func (h *timeoutHandler) OnClose(s *Session) { h.closeErrs, h.closeStatus = s.Errors(), s.State.Status }
+
+func TestSessionTimeouts(t *testing.T) {
+	const hello = `<hello xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><capabilities>
+<capability>urn:ietf:params:netconf:base:1.0</capability>
+</capabilities></hello>]]>]]>`
+	for _, tc := range []struct {
+		name     string
+		config   Config
+		input    []string
+		interval time.Duration
+		wantErr  error
+		wantMsgs int
+	}{
+		{
+			name:    "hello timeout",
+			config:  Config{HelloTimeout: 10 * time.Millisecond},
+			wantErr: ErrHelloTimeout,
+		},
+		{
+			name:    "idle timeout",
+			config:  Config{HelloTimeout: time.Second, IdleTimeout: 20 * time.Millisecond},
+			input:   []string{hello},
+			wantErr: ErrIdleTimeout,
+		},
+		{
+			name:     "idle timer reset by messages",
+			config:   Config{IdleTimeout: 40 * time.Millisecond},
+			input:    []string{hello, "<rpc/>]]>]]>", "<rpc/>]]>]]>", "<rpc/>]]>]]>"},
+			interval: 20 * time.Millisecond,
+			wantErr:  ErrIdleTimeout,
+			wantMsgs: 3,
+		},
+		{
+			name:     "lifetime exceeded",
+			config:   Config{IdleTimeout: time.Second, MaxLifetime: 30 * time.Millisecond},
+			input:    []string{hello, "<rpc/>]]>]]>"},
+			wantErr:  ErrLifetimeExceeded,
+			wantMsgs: 1,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			a := assert.New(t)
+			src, w, err := os.Pipe()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer w.Close()
+			input, interval := tc.input, tc.interval
+			go func() {
+				for _, in := range input {
+					if _, err := w.Write([]byte(in)); err != nil {
+						return
+					}
+					time.Sleep(interval)
+				}
+			}()
+			tc.config.ID = 1
+			tc.config.Capabilities = Capabilities{capBase10}
+			var changes []string
+			tc.config.OnStatusChange = func(s *Session, old, new Status) {
+				changes = append(changes, old.String()+"->"+new.String())
+			}
+			s := New(src, closeBuffer{&bytes.Buffer{}}, tc.config)
+			h := &timeoutHandler{}
+			s.Run(h)
+			a.Equal(tc.wantMsgs, h.msgs)
+			if a.NotEmpty(h.errorErrs) {
+				a.ErrorIs(h.errorErrs[0], tc.wantErr)
+			}
+			if a.NotEmpty(h.closeErrs) {
+				a.ErrorIs(h.closeErrs[0], tc.wantErr)
+			}
+			a.Equal(StatusClosed, h.closeStatus)
+			// the timeout moves the session to error, then closed
+			want := []string{"inactive->capabilities-exchange", "capabilities-exchange->established", "established->error", "error->closed"}
+			if tc.wantErr == ErrHelloTimeout {
+				want = []string{"inactive->capabilities-exchange", "capabilities-exchange->error", "error->closed"}
+			}
+			a.Equal(want, changes)
+		})
+	}
+}
+
+func TestSessionMessages(t *testing.T) {
+	input := `<hello xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<capabilities><capability>urn:ietf:params:netconf:base:1.0</capability></capabilities>
+</hello>]]>]]><rpc message-id="1"/>]]>]]><rpc message-id="2"/>]]>]]>`
+	a := assert.New(t)
+
+	// not yet established
+	s := New(strings.NewReader(input), closeBuffer{&bytes.Buffer{}}, Config{ID: 1, Capabilities: Capabilities{capBase10}})
+	m, ok := <-s.Messages(context.Background())
+	a.True(ok)
+	a.ErrorIs(m.Err, ErrNotEstablished)
+	a.ErrorIs(s.Send(context.Background(), strings.NewReader(`<rpc-reply/>`)), ErrNotEstablished)
+
+	dst := closeBuffer{&bytes.Buffer{}}
+	s = New(strings.NewReader(input), dst, Config{ID: 1, Capabilities: Capabilities{capBase10}})
+	if !a.True(s.InitialHandshake()) {
+		return
+	}
+	var got []string
+	for m := range s.Messages(context.Background()) {
+		a.NoError(m.Err)
+		got = append(got, string(m.Data))
+		a.NoError(s.Send(context.Background(), strings.NewReader(`<rpc-reply/>`)))
+	}
+	a.Equal([]string{`<rpc message-id="1"/>`, `<rpc message-id="2"/>`}, got)
+	a.Equal(StatusClosed, s.State.Status)
+	a.True(strings.HasSuffix(dst.String(), `<rpc-reply/>]]>]]><rpc-reply/>]]>]]>`))
+
+	// a cancelled context stops delivery and sending
+	s = New(strings.NewReader(input), closeBuffer{&bytes.Buffer{}}, Config{ID: 1, Capabilities: Capabilities{capBase10}})
+	if !a.True(s.InitialHandshake()) {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for range s.Messages(ctx) {
+	}
+	a.ErrorIs(s.Send(ctx, strings.NewReader(`<rpc-reply/>`)), context.Canceled)
+
+	// a message whose content is cancelled is not sent
+	dst = closeBuffer{&bytes.Buffer{}}
+	s = New(strings.NewReader(input), dst, Config{ID: 1, Capabilities: Capabilities{capBase10}})
+	if !a.True(s.InitialHandshake()) {
+		return
+	}
+	sent := dst.Len()
+	ctx, cancel = context.WithCancel(context.Background())
+	r := io.MultiReader(strings.NewReader(`<rpc-reply>`), readerFunc(func([]byte) (int, error) {
+		cancel()
+		return 0, nil
+	}), strings.NewReader(`</rpc-reply>`))
+	a.ErrorIs(s.Send(ctx, r), context.Canceled)
+	a.Equal(sent, dst.Len())
+	a.NoError(s.Send(context.Background(), strings.NewReader(`<rpc-reply/>`)))
+	a.True(strings.HasSuffix(dst.String(), `]]>]]><rpc-reply/>]]>]]>`))
+}
+
+// readerFunc is an io.Reader calling the function
+type readerFunc func([]byte) (int, error)
+
+func (f readerFunc) Read(p []byte) (int, error) { return f(p) }
+
+func TestSessionStatus(t *testing.T) {
+	a := assert.New(t)
+	a.Equal("capabilities-exchange", StatusCapabilitiesExchange.String())
+	a.Equal("Status(9)", Status(9).String())
+
+	var changes []string
+	config := Config{
+		ID:           1,
+		Capabilities: Capabilities{capBase10},
+		OnStatusChange: func(s *Session, old, new Status) {
+			changes = append(changes, old.String()+"->"+new.String())
+		},
+	}
+	input := `<hello xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<capabilities><capability>urn:ietf:params:netconf:base:1.0</capability></capabilities>
+</hello>]]>]]><rpc/>]]>]]>`
+	s := New(strings.NewReader(input), closeBuffer{&bytes.Buffer{}}, config)
+	s.Run(&mockSession{})
+	a.Equal([]string{"inactive->capabilities-exchange", "capabilities-exchange->established", "established->closed"}, changes)
+
+	changes = nil
+	s = New(strings.NewReader(""), closeBuffer{&bytes.Buffer{}}, config)
+	for _, tc := range []struct {
+		status  Status
+		wantErr bool
+	}{
+		{status: StatusEstablished, wantErr: true},
+		{status: StatusCapabilitiesExchange},
+		{status: StatusCapabilitiesExchange},
+		{status: StatusEstablished},
+		{status: StatusInactive, wantErr: true},
+		{status: StatusError},
+		{status: StatusEstablished, wantErr: true},
+		{status: StatusClosed},
+		{status: StatusError, wantErr: true},
+	} {
+		if err := s.SetStatus(tc.status); tc.wantErr {
+			a.ErrorIs(err, ErrStatusTransition, tc.status)
+		} else {
+			a.NoError(err, tc.status)
+		}
+	}
+	a.Equal(StatusClosed, s.State.Status)
+	a.Equal([]string{
+		"inactive->capabilities-exchange",
+		"capabilities-exchange->established",
+		"established->error",
+		"error->closed",
+	}, changes)
+	a.EqualError(s.SetStatus(StatusInactive), "invalid session status transition: closed to inactive")
+
+	// hooks are combined, in order
+	changes = nil
+	hook := func(name string) func(s *Session, old, new Status) {
+		return func(s *Session, old, new Status) { changes = append(changes, name+" "+new.String()) }
+	}
+	config.OnStatusChange = OnStatusChanges(hook("a"), nil, hook("b"))
+	s = New(strings.NewReader(""), closeBuffer{&bytes.Buffer{}}, config)
+	a.NoError(s.SetStatus(StatusClosed))
+	a.Equal([]string{"a closed", "b closed"}, changes)
+}
+
+// closeHandler closes the session after reading one message
+type closeHandler struct{ timeoutHandler }
+
+func (h *closeHandler) OnMessage(s *Session) {
+	io.ReadAll(s.Incoming())
+	s.SetStatus(StatusClosed)
+}
+
+func TestSessionTerminationReason(t *testing.T) {
+	const hello = `<hello xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<capabilities><capability>urn:ietf:params:netconf:base:1.0</capability></capabilities>
+</hello>]]>]]>`
+	config := Config{ID: 1, Capabilities: Capabilities{capBase10}}
+	for _, tc := range []struct {
+		name    string
+		input   string
+		handler Handler
+		want    string
+	}{
+		{name: "dropped", input: hello + `<rpc/>]]>]]>`, handler: &mockSession{}, want: TerminationDropped},
+		{name: "closed", input: hello + `<rpc/>]]>]]>`, handler: &closeHandler{}, want: TerminationClosed},
+		{name: "bad hello", input: `<rpc/>]]>]]>`, handler: &mockSession{}, want: TerminationBadHello},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := New(strings.NewReader(tc.input), closeBuffer{&bytes.Buffer{}}, config)
+			s.Run(tc.handler)
+			assert.Equal(t, tc.want, s.TerminationReason())
+		})
+	}
+
+	// a killed session records the killing session
+	src, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	go w.Write([]byte(hello))
+	s := New(src, closeBuffer{&bytes.Buffer{}}, config)
+	h := &timeoutHandler{}
+	time.AfterFunc(20*time.Millisecond, func() { s.Kill(7) })
+	s.Run(h)
+	assert.Equal(t, TerminationKilled, s.TerminationReason())
+	assert.Equal(t, uint32(7), s.KilledBy())
+}
+
+func TestSessionLogging(t *testing.T) {
+	input := `<hello xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+<capabilities><capability>urn:ietf:params:netconf:base:1.0</capability></capabilities>
+</hello>]]>]]><rpc><password>secret</password></rpc>]]>]]>`
+	a := assert.New(t)
+
+	log := &recordLogger{}
+	s := New(strings.NewReader(input), closeBuffer{&bytes.Buffer{}}, Config{
+		ID:           1,
+		Capabilities: Capabilities{capBase10},
+		Logger:       log,
+		LogMessages:  true,
+		LogRedact:    func(b []byte) []byte { return bytes.ReplaceAll(b, []byte("secret"), []byte("***")) },
+	})
+	s.Run(&mockSession{})
+	a.Equal([]string{
+		"DEBUG session status changed session-id=0 old=inactive new=capabilities-exchange",
+		"DEBUG sending hello session-id=1 capabilities=1",
+	}, log.records[:2])
+	a.Regexp(`^DEBUG message sent session-id=0 bytes=\d+ xml=<hello .*</hello>$`, log.records[2])
+	a.Contains(log.records, "INFO session established session-id=1 framing=end-of-message capabilities=1")
+	a.Contains(log.records, "DEBUG message received session-id=1 bytes=38 xml=<rpc><password>***</password></rpc>")
+	a.Contains(log.records, "INFO session closed session-id=1 status=closed received=2")
+
+	log = &recordLogger{}
+	s = New(strings.NewReader(input), closeBuffer{&bytes.Buffer{}}, Config{
+		ID:           1,
+		Capabilities: Capabilities{capBase11},
+		Logger:       log,
+	})
+	s.Run(&mockSession{})
+	a.Contains(log.records, "WARN capability mismatch session-id=1"+
+		" local=[urn:ietf:params:netconf:base:1.1] peer=[urn:ietf:params:netconf:base:1.0] enabled=[]")
+	a.Contains(log.records, "ERROR session error session-id=1 error=session failed to negotiate framing mode")
+	a.Contains(log.records,
+		"INFO session closed session-id=1 status=error received=1 reason=session failed to negotiate framing mode")
+}
+
+// recordLogger records log records as strings
+type recordLogger struct{ records []string }
+
+func (l *recordLogger) record(level, msg string, args ...interface{}) {
+	r := level + " " + msg
+	for i := 0; i+1 < len(args); i += 2 {
+		r += fmt.Sprintf(" %v=%v", args[i], args[i+1])
+	}
+	l.records = append(l.records, r)
+}
+
+func (l *recordLogger) Debug(msg string, args ...interface{}) { l.record("DEBUG", msg, args...) }
+func (l *recordLogger) Info(msg string, args ...interface{})  { l.record("INFO", msg, args...) }
+func (l *recordLogger) Warn(msg string, args ...interface{})  { l.record("WARN", msg, args...) }
+func (l *recordLogger) Error(msg string, args ...interface{}) { l.record("ERROR", msg, args...) }
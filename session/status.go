@@ -0,0 +1,94 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// ErrStatusTransition is returned by SetStatus for an invalid status transition
+var ErrStatusTransition = errors.New("invalid session status transition")
+
+var statusNames = map[Status]string{
+	StatusInactive:             "inactive",
+	StatusCapabilitiesExchange: "capabilities-exchange",
+	StatusEstablished:          "established",
+	StatusError:                "error",
+	StatusClosed:               "closed",
+}
+
+func (s Status) String() string {
+	if name, ok := statusNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("Status(%d)", int(s))
+}
+
+// transitions holds the valid session status transitions
+var transitions = map[Status][]Status{
+	StatusInactive:             {StatusCapabilitiesExchange, StatusError, StatusClosed},
+	StatusCapabilitiesExchange: {StatusEstablished, StatusError, StatusClosed},
+	StatusEstablished:          {StatusError, StatusClosed},
+	StatusError:                {StatusClosed},
+}
+
+// SetStatus sets the session status, calling Config.OnStatusChange if
+// the status changed. Returns an error wrapping ErrStatusTransition
+// (leaving the status unchanged) if the transition is not valid.
+// Valid transitions are those of the session lifecycle:
+//
+//	StatusInactive -> StatusCapabilitiesExchange -> StatusEstablished
+//
+// from any of which the session may move to StatusError or StatusClosed,
+// and from StatusError to StatusClosed. Setting the current status is a
+// no-op.
+func (s *Session) SetStatus(status Status) error {
+	old := s.State.Status
+	if status == old {
+		return nil
+	}
+	for _, valid := range transitions[old] {
+		if status == valid {
+			s.setStatus(status)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %v to %v", ErrStatusTransition, old, status)
+}
+
+// OnStatusChanges returns a Config.OnStatusChange hook calling each of
+// the non-nil hooks in turn, such as to combine the hooks of several
+// packages observing the session lifecycle.
+func OnStatusChanges(hooks ...func(s *Session, old, new Status)) func(s *Session, old, new Status) {
+	return func(s *Session, old, new Status) {
+		for _, hook := range hooks {
+			if hook != nil {
+				hook(s, old, new)
+			}
+		}
+	}
+}
+
+// setStatus sets the session status without validation, notifying
+// Config.OnStatusChange of the change.
+func (s *Session) setStatus(status Status) {
+	s.syncStatus()
+	s.State.Status = status
+	s.syncStatus()
+}
+
+// syncStatus calls Config.OnStatusChange if the session status has
+// changed since last notified, including by direct assignment to
+// State.Status.
+func (s *Session) syncStatus() {
+	if old := s.status; old != s.State.Status {
+		s.status = s.State.Status
+		if s.status == StatusEstablished {
+			atomic.StoreUint32(&s.established, 1)
+		}
+		s.log().Debug("session status changed", "session-id", s.State.ID, "old", old, "new", s.status)
+		if s.Config.OnStatusChange != nil {
+			s.Config.OnStatusChange(s, old, s.status)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package session
+
+import (
+	"errors"
+	"io"
+	"sync/atomic"
+)
+
+// Reasons a session ended, as reported by the termination-reason of
+// the RFC6470 netconf-session-end notification (see TerminationReason)
+const (
+	TerminationClosed   = "closed"
+	TerminationKilled   = "killed"
+	TerminationDropped  = "dropped"
+	TerminationTimeout  = "timeout"
+	TerminationBadHello = "bad-hello"
+	TerminationOther    = "other"
+)
+
+// Kill ends the session s, as requested by a <kill-session> operation
+// on the session killedBy, recording killedBy (see KilledBy). The
+// session's transport is closed, unblocking any pending reads, such
+// that Run ends the session.
+func (s *Session) Kill(killedBy uint32) {
+	atomic.StoreUint32(&s.killedBy, killedBy)
+	if c, ok := s.src.(io.Closer); ok {
+		c.Close()
+	}
+	s.dst.Close()
+}
+
+// KilledBy returns the session-id of the session which killed s (see
+// Kill), or 0 if s was not killed.
+func (s *Session) KilledBy() uint32 { return atomic.LoadUint32(&s.killedBy) }
+
+// TerminationReason returns the reason the session ended, one of the
+// Termination* values: a killed session is TerminationKilled, a session
+// with a timeout error is TerminationTimeout and one failing to receive
+// a valid <hello> is TerminationBadHello, while other errors are
+// TerminationOther. A session closed without error is TerminationDropped
+// if its transport reached end of stream, and TerminationClosed if it
+// was closed locally (e.g., following <close-session>).
+//
+// A timeout is reported as soon as the session has expired, such as
+// when Config.OnStatusChange is called for the session ending.
+func (s *Session) TerminationReason() string {
+	if s.KilledBy() != 0 {
+		return TerminationKilled
+	}
+	s.timers.mu.Lock()
+	timeout := s.timers.reason
+	s.timers.mu.Unlock()
+	var helloErr *HelloError
+	switch err := s.Err(); {
+	case timeout == ErrIdleTimeout, timeout == ErrLifetimeExceeded,
+		errors.Is(err, ErrIdleTimeout), errors.Is(err, ErrLifetimeExceeded):
+		return TerminationTimeout
+	case timeout == ErrHelloTimeout, errors.Is(err, ErrHelloTimeout), errors.As(err, &helloErr):
+		return TerminationBadHello
+	case err != nil && !errors.Is(err, ErrEndOfStream):
+		return TerminationOther
+	}
+	if atomic.LoadUint32(&s.eof) != 0 {
+		return TerminationDropped
+	}
+	return TerminationClosed
+}
+
+// eofReader records the end of stream of its reader on a session
+type eofReader struct {
+	r io.Reader
+	s *Session
+}
+
+func (r *eofReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if err == io.EOF {
+		atomic.StoreUint32(&r.s.eof, 1)
+	}
+	return n, err
+}
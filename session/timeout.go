@@ -0,0 +1,107 @@
+package session
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrHelloTimeout is the session error recorded when the peer's
+	// <hello> was not received within Config.HelloTimeout.
+	ErrHelloTimeout = errors.New("hello timeout")
+	// ErrIdleTimeout is the session error recorded when no message was
+	// received from the peer within Config.IdleTimeout.
+	ErrIdleTimeout = errors.New("idle timeout")
+	// ErrLifetimeExceeded is the session error recorded when the session
+	// was open for longer than Config.MaxLifetime.
+	ErrLifetimeExceeded = errors.New("session lifetime exceeded")
+)
+
+// timers holds the session's timeout timers
+type timers struct {
+	mu       sync.Mutex
+	hello    *time.Timer
+	idle     *time.Timer
+	lifetime *time.Timer
+	reason   error
+	recorded bool
+}
+
+// startTimer returns a timer expiring the session with reason after d,
+// or nil if d is not positive.
+func (s *Session) startTimer(d time.Duration, reason error) *time.Timer {
+	if d <= 0 {
+		return nil
+	}
+	return time.AfterFunc(d, func() { s.expire(reason) })
+}
+
+// expire records the timeout reason and closes the session's transport,
+// unblocking any pending reads. Only the first reason is kept.
+func (s *Session) expire(reason error) {
+	s.timers.mu.Lock()
+	if s.timers.reason == nil {
+		s.timers.reason = reason
+	}
+	s.timers.mu.Unlock()
+	if c, ok := s.src.(io.Closer); ok {
+		c.Close()
+	}
+	s.dst.Close()
+}
+
+// stopTimers stops all session timers
+func (s *Session) stopTimers() {
+	s.timers.mu.Lock()
+	defer s.timers.mu.Unlock()
+	for _, t := range []*time.Timer{s.timers.hello, s.timers.idle, s.timers.lifetime} {
+		if t != nil {
+			t.Stop()
+		}
+	}
+}
+
+// resetIdleTimer restarts the idle timer, if running
+func (s *Session) resetIdleTimer() {
+	s.timers.mu.Lock()
+	defer s.timers.mu.Unlock()
+	if s.timers.idle != nil && s.timers.reason == nil {
+		s.timers.idle.Reset(s.Config.IdleTimeout)
+	}
+}
+
+// checkTimeout records any timeout as the first session error,
+// moving the session to StatusError unless it has already closed.
+// Returns true if the session timed out.
+func (s *Session) checkTimeout() bool {
+	s.timers.mu.Lock()
+	reason, recorded := s.timers.reason, s.timers.recorded
+	s.timers.recorded = reason != nil
+	s.timers.mu.Unlock()
+	if reason == nil {
+		return false
+	}
+	if !recorded {
+		s.log().Warn("session timeout", "session-id", s.State.ID, "reason", reason)
+		s.State.errs = append([]error{reason}, s.State.errs...)
+	}
+	// a closed session remains closed, as StatusError may not follow it
+	_ = s.SetStatus(StatusError)
+	return true
+}
+
+// timedOutClose moves the session to StatusError if it timed out while
+// a handler assigned StatusClosed (e.g., on the end of stream following
+// the timeout) without it having been notified yet, such that the timeout
+// is reported as the session's error rather than following its closure.
+func (s *Session) timedOutClose() {
+	s.timers.mu.Lock()
+	timedOut := s.timers.reason != nil
+	s.timers.mu.Unlock()
+	if timedOut && s.State.Status == StatusClosed && s.status != StatusClosed {
+		s.State.Status = s.status
+		s.checkTimeout()
+	}
+}
@@ -18,9 +18,18 @@ import (
 //
 // The Reader decodes data using the current framing protocol, making
 // it available to users via the Read call.
+//
+// If Tee is non-nil, decoded data is written to Tee as it is decoded,
+// before the end of message callback for the message is called. If
+// OnError is non-nil, it is called with any framing error. Both must
+// be set before the first call to Read.
 type Reader struct {
+	Tee     io.Writer
+	OnError func(error)
+
 	src     *buckFilter
 	eom     func()
+	atEOM   bool
 	scanner *bufio.Scanner
 	framing bufio.SplitFunc
 	buf     []byte
@@ -32,7 +41,30 @@ func NewReader(source io.Reader, eomCallback func()) *Reader {
 	if eomCallback == nil || source == nil {
 		panic("NewReader: both source and eomCallback must be non-nil")
 	}
-	return &Reader{src: &buckFilter{src: source}, eom: eomCallback, framing: framing.SplitEOM(eomCallback)}
+	r := &Reader{src: &buckFilter{src: source}, eom: eomCallback}
+	r.framing = framing.SplitEOM(r.endOfMessage)
+	return r
+}
+
+// endOfMessage is the framing end of message callback, deferring the
+// user's callback until the message's final data has been teed.
+func (r *Reader) endOfMessage() { r.atEOM = true }
+
+// split calls the current framing function, writing tokens to Tee
+// and calling the end of message callback after the message's data.
+func (r *Reader) split(b []byte, atEOF bool) (advance int, token []byte, err error) {
+	advance, token, err = r.framing(b, atEOF)
+	if len(token) > 0 && r.Tee != nil {
+		_, _ = r.Tee.Write(token)
+	}
+	if err != nil && r.OnError != nil {
+		r.OnError(err)
+	}
+	if r.atEOM {
+		r.atEOM = false
+		r.eom()
+	}
+	return
 }
 
 const (
@@ -46,7 +78,7 @@ func (r *Reader) setup() {
 	}
 	r.scanner = bufio.NewScanner(r.src)
 	r.scanner.Buffer(make([]byte, readerBufsize), readerBufsize)
-	r.scanner.Split(func(b []byte, eof bool) (int, []byte, error) { return r.framing(b, eof) })
+	r.scanner.Split(r.split)
 }
 
 func (r *Reader) Read(b []byte) (n int, err error) {
@@ -79,14 +111,49 @@ func (r *Reader) Read(b []byte) (n int, err error) {
 
 // SetFramingMode sets the NETCONF transport framing to end of message
 // mode (chunked=false) or chunked framing mode (chunked=true).
-func (r *Reader) SetFramingMode(chunked bool) {
+func (r *Reader) SetFramingMode(chunked bool) { r.SetFramingModeLenient(chunked, Lenient{}) }
+
+// Lenient configures tolerance of non-conformant peers' transport framing.
+// The zero value tolerates nothing.
+type Lenient struct {
+	// EOMAfterChunked, if true, detects peers continuing to use end of
+	// message framing after chunked framing was negotiated, using end of
+	// message framing for the remainder of the stream if so, in which case
+	// OnEOMAfterChunked is called, if non-nil.
+	EOMAfterChunked   bool
+	OnEOMAfterChunked func()
+	// TrailingGarbage, if true, discards any data preceding the first
+	// message after the framing mode is set (i.e., following the <hello>
+	// message's end of message marker), in which case OnTrailingGarbage
+	// is called with the number of bytes discarded, if non-nil.
+	TrailingGarbage   bool
+	OnTrailingGarbage func(n int)
+}
+
+// SetFramingModeLenient sets the NETCONF transport framing mode
+// as per SetFramingMode, tolerating the non-conformant framing
+// configured in lenient.
+func (r *Reader) SetFramingModeLenient(chunked bool, lenient Lenient) {
 	if r.src.disabled {
 		panic("SetFramingMode must only be called once")
 	}
-	if chunked {
-		r.framing = framing.SplitChunked(r.eom)
-	} else {
-		r.framing = framing.SplitEOM(r.eom)
+	markers := [][]byte{[]byte("<")}
+	switch {
+	case chunked && lenient.EOMAfterChunked:
+		markers = append(markers, []byte("\n#"))
+		r.framing = framing.SplitDetect(framing.SplitEOM(r.endOfMessage), framing.SplitChunked(r.endOfMessage), func(chunked bool) {
+			if !chunked && lenient.OnEOMAfterChunked != nil {
+				lenient.OnEOMAfterChunked()
+			}
+		})
+	case chunked:
+		markers = [][]byte{[]byte("\n#")}
+		r.framing = framing.SplitChunked(r.endOfMessage)
+	default:
+		r.framing = framing.SplitEOM(r.endOfMessage)
+	}
+	if lenient.TrailingGarbage {
+		r.framing = framing.SkipTo(r.framing, lenient.OnTrailingGarbage, markers...)
 	}
 	// disable message bucker
 	r.src.disabled = true
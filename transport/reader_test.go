@@ -57,3 +57,26 @@ func TestSetFramingModeDouble(t *testing.T) {
 	r.SetFramingMode(true)
 	a.Panics(func() { r.SetFramingMode(true) })
 }
+
+func TestReaderTee(t *testing.T) {
+	a := assert.New(t)
+	var msgs, errs []string
+	tee := &bytes.Buffer{}
+	var rdr *Reader
+	rdr = NewReader(strings.NewReader("foo]]>]]>\n#3\nbar\n#2\nba\n##\n\n#x"), func() {
+		// the message's data is teed before the end of message callback
+		msgs = append(msgs, tee.String())
+		tee.Reset()
+		if len(msgs) == 1 {
+			rdr.SetFramingMode(true)
+		}
+	})
+	rdr.Tee = tee
+	rdr.OnError = func(err error) { errs = append(errs, err.Error()) }
+	b := closeBuffer{&bytes.Buffer{}}
+	_, err := io.Copy(b, rdr)
+	a.Error(err)
+	a.Equal("foobarba", b.String())
+	a.Equal([]string{"foo", "barba"}, msgs)
+	a.Equal([]string{err.Error()}, errs)
+}
@@ -13,7 +13,14 @@ import (
 //
 // It supports both RFC4742 NETCONF 1.0 end-of-message framing
 // as well as NETCONF 1.1 chunked framing.
+//
+// If Tee is non-nil, data written (before encoding) is also written to
+// Tee. If OnEnd is non-nil, it is called after each end of message is
+// written.
 type Writer struct {
+	Tee   io.Writer
+	OnEnd func()
+
 	dst     io.WriteCloser
 	chunked bool
 }
@@ -24,6 +31,11 @@ func NewWriter(dst io.WriteCloser) *Writer { return &Writer{dst: dst} }
 
 // Write writes b to the Encoder's destination using the current framing mode.
 func (w *Writer) Write(b []byte) (n int, err error) {
+	defer func() {
+		if n > 0 && w.Tee != nil {
+			_, _ = w.Tee.Write(b[:n])
+		}
+	}()
 	if w.chunked {
 		data := []byte(fmt.Sprintf("\n#%d\n%s", len(b), b))
 		if n, err = w.dst.Write(data); err == nil && n < len(data) {
@@ -42,7 +54,10 @@ func (w *Writer) Write(b []byte) (n int, err error) {
 // each request/response message sent by a NETCONF client or server.
 //
 // It returns the number of bytes written, along with any error.
-func (w *Writer) WriteEnd() (int, error) {
+func (w *Writer) WriteEnd() (n int, err error) {
+	if w.OnEnd != nil {
+		defer w.OnEnd()
+	}
 	if w.chunked {
 		return w.dst.Write([]byte("\n##\n"))
 	}
@@ -51,3 +51,19 @@ func TestWriter(t *testing.T) {
 type closeBuffer struct{ *bytes.Buffer }
 
 func (cb closeBuffer) Close() error { return nil }
+
+func TestWriterTee(t *testing.T) {
+	a := assert.New(t)
+	dst, tee := closeBuffer{&bytes.Buffer{}}, &bytes.Buffer{}
+	var ends []string
+	w := NewWriter(dst)
+	w.Tee = tee
+	w.OnEnd = func() { ends = append(ends, tee.String()) }
+	w.SetFramingMode(true)
+	_, err := w.Write([]byte("foo"))
+	a.NoError(err)
+	_, err = w.WriteEnd()
+	a.NoError(err)
+	a.Equal("\n#3\nfoo\n##\n", dst.String())
+	a.Equal([]string{"foo"}, ends)
+}
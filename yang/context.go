@@ -0,0 +1,256 @@
+package yang
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Context is a set of YANG modules, loaded from source files found in
+// its search path, and resolved together into schema trees.
+type Context struct {
+	// Path holds the directories searched for module and submodule files,
+	// named "name.yang" or "name@revision.yang"
+	Path []string
+	// FeatureEnabled, if non-nil, returns true if the feature of the module
+	// is enabled. If nil, all features are enabled.
+	FeatureEnabled func(module, feature string) bool
+	// Modules holds the loaded modules, by name
+	Modules map[string]*Module
+
+	order    []*Module
+	units    map[*Module][]*unit
+	resolved bool
+}
+
+// unit is a module or submodule source file's statement
+type unit struct {
+	stmt *Statement
+	// prefixes maps the unit's import prefixes (and its own) to modules
+	prefixes map[string]*Module
+}
+
+// NewContext returns a new Context searching the directories path
+func NewContext(path ...string) *Context {
+	return &Context{Path: path, Modules: map[string]*Module{}, units: map[*Module][]*unit{}}
+}
+
+// Load loads the named modules, along with the modules they import and
+// the submodules they include. Each name may be suffixed with
+// "@revision" to load a specific revision. Call Resolve once all modules
+// have been loaded.
+func (c *Context) Load(names ...string) error {
+	for _, name := range names {
+		rev := ""
+		if i := strings.IndexByte(name, '@'); i >= 0 {
+			name, rev = name[:i], name[i+1:]
+		}
+		if _, err := c.load(name, rev, Pos{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Module returns the loaded module with name, or nil
+func (c *Context) Module(name string) *Module { return c.Modules[name] }
+
+// ModuleByNamespace returns the loaded module with namespace ns, or nil
+func (c *Context) ModuleByNamespace(ns string) *Module {
+	for _, m := range c.order {
+		if m.Namespace == ns {
+			return m
+		}
+	}
+	return nil
+}
+
+// find returns the path of the file for module or submodule name at
+// revision rev (or the latest revision, if rev is empty)
+func (c *Context) find(name, rev string) (string, error) {
+	var candidates []string
+	for _, dir := range c.Path {
+		if rev == "" {
+			if file := filepath.Join(dir, name+".yang"); exists(file) {
+				return file, nil
+			}
+			matches, _ := filepath.Glob(filepath.Join(dir, name+"@*.yang"))
+			candidates = append(candidates, matches...)
+		} else if file := filepath.Join(dir, name+"@"+rev+".yang"); exists(file) {
+			return file, nil
+		}
+	}
+	if len(candidates) == 0 && rev != "" {
+		// accept an unrevisioned file name, checking the revision once parsed
+		for _, dir := range c.Path {
+			if file := filepath.Join(dir, name+".yang"); exists(file) {
+				return file, nil
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("module %q not found in path %v", name, c.Path)
+	}
+	// the latest revision sorts last
+	sort.Slice(candidates, func(i, j int) bool { return filepath.Base(candidates[i]) < filepath.Base(candidates[j]) })
+	return candidates[len(candidates)-1], nil
+}
+
+func exists(file string) bool {
+	fi, err := os.Stat(file)
+	return err == nil && !fi.IsDir()
+}
+
+// parseFile finds and parses the module or submodule name
+func (c *Context) parseFile(name, rev string, from Pos) (*Statement, error) {
+	file, err := c.find(name, rev)
+	if err != nil {
+		if from.File != "" {
+			return nil, errorf(from, "%v", err)
+		}
+		return nil, err
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	stmt, err := Parse(f, file)
+	if err != nil {
+		return nil, err
+	}
+	if stmt.Argument != name {
+		return nil, errorf(stmt.Pos, "%s %q found, expected %q", stmt.Keyword, stmt.Argument, name)
+	}
+	if rev != "" && latestRevision(stmt) != rev {
+		return nil, errorf(stmt.Pos, "%s %q has no revision %s", stmt.Keyword, name, rev)
+	}
+	return stmt, nil
+}
+
+// latestRevision returns the latest revision date of the module or submodule stmt
+func latestRevision(stmt *Statement) (rev string) {
+	for _, r := range stmt.Subs("revision") {
+		if r.Argument > rev {
+			rev = r.Argument
+		}
+	}
+	return
+}
+
+// load loads the module name (revision rev, if not empty), which is
+// imported at position from, if known
+func (c *Context) load(name, rev string, from Pos) (*Module, error) {
+	if m := c.Modules[name]; m != nil {
+		if rev != "" && m.Revision != rev {
+			return nil, errorf(from, "module %q revision %s conflicts with loaded revision %s", name, rev, m.Revision)
+		}
+		return m, nil
+	}
+	c.resolved = false
+	stmt, err := c.parseFile(name, rev, from)
+	if err != nil {
+		return nil, err
+	}
+	if stmt.Keyword != "module" {
+		return nil, errorf(stmt.Pos, "%q is a %s, not a module", name, stmt.Keyword)
+	}
+	m := &Module{
+		Name:        name,
+		Namespace:   stmt.arg("namespace"),
+		Prefix:      stmt.arg("prefix"),
+		Revision:    latestRevision(stmt),
+		YANGVersion: stmt.arg("yang-version"),
+		Stmt:        stmt,
+	}
+	if m.YANGVersion == "" {
+		m.YANGVersion = "1"
+	}
+	if m.Namespace == "" || m.Prefix == "" {
+		return nil, errorf(stmt.Pos, "module %q requires namespace and prefix statements", name)
+	}
+	c.Modules[name] = m
+	c.order = append(c.order, m)
+	u, err := c.unit(m, stmt, m.Prefix)
+	if err == nil {
+		err = c.include(m, u)
+	}
+	if err != nil {
+		c.forget(m)
+		return nil, err
+	}
+	return m, nil
+}
+
+// forget removes the module m, which failed to load
+func (c *Context) forget(m *Module) {
+	delete(c.Modules, m.Name)
+	delete(c.units, m)
+	for i, o := range c.order {
+		if o == m {
+			c.order = append(c.order[:i:i], c.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// unit returns a new unit of module m for stmt, loading its imports
+func (c *Context) unit(m *Module, stmt *Statement, prefix string) (*unit, error) {
+	u := &unit{stmt: stmt, prefixes: map[string]*Module{prefix: m}}
+	for _, imp := range stmt.Subs("import") {
+		pfx := imp.arg("prefix")
+		if pfx == "" {
+			return nil, errorf(imp.Pos, "import of %q requires a prefix", imp.Argument)
+		}
+		if _, dup := u.prefixes[pfx]; dup {
+			return nil, errorf(imp.Pos, "duplicate prefix %q", pfx)
+		}
+		im, err := c.load(imp.Argument, imp.arg("revision-date"), imp.Pos)
+		if err != nil {
+			return nil, err
+		}
+		u.prefixes[pfx] = im
+	}
+	c.units[m] = append(c.units[m], u)
+	return u, nil
+}
+
+// include loads the submodules included by u, into module m
+func (c *Context) include(m *Module, u *unit) error {
+	for _, inc := range u.stmt.Subs("include") {
+		if contains(m.Submodules, inc.Argument) {
+			continue
+		}
+		stmt, err := c.parseFile(inc.Argument, inc.arg("revision-date"), inc.Pos)
+		if err != nil {
+			return err
+		}
+		if stmt.Keyword != "submodule" {
+			return errorf(inc.Pos, "%q is a %s, not a submodule", inc.Argument, stmt.Keyword)
+		}
+		bt := stmt.Sub("belongs-to")
+		if bt == nil || bt.Argument != m.Name {
+			return errorf(stmt.Pos, "submodule %q does not belong to module %q", inc.Argument, m.Name)
+		}
+		m.Submodules = append(m.Submodules, inc.Argument)
+		su, err := c.unit(m, stmt, bt.arg("prefix"))
+		if err != nil {
+			return err
+		}
+		if err := c.include(m, su); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
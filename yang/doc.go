@@ -0,0 +1,34 @@
+/*
+Package yang parses YANG 1.1 (RFC7950) modules and resolves them into
+schema trees.
+
+A Context loads modules by name from source files in its search path,
+named "name.yang" or "name@revision.yang", along with the modules they
+import and the submodules they include:
+
+	c := yang.NewContext("./yang")
+	if err := c.Load("example-system"); err != nil {
+		// ...
+	}
+	if err := c.Resolve(); err != nil {
+		// ...
+	}
+	root := c.Module("example-system").Root
+
+Resolve expands groupings (applying refines and uses-augments), applies
+augments and deviations, evaluates if-feature conditions against
+Context.FeatureEnabled, and resolves typedefs, identities and types,
+including their effective range, length and pattern restrictions.
+The resulting Node trees hold each module's top-level data nodes, rpcs
+and notifications, with augmenting nodes found in their target modules'
+trees.
+
+Errors identify their source file position, as "file:line:col: message".
+Resolve reports all errors found, as an ErrorList.
+
+XPath expressions (must, when and leafref paths) are retained, but not
+parsed, except that leafref paths are resolved (ignoring predicates) to
+their target leaf or leaf-list, as Type.Target. Extension statements are
+retained on the nodes they appear in.
+*/
+package yang
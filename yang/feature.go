@@ -0,0 +1,83 @@
+package yang
+
+import (
+	"fmt"
+	"strings"
+)
+
+// evalIfFeature evaluates the if-feature expression expr (RFC7950
+// section 7.20.2), calling feature for each feature reference.
+func evalIfFeature(expr string, feature func(ref string) bool) (bool, error) {
+	e := &featureExpr{feature: feature}
+	// tokenize, separating parentheses
+	e.tokens = strings.Fields(strings.NewReplacer("(", " ( ", ")", " ) ").Replace(expr))
+	if len(e.tokens) == 0 {
+		return false, fmt.Errorf("empty expression")
+	}
+	v, err := e.or()
+	if err == nil && e.pos < len(e.tokens) {
+		err = fmt.Errorf("unexpected %q", e.tokens[e.pos])
+	}
+	return v, err
+}
+
+type featureExpr struct {
+	tokens  []string
+	pos     int
+	feature func(string) bool
+}
+
+func (e *featureExpr) peek() string {
+	if e.pos < len(e.tokens) {
+		return e.tokens[e.pos]
+	}
+	return ""
+}
+
+func (e *featureExpr) or() (bool, error) {
+	v, err := e.and()
+	for err == nil && e.peek() == "or" {
+		e.pos++
+		var w bool
+		w, err = e.and()
+		v = v || w
+	}
+	return v, err
+}
+
+func (e *featureExpr) and() (bool, error) {
+	v, err := e.factor()
+	for err == nil && e.peek() == "and" {
+		e.pos++
+		var w bool
+		w, err = e.factor()
+		v = v && w
+	}
+	return v, err
+}
+
+func (e *featureExpr) factor() (bool, error) {
+	tok := e.peek()
+	e.pos++
+	switch {
+	case tok == "not":
+		v, err := e.factor()
+		return !v, err
+	case tok == "(":
+		v, err := e.or()
+		if err == nil && e.peek() != ")" {
+			return false, fmt.Errorf("missing %q", ")")
+		}
+		e.pos++
+		return v, err
+	case tok == "":
+		return false, fmt.Errorf("unexpected end of expression")
+	case tok == ")" || tok == "and" || tok == "or":
+		return false, fmt.Errorf("unexpected %q", tok)
+	}
+	prefix, name := splitPrefix(tok)
+	if !isIdentifier(name) || (prefix != "" && !isIdentifier(prefix)) {
+		return false, fmt.Errorf("invalid feature reference %q", tok)
+	}
+	return e.feature(tok), nil
+}
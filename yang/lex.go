@@ -0,0 +1,184 @@
+package yang
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// tokenKind is the kind of a lexical token
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokString
+	tokQuoted // quoted string, which may be concatenated with "+"
+	tokSemicolon
+	tokOpenBrace
+	tokCloseBrace
+	tokPlus
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  Pos
+}
+
+// lexer splits YANG source text into tokens (RFC7950 section 6.1)
+type lexer struct {
+	src       string
+	file      string
+	off       int
+	line, col int
+}
+
+func newLexer(src, file string) *lexer { return &lexer{src: src, file: file, line: 1, col: 1} }
+
+func (l *lexer) pos() Pos { return Pos{File: l.file, Line: l.line, Col: l.col} }
+
+// advance consumes n bytes
+func (l *lexer) advance(n int) {
+	for _, r := range l.src[l.off : l.off+n] {
+		if r == '\n' {
+			l.line, l.col = l.line+1, 1
+		} else {
+			l.col++
+		}
+	}
+	l.off += n
+}
+
+// skip consumes whitespace and comments
+func (l *lexer) skip() *Error {
+	for l.off < len(l.src) {
+		rest := l.src[l.off:]
+		switch {
+		case rest[0] == ' ' || rest[0] == '\t' || rest[0] == '\n' || rest[0] == '\r':
+			l.advance(1)
+		case strings.HasPrefix(rest, "//"):
+			end := strings.IndexByte(rest, '\n')
+			if end < 0 {
+				end = len(rest)
+			}
+			l.advance(end)
+		case strings.HasPrefix(rest, "/*"):
+			end := strings.Index(rest[2:], "*/")
+			if end < 0 {
+				return errorf(l.pos(), "unterminated comment")
+			}
+			l.advance(end + 4)
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// next returns the next token
+func (l *lexer) next() (token, *Error) {
+	if err := l.skip(); err != nil {
+		return token{}, err
+	}
+	pos := l.pos()
+	if l.off == len(l.src) {
+		return token{kind: tokEOF, pos: pos}, nil
+	}
+	rest := l.src[l.off:]
+	switch rest[0] {
+	case ';':
+		l.advance(1)
+		return token{kind: tokSemicolon, text: ";", pos: pos}, nil
+	case '{':
+		l.advance(1)
+		return token{kind: tokOpenBrace, text: "{", pos: pos}, nil
+	case '}':
+		l.advance(1)
+		return token{kind: tokCloseBrace, text: "}", pos: pos}, nil
+	case '+':
+		if len(rest) == 1 || strings.ContainsRune(" \t\r\n\"'", rune(rest[1])) {
+			l.advance(1)
+			return token{kind: tokPlus, text: "+", pos: pos}, nil
+		}
+	case '\'':
+		end := strings.IndexByte(rest[1:], '\'')
+		if end < 0 {
+			return token{}, errorf(pos, "unterminated single-quoted string")
+		}
+		l.advance(end + 2)
+		return token{kind: tokQuoted, text: rest[1 : end+1], pos: pos}, nil
+	case '"':
+		return l.doubleQuoted(pos)
+	}
+	// unquoted string
+	n := 0
+	for n < len(rest) {
+		c := rest[n]
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ';' || c == '{' || c == '}' ||
+			strings.HasPrefix(rest[n:], "//") || strings.HasPrefix(rest[n:], "/*") {
+			break
+		}
+		if c == '"' || c == '\'' {
+			return token{}, errorf(l.pos(), "quote character in unquoted string")
+		}
+		n++
+	}
+	l.advance(n)
+	return token{kind: tokString, text: rest[:n], pos: pos}, nil
+}
+
+// doubleQuoted lexes a double-quoted string, processing escapes and
+// trimming the indentation of continuation lines (RFC7950 section 6.1.3)
+func (l *lexer) doubleQuoted(pos Pos) (token, *Error) {
+	indent := pos.Col // columns to trim from continuation lines
+	var b strings.Builder
+	l.advance(1)
+	for l.off < len(l.src) {
+		c := l.src[l.off]
+		switch c {
+		case '"':
+			l.advance(1)
+			return token{kind: tokQuoted, text: b.String(), pos: pos}, nil
+		case '\\':
+			if l.off+1 == len(l.src) {
+				return token{}, errorf(pos, "unterminated double-quoted string")
+			}
+			switch e := l.src[l.off+1]; e {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case '"', '\\':
+				b.WriteByte(e)
+			default:
+				return token{}, errorf(l.pos(), "invalid escape sequence \\%c", e)
+			}
+			l.advance(2)
+		case '\n':
+			// trailing whitespace before a newline is removed
+			s := strings.TrimRight(b.String(), " \t")
+			b.Reset()
+			b.WriteString(s)
+			b.WriteByte('\n')
+			l.advance(1)
+			// leading whitespace of the next line is trimmed to the
+			// column following the opening quote
+			for col := 1; col <= indent && l.off < len(l.src); {
+				switch l.src[l.off] {
+				case ' ':
+					col++
+				case '\t':
+					col += 8
+				default:
+					col = indent + 1
+					continue
+				}
+				l.advance(1)
+			}
+		default:
+			_, n := utf8.DecodeRuneInString(l.src[l.off:])
+			b.WriteString(l.src[l.off : l.off+n])
+			l.advance(n)
+		}
+	}
+	return token{}, errorf(pos, "unterminated double-quoted string")
+}
@@ -0,0 +1,190 @@
+package yang
+
+import (
+	"io"
+	"strings"
+)
+
+// Statement is a YANG statement, as parsed from a YANG source file
+type Statement struct {
+	// Keyword is the statement's keyword, including any prefix for
+	// extension statements (e.g., "container" or "md:annotation")
+	Keyword string
+	// Argument is the statement's argument, if any
+	Argument string
+	// HasArgument is set if the statement has an argument
+	HasArgument bool
+	// Statements holds the statement's substatements
+	Statements []*Statement
+	// Pos is the position of the statement's keyword
+	Pos Pos
+}
+
+// Parse parses the YANG source read from r (named file in errors), returning
+// its single top-level statement (a "module" or "submodule" statement).
+func Parse(r io.Reader, file string) (*Statement, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return parseString(string(b), file)
+}
+
+func parseString(src, file string) (*Statement, error) {
+	p := &parser{lex: newLexer(src, file)}
+	stmt, err := p.statement()
+	if err != nil {
+		return nil, err
+	}
+	if stmt == nil {
+		return nil, errorf(p.lex.pos(), "no module or submodule statement")
+	}
+	if stmt.Keyword != "module" && stmt.Keyword != "submodule" {
+		return nil, errorf(stmt.Pos, "expected module or submodule statement, found %q", stmt.Keyword)
+	}
+	if tok, err := p.next(); err != nil {
+		return nil, err
+	} else if tok.kind != tokEOF {
+		return nil, errorf(tok.pos, "unexpected %q after %s statement", tok.text, stmt.Keyword)
+	}
+	return stmt, nil
+}
+
+type parser struct {
+	lex  *lexer
+	peek *token
+}
+
+func (p *parser) next() (token, *Error) {
+	if p.peek != nil {
+		tok := *p.peek
+		p.peek = nil
+		return tok, nil
+	}
+	return p.lex.next()
+}
+
+// statement parses a statement, returning nil at EOF or a closing brace
+// (which is left unconsumed).
+func (p *parser) statement() (*Statement, *Error) {
+	tok, err := p.next()
+	switch {
+	case err != nil:
+		return nil, err
+	case tok.kind == tokEOF:
+		return nil, nil
+	case tok.kind == tokCloseBrace:
+		p.peek = &tok
+		return nil, nil
+	case tok.kind != tokString || !isKeyword(tok.text):
+		return nil, errorf(tok.pos, "expected statement keyword, found %q", tok.text)
+	}
+	stmt := &Statement{Keyword: tok.text, Pos: tok.pos}
+	if tok, err = p.next(); err != nil {
+		return nil, err
+	}
+	switch tok.kind {
+	case tokString:
+		stmt.Argument, stmt.HasArgument = tok.text, true
+		if tok, err = p.next(); err != nil {
+			return nil, err
+		}
+	case tokQuoted:
+		stmt.Argument, stmt.HasArgument = tok.text, true
+		for {
+			// concatenation of quoted strings
+			if tok, err = p.next(); err != nil {
+				return nil, err
+			}
+			if tok.kind != tokPlus {
+				break
+			}
+			if tok, err = p.next(); err != nil {
+				return nil, err
+			}
+			if tok.kind != tokQuoted {
+				return nil, errorf(tok.pos, "expected quoted string after '+'")
+			}
+			stmt.Argument += tok.text
+		}
+	}
+	switch tok.kind {
+	case tokSemicolon:
+		return stmt, nil
+	case tokOpenBrace:
+		for {
+			sub, err := p.statement()
+			if err != nil {
+				return nil, err
+			}
+			if sub == nil {
+				break
+			}
+			stmt.Statements = append(stmt.Statements, sub)
+		}
+		if tok, err = p.next(); err != nil {
+			return nil, err
+		}
+		if tok.kind != tokCloseBrace {
+			return nil, errorf(tok.pos, "missing '}' closing %s statement at %v", stmt.Keyword, stmt.Pos)
+		}
+		return stmt, nil
+	case tokEOF:
+		return nil, errorf(tok.pos, "unexpected end of file in %s statement", stmt.Keyword)
+	}
+	return nil, errorf(tok.pos, "expected ';' or '{' after %s statement, found %q", stmt.Keyword, tok.text)
+}
+
+// isKeyword returns true if s is a keyword or prefixed extension keyword
+func isKeyword(s string) bool {
+	for i, part := range strings.SplitN(s, ":", 2) {
+		if !isIdentifier(part) || (i == 0 && strings.Count(s, ":") > 1) {
+			return false
+		}
+	}
+	return true
+}
+
+// isIdentifier returns true if s is a YANG identifier
+func isIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		switch {
+		case c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+		case i > 0 && (c == '-' || c == '.' || (c >= '0' && c <= '9')):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Sub returns the first substatement of s with keyword, or nil
+func (s *Statement) Sub(keyword string) *Statement {
+	for _, sub := range s.Statements {
+		if sub.Keyword == keyword {
+			return sub
+		}
+	}
+	return nil
+}
+
+// Subs returns the substatements of s with keyword
+func (s *Statement) Subs(keyword string) (subs []*Statement) {
+	for _, sub := range s.Statements {
+		if sub.Keyword == keyword {
+			subs = append(subs, sub)
+		}
+	}
+	return
+}
+
+// arg returns the argument of the first substatement with keyword, or ""
+func (s *Statement) arg(keyword string) string {
+	if sub := s.Sub(keyword); sub != nil {
+		return sub.Argument
+	}
+	return ""
+}
@@ -0,0 +1,82 @@
+package yang
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		src     string
+		want    *Statement
+		wantErr string
+	}{
+		{
+			name: "module",
+			src:  "module m { namespace \"urn:m\"; prefix m; }",
+			want: &Statement{Keyword: "module", Argument: "m", HasArgument: true, Pos: Pos{"m.yang", 1, 1}, Statements: []*Statement{
+				{Keyword: "namespace", Argument: "urn:m", HasArgument: true, Pos: Pos{"m.yang", 1, 12}},
+				{Keyword: "prefix", Argument: "m", HasArgument: true, Pos: Pos{"m.yang", 1, 31}},
+			}},
+		},
+		{
+			name: "strings",
+			src: "module m {\n" +
+				"  // comment\n" +
+				"  description \"a\\tb\\n\\\"c\\\"\" + 'd\\e' /* block */;\n" +
+				"  contact\n    \"first\n     second  \n     third\";\n" +
+				"  ex:ext;\n" +
+				"}\n",
+			want: &Statement{Keyword: "module", Argument: "m", HasArgument: true, Pos: Pos{"m.yang", 1, 1}, Statements: []*Statement{
+				{Keyword: "description", Argument: "a\tb\n\"c\"d\\e", HasArgument: true, Pos: Pos{"m.yang", 3, 3}},
+				{Keyword: "contact", Argument: "first\nsecond\nthird", HasArgument: true, Pos: Pos{"m.yang", 4, 3}},
+				{Keyword: "ex:ext", Pos: Pos{"m.yang", 8, 3}},
+			}},
+		},
+		{
+			name:    "missing semicolon",
+			src:     "module m {\n  prefix m\n}\n",
+			wantErr: `m.yang:3:1: expected ';' or '{' after prefix statement, found "}"`,
+		},
+		{
+			name:    "unterminated string",
+			src:     "module m {\n  description \"abc;\n}\n",
+			wantErr: "m.yang:2:15: unterminated double-quoted string",
+		},
+		{
+			name:    "unterminated string trailing backslash",
+			src:     "module m {\n  description \"abc\\",
+			wantErr: "m.yang:2:15: unterminated double-quoted string",
+		},
+		{
+			name:    "invalid keyword",
+			src:     "module m {\n  1prefix m;\n}\n",
+			wantErr: `m.yang:2:3: expected statement keyword, found "1prefix"`,
+		},
+		{
+			name:    "not a module",
+			src:     "container c;",
+			wantErr: `m.yang:1:1: expected module or submodule statement, found "container"`,
+		},
+		{
+			name:    "trailing statement",
+			src:     "module m;\nmodule n;",
+			wantErr: `m.yang:2:1: unexpected "module" after module statement`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			a := assert.New(t)
+			got, err := Parse(strings.NewReader(tc.src), "m.yang")
+			if tc.wantErr != "" {
+				a.EqualError(err, tc.wantErr)
+				return
+			}
+			if a.NoError(err) {
+				a.Equal(tc.want, got)
+			}
+		})
+	}
+}
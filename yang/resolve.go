@@ -0,0 +1,770 @@
+package yang
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Resolve resolves the schema trees of the loaded modules, expanding
+// groupings and applying augments, refines, deviations and if-feature
+// conditions (per Context.FeatureEnabled). Errors are returned as an
+// ErrorList, with source positions.
+//
+// Resolve must be called again after further modules are loaded.
+func (c *Context) Resolve() error {
+	r := &resolver{
+		c:         c,
+		modScopes: map[*Module]*scope{},
+		unitScope: map[*unit]*scope{},
+		defScopes: map[*Statement]*scope{},
+		typedefs:  map[*Statement]*Typedef{},
+		features:  map[*Module]map[string]*feature{},
+		expanding: map[*Statement]bool{},
+	}
+	r.resolve()
+	c.resolved = len(r.errs) == 0
+	return r.errs.err()
+}
+
+type resolver struct {
+	c    *Context
+	errs ErrorList
+
+	modScopes map[*Module]*scope
+	unitScope map[*unit]*scope
+	defScopes map[*Statement]*scope
+	typedefs  map[*Statement]*Typedef
+	features  map[*Module]map[string]*feature
+	expanding map[*Statement]bool
+}
+
+type feature struct {
+	stmt  *Statement
+	scope *scope
+	state int // 0: unresolved, 1: resolving, 2: resolved
+	on    bool
+}
+
+func (r *resolver) errorf(pos Pos, format string, args ...interface{}) {
+	r.errs = append(r.errs, errorf(pos, format, args...))
+}
+
+func (r *resolver) resolve() {
+	modules := r.c.order
+	// module scopes, shared by the module's submodules
+	for _, m := range modules {
+		ms := &scope{mod: m}
+		r.modScopes[m] = ms
+		m.Root = &Node{Kind: KindModule, Name: m.Name, Module: m, Config: true, Pos: m.Stmt.Pos}
+		m.Features, m.Identities, m.Typedefs = map[string]bool{}, map[string]*Identity{}, map[string]*Typedef{}
+		r.features[m] = map[string]*feature{}
+		for _, u := range r.c.units[m] {
+			us := &scope{parent: ms, mod: m, unit: u}
+			r.unitScope[u] = us
+			r.define(ms, us, u.stmt.Statements)
+			for _, st := range u.stmt.Subs("feature") {
+				if prev := r.features[m][st.Argument]; prev != nil {
+					r.errorf(st.Pos, "duplicate feature %q (previously defined at %v)", st.Argument, prev.stmt.Pos)
+					continue
+				}
+				r.features[m][st.Argument] = &feature{stmt: st, scope: us}
+			}
+		}
+	}
+	for _, m := range modules {
+		for _, u := range r.c.units[m] {
+			for _, st := range u.stmt.Subs("feature") {
+				m.Features[st.Argument] = r.feature(m, st.Argument, st.Pos)
+			}
+		}
+	}
+	r.identities(modules)
+	for _, m := range modules {
+		for _, u := range r.c.units[m] {
+			for _, def := range u.stmt.Subs("typedef") {
+				if td := r.typedef(def); td != nil && r.modScopes[m].typedefs[def.Argument] == def {
+					m.Typedefs[def.Argument] = td
+				}
+			}
+		}
+	}
+	// schema trees
+	type pending struct {
+		stmt  *Statement
+		scope *scope
+	}
+	var augments []pending
+	for _, m := range modules {
+		for _, u := range r.c.units[m] {
+			us := r.unitScope[u]
+			r.children(m.Root, u.stmt.Statements, us, m)
+			for _, aug := range u.stmt.Subs("augment") {
+				augments = append(augments, pending{aug, us})
+			}
+		}
+	}
+	// augments may target nodes added by other augments
+	for progress := true; progress && len(augments) > 0; {
+		progress = false
+		var next []pending
+		for _, p := range augments {
+			if target := r.absolute(p.scope, p.stmt.Argument, p.stmt.Pos, false); target != nil {
+				r.augment(target, p.stmt, p.scope, p.scope.mod)
+				progress = true
+			} else {
+				next = append(next, p)
+			}
+		}
+		augments = next
+	}
+	for _, p := range augments {
+		r.absolute(p.scope, p.stmt.Argument, p.stmt.Pos, true)
+	}
+	for _, m := range modules {
+		for _, u := range r.c.units[m] {
+			for _, dev := range u.stmt.Subs("deviation") {
+				r.deviation(dev, r.unitScope[u])
+			}
+		}
+	}
+	for _, m := range modules {
+		r.leafrefs(m.Root)
+	}
+	for _, m := range modules {
+		r.leafrefCycles(m.Root)
+	}
+	for _, m := range modules {
+		r.check(m.Root)
+	}
+}
+
+// feature returns true if the feature name of module m is enabled
+func (r *resolver) feature(m *Module, name string, pos Pos) bool {
+	f := r.features[m][name]
+	switch {
+	case f == nil:
+		r.errorf(pos, "feature %q not found in module %q", name, m.Name)
+		return false
+	case f.state == 1:
+		r.errorf(f.stmt.Pos, "feature %q depends on itself", name)
+		return false
+	case f.state == 2:
+		return f.on
+	}
+	f.state = 1
+	f.on = (r.c.FeatureEnabled == nil || r.c.FeatureEnabled(m.Name, name)) && r.enabled(f.stmt, f.scope)
+	f.state = 2
+	return f.on
+}
+
+// enabled returns true if the if-feature conditions of stmt are all true
+func (r *resolver) enabled(stmt *Statement, s *scope) bool {
+	for _, iff := range stmt.Subs("if-feature") {
+		on, err := evalIfFeature(iff.Argument, func(ref string) bool {
+			prefix, name := splitPrefix(ref)
+			if m := r.module(s, prefix, iff.Pos); m != nil {
+				return r.feature(m, name, iff.Pos)
+			}
+			return false
+		})
+		if err != nil {
+			r.errorf(iff.Pos, "invalid if-feature %q: %v", iff.Argument, err)
+		}
+		if !on {
+			return false
+		}
+	}
+	return true
+}
+
+// identities resolves the identities of modules
+func (r *resolver) identities(modules []*Module) {
+	type def struct {
+		id    *Identity
+		stmt  *Statement
+		scope *scope
+	}
+	var defs []def
+	for _, m := range modules {
+		for _, u := range r.c.units[m] {
+			for _, st := range u.stmt.Subs("identity") {
+				if !r.enabled(st, r.unitScope[u]) {
+					continue
+				}
+				if prev := m.Identities[st.Argument]; prev != nil {
+					r.errorf(st.Pos, "duplicate identity %q (previously defined at %v)", st.Argument, prev.Pos)
+					continue
+				}
+				id := &Identity{Name: st.Argument, Module: m, Pos: st.Pos}
+				m.Identities[st.Argument] = id
+				defs = append(defs, def{id, st, r.unitScope[u]})
+			}
+		}
+	}
+	for _, d := range defs {
+		for _, b := range d.stmt.Subs("base") {
+			if base := r.identity(d.scope, b.Argument, b.Pos); base != nil {
+				d.id.Bases = append(d.id.Bases, base)
+			}
+		}
+	}
+	for _, d := range defs {
+		if derivesFromSelf(d.id, d.id, map[*Identity]bool{}) {
+			r.errorf(d.id.Pos, "identity %q is derived from itself", d.id.Name)
+			d.id.Bases = nil
+		}
+	}
+}
+
+// derivesFromSelf returns true if id is reachable from the bases of i
+func derivesFromSelf(id, i *Identity, seen map[*Identity]bool) bool {
+	for _, b := range i.Bases {
+		if b == id {
+			return true
+		}
+		if !seen[b] {
+			seen[b] = true
+			if derivesFromSelf(id, b, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// identity returns the identity referenced by ref in scope s
+func (r *resolver) identity(s *scope, ref string, pos Pos) *Identity {
+	prefix, name := splitPrefix(ref)
+	m := r.module(s, prefix, pos)
+	if m == nil {
+		return nil
+	}
+	if id := m.Identities[name]; id != nil {
+		return id
+	}
+	r.errorf(pos, "identity %q not found", ref)
+	return nil
+}
+
+// children adds the schema nodes defined by stmts to parent, in the
+// namespace of module mod
+func (r *resolver) children(parent *Node, stmts []*Statement, s *scope, mod *Module) {
+	for _, st := range stmts {
+		switch st.Keyword {
+		case "uses":
+			r.uses(parent, st, s, mod)
+		case "input", "output":
+			// handled by the parent rpc or action
+		default:
+			if _, ok := kindsByKeyword[st.Keyword]; ok {
+				r.node(parent, st, s, mod)
+			}
+		}
+	}
+}
+
+// node adds the schema node defined by st to parent
+func (r *resolver) node(parent *Node, st *Statement, s *scope, mod *Module) {
+	if !r.enabled(st, s) {
+		return
+	}
+	kind := kindsByKeyword[st.Keyword]
+	switch {
+	case !isIdentifier(st.Argument):
+		r.errorf(st.Pos, "invalid %s name %q", st.Keyword, st.Argument)
+		return
+	case kind == KindCase && parent.Kind != KindChoice:
+		r.errorf(st.Pos, "case %q is not within a choice", st.Argument)
+		return
+	case kind == KindRPC && parent.Kind != KindModule:
+		r.errorf(st.Pos, "rpc %q is not a top-level statement", st.Argument)
+		return
+	case (kind == KindAction || kind == KindNotification) && parent.Kind != KindModule && inOperation(parent):
+		r.errorf(st.Pos, "%s %q is within an rpc, action or notification", st.Keyword, st.Argument)
+		return
+	case kind == KindAction && parent.Kind == KindModule:
+		r.errorf(st.Pos, "action %q is a top-level statement", st.Argument)
+		return
+	case parent.Kind == KindChoice && kind != KindCase:
+		// shorthand case statement
+		c := &Node{Kind: KindCase, Name: st.Argument, Module: mod, Parent: parent,
+			Config: parent.Config, Status: "current", Pos: st.Pos}
+		if !r.add(parent, c) {
+			return
+		}
+		parent = c
+	}
+	n := &Node{
+		Kind:        kind,
+		Name:        st.Argument,
+		Module:      mod,
+		Parent:      parent,
+		Description: st.arg("description"),
+		Status:      st.arg("status"),
+		Pos:         st.Pos,
+	}
+	if n.Status == "" {
+		n.Status = "current"
+	}
+	r.config(n, st)
+	ns := r.scope(s, st)
+	r.properties(n, st, ns)
+	if !r.add(parent, n) {
+		return
+	}
+	if kind == KindRPC || kind == KindAction {
+		for _, io := range []string{"input", "output"} {
+			ion := &Node{Kind: KindInput, Name: io, Module: mod, Parent: n, Status: "current", Pos: st.Pos}
+			if io == "output" {
+				ion.Kind = KindOutput
+			}
+			n.Children = append(n.Children, ion)
+			if ios := st.Sub(io); ios != nil {
+				ion.Pos = ios.Pos
+				ion.Must = musts(ios, ns)
+				r.children(ion, ios.Statements, r.scope(ns, ios), mod)
+			}
+		}
+		return
+	}
+	r.children(n, st.Statements, ns, mod)
+	if kind == KindList || kind == KindChoice {
+		r.checkKeys(n, st)
+	}
+}
+
+// add adds n to parent's children, returning false for duplicates
+func (r *resolver) add(parent *Node, n *Node) bool {
+	if prev := parent.Child(n.Module, n.Name); prev != nil {
+		r.errorf(n.Pos, "duplicate node %q (previously defined at %v)", n.Name, prev.Pos)
+		return false
+	}
+	n.Parent = parent
+	parent.Children = append(parent.Children, n)
+	return true
+}
+
+// config sets the effective config property of n
+func (r *resolver) config(n *Node, st *Statement) {
+	switch n.Kind {
+	case KindRPC, KindAction, KindNotification, KindInput, KindOutput:
+		return
+	}
+	n.Config = n.Parent.Config
+	switch cfg := st.Sub("config"); {
+	case cfg == nil:
+	case cfg.Argument == "false":
+		n.Config = false
+	case cfg.Argument != "true":
+		r.errorf(cfg.Pos, "invalid config value %q", cfg.Argument)
+	case !n.Parent.Config && !inOperation(n.Parent):
+		r.errorf(cfg.Pos, "config true node %q within config false node", n.Name)
+	}
+}
+
+// inOperation returns true if n is within an rpc, action or notification
+func inOperation(n *Node) bool {
+	for ; n != nil; n = n.Parent {
+		switch n.Kind {
+		case KindRPC, KindAction, KindNotification:
+			return true
+		}
+	}
+	return false
+}
+
+// musts returns the must statements of st, defined in scope s
+func musts(st *Statement, s *scope) (m []Must) {
+	for _, must := range st.Subs("must") {
+		m = append(m, newMust(must, s))
+	}
+	return
+}
+
+func newMust(must *Statement, s *scope) Must {
+	return Must{
+		XPath:        XPath{Expr: must.Argument, Prefixes: s.prefixes()},
+		ErrorMessage: must.arg("error-message"),
+		ErrorAppTag:  must.arg("error-app-tag"),
+	}
+}
+
+// when returns the when condition of st, defined in scope s, or nil
+func when(st *Statement, s *scope, parent bool) *When {
+	if w := st.Sub("when"); w != nil {
+		return &When{XPath: XPath{Expr: w.Argument, Prefixes: s.prefixes()}, Parent: parent}
+	}
+	return nil
+}
+
+// properties sets the properties of n from the substatements of st
+func (r *resolver) properties(n *Node, st *Statement, s *scope) {
+	n.Must = musts(st, s)
+	if w := when(st, s, false); w != nil {
+		n.When = append(n.When, *w)
+	}
+	n.Presence = st.arg("presence")
+	n.Units = st.arg("units")
+	n.OrderedBy = st.arg("ordered-by")
+	for _, sub := range st.Statements {
+		if strings.Contains(sub.Keyword, ":") {
+			n.Extensions = append(n.Extensions, sub)
+		}
+	}
+	if m := st.Sub("mandatory"); m != nil {
+		n.Mandatory = r.boolean(m)
+	}
+	for _, d := range st.Subs("default") {
+		n.Default = append(n.Default, d.Argument)
+	}
+	if st.Sub("min-elements") != nil {
+		n.MinElements = r.elements(st.Sub("min-elements"), false)
+	}
+	if st.Sub("max-elements") != nil {
+		n.MaxElements = r.elements(st.Sub("max-elements"), true)
+	}
+	if key := st.Sub("key"); key != nil {
+		n.Keys = strings.Fields(key.Argument)
+	}
+	for _, u := range st.Subs("unique") {
+		n.Unique = append(n.Unique, strings.Fields(u.Argument))
+	}
+	if n.Kind != KindLeaf && n.Kind != KindLeafList {
+		return
+	}
+	ts := st.Sub("type")
+	if ts == nil {
+		r.errorf(st.Pos, "%s %q has no type", st.Keyword, n.Name)
+		return
+	}
+	n.Type = r.resolveType(ts, s)
+	if n.Type == nil {
+		return
+	}
+	// units and default may be inherited from the type's typedefs
+	for td := n.Type.Typedef; td != nil; td = td.Type.Typedef {
+		if n.Units == "" {
+			n.Units = td.Units
+		}
+		if len(n.Default) == 0 && td.Default != "" && n.Kind == KindLeaf {
+			n.Default = []string{td.Default}
+		}
+	}
+}
+
+func (r *resolver) boolean(st *Statement) bool {
+	switch st.Argument {
+	case "true":
+		return true
+	case "false":
+	default:
+		r.errorf(st.Pos, "invalid %s value %q", st.Keyword, st.Argument)
+	}
+	return false
+}
+
+// elements returns the min-elements or max-elements value of st
+func (r *resolver) elements(st *Statement, max bool) int {
+	if max && st.Argument == "unbounded" {
+		return 0
+	}
+	v, err := strconv.ParseUint(st.Argument, 10, 31)
+	if err != nil || (max && v == 0) {
+		r.errorf(st.Pos, "invalid %s value %q", st.Keyword, st.Argument)
+	}
+	return int(v)
+}
+
+// checkKeys validates list keys and choice defaults once n's children are known
+func (r *resolver) checkKeys(n *Node, st *Statement) {
+	if n.Kind == KindChoice {
+		if len(n.Default) > 0 && n.Child(nil, n.Default[0]) == nil {
+			r.errorf(st.Pos, "default case %q not found in choice %q", n.Default[0], n.Name)
+		}
+		return
+	}
+	if len(n.Keys) == 0 && n.Config {
+		r.errorf(st.Pos, "config list %q has no key", n.Name)
+	}
+	for _, k := range n.Keys {
+		if leaf := n.Child(n.Module, k); leaf == nil || leaf.Kind != KindLeaf {
+			r.errorf(st.Sub("key").Pos, "key leaf %q not found in list %q", k, n.Name)
+		}
+	}
+}
+
+// uses expands the grouping used by st into parent
+func (r *resolver) uses(parent *Node, st *Statement, s *scope, mod *Module) {
+	if !r.enabled(st, s) {
+		return
+	}
+	g, gs := r.lookup(s, "grouping", st.Argument, st.Pos)
+	if g == nil {
+		return
+	}
+	if r.expanding[g] {
+		r.errorf(st.Pos, "grouping %q uses itself", st.Argument)
+		return
+	}
+	r.expanding[g] = true
+	defer delete(r.expanding, g)
+	before := len(parent.Children)
+	r.children(parent, g.Statements, r.scope(gs, g), mod)
+	added := append([]*Node(nil), parent.Children[before:]...)
+	if w := when(st, s, true); w != nil {
+		for _, n := range added {
+			n.When = append(n.When, *w)
+		}
+	}
+	for _, ref := range st.Subs("refine") {
+		if target := r.descendant(added, s, mod, ref.Argument, ref.Pos); target != nil {
+			r.refine(target, ref, s)
+		}
+	}
+	for _, aug := range st.Subs("augment") {
+		if target := r.descendant(added, s, mod, aug.Argument, aug.Pos); target != nil {
+			r.augment(target, aug, s, mod)
+		}
+	}
+}
+
+// descendant returns the node among nodes (and their descendants)
+// identified by the descendant schema node identifier path
+func (r *resolver) descendant(nodes []*Node, s *scope, mod *Module, path string, pos Pos) *Node {
+	steps := strings.Split(path, "/")
+	var n *Node
+	for i, step := range steps {
+		prefix, name := splitPrefix(step)
+		m := mod
+		if prefix != "" {
+			if m = r.module(s, prefix, pos); m == nil {
+				return nil
+			}
+		}
+		var next *Node
+		if i == 0 {
+			for _, c := range nodes {
+				if c.Name == name && c.Module == m {
+					next = c
+				}
+			}
+		} else {
+			next = n.Child(m, name)
+		}
+		if next == nil {
+			r.errorf(pos, "schema node %q not found", path)
+			return nil
+		}
+		n = next
+	}
+	return n
+}
+
+// absolute returns the node identified by the absolute schema node
+// identifier path in scope s, recording an error if not found and report is set
+func (r *resolver) absolute(s *scope, path string, pos Pos, report bool) *Node {
+	if !strings.HasPrefix(path, "/") {
+		r.errorf(pos, "schema node identifier %q is not absolute", path)
+		return nil
+	}
+	var n *Node
+	for _, step := range strings.Split(path[1:], "/") {
+		prefix, name := splitPrefix(strings.TrimSpace(step))
+		m := r.module(s, prefix, pos)
+		if m == nil {
+			return nil
+		}
+		if n == nil {
+			n = m.Root
+		}
+		if n = n.Child(m, name); n == nil {
+			if report {
+				r.errorf(pos, "schema node %q not found", path)
+			}
+			return nil
+		}
+	}
+	return n
+}
+
+// refine applies the refine statement st to target
+func (r *resolver) refine(target *Node, st *Statement, s *scope) {
+	if !r.enabled(st, s) {
+		remove(target)
+		return
+	}
+	if d := st.arg("description"); d != "" {
+		target.Description = d
+	}
+	if cfg := st.Sub("config"); cfg != nil {
+		target.Config = r.boolean(cfg)
+		if !target.Config {
+			setConfigFalse(target)
+		}
+	}
+	if defaults := st.Subs("default"); len(defaults) > 0 {
+		target.Default = nil
+		for _, d := range defaults {
+			target.Default = append(target.Default, d.Argument)
+		}
+	}
+	if m := st.Sub("mandatory"); m != nil {
+		target.Mandatory = r.boolean(m)
+	}
+	if p := st.Sub("presence"); p != nil {
+		target.Presence = p.Argument
+	}
+	if m := st.Sub("min-elements"); m != nil {
+		target.MinElements = r.elements(m, false)
+	}
+	if m := st.Sub("max-elements"); m != nil {
+		target.MaxElements = r.elements(m, true)
+	}
+	target.Must = append(target.Must, musts(st, s)...)
+}
+
+// augment adds the nodes defined by the augment statement st to target
+func (r *resolver) augment(target *Node, st *Statement, s *scope, mod *Module) {
+	if !r.enabled(st, s) {
+		return
+	}
+	switch target.Kind {
+	case KindLeaf, KindLeafList, KindAnydata, KindAnyxml:
+		r.errorf(st.Pos, "augment target %q is a %v", st.Argument, target.Kind)
+		return
+	}
+	before := len(target.Children)
+	r.children(target, st.Statements, r.scope(s, st), mod)
+	if w := when(st, s, true); w != nil {
+		for _, n := range target.Children[before:] {
+			n.When = append(n.When, *w)
+		}
+	}
+}
+
+// remove removes n from its parent
+func remove(n *Node) {
+	p := n.Parent
+	for i, c := range p.Children {
+		if c == n {
+			p.Children = append(p.Children[:i:i], p.Children[i+1:]...)
+			return
+		}
+	}
+}
+
+func setConfigFalse(n *Node) {
+	n.Config = false
+	for _, c := range n.Children {
+		setConfigFalse(c)
+	}
+}
+
+// deviation applies the deviation statement st
+func (r *resolver) deviation(st *Statement, s *scope) {
+	target := r.absolute(s, st.Argument, st.Pos, true)
+	if target == nil {
+		return
+	}
+	for _, dev := range st.Subs("deviate") {
+		switch dev.Argument {
+		case "not-supported":
+			remove(target)
+			return
+		case "add", "replace", "delete":
+			r.deviate(target, dev, s)
+		default:
+			r.errorf(dev.Pos, "invalid deviate argument %q", dev.Argument)
+		}
+	}
+}
+
+// deviate applies the add, replace or delete deviate statement dev to target
+func (r *resolver) deviate(target *Node, dev *Statement, s *scope) {
+	op := dev.Argument
+	for _, sub := range dev.Statements {
+		switch sub.Keyword {
+		case "config", "mandatory", "min-elements", "max-elements", "type":
+			if op == "delete" {
+				r.errorf(sub.Pos, "cannot delete %s", sub.Keyword)
+				continue
+			}
+		}
+		switch sub.Keyword {
+		case "config":
+			if target.Config = r.boolean(sub); !target.Config {
+				setConfigFalse(target)
+			}
+		case "mandatory":
+			target.Mandatory = r.boolean(sub)
+		case "min-elements":
+			target.MinElements = r.elements(sub, false)
+		case "max-elements":
+			target.MaxElements = r.elements(sub, true)
+		case "type":
+			if op != "replace" {
+				r.errorf(sub.Pos, "type can only be replaced")
+			} else if t := r.resolveType(sub, s); t != nil {
+				target.Type = t
+			}
+		case "units":
+			if op == "delete" {
+				target.Units = ""
+			} else {
+				target.Units = sub.Argument
+			}
+		case "default":
+			switch op {
+			case "add":
+				target.Default = append(target.Default, sub.Argument)
+			case "replace":
+				target.Default = []string{sub.Argument}
+			case "delete":
+				target.Default = removeString(target.Default, sub.Argument)
+			}
+		case "must":
+			if op == "delete" {
+				for i := len(target.Must) - 1; i >= 0; i-- {
+					if target.Must[i].Expr == sub.Argument {
+						target.Must = append(target.Must[:i:i], target.Must[i+1:]...)
+					}
+				}
+			} else {
+				target.Must = append(target.Must, newMust(sub, s))
+			}
+		case "unique":
+			fields := strings.Fields(sub.Argument)
+			if op == "delete" {
+				for i := len(target.Unique) - 1; i >= 0; i-- {
+					if strings.Join(target.Unique[i], " ") == strings.Join(fields, " ") {
+						target.Unique = append(target.Unique[:i:i], target.Unique[i+1:]...)
+					}
+				}
+			} else {
+				target.Unique = append(target.Unique, fields)
+			}
+		}
+	}
+}
+
+func removeString(list []string, s string) (out []string) {
+	for _, v := range list {
+		if v != s {
+			out = append(out, v)
+		}
+	}
+	return
+}
+
+// check validates the resolved tree rooted at n
+func (r *resolver) check(n *Node) {
+	if n.Kind == KindLeaf && n.Mandatory && len(n.Default) > 0 {
+		r.errorf(n.Pos, "mandatory leaf %q has a default", n.Name)
+	}
+	if n.MaxElements > 0 && n.MinElements > n.MaxElements {
+		r.errorf(n.Pos, "%s %q min-elements exceeds max-elements", n.Kind, n.Name)
+	}
+	for _, c := range n.Children {
+		r.check(c)
+	}
+}
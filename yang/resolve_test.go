@@ -0,0 +1,360 @@
+package yang
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolve(t *testing.T) {
+	a := assert.New(t)
+	c := NewContext("testdata")
+	if !a.NoError(c.Load("example-system", "example-augment")) || !a.NoError(c.Resolve()) {
+		return
+	}
+	sys, types, aug := c.Module("example-system"), c.Module("example-types"), c.Module("example-augment")
+	if !a.NotNil(sys) || !a.NotNil(types) || !a.NotNil(aug) {
+		return
+	}
+	a.Equal("2024-02-01", sys.Revision)
+	a.Equal([]string{"example-system-sub"}, sys.Submodules)
+	a.Equal(map[string]bool{"ntp": true, "ntp-auth": true, "radius": true}, sys.Features)
+
+	// identities
+	aes256 := types.Identities["aes-256"]
+	if a.NotNil(aes256) {
+		a.True(aes256.DerivedFrom(types.Identities["crypto-alg"]))
+		a.False(types.Identities["crypto-alg"].DerivedFrom(aes256))
+	}
+
+	// top-level nodes, including those of the submodule
+	var names []string
+	for _, n := range sys.Root.Children {
+		names = append(names, n.Kind.String()+" "+n.Name)
+	}
+	a.Equal([]string{"container system", "rpc restart", "notification restarted", "container users"}, names)
+	system := sys.Root.Child(sys, "system")
+
+	// typedef chain, with inherited units and default
+	load := system.Child(sys, "load")
+	if a.NotNil(load) {
+		a.False(load.Config)
+		a.Equal(TypeUint8, load.Type.Kind)
+		a.Equal("level", load.Type.Typedef.Name)
+		a.Equal("10..90", load.Type.Range.String())
+		a.Equal("percent", load.Units)
+		a.Equal([]string{"50"}, load.Default)
+	}
+	hostname := system.Child(sys, "hostname")
+	// leafref target
+	if ref := system.Child(sys, "ref"); a.NotNil(ref) {
+		a.Equal(hostname, ref.Type.Target)
+	}
+	if a.NotNil(hostname) {
+		a.True(hostname.Config)
+		a.Equal("1..64", hostname.Type.Length.String())
+		if a.Len(hostname.Type.Patterns, 1) {
+			a.True(hostname.Type.Patterns[0].Match("r1"))
+			a.False(hostname.Type.Patterns[0].Match("1r"))
+		}
+		// deviated
+		a.False(hostname.Mandatory)
+		a.Equal([]string{"router"}, hostname.Default)
+	}
+
+	// groupings, refine, uses-augment and if-feature
+	server := system.Child(sys, "ntp").Child(sys, "server")
+	if a.NotNil(server) {
+		a.Equal("/sys:system/sys:ntp/sys:server", server.Path())
+		a.Equal([]string{"name"}, server.Keys)
+		a.Equal([][]string{{"ip", "port"}}, server.Unique)
+		a.Equal("user", server.OrderedBy)
+		a.Equal(8, server.MaxElements)
+		names = nil
+		for _, n := range server.Children {
+			names = append(names, n.Module.Prefix+":"+n.Name)
+		}
+		a.Equal([]string{"sys:name", "sys:ip", "sys:port", "sys:options", "sys:key", "aug:iburst"}, names)
+		a.True(server.Child(sys, "name").IsKey())
+		a.Equal([]string{"4830"}, server.Child(sys, "port").Default)
+		a.NotNil(server.Child(sys, "options").Child(sys, "prefer"))
+		if when := server.Child(aug, "iburst").When; a.Len(when, 1) {
+			a.Equal("../sys:name != 'local'", when[0].Expr)
+			a.Equal(sys, when[0].Prefixes["sys"])
+			a.True(when[0].Parent)
+		}
+	}
+	// deviated
+	a.Nil(system.Child(sys, "radius"))
+
+	// augments targeting augmented nodes
+	logging := system.Child(aug, "logging")
+	if a.NotNil(logging) {
+		a.NotNil(logging.Child(aug, "facility"))
+	}
+
+	// choices, with shorthand cases
+	transport := system.Child(sys, "transport")
+	if a.NotNil(transport) && a.Len(transport.Children, 2) {
+		a.Equal(KindCase, transport.Children[1].Kind)
+		a.Equal("tls-port", transport.Children[1].Name)
+		a.Equal([]string{"ssh"}, transport.Default)
+		a.Equal(system.Child(sys, "transport").Children[1].Children[0], system.DataChild(sys, "tls-port"))
+	}
+
+	// built-in types
+	mode := system.Child(sys, "mode").Type
+	a.Equal([]Enum{{"auto", 0}, {"manual", 10}, {"off", 11}}, mode.Enums)
+	flags := system.Child(sys, "flags").Type
+	a.Equal([]Bit{{"up", 0}, {"down", 4}, {"testing", 5}}, flags.Bits)
+	ratio := system.Child(sys, "ratio").Type
+	a.Equal(2, ratio.FractionDigits)
+	a.True(ratio.Range.Contains(big.NewRat(1, 2)))
+	a.False(ratio.Range.Contains(big.NewRat(3, 2)))
+	alg := system.Child(sys, "alg").Type
+	a.Equal([]*Identity{types.Identities["crypto-alg"]}, alg.Bases)
+	id := system.Child(sys, "id").Type
+	if a.Len(id.Union, 2) && a.Len(id.Union[1].Patterns, 1) {
+		a.True(id.Union[1].Patterns[0].Match("abc"))
+		a.False(id.Union[1].Patterns[0].Match("123"))
+	}
+	ref := system.Child(sys, "ref").Type
+	a.Equal("../hostname", ref.Path.Expr)
+	a.True(ref.RequireInstance)
+
+	// rpcs and notifications
+	restart := sys.Root.Child(sys, "restart")
+	if a.NotNil(restart) && a.Len(restart.Children, 2) {
+		a.Equal(KindInput, restart.Children[0].Kind)
+		a.Equal(KindOutput, restart.Children[1].Kind)
+		a.False(restart.Children[0].Child(sys, "delay").Config)
+	}
+
+	// submodule nodes, with deviations from another module
+	password := sys.Root.Child(sys, "users").Child(sys, "user").Child(sys, "password")
+	if a.NotNil(password) {
+		if a.Len(password.Must, 1) {
+			a.Equal("string-length(.) > 8", password.Must[0].Expr)
+			a.Equal("password too short", password.Must[0].ErrorMessage)
+			a.Equal(aug, password.Must[0].Prefixes["aug"])
+		}
+	}
+}
+
+func TestResolveFeatures(t *testing.T) {
+	a := assert.New(t)
+	c := NewContext("testdata")
+	c.FeatureEnabled = func(module, feature string) bool { return feature != "ntp" }
+	if !a.NoError(c.Load("example-system")) || !a.NoError(c.Resolve()) {
+		return
+	}
+	sys := c.Module("example-system")
+	a.Equal(map[string]bool{"ntp": false, "ntp-auth": false, "radius": true}, sys.Features)
+	system := sys.Root.Child(sys, "system")
+	a.Nil(system.Child(sys, "ntp"))
+	a.NotNil(system.Child(sys, "radius"))
+}
+
+func TestEvalIfFeature(t *testing.T) {
+	features := map[string]bool{"a": true, "b": false, "p:c": true}
+	for _, tc := range []struct {
+		expr    string
+		want    bool
+		wantErr string
+	}{
+		{expr: "a", want: true},
+		{expr: "b"},
+		{expr: "p:c", want: true},
+		{expr: "not b", want: true},
+		{expr: "a and b"},
+		{expr: "a or b", want: true},
+		{expr: "b or a and p:c", want: true},
+		{expr: "(b or a) and not p:c"},
+		{expr: "not (a and b)", want: true},
+		{expr: "", wantErr: "empty expression"},
+		{expr: "a and", wantErr: "unexpected end of expression"},
+		{expr: "(a or b", wantErr: `missing ")"`},
+		{expr: "a b", wantErr: `unexpected "b"`},
+		{expr: "a and 1x", wantErr: `invalid feature reference "1x"`},
+	} {
+		t.Run(tc.expr, func(t *testing.T) {
+			a := assert.New(t)
+			got, err := evalIfFeature(tc.expr, func(ref string) bool { return features[ref] })
+			if tc.wantErr != "" {
+				a.EqualError(err, tc.wantErr)
+				return
+			}
+			if a.NoError(err) {
+				a.Equal(tc.want, got)
+			}
+		})
+	}
+}
+
+func TestResolveErrors(t *testing.T) {
+	const header = "module m {\n  yang-version 1.1;\n  namespace \"urn:m\";\n  prefix m;\n"
+	for _, tc := range []struct {
+		name    string
+		body    string
+		wantErr string
+	}{
+		{
+			name:    "unknown typedef",
+			body:    "  leaf a {\n    type foo;\n  }\n",
+			wantErr: `DIR/m.yang:6:5: typedef "foo" not found`,
+		},
+		{
+			name:    "unknown prefix",
+			body:    "  leaf a {\n    type x:foo;\n  }\n",
+			wantErr: `DIR/m.yang:6:5: unknown prefix "x"`,
+		},
+		{
+			name:    "unknown grouping",
+			body:    "  container c {\n    uses g;\n  }\n",
+			wantErr: `DIR/m.yang:6:5: grouping "g" not found`,
+		},
+		{
+			name:    "recursive grouping",
+			body:    "  grouping g {\n    container c {\n      uses g;\n    }\n  }\n  uses g;\n",
+			wantErr: `DIR/m.yang:7:7: grouping "g" uses itself`,
+		},
+		{
+			name:    "recursive typedef",
+			body:    "  typedef t {\n    type u;\n  }\n  typedef u {\n    type t;\n  }\n",
+			wantErr: `DIR/m.yang:5:3: typedef "t" is derived from itself`,
+		},
+		{
+			name:    "invalid range",
+			body:    "  leaf a {\n    type uint8 {\n      range \"0..300\";\n    }\n  }\n",
+			wantErr: `DIR/m.yang:7:7: invalid range "0..300": "0..300" is not within the base type's restriction "0..255"`,
+		},
+		{
+			name:    "invalid pattern",
+			body:    "  leaf a {\n    type string {\n      pattern '[a-';\n    }\n  }\n",
+			wantErr: "DIR/m.yang:7:7: invalid pattern \"[a-\": error parsing regexp: invalid character class range: `a-)`",
+		},
+		{
+			name:    "leafref target not found",
+			body:    "  leaf a {\n    type leafref {\n      path \"../b\";\n    }\n  }\n",
+			wantErr: `DIR/m.yang:6:5: leafref path "../b" does not identify a leaf or leaf-list`,
+		},
+		{
+			name:    "leafref cycle",
+			body:    "  leaf a {\n    type leafref {\n      path \"../b\";\n    }\n  }\n  leaf b {\n    type leafref {\n      path \"/m:a\";\n    }\n  }\n",
+			wantErr: `DIR/m.yang:6:5: leafref path "../b" forms a cycle`,
+		},
+		{
+			name:    "duplicate node",
+			body:    "  leaf a {\n    type string;\n  }\n  container a;\n",
+			wantErr: `DIR/m.yang:8:3: duplicate node "a" (previously defined at DIR/m.yang:5:3)`,
+		},
+		{
+			name:    "missing key",
+			body:    "  list l {\n    key k;\n    leaf a {\n      type string;\n    }\n  }\n",
+			wantErr: `DIR/m.yang:6:5: key leaf "k" not found in list "l"`,
+		},
+		{
+			name:    "config true within config false",
+			body:    "  container c {\n    config false;\n    leaf a {\n      type string;\n      config true;\n    }\n  }\n",
+			wantErr: `DIR/m.yang:9:7: config true node "a" within config false node`,
+		},
+		{
+			name:    "augment target not found",
+			body:    "  augment \"/m:missing\" {\n    leaf a {\n      type string;\n    }\n  }\n",
+			wantErr: `DIR/m.yang:5:3: schema node "/m:missing" not found`,
+		},
+		{
+			name:    "unknown feature",
+			body:    "  container c {\n    if-feature f;\n  }\n",
+			wantErr: `DIR/m.yang:6:5: feature "f" not found in module "m"`,
+		},
+		{
+			name:    "mandatory default",
+			body:    "  leaf a {\n    type string;\n    mandatory true;\n    default x;\n  }\n",
+			wantErr: `DIR/m.yang:5:3: mandatory leaf "a" has a default`,
+		},
+		{
+			name:    "missing fraction-digits",
+			body:    "  leaf a {\n    type decimal64;\n  }\n",
+			wantErr: "DIR/m.yang:6:5: decimal64 type requires fraction-digits",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			a := assert.New(t)
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "m.yang"), []byte(header+tc.body+"}\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			c := NewContext(dir)
+			if !a.NoError(c.Load("m")) {
+				return
+			}
+			err := c.Resolve()
+			if a.Error(err) {
+				a.Equal(tc.wantErr, strings.ReplaceAll(err.Error(), dir, "DIR"))
+			}
+		})
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		files   map[string]string
+		load    string
+		wantErr string
+	}{
+		{
+			name:    "not found",
+			load:    "m",
+			wantErr: `module "m" not found in path [DIR]`,
+		},
+		{
+			name:    "import not found",
+			files:   map[string]string{"m.yang": "module m {\n  namespace urn:m;\n  prefix m;\n  import x {\n    prefix x;\n  }\n}\n"},
+			load:    "m",
+			wantErr: `DIR/m.yang:4:3: module "x" not found in path [DIR]`,
+		},
+		{
+			name:    "revision not found",
+			files:   map[string]string{"m.yang": "module m {\n  namespace urn:m;\n  prefix m;\n  revision 2020-01-01;\n}\n"},
+			load:    "m@2021-01-01",
+			wantErr: `DIR/m.yang:1:1: module "m" has no revision 2021-01-01`,
+		},
+		{
+			name: "wrong submodule",
+			files: map[string]string{
+				"m.yang": "module m {\n  namespace urn:m;\n  prefix m;\n  include s;\n}\n",
+				"s.yang": "submodule s {\n  belongs-to n {\n    prefix n;\n  }\n}\n",
+			},
+			load:    "m",
+			wantErr: `DIR/s.yang:1:1: submodule "s" does not belong to module "m"`,
+		},
+		{
+			name:    "missing namespace",
+			files:   map[string]string{"m.yang": "module m {\n  prefix m;\n}\n"},
+			load:    "m",
+			wantErr: `DIR/m.yang:1:1: module "m" requires namespace and prefix statements`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			a := assert.New(t)
+			dir := t.TempDir()
+			for name, src := range tc.files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			c := NewContext(dir)
+			err := c.Load(tc.load)
+			if a.Error(err) {
+				a.Equal(tc.wantErr, strings.ReplaceAll(err.Error(), dir, "DIR"))
+			}
+			a.Empty(c.Modules)
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package yang
+
+import "strings"
+
+// Module is a resolved YANG module, including its submodules
+type Module struct {
+	Name      string
+	Namespace string
+	Prefix    string
+	// Revision is the module's most recent revision date, if any
+	Revision string
+	// YANGVersion is the module's yang-version ("1" or "1.1")
+	YANGVersion string
+	// Features holds the module's features, and whether each is enabled
+	Features map[string]bool
+	// Identities holds the module's identities, by name
+	Identities map[string]*Identity
+	// Typedefs holds the module's top-level typedefs, by name
+	Typedefs map[string]*Typedef
+	// Root holds the module's top-level schema nodes (data nodes, rpcs
+	// and notifications) as its children. Nodes augmenting other
+	// modules are found in the target modules' trees.
+	Root *Node
+	// Submodules holds the names of the module's included submodules
+	Submodules []string
+	// Stmt is the module's statement
+	Stmt *Statement
+}
+
+// Kind is a kind of schema node
+type Kind int
+
+// Kinds of schema node
+const (
+	KindModule Kind = iota
+	KindContainer
+	KindLeaf
+	KindLeafList
+	KindList
+	KindChoice
+	KindCase
+	KindAnydata
+	KindAnyxml
+	KindRPC
+	KindAction
+	KindInput
+	KindOutput
+	KindNotification
+)
+
+var kindNames = [...]string{"module", "container", "leaf", "leaf-list", "list", "choice", "case",
+	"anydata", "anyxml", "rpc", "action", "input", "output", "notification"}
+
+func (k Kind) String() string {
+	if int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+	return "unknown"
+}
+
+var kindsByKeyword = map[string]Kind{
+	"container": KindContainer, "leaf": KindLeaf, "leaf-list": KindLeafList, "list": KindList,
+	"choice": KindChoice, "case": KindCase, "anydata": KindAnydata, "anyxml": KindAnyxml,
+	"rpc": KindRPC, "action": KindAction, "input": KindInput, "output": KindOutput,
+	"notification": KindNotification,
+}
+
+// Node is a resolved schema node
+type Node struct {
+	Kind Kind
+	Name string
+	// Module is the module defining the node's namespace
+	Module *Module
+	Parent *Node
+	// Children holds the node's child schema nodes, in schema order
+	Children []*Node
+
+	Description string
+	// Config is the node's effective config property; false for
+	// state data, and for the contents of rpcs, actions and notifications
+	Config bool
+	// Mandatory is set for mandatory leafs, choices, anydata and anyxml
+	Mandatory bool
+	// Presence is the presence statement of a presence container
+	Presence string
+	// Type is the type of a leaf or leaf-list
+	Type  *Type
+	Units string
+	// Default holds the default value(s) of a leaf, leaf-list or choice
+	// (being the default case name)
+	Default []string
+	// Keys holds the key leaf names of a list
+	Keys []string
+	// Unique holds the unique statements of a list, as descendant paths
+	Unique [][]string
+	// MinElements and MaxElements constrain lists and leaf-lists;
+	// MaxElements is zero if unbounded
+	MinElements, MaxElements int
+	// OrderedBy is "user" for user ordered lists and leaf-lists
+	OrderedBy string
+	// Must and When hold the node's XPath constraint expressions
+	Must []Must
+	When []When
+	// Status is "current", "deprecated" or "obsolete"
+	Status string
+	// Extensions holds the node's extension statements
+	Extensions []*Statement
+
+	Pos Pos
+}
+
+// XPath is an XPath expression of a must, when or leafref path
+// statement, along with the import prefixes in scope where it is defined.
+type XPath struct {
+	Expr     string
+	Prefixes map[string]*Module
+}
+
+// Must is a must constraint
+type Must struct {
+	XPath
+	ErrorMessage string
+	ErrorAppTag  string
+}
+
+// When is a when condition
+type When struct {
+	XPath
+	// Parent is set for the conditions of uses and augment statements,
+	// whose context node is the parent of the node
+	Parent bool
+}
+
+// IsData returns true for data nodes (containers, leafs, leaf-lists,
+// lists, anydata and anyxml)
+func (n *Node) IsData() bool {
+	switch n.Kind {
+	case KindContainer, KindLeaf, KindLeafList, KindList, KindAnydata, KindAnyxml:
+		return true
+	}
+	return false
+}
+
+// Child returns the child schema node with name, in the namespace of
+// module, or nil. If module is nil, the first child with name in any
+// module is returned.
+func (n *Node) Child(module *Module, name string) *Node {
+	for _, c := range n.Children {
+		if c.Name == name && (module == nil || c.Module == module) {
+			return c
+		}
+	}
+	return nil
+}
+
+// DataChild returns the data node child with name (in the namespace of
+// module, if non-nil), descending through choices and cases, or nil.
+func (n *Node) DataChild(module *Module, name string) *Node {
+	for _, c := range n.Children {
+		switch c.Kind {
+		case KindChoice, KindCase:
+			if d := c.DataChild(module, name); d != nil {
+				return d
+			}
+		default:
+			if c.Name == name && (module == nil || c.Module == module) {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+// DataChildren returns the data node children of n, descending through
+// choices and cases.
+func (n *Node) DataChildren() (children []*Node) {
+	for _, c := range n.Children {
+		switch c.Kind {
+		case KindChoice, KindCase:
+			children = append(children, c.DataChildren()...)
+		default:
+			children = append(children, c)
+		}
+	}
+	return
+}
+
+// Path returns the node's schema node identifier, e.g., "/if:interfaces/if:interface"
+func (n *Node) Path() string {
+	var parts []string
+	for ; n != nil && n.Kind != KindModule; n = n.Parent {
+		parts = append([]string{n.Module.Prefix + ":" + n.Name}, parts...)
+	}
+	return "/" + strings.Join(parts, "/")
+}
+
+// IsKey returns true if n is a key leaf of its parent list
+func (n *Node) IsKey() bool {
+	if n.Kind != KindLeaf || n.Parent == nil || n.Parent.Kind != KindList {
+		return false
+	}
+	for _, k := range n.Parent.Keys {
+		if k == n.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// Identity is a YANG identity
+type Identity struct {
+	Name   string
+	Module *Module
+	Bases  []*Identity
+	Pos    Pos
+}
+
+// DerivedFrom returns true if i is derived (directly or indirectly) from base
+func (i *Identity) DerivedFrom(base *Identity) bool {
+	for _, b := range i.Bases {
+		if b == base || b.DerivedFrom(base) {
+			return true
+		}
+	}
+	return false
+}
+
+// Typedef is a YANG typedef
+type Typedef struct {
+	Name    string
+	Module  *Module
+	Type    *Type
+	Units   string
+	Default string
+	Pos     Pos
+}
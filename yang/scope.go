@@ -0,0 +1,106 @@
+package yang
+
+import "strings"
+
+// scope is a lexical scope of typedef and grouping definitions
+type scope struct {
+	parent *scope
+	// mod is the module in which the scope's definitions are made
+	mod *Module
+	// unit is set for module and submodule scopes, providing prefixes
+	unit      *unit
+	typedefs  map[string]*Statement
+	groupings map[string]*Statement
+}
+
+// prefixes returns the import prefixes in scope
+func (s *scope) prefixes() map[string]*Module {
+	for ; s != nil; s = s.parent {
+		if s.unit != nil {
+			return s.unit.prefixes
+		}
+	}
+	return nil
+}
+
+// splitPrefix splits a prefixed identifier reference
+func splitPrefix(ref string) (prefix, name string) {
+	if i := strings.IndexByte(ref, ':'); i >= 0 {
+		return ref[:i], ref[i+1:]
+	}
+	return "", ref
+}
+
+// module returns the module referenced by prefix in scope s, being
+// the scope's own module if prefix is empty
+func (r *resolver) module(s *scope, prefix string, pos Pos) *Module {
+	if prefix == "" {
+		return s.mod
+	}
+	if m := s.prefixes()[prefix]; m != nil {
+		return m
+	}
+	r.errorf(pos, "unknown prefix %q", prefix)
+	return nil
+}
+
+// scope returns a new scope within parent, for the definitions of stmt
+func (r *resolver) scope(parent *scope, stmt *Statement) *scope {
+	s := &scope{parent: parent, mod: parent.mod}
+	r.define(s, s, stmt.Statements)
+	return s
+}
+
+// define adds the typedefs and groupings of stmts to s, which are
+// resolved in the scope def
+func (r *resolver) define(s, def *scope, stmts []*Statement) {
+	for _, st := range stmts {
+		var defs *map[string]*Statement
+		switch st.Keyword {
+		case "typedef":
+			defs = &s.typedefs
+			if _, builtin := typeKinds[st.Argument]; builtin {
+				r.errorf(st.Pos, "typedef %q redefines a built-in type", st.Argument)
+				continue
+			}
+		case "grouping":
+			defs = &s.groupings
+		default:
+			continue
+		}
+		if *defs == nil {
+			*defs = map[string]*Statement{}
+		}
+		if prev := (*defs)[st.Argument]; prev != nil {
+			r.errorf(st.Pos, "duplicate %s %q (previously defined at %v)", st.Keyword, st.Argument, prev.Pos)
+			continue
+		}
+		(*defs)[st.Argument] = st
+		r.defScopes[st] = def
+	}
+}
+
+// lookup returns the typedef or grouping (per keyword) referenced by ref
+// in scope s, along with the scope in which it is defined
+func (r *resolver) lookup(s *scope, keyword, ref string, pos Pos) (*Statement, *scope) {
+	prefix, name := splitPrefix(ref)
+	m := r.module(s, prefix, pos)
+	if m == nil {
+		return nil, nil
+	}
+	if m != s.mod {
+		// only top-level definitions of other modules are visible
+		s = r.modScopes[m]
+	}
+	for ; s != nil; s = s.parent {
+		defs := s.typedefs
+		if keyword == "grouping" {
+			defs = s.groupings
+		}
+		if def := defs[name]; def != nil {
+			return def, r.defScopes[def]
+		}
+	}
+	r.errorf(pos, "%s %q not found", keyword, ref)
+	return nil, nil
+}
@@ -0,0 +1,426 @@
+package yang
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// typedef returns the resolved typedef defined by def
+func (r *resolver) typedef(def *Statement) *Typedef {
+	if td, ok := r.typedefs[def]; ok {
+		return td
+	}
+	if r.expanding[def] {
+		r.errorf(def.Pos, "typedef %q is derived from itself", def.Argument)
+		return nil
+	}
+	ts := def.Sub("type")
+	if ts == nil {
+		r.errorf(def.Pos, "typedef %q has no type", def.Argument)
+		r.typedefs[def] = nil
+		return nil
+	}
+	r.expanding[def] = true
+	s := r.defScopes[def]
+	t := r.resolveType(ts, s)
+	delete(r.expanding, def)
+	var td *Typedef
+	if t != nil {
+		td = &Typedef{Name: def.Argument, Module: s.mod, Type: t, Units: def.arg("units"), Default: def.arg("default"), Pos: def.Pos}
+	}
+	r.typedefs[def] = td
+	return td
+}
+
+// resolveType resolves the type statement ts in scope s
+func (r *resolver) resolveType(ts *Statement, s *scope) *Type {
+	t := &Type{Name: ts.Argument, Pos: ts.Pos}
+	prefix, name := splitPrefix(ts.Argument)
+	builtin := false
+	if kind, ok := typeKinds[name]; ok && prefix == "" {
+		builtin = true
+		t.Kind = kind
+		t.Range = builtinRange(kind)
+		t.RequireInstance = kind == TypeLeafref || kind == TypeInstanceIdentifier
+		if kind == TypeString || kind == TypeBinary {
+			t.Length = builtinLength()
+		}
+	} else {
+		def, _ := r.lookup(s, "typedef", ts.Argument, ts.Pos)
+		if def == nil {
+			return nil
+		}
+		td := r.typedef(def)
+		if td == nil {
+			return nil
+		}
+		base := td.Type
+		*t = Type{
+			Name:            ts.Argument,
+			Kind:            base.Kind,
+			Typedef:         td,
+			Range:           base.Range,
+			Length:          base.Length,
+			Patterns:        append([]*Pattern(nil), base.Patterns...),
+			Enums:           base.Enums,
+			Bits:            base.Bits,
+			FractionDigits:  base.FractionDigits,
+			Path:            base.Path,
+			RequireInstance: base.RequireInstance,
+			Bases:           base.Bases,
+			Union:           base.Union,
+			Pos:             ts.Pos,
+		}
+	}
+	restrict := func(keyword string, kinds ...TypeKind) *Statement {
+		st := ts.Sub(keyword)
+		if st == nil {
+			return nil
+		}
+		for _, k := range kinds {
+			if t.Kind == k {
+				return st
+			}
+		}
+		r.errorf(st.Pos, "%s is not valid for type %v", keyword, t.Kind)
+		return nil
+	}
+	if fd := ts.Sub("fraction-digits"); fd != nil {
+		v, err := strconv.Atoi(fd.Argument)
+		switch {
+		case !builtin || t.Kind != TypeDecimal64:
+			r.errorf(fd.Pos, "fraction-digits is only valid for the built-in decimal64 type")
+		case err != nil || v < 1 || v > 18:
+			r.errorf(fd.Pos, "invalid fraction-digits %q", fd.Argument)
+		default:
+			t.FractionDigits = v
+			t.Range = decimalRange(v)
+		}
+	} else if builtin && t.Kind == TypeDecimal64 {
+		r.errorf(ts.Pos, "decimal64 type requires fraction-digits")
+		return nil
+	}
+	numeric := []TypeKind{TypeInt8, TypeInt16, TypeInt32, TypeInt64, TypeUint8, TypeUint16, TypeUint32, TypeUint64, TypeDecimal64}
+	if st := restrict("range", numeric...); st != nil && t.Range != nil {
+		if res, err := parseRestriction(st.Argument, t.Range); err != nil {
+			r.errorf(st.Pos, "invalid range %q: %v", st.Argument, err)
+		} else {
+			res.ErrorMessage, res.ErrorAppTag = st.arg("error-message"), st.arg("error-app-tag")
+			t.Range = res
+		}
+	}
+	if st := restrict("length", TypeString, TypeBinary); st != nil {
+		if res, err := parseRestriction(st.Argument, t.Length); err != nil {
+			r.errorf(st.Pos, "invalid length %q: %v", st.Argument, err)
+		} else {
+			res.ErrorMessage, res.ErrorAppTag = st.arg("error-message"), st.arg("error-app-tag")
+			t.Length = res
+		}
+	}
+	if restrict("pattern", TypeString) != nil {
+		for _, st := range ts.Subs("pattern") {
+			re, err := CompilePattern(st.Argument)
+			if err != nil {
+				r.errorf(st.Pos, "invalid pattern %q: %v", st.Argument, err)
+				continue
+			}
+			p := &Pattern{Expr: st.Argument, ErrorMessage: st.arg("error-message"), ErrorAppTag: st.arg("error-app-tag"), re: re}
+			if m := st.Sub("modifier"); m != nil {
+				if m.Argument != "invert-match" {
+					r.errorf(m.Pos, "invalid modifier %q", m.Argument)
+				}
+				p.Invert = true
+			}
+			t.Patterns = append(t.Patterns, p)
+		}
+	}
+	if restrict("enum", TypeEnumeration) != nil {
+		t.Enums = r.enums(ts, t.Enums, s)
+	} else if builtin && t.Kind == TypeEnumeration {
+		r.errorf(ts.Pos, "enumeration type requires at least one enum")
+	}
+	if restrict("bit", TypeBits) != nil {
+		t.Bits = r.bits(ts, t.Bits, s)
+	} else if builtin && t.Kind == TypeBits {
+		r.errorf(ts.Pos, "bits type requires at least one bit")
+	}
+	if st := restrict("path", TypeLeafref); st != nil {
+		if !builtin {
+			r.errorf(st.Pos, "path can only be specified for the built-in leafref type")
+		}
+		t.Path = XPath{Expr: st.Argument, Prefixes: s.prefixes()}
+	} else if builtin && t.Kind == TypeLeafref {
+		r.errorf(ts.Pos, "leafref type requires a path")
+	}
+	if st := restrict("require-instance", TypeLeafref, TypeInstanceIdentifier); st != nil {
+		t.RequireInstance = r.boolean(st)
+	}
+	if restrict("base", TypeIdentityref) != nil {
+		if !builtin {
+			r.errorf(ts.Sub("base").Pos, "base can only be specified for the built-in identityref type")
+		}
+		t.Bases = nil
+		for _, st := range ts.Subs("base") {
+			if id := r.identity(s, st.Argument, st.Pos); id != nil {
+				t.Bases = append(t.Bases, id)
+			}
+		}
+	} else if builtin && t.Kind == TypeIdentityref {
+		r.errorf(ts.Pos, "identityref type requires a base")
+	}
+	if restrict("type", TypeUnion) != nil {
+		if !builtin {
+			r.errorf(ts.Sub("type").Pos, "member types can only be specified for the built-in union type")
+		}
+		for _, st := range ts.Subs("type") {
+			if member := r.resolveType(st, s); member != nil {
+				t.Union = append(t.Union, member)
+			}
+		}
+	} else if builtin && t.Kind == TypeUnion {
+		r.errorf(ts.Pos, "union type requires member types")
+	}
+	return t
+}
+
+// enums returns the enums of enumeration type ts, restricting base if
+// the type is derived
+func (r *resolver) enums(ts *Statement, base []Enum, s *scope) (enums []Enum) {
+	var next int64
+	for _, st := range ts.Subs("enum") {
+		if !r.enabled(st, s) {
+			continue
+		}
+		e := Enum{Name: st.Argument, Value: next}
+		if v := st.Sub("value"); v != nil {
+			n, err := strconv.ParseInt(v.Argument, 10, 32)
+			if err != nil {
+				r.errorf(v.Pos, "invalid enum value %q", v.Argument)
+				continue
+			}
+			e.Value = n
+		} else if len(base) > 0 {
+			for _, b := range base {
+				if b.Name == e.Name {
+					e.Value = b.Value
+				}
+			}
+		} else if next > math.MaxInt32 {
+			r.errorf(st.Pos, "enum %q requires a value", st.Argument)
+			continue
+		}
+		if base != nil && !containsEnum(base, e) {
+			r.errorf(st.Pos, "enum %q is not in the base type", st.Argument)
+			continue
+		}
+		if containsEnum(enums, Enum{Name: e.Name, Value: -1}) || containsValue(enums, e.Value) {
+			r.errorf(st.Pos, "duplicate enum %q", st.Argument)
+			continue
+		}
+		enums = append(enums, e)
+		if e.Value >= next {
+			next = e.Value + 1
+		}
+	}
+	return
+}
+
+func containsEnum(enums []Enum, e Enum) bool {
+	for _, v := range enums {
+		if v.Name == e.Name && (e.Value < 0 || v.Value == e.Value) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsValue(enums []Enum, value int64) bool {
+	for _, v := range enums {
+		if v.Value == value {
+			return true
+		}
+	}
+	return false
+}
+
+// bits returns the bits of bits type ts, restricting base if the type
+// is derived
+func (r *resolver) bits(ts *Statement, base []Bit, s *scope) (bits []Bit) {
+	var next int64
+	for _, st := range ts.Subs("bit") {
+		if !r.enabled(st, s) {
+			continue
+		}
+		pos := next
+		if p := st.Sub("position"); p != nil {
+			n, err := strconv.ParseUint(p.Argument, 10, 32)
+			if err != nil {
+				r.errorf(p.Pos, "invalid bit position %q", p.Argument)
+				continue
+			}
+			pos = int64(n)
+		} else if len(base) > 0 {
+			for _, b := range base {
+				if b.Name == st.Argument {
+					pos = int64(b.Position)
+				}
+			}
+		} else if next > math.MaxUint32 {
+			r.errorf(st.Pos, "bit %q requires a position", st.Argument)
+			continue
+		}
+		b := Bit{Name: st.Argument, Position: uint32(pos)}
+		found := base == nil
+		for _, v := range base {
+			found = found || v == b
+		}
+		if !found {
+			r.errorf(st.Pos, "bit %q is not in the base type", st.Argument)
+			continue
+		}
+		dup := false
+		for _, v := range bits {
+			dup = dup || v.Name == b.Name || v.Position == b.Position
+		}
+		if dup {
+			r.errorf(st.Pos, "duplicate bit %q", st.Argument)
+			continue
+		}
+		bits = append(bits, b)
+		if pos >= next {
+			next = pos + 1
+		}
+	}
+	return
+}
+
+// leafrefs resolves the targets of the leafref types of the leafs and
+// leaf-lists of the tree n
+func (r *resolver) leafrefs(n *Node) {
+	if n.Type != nil {
+		r.leafrefTarget(n, n.Type)
+	}
+	for _, c := range n.Children {
+		r.leafrefs(c)
+	}
+}
+
+// leafrefTarget resolves the target of t, the type of the node n, if a
+// leafref, or of the leafref member types of t if a union. Member types
+// may be shared with the typedef t derives from, so are copied before
+// their target (relative to n) is set.
+func (r *resolver) leafrefTarget(n *Node, t *Type) {
+	switch t.Kind {
+	case TypeLeafref:
+		if t.Target == nil {
+			t.Target = r.leafref(n, t)
+		}
+	case TypeUnion:
+		union := make([]*Type, len(t.Union))
+		for i, member := range t.Union {
+			if member.Kind == TypeLeafref || member.Kind == TypeUnion {
+				copied := *member
+				member = &copied
+				member.Target = nil
+				r.leafrefTarget(n, member)
+			}
+			union[i] = member
+		}
+		t.Union = union
+	}
+}
+
+// leafref returns the leaf or leaf-list identified by the path of the
+// leafref type t of the node n, ignoring the path's predicates
+func (r *resolver) leafref(n *Node, t *Type) *Node {
+	path := stripPredicates(t.Path.Expr)
+	var cur *Node
+	if strings.HasPrefix(path, "/") {
+		path = path[1:]
+	} else {
+		cur = n
+	}
+	for _, step := range strings.Split(path, "/") {
+		step = strings.TrimSpace(step)
+		if step == ".." {
+			if cur = dataParent(cur); cur == nil {
+				break
+			}
+			continue
+		}
+		prefix, name := splitPrefix(step)
+		var m *Module
+		if prefix != "" {
+			if m = t.Path.Prefixes[prefix]; m == nil {
+				r.errorf(t.Pos, "unknown prefix %q in leafref path %q", prefix, t.Path.Expr)
+				return nil
+			}
+		}
+		switch {
+		case cur == nil && m == nil:
+			m, cur = n.Module, n.Module.Root
+		case cur == nil:
+			cur = m.Root
+		case m == nil:
+			m = cur.Module
+		}
+		if cur = cur.DataChild(m, name); cur == nil {
+			break
+		}
+	}
+	if cur == nil || (cur.Kind != KindLeaf && cur.Kind != KindLeafList) {
+		r.errorf(t.Pos, "leafref path %q does not identify a leaf or leaf-list", t.Path.Expr)
+		return nil
+	}
+	return cur
+}
+
+// leafrefCycles reports the leafrefs of the tree n whose targets lead
+// back to themselves, clearing their targets
+func (r *resolver) leafrefCycles(n *Node) {
+	if t := n.Type; t != nil && t.Kind == TypeLeafref {
+		seen := map[*Node]bool{n: true}
+		for target := t.Target; target != nil && target.Type != nil && target.Type.Kind == TypeLeafref; target = target.Type.Target {
+			if seen[target] {
+				r.errorf(t.Pos, "leafref path %q forms a cycle", t.Path.Expr)
+				t.Target = nil
+				break
+			}
+			seen[target] = true
+		}
+	}
+	for _, c := range n.Children {
+		r.leafrefCycles(c)
+	}
+}
+
+// dataParent returns the parent data node of n (or the module root),
+// skipping choices, cases, inputs and outputs
+func dataParent(n *Node) *Node {
+	for n = n.Parent; n != nil; n = n.Parent {
+		switch n.Kind {
+		case KindChoice, KindCase, KindInput, KindOutput:
+		default:
+			return n
+		}
+	}
+	return nil
+}
+
+// stripPredicates returns the leafref path expr without its predicates
+func stripPredicates(expr string) string {
+	var b strings.Builder
+	depth := 0
+	for _, c := range expr {
+		switch {
+		case c == '[':
+			depth++
+		case c == ']' && depth > 0:
+			depth--
+		case depth == 0:
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,286 @@
+package yang
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// TypeKind is a YANG built-in type (RFC7950 section 4.2.4)
+type TypeKind int
+
+// Built-in types
+const (
+	TypeInt8 TypeKind = iota + 1
+	TypeInt16
+	TypeInt32
+	TypeInt64
+	TypeUint8
+	TypeUint16
+	TypeUint32
+	TypeUint64
+	TypeDecimal64
+	TypeString
+	TypeBoolean
+	TypeEnumeration
+	TypeBits
+	TypeBinary
+	TypeLeafref
+	TypeIdentityref
+	TypeEmpty
+	TypeUnion
+	TypeInstanceIdentifier
+)
+
+var typeKinds = map[string]TypeKind{
+	"int8": TypeInt8, "int16": TypeInt16, "int32": TypeInt32, "int64": TypeInt64,
+	"uint8": TypeUint8, "uint16": TypeUint16, "uint32": TypeUint32, "uint64": TypeUint64,
+	"decimal64": TypeDecimal64, "string": TypeString, "boolean": TypeBoolean,
+	"enumeration": TypeEnumeration, "bits": TypeBits, "binary": TypeBinary,
+	"leafref": TypeLeafref, "identityref": TypeIdentityref, "empty": TypeEmpty,
+	"union": TypeUnion, "instance-identifier": TypeInstanceIdentifier,
+}
+
+func (k TypeKind) String() string {
+	for name, kind := range typeKinds {
+		if kind == k {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// IsInteger returns true for the integer types
+func (k TypeKind) IsInteger() bool { return k >= TypeInt8 && k <= TypeUint64 }
+
+// Type is a resolved YANG type, holding the effective restrictions of
+// its typedef chain along with its own.
+type Type struct {
+	// Name is the type's name, as referenced (e.g., "inet:port-number")
+	Name string
+	// Kind is the type's built-in base type
+	Kind TypeKind
+	// Typedef is the typedef the type is derived from, if not built-in
+	Typedef *Typedef
+
+	// Range restricts numeric types, Length restricts string and
+	// binary types (in characters and octets, respectively)
+	Range  *Restriction
+	Length *Restriction
+	// Patterns restrict string types; values must match all patterns
+	Patterns []*Pattern
+	// Enums holds the enumeration type's values
+	Enums []Enum
+	// Bits holds the bits type's bits
+	Bits []Bit
+	// FractionDigits is the decimal64 type's fraction-digits
+	FractionDigits int
+	// Path is the leafref type's path
+	Path XPath
+	// Target is the leafref type's target leaf or leaf-list, the schema
+	// node identified by Path, whose type is that of the leafref's values
+	Target *Node
+	// RequireInstance is set for leafref and instance-identifier types
+	// requiring a referenced instance
+	RequireInstance bool
+	// Bases holds the identityref type's base identities
+	Bases []*Identity
+	// Union holds the union type's member types
+	Union []*Type
+
+	Pos Pos
+}
+
+// Restriction is a range or length restriction
+type Restriction struct {
+	Intervals    []Interval
+	ErrorMessage string
+	ErrorAppTag  string
+}
+
+// Interval is a closed interval of a Restriction
+type Interval struct{ Min, Max *big.Rat }
+
+// Contains returns true if v is within one of r's intervals
+func (r *Restriction) Contains(v *big.Rat) bool {
+	for _, i := range r.Intervals {
+		if v.Cmp(i.Min) >= 0 && v.Cmp(i.Max) <= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Restriction) String() string {
+	parts := make([]string, len(r.Intervals))
+	for i, iv := range r.Intervals {
+		parts[i] = ratString(iv.Min)
+		if iv.Min.Cmp(iv.Max) != 0 {
+			parts[i] += ".." + ratString(iv.Max)
+		}
+	}
+	return strings.Join(parts, " | ")
+}
+
+func ratString(r *big.Rat) string {
+	if r.IsInt() {
+		return r.Num().String()
+	}
+	return strings.TrimRight(r.FloatString(18), "0")
+}
+
+// Pattern is a pattern restriction
+type Pattern struct {
+	// Expr is the pattern's XML Schema regular expression
+	Expr string
+	// Invert is set for patterns with "invert-match" modifiers
+	Invert       bool
+	ErrorMessage string
+	ErrorAppTag  string
+	re           *regexp.Regexp
+}
+
+// Match returns true if s satisfies the pattern
+func (p *Pattern) Match(s string) bool { return p.re.MatchString(s) != p.Invert }
+
+// Enum is an enumeration value
+type Enum struct {
+	Name  string
+	Value int64
+}
+
+// Bit is a bits type bit
+type Bit struct {
+	Name     string
+	Position uint32
+}
+
+// builtinRange returns the range of values of integer kind k
+func builtinRange(k TypeKind) *Restriction {
+	var min, max string
+	switch k {
+	case TypeInt8:
+		min, max = "-128", "127"
+	case TypeInt16:
+		min, max = "-32768", "32767"
+	case TypeInt32:
+		min, max = "-2147483648", "2147483647"
+	case TypeInt64:
+		min, max = "-9223372036854775808", "9223372036854775807"
+	case TypeUint8:
+		min, max = "0", "255"
+	case TypeUint16:
+		min, max = "0", "65535"
+	case TypeUint32:
+		min, max = "0", "4294967295"
+	case TypeUint64:
+		min, max = "0", "18446744073709551615"
+	default:
+		return nil
+	}
+	lo, _ := new(big.Rat).SetString(min)
+	hi, _ := new(big.Rat).SetString(max)
+	return &Restriction{Intervals: []Interval{{lo, hi}}}
+}
+
+// decimalRange returns the range of a decimal64 with fraction digits fd
+func decimalRange(fd int) *Restriction {
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(fd)), nil)
+	hi := new(big.Rat).SetFrac(big.NewInt(9223372036854775807), scale)
+	lo := new(big.Rat).SetFrac(big.NewInt(-9223372036854775808), scale)
+	return &Restriction{Intervals: []Interval{{lo, hi}}}
+}
+
+// builtinLength is the length range of string and binary types
+func builtinLength() *Restriction {
+	hi, _ := new(big.Rat).SetString("18446744073709551615")
+	return &Restriction{Intervals: []Interval{{new(big.Rat), hi}}}
+}
+
+// parseRestriction parses the range or length expression arg, restricting base
+func parseRestriction(arg string, base *Restriction) (*Restriction, error) {
+	if base == nil || len(base.Intervals) == 0 {
+		return nil, fmt.Errorf("type cannot be restricted")
+	}
+	lowest, highest := base.Intervals[0].Min, base.Intervals[len(base.Intervals)-1].Max
+	bound := func(s string) (*big.Rat, error) {
+		switch s = strings.TrimSpace(s); s {
+		case "min":
+			return lowest, nil
+		case "max":
+			return highest, nil
+		}
+		if _, err := strconv.ParseFloat(s, 64); err != nil {
+			return nil, fmt.Errorf("invalid bound %q", s)
+		}
+		v, ok := new(big.Rat).SetString(s)
+		if !ok {
+			return nil, fmt.Errorf("invalid bound %q", s)
+		}
+		return v, nil
+	}
+	r := &Restriction{}
+	for _, part := range strings.Split(arg, "|") {
+		lo, hi := part, part
+		if i := strings.Index(part, ".."); i >= 0 {
+			lo, hi = part[:i], part[i+2:]
+		}
+		min, err := bound(lo)
+		if err != nil {
+			return nil, err
+		}
+		max, err := bound(hi)
+		if err != nil {
+			return nil, err
+		}
+		if min.Cmp(max) > 0 {
+			return nil, fmt.Errorf("invalid interval %q", strings.TrimSpace(part))
+		}
+		if n := len(r.Intervals); n > 0 && min.Cmp(r.Intervals[n-1].Max) <= 0 {
+			return nil, fmt.Errorf("intervals must be disjoint and ascending")
+		}
+		if !base.Contains(min) || !base.Contains(max) {
+			return nil, fmt.Errorf("%q is not within the base type's restriction %q", strings.TrimSpace(part), base)
+		}
+		r.Intervals = append(r.Intervals, Interval{min, max})
+	}
+	return r, nil
+}
+
+// CompilePattern compiles the XML Schema regular expression expr (as
+// used by pattern statements and the re-match XPath function), which is
+// implicitly anchored, to a Go regular expression. Character
+// class subtraction and the \i and \c escapes are not supported.
+func CompilePattern(expr string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString(`^(?:`)
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		switch {
+		case c == '\\' && i+1 < len(expr):
+			switch expr[i+1] {
+			case 'i', 'c', 'I', 'C':
+				return nil, fmt.Errorf("unsupported escape \\%c", expr[i+1])
+			case 'p', 'P':
+				// \p{IsBlock} block escapes are unsupported, while
+				// general category escapes are the same in Go
+				if strings.HasPrefix(expr[i+2:], "{Is") {
+					return nil, fmt.Errorf("unsupported block escape in %q", expr)
+				}
+			}
+			b.WriteString(expr[i : i+2])
+			i++
+		case c == '^' && (i == 0 || expr[i-1] != '['):
+			// XSD treats ^ and $ as literals outside character classes
+			b.WriteString(`\^`)
+		case c == '$':
+			b.WriteString(`\$`)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteString(`)$`)
+	return regexp.Compile(b.String())
+}